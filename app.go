@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os/user"
+	"strings"
+	"sync"
+	"time"
 
 	"cleanforge/internal/backup"
 	"cleanforge/internal/cleaner"
@@ -10,18 +14,46 @@ import (
 	"cleanforge/internal/memory"
 	"cleanforge/internal/monitor"
 	"cleanforge/internal/network"
+	"cleanforge/internal/plan"
 	"cleanforge/internal/privacy"
+	"cleanforge/internal/quarantine"
 	"cleanforge/internal/startup"
 	"cleanforge/internal/system"
 	"cleanforge/internal/toolkit"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type App struct {
 	ctx           context.Context
 	username      string
-	cleanerModule *cleaner.Cleaner
 	gamingModule  *gaming.GameBooster
 	startupModule *startup.StartupManager
+
+	// cleanerMu guards the cleaner and its settings, since
+	// ReloadCleanerConfig may replace it while a scan is running.
+	cleanerMu     sync.Mutex
+	cleanerModule *cleaner.Cleaner
+	configErr     error // problem loading the user's cleanup categories
+	quarantine    bool  // clean into quarantine.Default instead of deleting
+	audit         bool  // log every cleaned entry to cleaner.DefaultAudit
+	skipRunning   bool  // leave the caches of running browsers alone
+
+	// pending is the last plan handed to the frontend for confirmation.
+	// Only it can be executed, so the changes made are exactly the ones shown.
+	pendingMu sync.Mutex
+	pending   *plan.Plan
+
+	// cancelClean holds a cancel function for each scan or cleanup in
+	// progress, keyed by the number startClean gave it.
+	cleanMu     sync.Mutex
+	cancelClean map[int]context.CancelFunc
+	lastClean   int
+
+	// interrupted is the boost session an earlier run left in effect, until
+	// the user chooses to restore or keep it.
+	boostMu     sync.Mutex
+	interrupted *gaming.BoostStatus
 }
 
 func NewApp() *App {
@@ -37,9 +69,12 @@ func NewApp() *App {
 		}
 	}
 
+	c, configErr := newCleaner(username)
 	return &App{
 		username:      username,
-		cleanerModule: cleaner.NewCleaner(username),
+		cleanerModule: c,
+		configErr:     configErr,
+		skipRunning:   true,
 		gamingModule:  gaming.NewGameBooster(),
 		startupModule: startup.NewStartupManager(),
 	}
@@ -47,6 +82,63 @@ func NewApp() *App {
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.cleanerMu.Lock()
+	configErr := a.configErr
+	a.cleanerMu.Unlock()
+	if configErr != nil {
+		runtime.LogWarning(ctx, configErr.Error())
+	}
+	go a.retryPendingDeletes()
+	go a.detectBoost()
+}
+
+// retryPendingDeletes deletes the files earlier cleanups found in use.
+func (a *App) retryPendingDeletes() {
+	result, err := cleaner.DefaultPending.Retry()
+	if err != nil {
+		runtime.LogWarning(a.ctx, err.Error())
+		return
+	}
+	if result.DeletedFiles > 0 {
+		runtime.LogInfof(a.ctx, "Deleted %d files left over from earlier cleanups", result.DeletedFiles)
+	}
+}
+
+// detectBoost rebuilds the boost status. A boost session saved by an earlier
+// run that is still in effect was interrupted by a crash, a restart or the
+// app closing, so the frontend is told to offer restoring or keeping it.
+func (a *App) detectBoost() {
+	saved, err := gaming.LoadSession()
+	if err != nil {
+		runtime.LogWarning(a.ctx, err.Error())
+	}
+	status := a.gamingModule.DetectStatus()
+	if saved == nil || !status.Active {
+		return
+	}
+	a.boostMu.Lock()
+	a.interrupted = status
+	a.boostMu.Unlock()
+	runtime.EventsEmit(a.ctx, "boost:interrupted", status)
+}
+
+// newCleaner returns a cleaner with the built-in categories plus those in the
+// user's config file. If the file is invalid, the cleaner has only the
+// built-ins and the error says why. Files found in use are left to
+// cleaner.DefaultPending, and the caches of running browsers are left alone.
+func newCleaner(username string) (*cleaner.Cleaner, error) {
+	c := cleaner.NewCleaner(username)
+	c.SetPending(cleaner.DefaultPending)
+	c.SetSkipRunning(true)
+	return c, c.LoadConfig(cleaner.ConfigPath())
+}
+
+// currentCleaner returns the cleaner. Operations that run for a while keep
+// using it even if ReloadCleanerConfig replaces it meanwhile.
+func (a *App) currentCleaner() *cleaner.Cleaner {
+	a.cleanerMu.Lock()
+	defer a.cleanerMu.Unlock()
+	return a.cleanerModule
 }
 
 // ============================================================
@@ -61,12 +153,232 @@ func (a *App) GetSystemInfo() (*system.SystemInfo, error) {
 // Cleaner
 // ============================================================
 
+// ScanSystem and CleanSystem emit a "cleaner:progress" event with a
+// cleaner.CleanProgress as they go. CancelClean stops either one.
+
 func (a *App) ScanSystem() (*cleaner.ScanResult, error) {
-	return a.cleanerModule.Scan()
+	ctx, done := a.startClean()
+	defer done()
+	return a.currentCleaner().ScanContext(ctx, a.emitCleanProgress)
 }
 
 func (a *App) CleanSystem(categoryIDs []string) (*cleaner.CleanResult, error) {
-	return a.cleanerModule.Clean(categoryIDs)
+	ctx, done := a.startClean()
+	defer done()
+	return a.currentCleaner().CleanContext(ctx, categoryIDs, a.emitCleanProgress)
+}
+
+// CancelClean stops every scan and cleanup in progress.
+func (a *App) CancelClean() {
+	a.cleanMu.Lock()
+	defer a.cleanMu.Unlock()
+	for _, cancel := range a.cancelClean {
+		cancel()
+	}
+}
+
+// startClean returns a context that CancelClean cancels, and a function to
+// release it once the operation is over. Operations running at the same
+// time each keep their own, so one finishing leaves the others cancellable.
+func (a *App) startClean() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.cleanMu.Lock()
+	if a.cancelClean == nil {
+		a.cancelClean = make(map[int]context.CancelFunc)
+	}
+	a.lastClean++
+	id := a.lastClean
+	a.cancelClean[id] = cancel
+	a.cleanMu.Unlock()
+	return ctx, func() {
+		cancel()
+		a.cleanMu.Lock()
+		delete(a.cancelClean, id)
+		a.cleanMu.Unlock()
+	}
+}
+
+func (a *App) emitCleanProgress(p cleaner.CleanProgress) {
+	runtime.EventsEmit(a.ctx, "cleaner:progress", p)
+}
+
+// FindDuplicates searches roots for duplicate files, emitting progress like
+// ScanSystem. Clean the copies picked with PickDuplicates by passing
+// cleaner.DuplicatesID to PlanClean or CleanSystem.
+func (a *App) FindDuplicates(roots []string) (*cleaner.CleanCategory, error) {
+	ctx, done := a.startClean()
+	defer done()
+	return a.currentCleaner().FindDuplicatesContext(ctx, roots, a.emitCleanProgress)
+}
+
+func (a *App) PickDuplicates(remove []string) error {
+	return a.currentCleaner().PickDuplicates(cleaner.DuplicatesID, remove)
+}
+
+func (a *App) PlanClean(categoryIDs []string) (*plan.Plan, error) {
+	return a.confirm(a.currentCleaner().Plan(categoryIDs))
+}
+
+// ReloadCleanerConfig rereads the user's cleanup categories, so edits to the
+// config file apply without a restart.
+func (a *App) ReloadCleanerConfig() error {
+	c, err := newCleaner(a.username)
+
+	a.cleanerMu.Lock()
+	defer a.cleanerMu.Unlock()
+	a.cleanerModule = c
+	a.configErr = err
+	a.applyCleanerSettings()
+	return err
+}
+
+// applyCleanerSettings passes the App's cleaner settings on to the cleaner.
+// Callers must hold a.cleanerMu.
+func (a *App) applyCleanerSettings() {
+	c := a.cleanerModule
+	c.SetSkipRunning(a.skipRunning)
+	if a.quarantine {
+		c.SetQuarantine(quarantine.Default)
+	} else {
+		c.SetQuarantine(nil)
+	}
+	if a.audit {
+		c.SetAudit(cleaner.DefaultAudit)
+	} else {
+		c.SetAudit(nil)
+	}
+}
+
+// SetSkipRunningBrowsers makes later scans and cleanups leave out the caches
+// of browsers that are running.
+func (a *App) SetSkipRunningBrowsers(enabled bool) {
+	a.cleanerMu.Lock()
+	defer a.cleanerMu.Unlock()
+	a.skipRunning = enabled
+	a.applyCleanerSettings()
+}
+
+func (a *App) GetSkipRunningBrowsers() bool {
+	a.cleanerMu.Lock()
+	defer a.cleanerMu.Unlock()
+	return a.skipRunning
+}
+
+// GetBrowsers lists the installed browsers and their profiles.
+func (a *App) GetBrowsers() []cleaner.Browser {
+	return cleaner.DiscoverBrowsers(cleaner.SystemPaths(a.username))
+}
+
+// SetAuditLog makes later cleanups write a JSON Lines record of every entry
+// they remove to ~/.cleanforge/logs.
+func (a *App) SetAuditLog(enabled bool) {
+	a.cleanerMu.Lock()
+	defer a.cleanerMu.Unlock()
+	a.audit = enabled
+	a.applyCleanerSettings()
+}
+
+func (a *App) GetAuditLog() bool {
+	a.cleanerMu.Lock()
+	defer a.cleanerMu.Unlock()
+	return a.audit
+}
+
+// GetPendingDeletes lists the files earlier cleanups found in use.
+func (a *App) GetPendingDeletes() ([]cleaner.PendingFile, error) {
+	return cleaner.DefaultPending.Files()
+}
+
+// RetryPendingDeletes tries again to delete the files earlier cleanups found
+// in use. It also runs at startup.
+func (a *App) RetryPendingDeletes() (*cleaner.CleanResult, error) {
+	return cleaner.DefaultPending.Retry()
+}
+
+// ============================================================
+// Disk Usage
+// ============================================================
+
+// AnalyzeDiskUsage reports what takes up the space under root, emitting
+// "cleaner:progress" events like ScanSystem. CancelClean stops it.
+func (a *App) AnalyzeDiskUsage(root string) (*cleaner.UsageReport, error) {
+	ctx, done := a.startClean()
+	defer done()
+	return cleaner.AnalyzeUsage(ctx, root, cleaner.UsageOptions{}, a.emitCleanProgress)
+}
+
+// AnalyzeLowDisks analyzes every disk the health score flags for having
+// less than 10% free space.
+func (a *App) AnalyzeLowDisks() ([]*cleaner.UsageReport, error) {
+	info, err := system.GetSystemInfo()
+	if err != nil {
+		return nil, err
+	}
+	reports := []*cleaner.UsageReport{}
+	for _, d := range system.LowDisks(info) {
+		r, err := a.AnalyzeDiskUsage(driveRoot(d.Drive))
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// ExportDiskUsage saves a report as JSON and returns where it was written.
+// An empty path saves it under ~/.cleanforge/reports.
+func (a *App) ExportDiskUsage(report *cleaner.UsageReport, path string) (string, error) {
+	if path == "" {
+		path = report.DefaultPath()
+	}
+	return path, report.SaveJSON(path)
+}
+
+// driveRoot turns a drive such as "C:" into its root directory.
+func driveRoot(drive string) string {
+	if strings.HasSuffix(drive, `\`) || strings.HasSuffix(drive, "/") {
+		return drive
+	}
+	return drive + `\`
+}
+
+// ============================================================
+// Quarantine
+// ============================================================
+
+// SetQuarantineMode makes later cleanups move files into the quarantine
+// store instead of deleting them.
+func (a *App) SetQuarantineMode(enabled bool) {
+	a.cleanerMu.Lock()
+	defer a.cleanerMu.Unlock()
+	a.quarantine = enabled
+	a.applyCleanerSettings()
+}
+
+func (a *App) GetQuarantineMode() bool {
+	a.cleanerMu.Lock()
+	defer a.cleanerMu.Unlock()
+	return a.quarantine
+}
+
+func (a *App) GetQuarantineRuns() ([]*quarantine.Run, error) {
+	return quarantine.Default.Runs()
+}
+
+func (a *App) RestoreQuarantineItem(runID string, itemID int) error {
+	return quarantine.Default.RestoreItem(runID, itemID)
+}
+
+func (a *App) RestoreQuarantineRun(runID string) error {
+	return quarantine.Default.RestoreRun(runID)
+}
+
+func (a *App) PurgeQuarantineRun(runID string) error {
+	return quarantine.Default.Purge(runID)
+}
+
+func (a *App) PurgeExpiredQuarantine() ([]string, error) {
+	return quarantine.Default.PurgeExpired()
 }
 
 // ============================================================
@@ -89,14 +401,57 @@ func (a *App) ApplyGameProfile(profileID string) error {
 	return a.gamingModule.ApplyProfile(profileID)
 }
 
+func (a *App) PlanGameProfile(profileID string) (*plan.Plan, error) {
+	return a.confirm(a.gamingModule.PlanProfile(profileID))
+}
+
 func (a *App) RestoreGameSettings() error {
+	a.boostMu.Lock()
+	a.interrupted = nil
+	a.boostMu.Unlock()
 	return a.gamingModule.RestoreAll()
 }
 
+// GetInterruptedBoost returns the boost session an earlier run left in
+// effect, or nil once the user has chosen what to do with it.
+func (a *App) GetInterruptedBoost() *gaming.BoostStatus {
+	a.boostMu.Lock()
+	defer a.boostMu.Unlock()
+	return a.interrupted
+}
+
+// ResolveInterruptedBoost restores the settings of the interrupted boost
+// session, or keeps the boost going.
+func (a *App) ResolveInterruptedBoost(restore bool) error {
+	a.boostMu.Lock()
+	a.interrupted = nil
+	a.boostMu.Unlock()
+	if restore {
+		return a.gamingModule.RestoreAll()
+	}
+	return nil
+}
+
 func (a *App) GetAvailableTweaks() []gaming.TweakInfo {
 	return a.gamingModule.GetAvailableTweaks()
 }
 
+// ToggleGameTweak reverts a tweak that is in effect and applies one that is
+// not. A tweak that is only partly in effect is applied again; use
+// RevertGameTweak to undo what is left of it instead.
+func (a *App) ToggleGameTweak(tweakID string) error {
+	for _, t := range a.gamingModule.GetAvailableTweaks() {
+		if t.ID == tweakID && t.Applied {
+			return a.gamingModule.RevertTweak(tweakID)
+		}
+	}
+	return a.gamingModule.ApplyTweak(tweakID)
+}
+
+func (a *App) RevertGameTweak(tweakID string) error {
+	return a.gamingModule.RevertTweak(tweakID)
+}
+
 // ============================================================
 // Startup Manager
 // ============================================================
@@ -133,6 +488,10 @@ func (a *App) DisableNagle() error {
 	return network.DisableNagle()
 }
 
+func (a *App) PlanDisableNagle() (*plan.Plan, error) {
+	return a.confirm(network.PlanDisableNagle())
+}
+
 func (a *App) EnableNagle() error {
 	return network.EnableNagle()
 }
@@ -213,6 +572,10 @@ func (a *App) ApplyAllPrivacy() error {
 	return privacy.ApplyAll()
 }
 
+func (a *App) PlanAllPrivacy() (*plan.Plan, error) {
+	return a.confirm(privacy.PlanAll())
+}
+
 func (a *App) RestoreAllPrivacy() error {
 	return privacy.RestoreAll()
 }
@@ -252,3 +615,48 @@ func (a *App) HasBackup() bool {
 func (a *App) RestoreAllBackup() error {
 	return backup.RestoreAll()
 }
+
+func (a *App) GetRestorePoints() ([]backup.RestorePoint, error) {
+	return backup.RestorePoints()
+}
+
+func (a *App) DiffRestorePoint(id string) ([]backup.Change, error) {
+	return backup.Diff(id)
+}
+
+func (a *App) RestoreToPoint(id string) error {
+	return backup.RestoreTo(id)
+}
+
+func (a *App) PruneRestorePoints(maxAgeDays int, keep int) (int, error) {
+	removed, err := backup.Prune(time.Duration(maxAgeDays)*24*time.Hour, keep)
+	return len(removed), err
+}
+
+// ============================================================
+// Plans
+// ============================================================
+
+// confirm keeps a plan so ExecutePlan can apply it once the user has
+// reviewed it. It replaces any plan that was shown but not executed.
+func (a *App) confirm(p *plan.Plan, err error) (*plan.Plan, error) {
+	if err != nil {
+		return nil, err
+	}
+	a.pendingMu.Lock()
+	a.pending = p
+	a.pendingMu.Unlock()
+	return p, nil
+}
+
+func (a *App) ExecutePlan(id string) (*plan.Result, error) {
+	a.pendingMu.Lock()
+	p := a.pending
+	if p == nil || p.ID != id {
+		a.pendingMu.Unlock()
+		return nil, fmt.Errorf("plan %s is no longer pending", id)
+	}
+	a.pending = nil
+	a.pendingMu.Unlock()
+	return plan.Execute(p)
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/user"
+	"os/signal"
 	"strings"
+	"time"
 
 	"cleanforge/internal/cleaner"
 	"cleanforge/internal/gaming"
 	"cleanforge/internal/memory"
 	"cleanforge/internal/network"
+	"cleanforge/internal/plan"
 	"cleanforge/internal/privacy"
+	"cleanforge/internal/quarantine"
 	"cleanforge/internal/system"
 	"cleanforge/internal/toolkit"
 
@@ -35,6 +40,13 @@ func runCLI() {
 	fmt.Println("  ─────────────────────────────────────────────")
 	fmt.Println()
 
+	if result, err := cleaner.DefaultPending.Retry(); err != nil {
+		red.Printf("  Pending deletes: %v\n", err)
+	} else if result.DeletedFiles > 0 {
+		green.Printf("  ✓ Deleted %d files (%s) left over from earlier cleanups\n\n", result.DeletedFiles, formatBytesHuman(result.FreedSpace))
+	}
+	cliInterruptedBoost(green, yellow, red)
+
 	for {
 		prompt := promptui.Select{
 			Label: "What would you like to do?",
@@ -42,14 +54,16 @@ func runCLI() {
 				"🖥️  System Info",
 				"🧹 Quick Clean (Safe files only)",
 				"🧹 Full Scan & Clean",
+				"📑 Duplicate Finder",
 				"🎮 Game Boost",
 				"🌐 Network Optimizer",
 				"🛡️  Privacy Protection",
 				"🔧 System Tools",
 				"💾 Memory Optimizer",
+				"♻️  Quarantine",
 				"❌ Exit",
 			},
-			Size: 9,
+			Size: 11,
 		}
 
 		i, _, err := prompt.Run()
@@ -67,16 +81,20 @@ func runCLI() {
 		case 2:
 			cliFullClean(green, yellow, red)
 		case 3:
-			cliGameBoost(green, yellow, red)
+			cliDuplicates(green, yellow, red)
 		case 4:
-			cliNetwork(green, yellow)
+			cliGameBoost(green, yellow, red)
 		case 5:
-			cliPrivacy(green, yellow)
+			cliNetwork(green, yellow)
 		case 6:
-			cliToolkit(green, yellow, red)
+			cliPrivacy(green, yellow)
 		case 7:
-			cliMemory(green, yellow)
+			cliToolkit(green, yellow, red)
 		case 8:
+			cliMemory(green, yellow)
+		case 9:
+			cliQuarantine(green, yellow, red)
+		case 10:
 			green.Println("  Thanks for using CleanForge! 🔥")
 			os.Exit(0)
 		}
@@ -90,7 +108,20 @@ func cliSystemInfo(cyan, yellow *color.Color) {
 		color.Red("  Error: %v", err)
 		return
 	}
+	cliPrintSystemInfo(info, cyan)
 
+	for _, d := range system.LowDisks(info) {
+		fmt.Println()
+		yellow.Printf("  ⚠ %s has only %.0f%% free space\n", d.Drive, 100-d.UsagePercent)
+		prompt := promptui.Prompt{Label: "Find what is eating " + d.Drive, IsConfirm: true}
+		if _, err := prompt.Run(); err == nil {
+			cliDiskUsage(driveRoot(d.Drive), cyan, yellow)
+		}
+	}
+}
+
+// cliPrintSystemInfo prints the system information and the disks.
+func cliPrintSystemInfo(info *system.SystemInfo, cyan *color.Color) {
 	cyan.Println("  ═══ System Information ═══")
 	fmt.Printf("  OS:          %s\n", info.OS)
 	fmt.Printf("  Hostname:    %s\n", info.Hostname)
@@ -123,19 +154,72 @@ func cliSystemInfo(cyan, yellow *color.Color) {
 	}
 }
 
-func cliQuickClean(green, yellow, red *color.Color) {
-	u, _ := user.Current()
-	username := "User"
-	if u != nil {
-		username = u.Username
-		if idx := strings.LastIndex(username, "\\"); idx >= 0 {
-			username = username[idx+1:]
-		}
+// cliDiskUsage analyzes root and prints where its space goes, offering to
+// save the full report as JSON.
+func cliDiskUsage(root string, cyan, yellow *color.Color) {
+	yellow.Printf("  Analyzing %s... (Ctrl+C to cancel)\n", root)
+	var r *cleaner.UsageReport
+	err := cliWithProgress(func(ctx context.Context, progress cleaner.ProgressFunc) (err error) {
+		r, err = cleaner.AnalyzeUsage(ctx, root, cleaner.UsageOptions{Top: 10}, progress)
+		return err
+	})
+	if errors.Is(err, context.Canceled) {
+		yellow.Println("  Analysis cancelled.")
+		return
+	}
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return
+	}
+
+	cyan.Printf("  ═══ %s: %s in %d files ═══\n", r.Root, formatBytesHuman(r.Size), r.Files)
+	fmt.Println("\n  Largest folders:")
+	for _, n := range r.Tree.Children {
+		fmt.Printf("    %10s  %s\n", formatBytesHuman(n.Size), n.Path)
+	}
+	fmt.Println("\n  Largest files:")
+	for _, e := range r.LargestFiles {
+		fmt.Printf("    %10s  %s\n", formatBytesHuman(e.Size), e.Path)
+	}
+	fmt.Println("\n  By type:")
+	for _, g := range r.ByExtension {
+		fmt.Printf("    %10s  %s (%d files)\n", formatBytesHuman(g.Size), g.Name, g.Files)
 	}
+	fmt.Println("\n  By last change:")
+	for _, g := range r.ByAge {
+		fmt.Printf("    %10s  %s (%d files)\n", formatBytesHuman(g.Size), g.Name, g.Files)
+	}
+	if len(r.Warnings) > 0 {
+		yellow.Printf("\n  %d folders could not be read\n", len(r.Warnings))
+	}
+
+	save := promptui.Prompt{Label: "Save the full report as JSON", IsConfirm: true}
+	if _, err := save.Run(); err != nil {
+		return
+	}
+	path := r.DefaultPath()
+	if err := r.SaveJSON(path); err != nil {
+		color.Red("  Error: %v", err)
+		return
+	}
+	fmt.Printf("  ✓ Saved to %s\n", path)
+}
 
-	c := cleaner.NewCleaner(username)
-	yellow.Println("  Scanning safe categories...")
-	result, err := c.Scan()
+func cliQuickClean(green, yellow, red *color.Color) {
+	c, err := newCleaner(currentUsername())
+	if err != nil {
+		red.Printf("  Custom categories ignored: %v\n", err)
+	}
+	yellow.Println("  Scanning safe categories... (Ctrl+C to cancel)")
+	var result *cleaner.ScanResult
+	err = cliWithProgress(func(ctx context.Context, progress cleaner.ProgressFunc) (err error) {
+		result, err = c.ScanContext(ctx, progress)
+		return err
+	})
+	if errors.Is(err, context.Canceled) {
+		yellow.Println("  Scan cancelled.")
+		return
+	}
 	if err != nil {
 		red.Printf("  Scan error: %v\n", err)
 		return
@@ -154,37 +238,43 @@ func cliQuickClean(green, yellow, red *color.Color) {
 		return
 	}
 
-	prompt := promptui.Prompt{
-		Label:     "Clean these safe categories",
-		IsConfirm: true,
+	if !cliChooseRemoval(c) {
+		return
 	}
-
-	_, err = prompt.Run()
-	if err != nil {
+	var p *plan.Plan
+	err = cliWithProgress(func(ctx context.Context, progress cleaner.ProgressFunc) (err error) {
+		p, err = c.PlanContext(ctx, safeIDs, progress)
+		return err
+	})
+	if errors.Is(err, context.Canceled) {
+		yellow.Println("  Cancelled.")
 		return
 	}
-
-	cleanResult, err := c.Clean(safeIDs)
 	if err != nil {
 		red.Printf("  Clean error: %v\n", err)
 		return
 	}
-	green.Printf("  ✓ Freed %s (%d files deleted)\n", formatBytesHuman(cleanResult.FreedSpace), cleanResult.DeletedFiles)
+	if !cliConfirmPlan(p, "Clean these safe categories", yellow) {
+		return
+	}
+	cliCleanPlan(p, green, yellow, red)
 }
 
 func cliFullClean(green, yellow, red *color.Color) {
-	u, _ := user.Current()
-	username := "User"
-	if u != nil {
-		username = u.Username
-		if idx := strings.LastIndex(username, "\\"); idx >= 0 {
-			username = username[idx+1:]
-		}
+	c, err := newCleaner(currentUsername())
+	if err != nil {
+		red.Printf("  Custom categories ignored: %v\n", err)
+	}
+	yellow.Println("  Scanning all categories... (Ctrl+C to cancel)")
+	var result *cleaner.ScanResult
+	err = cliWithProgress(func(ctx context.Context, progress cleaner.ProgressFunc) (err error) {
+		result, err = c.ScanContext(ctx, progress)
+		return err
+	})
+	if errors.Is(err, context.Canceled) {
+		yellow.Println("  Scan cancelled.")
+		return
 	}
-
-	c := cleaner.NewCleaner(username)
-	yellow.Println("  Scanning all categories...")
-	result, err := c.Scan()
 	if err != nil {
 		red.Printf("  Scan error: %v\n", err)
 		return
@@ -200,9 +290,21 @@ func cliFullClean(green, yellow, red *color.Color) {
 			} else if cat.Risk == "medium" {
 				riskLabel = "MEDIUM"
 			}
-			items = append(items, fmt.Sprintf("[%s] %s - %s", riskLabel, cat.Name, formatBytesHuman(cat.Size)))
+			item := fmt.Sprintf("[%s] %s - %s", riskLabel, cat.Name, formatBytesHuman(cat.Size))
+			if cat.KeptFiles > 0 {
+				item += fmt.Sprintf(" (keeping %d recent or filtered files, %s)", cat.KeptFiles, formatBytesHuman(cat.KeptSize))
+			}
+			if len(cat.Items) > 1 {
+				for _, sub := range cat.Items {
+					item += fmt.Sprintf("\n        %s - %s", sub.Name, formatBytesHuman(sub.Size))
+				}
+			}
+			items = append(items, item)
 			ids = append(ids, cat.ID)
 		}
+		if cat.Busy != "" {
+			yellow.Printf("  Skipping %s: %s\n", cat.Name, cat.Busy)
+		}
 	}
 
 	if len(items) == 0 {
@@ -216,22 +318,273 @@ func cliFullClean(green, yellow, red *color.Color) {
 	}
 	yellow.Printf("\n  Total: %s in %d files\n", formatBytesHuman(result.TotalSize), result.TotalFiles)
 
-	prompt := promptui.Prompt{
-		Label:     "Clean all categories",
-		IsConfirm: true,
+	if !cliChooseRemoval(c) {
+		return
+	}
+	var p *plan.Plan
+	err = cliWithProgress(func(ctx context.Context, progress cleaner.ProgressFunc) (err error) {
+		p, err = c.PlanContext(ctx, ids, progress)
+		return err
+	})
+	if errors.Is(err, context.Canceled) {
+		yellow.Println("  Cancelled.")
+		return
+	}
+	if err != nil {
+		red.Printf("  Clean error: %v\n", err)
+		return
+	}
+	if !cliConfirmPlan(p, "Clean all categories", yellow) {
+		return
 	}
+	cliCleanPlan(p, green, yellow, red)
+}
 
-	_, err = prompt.Run()
+func cliDuplicates(green, yellow, red *color.Color) {
+	folders := promptui.Prompt{
+		Label:   "Folders to search (separated by ;)",
+		Default: `~\Downloads;~\Documents`,
+	}
+	input, err := folders.Run()
 	if err != nil {
 		return
 	}
+	var roots []string
+	for _, r := range strings.Split(input, ";") {
+		if r = strings.TrimSpace(r); r != "" {
+			roots = append(roots, r)
+		}
+	}
 
-	cleanResult, err := c.Clean(ids)
+	c := cleaner.NewCleaner("")
+	c.SetPending(cleaner.DefaultPending)
+	yellow.Println("  Searching for duplicates... (Ctrl+C to cancel)")
+	var cat *cleaner.CleanCategory
+	err = cliWithProgress(func(ctx context.Context, progress cleaner.ProgressFunc) (err error) {
+		cat, err = c.FindDuplicatesContext(ctx, roots, progress)
+		return err
+	})
+	if errors.Is(err, context.Canceled) {
+		yellow.Println("  Search cancelled.")
+		return
+	}
 	if err != nil {
+		red.Printf("  Search error: %v\n", err)
+		return
+	}
+	if len(cat.Duplicates) == 0 {
+		green.Println("  No duplicate files found!")
+		return
+	}
+
+	var wasted int64
+	for _, set := range cat.Duplicates {
+		wasted += set.Wasted
+	}
+	fmt.Printf("  Found %d sets of duplicates wasting %s:\n", len(cat.Duplicates), formatBytesHuman(wasted))
+	for _, set := range cat.Duplicates[:min(len(cat.Duplicates), 10)] {
+		fmt.Printf("    %d × %s  %s\n", len(set.Files), formatBytesHuman(set.Size), set.Files[0].Path)
+	}
+	if len(cat.Duplicates) > 10 {
+		fmt.Printf("    … and %d more\n", len(cat.Duplicates)-10)
+	}
+
+	mode := promptui.Select{
+		Label: "Which copies should be kept?",
+		Items: []string{"📅 The oldest copy of each set", "👆 Choose the copy to keep in each set", "⬅️  Back"},
+	}
+	m, _, err := mode.Run()
+	if err != nil || m == 2 {
+		return
+	}
+	if m == 1 {
+		var remove []string
+		for _, set := range cat.Duplicates {
+			var names []string
+			for _, f := range set.Files {
+				names = append(names, fmt.Sprintf("%s (%s)", f.Path, f.ModTime.Local().Format("2006-01-02 15:04")))
+			}
+			pick := promptui.Select{Label: fmt.Sprintf("Keep which copy (%s each)?", formatBytesHuman(set.Size)), Items: names, Size: 10}
+			keep, _, err := pick.Run()
+			if err != nil {
+				return
+			}
+			for i, f := range set.Files {
+				if i != keep {
+					remove = append(remove, f.Path)
+				}
+			}
+		}
+		if err := c.PickDuplicates(cleaner.DuplicatesID, remove); err != nil {
+			red.Printf("  Error: %v\n", err)
+			return
+		}
+	}
+
+	if !cliChooseRemoval(c) {
+		return
+	}
+	p, err := c.Plan([]string{cleaner.DuplicatesID})
+	if err != nil {
+		red.Printf("  Clean error: %v\n", err)
+		return
+	}
+	if !cliConfirmPlan(p, "Remove these duplicate copies", yellow) {
+		return
+	}
+	cliCleanPlan(p, green, yellow, red)
+}
+
+// cliCleanPlan executes a confirmed cleanup plan and reports what it freed.
+// Ctrl+C stops it after the entry being deleted.
+func cliCleanPlan(p *plan.Plan, green, yellow, red *color.Color) {
+	var result *cleaner.CleanResult
+	err := cliWithProgress(func(ctx context.Context, progress cleaner.ProgressFunc) (err error) {
+		result, err = cleaner.ExecuteContext(ctx, p, progress)
+		return err
+	})
+	cliCleanReport(p, result, err, green, yellow, red)
+}
+
+// cliCleanReport prints what executing a cleanup plan did, or the error that
+// kept it from starting if result is nil.
+func cliCleanReport(p *plan.Plan, result *cleaner.CleanResult, err error, green, yellow, red *color.Color) {
+	if result == nil {
 		red.Printf("  Clean error: %v\n", err)
 		return
 	}
-	green.Printf("  ✓ Freed %s (%d files deleted)\n", formatBytesHuman(cleanResult.FreedSpace), cleanResult.DeletedFiles)
+	quarantined := false
+	for _, a := range p.Actions {
+		quarantined = quarantined || a.Kind == plan.KindQuarantine
+	}
+	if quarantined {
+		green.Printf("  ✓ Moved %s to quarantine (%d files); restore it from the Quarantine menu\n", formatBytesHuman(result.FreedSpace), result.DeletedFiles)
+	} else {
+		green.Printf("  ✓ Freed %s (%d files deleted)\n", formatBytesHuman(result.FreedSpace), result.DeletedFiles)
+	}
+	if len(result.Categories) > 1 {
+		for _, cat := range result.Categories {
+			line := fmt.Sprintf("    %s: %s in %d files", cat.ID, formatBytesHuman(cat.FreedSpace), cat.DeletedFiles)
+			if cat.Skipped > 0 {
+				line += fmt.Sprintf(", %d skipped", cat.Skipped)
+			}
+			if len(cat.Errors) > 0 {
+				line += fmt.Sprintf(", %d errors", len(cat.Errors))
+			}
+			fmt.Println(line)
+		}
+	}
+	fmt.Printf("  Took %s\n", time.Duration(result.DurationMs)*time.Millisecond)
+	if len(result.Errors) > 0 {
+		red.Printf("  ✗ %d items could not be deleted\n", len(result.Errors))
+		for _, class := range []cleaner.ErrorClass{cleaner.ErrorInUse, cleaner.ErrorAccessDenied, cleaner.ErrorNotFound, cleaner.ErrorPathTooLong, cleaner.ErrorOther} {
+			if n := result.ErrorCounts[class]; n > 0 {
+				fmt.Printf("    %s: %d\n", strings.ReplaceAll(string(class), "_", " "), n)
+			}
+		}
+	}
+	if result.Pending > 0 {
+		yellow.Printf("  %d files in use will be deleted the next time CleanForge starts\n", result.Pending)
+	}
+	if len(result.Skips) > 0 {
+		yellow.Printf("  %d items were left in place by the safety guard\n", len(result.Skips))
+		for _, s := range result.Skips {
+			fmt.Printf("    %s: %s\n", strings.ReplaceAll(string(s.Reason), "_", " "), s.Path)
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		yellow.Println("  Cancelled; the remaining items were left in place.")
+	}
+}
+
+// cliChooseRemoval asks whether cleaned files should be deleted or moved to
+// quarantine, and sets up c accordingly. It returns false if the user backs out.
+func cliChooseRemoval(c *cleaner.Cleaner) bool {
+	prompt := promptui.Select{
+		Label: "How should files be removed?",
+		Items: []string{
+			fmt.Sprintf("♻️  Move to quarantine (restorable for %d days)", int(quarantine.DefaultRetention.Hours()/24)),
+			"🗑️  Delete permanently",
+		},
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		return false
+	}
+	if i == 0 {
+		c.SetQuarantine(quarantine.Default)
+	} else {
+		c.SetQuarantine(nil)
+	}
+	return true
+}
+
+// cliWithProgress runs fn with a context that Ctrl+C cancels, drawing a
+// progress bar from the reports fn passes on and clearing it afterwards.
+func cliWithProgress(fn func(ctx context.Context, progress cleaner.ProgressFunc) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := fn(ctx, cliProgressBar)
+	fmt.Printf("\r%s\r", strings.Repeat(" ", cliProgressWidth))
+	return err
+}
+
+// cliProgressWidth is the width of the progress line, bar and label included.
+const cliProgressWidth = 79
+
+// cliProgressBar redraws the progress line in place.
+func cliProgressBar(p cleaner.CleanProgress) {
+	const barWidth = 24
+	filled := int(p.Percentage / 100 * barWidth)
+	if filled > barWidth {
+		filled = barWidth
+	}
+	label := p.Category
+	if p.CurrentFile != "" {
+		label += ": " + p.CurrentFile
+	}
+	line := fmt.Sprintf("  [%s%s] %3.0f%% %s",
+		strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled), p.Percentage, label)
+	r := []rune(line)
+	if len(r) > cliProgressWidth {
+		r = append(r[:cliProgressWidth-1], '…')
+	}
+	fmt.Print("\r" + string(r) + strings.Repeat(" ", cliProgressWidth-len(r)))
+}
+
+// cliInterruptedBoost offers to restore or keep a boost session that an
+// earlier run left in effect.
+func cliInterruptedBoost(green, yellow, red *color.Color) {
+	saved, err := gaming.LoadSession()
+	if err != nil {
+		red.Printf("  Boost session: %v\n", err)
+		return
+	}
+	if saved == nil {
+		return
+	}
+	gb := gaming.NewGameBooster()
+	status := gb.DetectStatus()
+	if !status.Active {
+		return
+	}
+
+	yellow.Printf("  A Game Boost session (%s, %d tweaks) is still active from an earlier run\n", status.Profile, len(status.TweaksApplied))
+	prompt := promptui.Select{
+		Label: "Interrupted Game Boost",
+		Items: []string{"Restore Original Settings", "Keep the Boost"},
+	}
+	i, _, err := prompt.Run()
+	if err != nil || i == 1 {
+		fmt.Println()
+		return
+	}
+	if err := gb.RestoreAll(); err != nil {
+		red.Printf("  Error: %v\n\n", err)
+	} else {
+		green.Println("  ✓ All settings restored!")
+		fmt.Println()
+	}
 }
 
 func cliGameBoost(green, yellow, red *color.Color) {
@@ -243,16 +596,17 @@ func cliGameBoost(green, yellow, red *color.Color) {
 	}
 
 	profiles := gb.GetProfiles()
-	items := make([]string, len(profiles)+1)
+	items := make([]string, len(profiles)+2)
 	for i, p := range profiles {
 		items[i] = fmt.Sprintf("%s - %s", p.Name, p.Description)
 	}
-	items[len(profiles)] = "Restore Original Settings"
+	items[len(profiles)] = "Revert a Tweak"
+	items[len(profiles)+1] = "Restore Original Settings"
 
 	prompt := promptui.Select{
 		Label: "Select Game Profile",
 		Items: items,
-		Size:  7,
+		Size:  8,
 	}
 
 	i, _, err := prompt.Run()
@@ -261,6 +615,11 @@ func cliGameBoost(green, yellow, red *color.Color) {
 	}
 
 	if i == len(profiles) {
+		cliRevertTweak(gb, green, yellow, red)
+		return
+	}
+
+	if i == len(profiles)+1 {
 		yellow.Println("  Restoring original settings...")
 		if err := gb.RestoreAll(); err != nil {
 			red.Printf("  Error: %v\n", err)
@@ -270,14 +629,57 @@ func cliGameBoost(green, yellow, red *color.Color) {
 		return
 	}
 
+	p, err := gb.PlanProfile(profiles[i].ID)
+	if err != nil {
+		red.Printf("  Error: %v\n", err)
+		return
+	}
+	if !cliConfirmPlan(p, "Apply "+profiles[i].Name+" profile", yellow) {
+		return
+	}
+
 	yellow.Printf("  Applying %s profile...\n", profiles[i].Name)
-	if err := gb.ApplyProfile(profiles[i].ID); err != nil {
+	if err := cliExecutePlan(p); err != nil {
 		red.Printf("  Error: %v\n", err)
 	} else {
 		green.Printf("  ✓ %s profile applied!\n", profiles[i].Name)
 	}
 }
 
+// cliRevertTweak lets the user pick one applied Game Boost tweak and undoes it.
+func cliRevertTweak(gb *gaming.GameBooster, green, yellow, red *color.Color) {
+	var on []gaming.TweakInfo
+	for _, t := range gb.GetAvailableTweaks() {
+		if t.Applied || t.Revertible {
+			on = append(on, t)
+		}
+	}
+	if len(on) == 0 {
+		yellow.Println("  No tweaks to revert")
+		return
+	}
+
+	items := make([]string, len(on))
+	for i, t := range on {
+		items[i] = fmt.Sprintf("%s - %s", t.Name, t.Description)
+	}
+	prompt := promptui.Select{
+		Label: "Select Tweak to Revert",
+		Items: items,
+		Size:  10,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		return
+	}
+
+	if err := gb.RevertTweak(on[i].ID); err != nil {
+		red.Printf("  Error: %v\n", err)
+	} else {
+		green.Printf("  ✓ %s reverted!\n", on[i].Name)
+	}
+}
+
 func cliNetwork(green, yellow *color.Color) {
 	prompt := promptui.Select{
 		Label: "Network Optimizer",
@@ -322,7 +724,18 @@ func cliNetwork(green, yellow *color.Color) {
 		network.ResetDNS()
 		green.Println("  ✓ DNS reset to DHCP")
 	case 4:
-		network.DisableNagle()
+		p, err := network.PlanDisableNagle()
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
+		if !cliConfirmPlan(p, "Disable Nagle", yellow) {
+			return
+		}
+		if err := cliExecutePlan(p); err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
 		green.Println("  ✓ Nagle disabled (lower latency)")
 	case 5:
 		network.EnableNagle()
@@ -371,8 +784,19 @@ func cliPrivacy(green, yellow *color.Color) {
 			fmt.Printf("  %s %s - %s\n", status, t.Name, t.Description)
 		}
 	case 1:
+		p, err := privacy.PlanAll()
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
+		if !cliConfirmPlan(p, "Apply all protections", yellow) {
+			return
+		}
 		yellow.Println("  Applying all privacy protections...")
-		privacy.ApplyAll()
+		if err := cliExecutePlan(p); err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
 		green.Println("  ✓ All protections applied!")
 	case 2:
 		yellow.Println("  Restoring defaults...")
@@ -475,27 +899,90 @@ func cliToolkit(green, yellow, red *color.Color) {
 	}
 }
 
-func cliMemory(green, yellow *color.Color) {
-	status, err := memory.GetMemoryStatus()
+func cliQuarantine(green, yellow, red *color.Color) {
+	store := quarantine.Default
+	runs, err := store.Runs()
 	if err != nil {
-		color.Red("  Error: %v", err)
+		red.Printf("  Error: %v\n", err)
+		return
+	}
+	if len(runs) == 0 {
+		yellow.Println("  Quarantine is empty.")
 		return
 	}
 
-	fmt.Printf("  RAM: %s / %s (%.1f%%)\n",
-		formatBytesHuman(int64(status.Used)),
-		formatBytesHuman(int64(status.Total)),
-		status.UsagePercent)
+	items := make([]string, 0, len(runs)+2)
+	for _, r := range runs {
+		size, files := r.Size()
+		created := r.Created
+		if t, err := time.Parse(time.RFC3339, created); err == nil {
+			created = t.Local().Format("2006-01-02 15:04")
+		}
+		items = append(items, fmt.Sprintf("%s  %s — %s in %d files", created, r.Description, formatBytesHuman(size), files))
+	}
+	items = append(items, "🧹 Purge expired runs", "⬅️  Back")
 
-	if len(status.TopProcesses) > 0 {
-		fmt.Println("\n  Top Memory Consumers:")
-		for i, p := range status.TopProcesses {
-			if i >= 5 {
-				break
+	prompt := promptui.Select{Label: "Quarantined cleanups", Items: items, Size: 10}
+	i, _, err := prompt.Run()
+	if err != nil || i == len(runs)+1 {
+		return
+	}
+	if i == len(runs) {
+		purged, err := store.PurgeExpired()
+		if err != nil {
+			red.Printf("  Error: %v\n", err)
+			return
+		}
+		green.Printf("  ✓ Purged %d expired runs\n", len(purged))
+		return
+	}
+
+	run := runs[i]
+	actions := promptui.Select{
+		Label: run.Description,
+		Items: []string{"↩️  Restore everything", "↩️  Restore one item", "🗑️  Delete permanently", "⬅️  Back"},
+	}
+	a, _, err := actions.Run()
+	if err != nil {
+		return
+	}
+	switch a {
+	case 0:
+		err = store.RestoreRun(run.ID)
+	case 1:
+		var names []string
+		var ids []int
+		for _, it := range run.Items {
+			if !it.Restored {
+				names = append(names, fmt.Sprintf("%s (%s)", it.Original, formatBytesHuman(it.Size)))
+				ids = append(ids, it.ID)
 			}
-			fmt.Printf("    %d. %s - %s (%.1f%%)\n", i+1, p.Name, formatBytesHuman(int64(p.Memory)), p.Percent)
 		}
+		pick := promptui.Select{Label: "Item to restore", Items: names, Size: 10}
+		n, _, perr := pick.Run()
+		if perr != nil {
+			return
+		}
+		err = store.RestoreItem(run.ID, ids[n])
+	case 2:
+		err = store.Purge(run.ID)
+	default:
+		return
+	}
+	if err != nil {
+		red.Printf("  Error: %v\n", err)
+		return
+	}
+	green.Println("  ✓ Done")
+}
+
+func cliMemory(green, yellow *color.Color) {
+	status, err := memory.GetMemoryStatus()
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return
 	}
+	cliPrintMemoryStatus(status)
 
 	prompt := promptui.Select{
 		Label: "Memory Optimizer",
@@ -526,6 +1013,67 @@ func cliMemory(green, yellow *color.Color) {
 	}
 }
 
+// cliPrintMemoryStatus prints memory use and the five largest processes.
+func cliPrintMemoryStatus(status *memory.MemoryStatus) {
+	fmt.Printf("  RAM: %s / %s (%.1f%%)\n",
+		formatBytesHuman(int64(status.Used)),
+		formatBytesHuman(int64(status.Total)),
+		status.UsagePercent)
+
+	if len(status.TopProcesses) > 0 {
+		fmt.Println("\n  Top Memory Consumers:")
+		for i, p := range status.TopProcesses {
+			if i >= 5 {
+				break
+			}
+			fmt.Printf("    %d. %s - %s (%.1f%%)\n", i+1, p.Name, formatBytesHuman(int64(p.Memory)), p.Percent)
+		}
+	}
+}
+
+// cliConfirmPlan prints every change in a plan and asks before applying it.
+func cliConfirmPlan(p *plan.Plan, label string, yellow *color.Color) bool {
+	if !cliPrintPlan(p, yellow) {
+		return false
+	}
+	fmt.Println()
+
+	prompt := promptui.Prompt{Label: label, IsConfirm: true}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+// cliPrintPlan prints a plan's warnings and every change in it. It returns
+// false if there is nothing to change.
+func cliPrintPlan(p *plan.Plan, yellow *color.Color) bool {
+	for _, w := range p.Warnings {
+		yellow.Printf("  ⚠ %s\n", w)
+	}
+	if len(p.Actions) == 0 {
+		yellow.Println("  Nothing to change.")
+		return false
+	}
+
+	fmt.Printf("  %s — %d changes:\n", p.Description, len(p.Actions))
+	for _, a := range p.Actions {
+		if a.Kind == plan.KindDelete || a.Kind == plan.KindQuarantine {
+			fmt.Printf("    • %s (%s)\n", a, formatBytesHuman(a.Size))
+		} else {
+			fmt.Printf("    • %s\n", a)
+		}
+	}
+	return true
+}
+
+// cliExecutePlan applies a confirmed plan, folding its failures into one error.
+func cliExecutePlan(p *plan.Plan) error {
+	result, err := plan.Execute(p)
+	if err != nil {
+		return err
+	}
+	return result.Err()
+}
+
 func formatBytesHuman(bytes int64) string {
 	if bytes == 0 {
 		return "0 B"
@@ -0,0 +1,801 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"cleanforge/internal/backup"
+	"cleanforge/internal/cleaner"
+	"cleanforge/internal/gaming"
+	"cleanforge/internal/memory"
+	"cleanforge/internal/network"
+	"cleanforge/internal/output"
+	"cleanforge/internal/plan"
+	"cleanforge/internal/privacy"
+	"cleanforge/internal/quarantine"
+	"cleanforge/internal/system"
+
+	"github.com/fatih/color"
+)
+
+// Exit codes of the command-line interface. Scripts rely on them, so a code
+// never changes meaning.
+const (
+	exitOK           = 0   // everything asked for was done
+	exitFailed       = 1   // the command failed
+	exitUsage        = 2   // the command line is wrong
+	exitPartial      = 3   // the command ran, but some changes failed
+	exitNotConfirmed = 4   // nothing was changed, as --yes was not given
+	exitCancelled    = 130 // interrupted with Ctrl+C
+)
+
+// command is one command of the command-line interface.
+type command struct {
+	name    string // the words that select it, e.g. "boost apply"
+	args    string // what follows the name in the usage line
+	summary string
+	run     func(args []string) int
+}
+
+// commands returns the commands in the order help lists them.
+func commands() []command {
+	return []command{
+		{"interactive", "", "Open the interactive menu", cmdInteractive},
+		{"daemon", "", "Run the scheduled cleanup policies until interrupted", cmdDaemon},
+		{"info", "", "Show system information", cmdInfo},
+		{"scan", "[--categories IDS]", "Measure what each cleanup category would free", cmdScan},
+		{"clean", "--categories IDS [--older-than AGE] [--quarantine] [--audit] [--yes]", "Clean categories; without --yes only print the plan", cmdClean},
+		{"boost list", "", "List the game profiles", cmdBoostList},
+		{"boost apply", "PROFILE [--yes]", "Apply a game profile; without --yes only print the plan", cmdBoostApply},
+		{"boost status", "", "Show whether a game profile is applied", cmdBoostStatus},
+		{"boost tweaks", "", "List the Game Boost tweaks and whether they are on", cmdBoostTweaks},
+		{"boost revert", "TWEAK... --yes", "Undo individual Game Boost tweaks", cmdBoostRevert},
+		{"boost restore", "--yes", "Undo every Game Boost change", cmdBoostRestore},
+		{"privacy list", "", "List the privacy protections and whether they are on", cmdPrivacyList},
+		{"privacy apply", "--all | ID... [--yes]", "Turn privacy protections on; without --yes only print the plan", cmdPrivacyApply},
+		{"privacy restore", "--yes", "Restore the Windows privacy defaults", cmdPrivacyRestore},
+		{"network status", "", "Show the active adapter, its DNS servers and Nagle", cmdNetworkStatus},
+		{"dns list", "", "List the DNS presets", cmdDNSList},
+		{"dns set", "PRESET --yes", "Use a DNS preset on the active adapter", cmdDNSSet},
+		{"dns reset", "--yes", "Get the DNS servers from DHCP again", cmdDNSReset},
+		{"memory status", "", "Show memory use and the largest processes", cmdMemoryStatus},
+		{"quarantine list", "", "List the cleanups kept in quarantine", cmdQuarantineList},
+		{"restore", "--list | [--point ID] --yes", "Undo every recorded change, or those since a restore point", cmdRestore},
+		{"help", "", "Show this help", cmdHelp},
+	}
+}
+
+// runCommand runs the command args name and returns its exit code.
+func runCommand(args []string) int {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		return cmdHelp(nil)
+	}
+	var found *command
+	var words int
+	for _, c := range commands() {
+		name := strings.Fields(c.name)
+		if len(name) > words && len(args) >= len(name) && strings.Join(args[:len(name)], " ") == c.name {
+			found, words = &c, len(name)
+		}
+	}
+	if found == nil {
+		fmt.Fprintf(os.Stderr, "cleanforge: unknown command %q\n\n", strings.Join(args, " "))
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+	return found.run(args[words:])
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: cleanforge [COMMAND] [FLAGS]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Without a command CleanForge opens its window.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands() {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", c.name, c.args, c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands that print a result take --output json|yaml|table, or --json for short.")
+	fmt.Fprintln(w, "Exit codes: 0 done, 1 failed, 2 bad command line, 3 some changes failed,")
+	fmt.Fprintln(w, "4 plan printed but not applied (add --yes), 130 interrupted.")
+}
+
+// newFlags returns the flag set of the command name, with the --output flag
+// every command takes and --json, short for --output json.
+func newFlags(name string) (*flag.FlagSet, *output.Format) {
+	fs := flag.NewFlagSet("cleanforge "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	out := output.Table
+	fs.Var(&out, "output", "print the result as json, yaml or a table")
+	fs.BoolFunc("json", "short for --output json", func(string) error {
+		out = output.JSON
+		return nil
+	})
+	return fs, &out
+}
+
+// parseFlags parses args with fs, allowing flags after the positional
+// arguments, and returns the positional arguments. fs reports errors itself.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// parseNoArgs is parseFlags for commands that take no positional arguments.
+// It returns the exit code to stop with, or -1 to go on.
+func parseNoArgs(fs *flag.FlagSet, args []string) int {
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return usageExit(err)
+	}
+	if len(rest) > 0 {
+		return usageError("unexpected argument %q", rest[0])
+	}
+	return -1
+}
+
+// usageExit returns the exit code for a flag parsing error: -h and --help
+// succeed.
+func usageExit(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
+
+func usageError(format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "cleanforge: "+format+"\n", args...)
+	return exitUsage
+}
+
+// fail reports err on stderr and returns the exit code for it.
+func fail(err error) int {
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "cleanforge: cancelled")
+		return exitCancelled
+	}
+	fmt.Fprintf(os.Stderr, "cleanforge: %v\n", err)
+	return exitFailed
+}
+
+// printResult writes v to stdout as JSON or YAML.
+func printResult(out output.Format, v interface{}) int {
+	if err := output.Write(os.Stdout, out, v); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+// doneMessage is what commands that only change something print as JSON or
+// YAML.
+type doneMessage struct {
+	Command string `json:"command"`
+	Message string `json:"message"`
+}
+
+// succeed reports that the command name did what message says.
+func succeed(name, message string, out output.Format) int {
+	if out.Structured() {
+		return printResult(out, doneMessage{Command: name, Message: message})
+	}
+	green, _, _ := cmdColors()
+	green.Printf("  ✓ %s\n", message)
+	return exitOK
+}
+
+// cmdColors returns the colors the interactive menu uses for success,
+// warnings and errors. They are turned off when stdout is not a terminal.
+func cmdColors() (green, yellow, red *color.Color) {
+	return color.New(color.FgHiGreen, color.Bold), color.New(color.FgHiYellow), color.New(color.FgHiRed)
+}
+
+// interruptible returns a context that Ctrl+C cancels.
+func interruptible() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+func cmdInteractive(args []string) int {
+	if code := parseNoArgs(flag.NewFlagSet("cleanforge interactive", flag.ContinueOnError), args); code >= 0 {
+		return code
+	}
+	runCLI()
+	return exitOK
+}
+
+func cmdDaemon(args []string) int {
+	if code := parseNoArgs(flag.NewFlagSet("cleanforge daemon", flag.ContinueOnError), args); code >= 0 {
+		return code
+	}
+	return runDaemon()
+}
+
+func cmdHelp([]string) int {
+	printUsage(os.Stdout)
+	return exitOK
+}
+
+func cmdInfo(args []string) int {
+	fs, out := newFlags("info")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	info, err := system.GetSystemInfo()
+	if err != nil {
+		return fail(err)
+	}
+	if out.Structured() {
+		return printResult(*out, info)
+	}
+	cliPrintSystemInfo(info, color.New(color.FgHiCyan))
+	return exitOK
+}
+
+// cmdCleaner returns the cleaner of the current user, warning on stderr if
+// the custom categories could not be loaded.
+func cmdCleaner() *cleaner.Cleaner {
+	c, err := newCleaner(currentUsername())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cleanforge: custom categories ignored: %v\n", err)
+	}
+	return c
+}
+
+// splitCategories splits a comma-separated list of category IDs, checking
+// that c has every one.
+func splitCategories(c *cleaner.Cleaner, list string) ([]string, error) {
+	known := make(map[string]bool)
+	for _, cat := range c.Categories() {
+		known[cat.ID] = true
+	}
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if !known[id] {
+			return nil, fmt.Errorf("unknown category %q; cleanforge scan lists them", id)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("no category given")
+	}
+	return ids, nil
+}
+
+func cmdScan(args []string) int {
+	fs, out := newFlags("scan")
+	list := fs.String("categories", "", "comma-separated IDs of the categories to scan, all by default")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+
+	c := cmdCleaner()
+	ctx, stop := interruptible()
+	defer stop()
+	var result *cleaner.ScanResult
+	var err error
+	if *list == "" {
+		result, err = c.ScanContext(ctx, nil)
+	} else {
+		ids, serr := splitCategories(c, *list)
+		if serr != nil {
+			return usageError("%v", serr)
+		}
+		result, err = c.ScanCategoriesContext(ctx, ids, nil)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if out.Structured() {
+		return printResult(*out, result)
+	}
+
+	_, yellow, _ := cmdColors()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, cat := range result.Categories {
+		if cat.Size > 0 {
+			fmt.Fprintf(tw, "  %s\t%s\t%d files\t%s\n", cat.ID, formatBytesHuman(cat.Size), cat.FileCount, cat.Risk)
+		}
+	}
+	tw.Flush()
+	for _, cat := range result.Categories {
+		if cat.Busy != "" {
+			yellow.Printf("  Skipped %s: %s\n", cat.ID, cat.Busy)
+		}
+	}
+	yellow.Printf("  Total: %s in %d files\n", formatBytesHuman(result.TotalSize), result.TotalFiles)
+	return exitOK
+}
+
+func cmdClean(args []string) int {
+	fs, out := newFlags("clean")
+	list := fs.String("categories", "", "comma-separated IDs of the categories to clean")
+	olderThan := fs.String("older-than", "", "only clean files last modified longer ago, e.g. 7d")
+	toQuarantine := fs.Bool("quarantine", false, "move files to quarantine instead of deleting them")
+	audit := fs.Bool("audit", false, "log every cleaned entry to the audit log")
+	yes := fs.Bool("yes", false, "clean without asking; otherwise only print the plan")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	if *list == "" {
+		return usageError("clean needs --categories")
+	}
+
+	c := cmdCleaner()
+	ids, err := splitCategories(c, *list)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	if *olderThan != "" {
+		age, err := cleaner.ParseAge(*olderThan)
+		if err != nil {
+			return usageError("--older-than: %v", err)
+		}
+		c.SetMinAge(age)
+	}
+	if *toQuarantine {
+		c.SetQuarantine(quarantine.Default)
+	}
+	if *audit {
+		c.SetAudit(cleaner.DefaultAudit)
+	}
+
+	ctx, stop := interruptible()
+	defer stop()
+	p, err := c.PlanContext(ctx, ids, nil)
+	if err != nil {
+		return fail(err)
+	}
+	if !*yes {
+		return unconfirmed(p, *out, "nothing was cleaned; add --yes to clean")
+	}
+
+	result, err := cleaner.ExecuteContext(ctx, p, nil)
+	if result == nil {
+		return fail(err)
+	}
+	if out.Structured() {
+		if code := printResult(*out, result); code != exitOK {
+			return code
+		}
+	} else {
+		green, yellow, red := cmdColors()
+		cliCleanReport(p, result, err, green, yellow, red)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if len(result.Errors) > 0 {
+		return exitPartial
+	}
+	return exitOK
+}
+
+// unconfirmed prints p for the user to confirm and explains on stderr that
+// it was not applied. A plan that changes nothing needs no confirmation, so
+// it exits with exitOK rather than exitNotConfirmed.
+func unconfirmed(p *plan.Plan, out output.Format, hint string) int {
+	if out.Structured() {
+		if code := printResult(out, p); code != exitOK {
+			return code
+		}
+	} else {
+		_, yellow, _ := cmdColors()
+		cliPrintPlan(p, yellow)
+	}
+	if len(p.Actions) == 0 {
+		return exitOK
+	}
+	fmt.Fprintln(os.Stderr, "cleanforge: "+hint)
+	return exitNotConfirmed
+}
+
+// notConfirmed explains on stderr that a command which has no plan to print
+// was not run without --yes, and what it would have done.
+func notConfirmed(would string) int {
+	fmt.Fprintf(os.Stderr, "cleanforge: this would %s; nothing was changed; add --yes to apply\n", would)
+	return exitNotConfirmed
+}
+
+// applyPlan applies p and prints what it did if yes is set, and otherwise
+// only prints p, like clean without --yes.
+func applyPlan(p *plan.Plan, yes bool, out output.Format) int {
+	if !yes {
+		return unconfirmed(p, out, "nothing was changed; add --yes to apply")
+	}
+
+	green, yellow, red := cmdColors()
+	result, err := plan.Execute(p)
+	if err != nil {
+		return fail(err)
+	}
+	if out.Structured() {
+		if code := printResult(out, result); code != exitOK {
+			return code
+		}
+	} else {
+		for _, w := range result.Warnings {
+			yellow.Printf("  ⚠ %s\n", w)
+		}
+		green.Printf("  ✓ %s: %d changes applied\n", p.Description, len(result.Applied))
+		for _, f := range result.Failures {
+			red.Printf("  ✗ %s\n", f)
+		}
+	}
+	if len(result.Failures) > 0 {
+		return exitPartial
+	}
+	return exitOK
+}
+
+func cmdBoostList(args []string) int {
+	fs, out := newFlags("boost list")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	profiles := gaming.NewGameBooster().GetProfiles()
+	if out.Structured() {
+		return printResult(*out, profiles)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, p := range profiles {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", p.ID, p.Name, p.Description)
+	}
+	tw.Flush()
+	return exitOK
+}
+
+func cmdBoostApply(args []string) int {
+	fs, out := newFlags("boost apply")
+	yes := fs.Bool("yes", false, "apply without asking; otherwise only print the plan")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return usageExit(err)
+	}
+	if len(rest) != 1 {
+		return usageError("boost apply needs one profile; cleanforge boost list lists them")
+	}
+	p, err := gaming.NewGameBooster().PlanProfile(rest[0])
+	if err != nil {
+		return usageError("%v", err)
+	}
+	return applyPlan(p, *yes, *out)
+}
+
+func cmdBoostTweaks(args []string) int {
+	fs, out := newFlags("boost tweaks")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	tweaks := gaming.NewGameBooster().GetAvailableTweaks()
+	if out.Structured() {
+		return printResult(*out, tweaks)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, t := range tweaks {
+		status := "off"
+		if t.Applied {
+			status = "on"
+		}
+		revert := ""
+		if t.Revertible {
+			revert = "revertible"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", t.ID, status, revert, t.Name)
+	}
+	tw.Flush()
+	return exitOK
+}
+
+func cmdBoostRevert(args []string) int {
+	fs, out := newFlags("boost revert")
+	yes := fs.Bool("yes", false, "revert without asking")
+	ids, err := parseFlags(fs, args)
+	if err != nil {
+		return usageExit(err)
+	}
+	if len(ids) == 0 {
+		return usageError("boost revert needs tweak IDs; cleanforge boost tweaks lists them")
+	}
+	gb := gaming.NewGameBooster()
+	known := make(map[string]bool)
+	for _, t := range gb.GetAvailableTweaks() {
+		known[t.ID] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return usageError("unknown tweak: %s", id)
+		}
+	}
+	if !*yes {
+		return notConfirmed("revert " + strings.Join(ids, ", "))
+	}
+	gb.DetectStatus() // so the saved boost session is updated too
+	for _, id := range ids {
+		if err := gb.RevertTweak(id); err != nil {
+			return fail(fmt.Errorf("%s: %w", id, err))
+		}
+	}
+	return succeed("boost revert", "Reverted "+strings.Join(ids, ", "), *out)
+}
+
+func cmdBoostRestore(args []string) int {
+	fs, out := newFlags("boost restore")
+	yes := fs.Bool("yes", false, "restore without asking")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	if !*yes {
+		return notConfirmed("undo every Game Boost change")
+	}
+	if err := gaming.NewGameBooster().RestoreAll(); err != nil {
+		return fail(err)
+	}
+	return succeed("boost restore", "Game settings restored", *out)
+}
+
+func cmdBoostStatus(args []string) int {
+	fs, out := newFlags("boost status")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	status := gaming.NewGameBooster().DetectStatus()
+	if out.Structured() {
+		return printResult(*out, status)
+	}
+	if !status.Active {
+		fmt.Println("  No game profile applied")
+		return exitOK
+	}
+	if status.StartedAt == "" {
+		fmt.Printf("  Profile %s applied: %s\n", status.Profile, strings.Join(status.TweaksApplied, ", "))
+		return exitOK
+	}
+	fmt.Printf("  Profile %s applied at %s: %s\n", status.Profile, status.StartedAt, strings.Join(status.TweaksApplied, ", "))
+	return exitOK
+}
+
+func cmdPrivacyList(args []string) int {
+	fs, out := newFlags("privacy list")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	tweaks, err := privacy.GetPrivacyTweaks()
+	if err != nil {
+		return fail(err)
+	}
+	if out.Structured() {
+		return printResult(*out, tweaks)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, t := range tweaks {
+		status := "off"
+		if t.Applied {
+			status = "on"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", t.ID, status, t.Name)
+	}
+	tw.Flush()
+	return exitOK
+}
+
+func cmdPrivacyApply(args []string) int {
+	fs, out := newFlags("privacy apply")
+	all := fs.Bool("all", false, "turn every protection on")
+	yes := fs.Bool("yes", false, "apply without asking; otherwise only print the plan")
+	ids, err := parseFlags(fs, args)
+	if err != nil {
+		return usageExit(err)
+	}
+	if *all == (len(ids) > 0) {
+		return usageError("privacy apply needs --all or protection IDs; cleanforge privacy list lists them")
+	}
+	var p *plan.Plan
+	if *all {
+		p, err = privacy.PlanAll()
+	} else {
+		p, err = privacy.PlanTweaks(ids)
+	}
+	if err != nil {
+		return usageError("%v", err)
+	}
+	return applyPlan(p, *yes, *out)
+}
+
+func cmdPrivacyRestore(args []string) int {
+	fs, out := newFlags("privacy restore")
+	yes := fs.Bool("yes", false, "restore without asking")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	if !*yes {
+		return notConfirmed("restore the Windows privacy defaults")
+	}
+	if err := privacy.RestoreAll(); err != nil {
+		return fail(err)
+	}
+	return succeed("privacy restore", "Privacy settings restored to defaults", *out)
+}
+
+func cmdNetworkStatus(args []string) int {
+	fs, out := newFlags("network status")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	status, err := network.GetNetworkStatus()
+	if err != nil {
+		return fail(err)
+	}
+	if out.Structured() {
+		return printResult(*out, status)
+	}
+	nagle := "enabled (default)"
+	if status.NagleDisabled {
+		nagle = "disabled (fast)"
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  Adapter:\t%s\n", status.Adapter)
+	fmt.Fprintf(tw, "  IP:\t%s\n", status.IPAddress)
+	fmt.Fprintf(tw, "  Gateway:\t%s\n", status.Gateway)
+	fmt.Fprintf(tw, "  DNS:\t%s\n", status.CurrentDNS)
+	fmt.Fprintf(tw, "  Nagle:\t%s\n", nagle)
+	tw.Flush()
+	return exitOK
+}
+
+func cmdDNSList(args []string) int {
+	fs, out := newFlags("dns list")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	presets := network.GetDNSPresets()
+	if out.Structured() {
+		return printResult(*out, presets)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, p := range presets {
+		fmt.Fprintf(tw, "  %s\t%s, %s\t%s\n", p.ID, p.Primary, p.Secondary, p.Description)
+	}
+	tw.Flush()
+	return exitOK
+}
+
+func cmdDNSSet(args []string) int {
+	fs, out := newFlags("dns set")
+	yes := fs.Bool("yes", false, "change the DNS servers without asking")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return usageExit(err)
+	}
+	if len(rest) != 1 {
+		return usageError("dns set needs one preset; cleanforge dns list lists them")
+	}
+	for _, preset := range network.GetDNSPresets() {
+		if preset.ID == rest[0] {
+			if !*yes {
+				return notConfirmed(fmt.Sprintf("set DNS to %s (%s)", preset.Name, preset.Primary))
+			}
+			if err := network.SetDNS(preset); err != nil {
+				return fail(err)
+			}
+			return succeed("dns set", fmt.Sprintf("DNS set to %s (%s)", preset.Name, preset.Primary), *out)
+		}
+	}
+	return usageError("unknown DNS preset %q; cleanforge dns list lists them", rest[0])
+}
+
+func cmdDNSReset(args []string) int {
+	fs, out := newFlags("dns reset")
+	yes := fs.Bool("yes", false, "change the DNS servers without asking")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	if !*yes {
+		return notConfirmed("reset DNS to DHCP")
+	}
+	if err := network.ResetDNS(); err != nil {
+		return fail(err)
+	}
+	return succeed("dns reset", "DNS reset to DHCP", *out)
+}
+
+func cmdMemoryStatus(args []string) int {
+	fs, out := newFlags("memory status")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	status, err := memory.GetMemoryStatus()
+	if err != nil {
+		return fail(err)
+	}
+	if out.Structured() {
+		return printResult(*out, status)
+	}
+	cliPrintMemoryStatus(status)
+	return exitOK
+}
+
+func cmdQuarantineList(args []string) int {
+	fs, out := newFlags("quarantine list")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	runs, err := quarantine.Default.Runs()
+	if err != nil {
+		return fail(err)
+	}
+	if out.Structured() {
+		return printResult(*out, runs)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range runs {
+		size, files := r.Size()
+		fmt.Fprintf(tw, "  %s\t%s\t%s in %d files\t%s\n", r.ID, r.Created, formatBytesHuman(size), files, r.Description)
+	}
+	tw.Flush()
+	return exitOK
+}
+
+func cmdRestore(args []string) int {
+	fs, out := newFlags("restore")
+	list := fs.Bool("list", false, "list the restore points instead")
+	point := fs.String("point", "", "only undo the changes made since this restore point")
+	yes := fs.Bool("yes", false, "undo the changes without asking")
+	if code := parseNoArgs(fs, args); code >= 0 {
+		return code
+	}
+	switch {
+	case *list && *point != "":
+		return usageError("--list and --point cannot be combined")
+	case !*list && !*yes:
+		if *point != "" {
+			return notConfirmed("undo the changes made since " + *point)
+		}
+		return notConfirmed("undo every recorded change")
+	case *list:
+		points, err := backup.RestorePoints()
+		if err != nil {
+			return fail(err)
+		}
+		if out.Structured() {
+			return printResult(*out, points)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, p := range points {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", p.ID, p.Created, p.Description)
+		}
+		tw.Flush()
+		return exitOK
+	case *point != "":
+		if err := backup.RestoreTo(*point); err != nil {
+			return fail(err)
+		}
+		return succeed("restore", "Restored to "+*point, *out)
+	default:
+		if err := backup.RestoreAll(); err != nil {
+			return fail(err)
+		}
+		return succeed("restore", "Every recorded change undone", *out)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"os/user"
+	"strings"
+	"time"
+
+	"cleanforge/internal/cleaner"
+	"cleanforge/internal/schedule"
+)
+
+// daemonInterval is how often the daemon checks which policies are due.
+const daemonInterval = time.Minute
+
+// currentUsername returns the name of the user running CleanForge, without
+// a Windows domain.
+func currentUsername() string {
+	u, _ := user.Current()
+	if u == nil {
+		return "User"
+	}
+	username := u.Username
+	if idx := strings.LastIndex(username, "\\"); idx >= 0 {
+		username = username[idx+1:]
+	}
+	return username
+}
+
+// runDaemon runs the cleanup policies in the schedule file until it is
+// interrupted, logging every run to stderr and to the schedule log. Scheduled
+// cleanups are audited, since nobody watches them. Policies are read once;
+// restart the daemon after editing them.
+func runDaemon() int {
+	logger := log.New(os.Stderr, "cleanforge: ", log.LstdFlags)
+
+	policies, err := schedule.LoadPolicies(schedule.ConfigPath())
+	if err != nil {
+		logger.Print(err)
+		return 1
+	}
+	if len(policies) == 0 {
+		logger.Printf("no policies in %s, nothing to do", schedule.ConfigPath())
+		return 1
+	}
+
+	if result, err := cleaner.DefaultPending.Retry(); err != nil {
+		logger.Printf("pending deletes: %v", err)
+	} else if result.DeletedFiles > 0 {
+		logger.Printf("deleted %d files left over from earlier cleanups", result.DeletedFiles)
+	}
+
+	username := currentUsername()
+	s := schedule.New(policies, func() *cleaner.Cleaner {
+		c, err := newCleaner(username)
+		if err != nil {
+			logger.Printf("custom categories ignored: %v", err)
+		}
+		c.SetAudit(cleaner.DefaultAudit)
+		return c
+	})
+	s.Logf = logger.Printf
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	logger.Printf("running %d policies, logging to %s", len(policies), s.LogPath)
+	if err := s.Run(ctx, daemonInterval); err != nil && !errors.Is(err, context.Canceled) {
+		logger.Print(err)
+		return 1
+	}
+	return 0
+}
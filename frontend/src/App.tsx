@@ -5,6 +5,7 @@ import {
   Quit,
 } from "../wailsjs/runtime/runtime";
 import Sidebar from "./components/Sidebar";
+import BoostSessionBanner from "./components/BoostSessionBanner";
 import Dashboard from "./pages/Dashboard";
 import Cleaner from "./pages/Cleaner";
 import GameBoost from "./pages/GameBoost";
@@ -57,6 +58,7 @@ function App() {
           </button>
         </div>
       </div>
+      <BoostSessionBanner />
       {/* Main content area */}
       <div className="flex flex-1 overflow-hidden">
         <Sidebar />
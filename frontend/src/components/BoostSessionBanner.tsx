@@ -0,0 +1,78 @@
+import { useState, useEffect } from "react";
+import { motion, AnimatePresence } from "framer-motion";
+import { Gamepad2, RotateCcw, Loader2 } from "lucide-react";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
+
+interface BoostStatus {
+  active: boolean;
+  profile: string;
+  tweaksApplied: string[];
+  startedAt: string;
+  restorePoint?: string;
+}
+
+// BoostSessionBanner offers to restore or keep a Game Boost session that an
+// earlier run left in effect, e.g. after a crash or a restart.
+export default function BoostSessionBanner() {
+  const [session, setSession] = useState<BoostStatus | null>(null);
+  const [resolving, setResolving] = useState(false);
+
+  useEffect(() => {
+    // The session may be found before or after this mounts
+    // @ts-ignore
+    window.go.main.App.GetInterruptedBoost()
+      .then((s: BoostStatus | null) => s && setSession(s))
+      .catch(() => {});
+    return EventsOn("boost:interrupted", (s: BoostStatus) => setSession(s));
+  }, []);
+
+  async function resolve(restore: boolean) {
+    setResolving(true);
+    try {
+      // @ts-ignore
+      await window.go.main.App.ResolveInterruptedBoost(restore);
+    } catch (e) {
+      console.warn("Restore completed with warnings:", e);
+    }
+    setResolving(false);
+    setSession(null);
+  }
+
+  const started = session?.startedAt ? new Date(session.startedAt).toLocaleString() : null;
+
+  return (
+    <AnimatePresence>
+      {session && (
+        <motion.div
+          initial={{ height: 0, opacity: 0 }}
+          animate={{ height: "auto", opacity: 1 }}
+          exit={{ height: 0, opacity: 0 }}
+          className="overflow-hidden shrink-0 bg-forge-card border-b border-forge-border"
+        >
+          <div className="flex items-center gap-3 px-4 py-2.5">
+            <Gamepad2 className="w-4 h-4 text-forge-accent shrink-0" />
+            <p className="flex-1 text-sm text-forge-text">
+              A Game Boost session ({session.profile}, {session.tweaksApplied.length} tweaks
+              {started ? `, started ${started}` : ""}) is still active from an earlier run.
+            </p>
+            <button
+              onClick={() => resolve(true)}
+              disabled={resolving}
+              className="flex items-center gap-1.5 px-3 py-1.5 bg-forge-accent/10 border border-forge-accent/30 text-forge-accent rounded-lg text-xs font-semibold hover:bg-forge-accent/20 transition-colors disabled:opacity-50"
+            >
+              {resolving ? <Loader2 className="w-3.5 h-3.5 animate-spin" /> : <RotateCcw className="w-3.5 h-3.5" />}
+              Restore
+            </button>
+            <button
+              onClick={() => resolve(false)}
+              disabled={resolving}
+              className="px-3 py-1.5 bg-forge-card border border-forge-border text-forge-muted rounded-lg text-xs font-medium hover:text-forge-text transition-colors disabled:opacity-50"
+            >
+              Keep
+            </button>
+          </div>
+        </motion.div>
+      )}
+    </AnimatePresence>
+  );
+}
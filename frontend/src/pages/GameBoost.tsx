@@ -43,12 +43,35 @@ const profiles = [
   { id: "nuclear", name: "Nuclear Mode", icon: Skull, desc: "MAX PERFORMANCE", color: "text-forge-danger", features: ["ALL tweaks enabled", "Maximum aggression", "Full system override"] },
 ];
 
-const tweakCategories = [
-  { name: "Mouse", icon: Mouse, tweaks: ["Disable pointer acceleration", "Raw input mode", "No smooth scroll"] },
-  { name: "Keyboard", icon: Keyboard, tweaks: ["Min repeat delay", "Max repeat rate", "Disable Sticky/Filter Keys"] },
-  { name: "GPU", icon: Monitor, tweaks: ["Max performance mode", "Low latency mode", "Disable Game DVR", "Disable Game Bar", "Disable fullscreen opt."] },
-  { name: "System", icon: Cpu, tweaks: ["Ultimate power plan", "Disable core parking", "Disable HPET", "Timer 0.5ms", "Disable SysMain", "Disable Win Search", "Disable Game Mode"] },
-];
+interface TweakInfo {
+  id: string;
+  name: string;
+  description: string;
+  category: string;
+  enabled: boolean;
+  applied: boolean;
+  revertible: boolean;
+}
+
+const categoryIcons: Record<string, any> = {
+  mouse: Mouse,
+  keyboard: Keyboard,
+  gpu: Monitor,
+  display: Monitor,
+  power: Zap,
+  system: Cpu,
+  network: Globe2,
+};
+
+const categoryLabels: Record<string, string> = {
+  mouse: "Mouse",
+  keyboard: "Keyboard",
+  gpu: "GPU",
+  display: "Display",
+  power: "Power",
+  system: "System",
+  network: "Network",
+};
 
 export default function GameBoost() {
   const [gpu, setGpu] = useState<GPUInfo | null>(null);
@@ -57,12 +80,15 @@ export default function GameBoost() {
   const [applying, setApplying] = useState(false);
   const [restoring, setRestoring] = useState(false);
   const [showTweaks, setShowTweaks] = useState(false);
+  const [tweaks, setTweaks] = useState<TweakInfo[]>([]);
+  const [togglingTweak, setTogglingTweak] = useState<string | null>(null);
   const [successMsg, setSuccessMsg] = useState<string | null>(null);
   const [restoredMsg, setRestoredMsg] = useState(false);
 
   useEffect(() => {
     loadGPU();
     loadStatus();
+    loadTweaks();
   }, []);
 
   async function loadGPU() {
@@ -82,6 +108,40 @@ export default function GameBoost() {
     } catch {}
   }
 
+  async function loadTweaks() {
+    try {
+      // @ts-ignore
+      const data = await window.go.main.App.GetAvailableTweaks();
+      setTweaks(data || []);
+    } catch {}
+  }
+
+  async function toggleTweak(tweak: TweakInfo) {
+    setTogglingTweak(tweak.id);
+    try {
+      // @ts-ignore
+      await window.go.main.App.ToggleGameTweak(tweak.id);
+    } catch (e) {
+      console.warn("Tweak toggled with warnings:", e);
+    }
+    await loadStatus();
+    await loadTweaks();
+    setTogglingTweak(null);
+  }
+
+  async function revertTweak(tweak: TweakInfo) {
+    setTogglingTweak(tweak.id);
+    try {
+      // @ts-ignore
+      await window.go.main.App.RevertGameTweak(tweak.id);
+    } catch (e) {
+      console.warn("Tweak reverted with warnings:", e);
+    }
+    await loadStatus();
+    await loadTweaks();
+    setTogglingTweak(null);
+  }
+
   async function applyBoost() {
     if (!selectedProfile) return;
     setApplying(true);
@@ -98,6 +158,7 @@ export default function GameBoost() {
     // Always refresh status after attempting to apply — the backend updates
     // the boost status even when some individual tweaks fail.
     await loadStatus();
+    await loadTweaks();
     const profileName = profiles.find((p) => p.id === selectedProfile)?.name ?? selectedProfile;
     setSuccessMsg(`${profileName} applied successfully! Your system is now optimized for gaming.`);
     setApplying(false);
@@ -117,6 +178,7 @@ export default function GameBoost() {
     }
     // Always refresh status and show restored message
     await loadStatus();
+    await loadTweaks();
     // If status is no longer active after restore, show success
     // @ts-ignore
     const freshStatus = await window.go.main.App.GetBoostStatus().catch(() => null);
@@ -292,22 +354,50 @@ export default function GameBoost() {
               exit={{ height: 0, opacity: 0 }}
               className="overflow-hidden mt-3 grid grid-cols-2 gap-3"
             >
-              {tweakCategories.map((cat) => (
-                <div key={cat.name} className="bg-forge-card border border-forge-border rounded-xl p-4">
-                  <div className="flex items-center gap-2 mb-3">
-                    <cat.icon className="w-4 h-4 text-forge-accent" />
-                    <span className="text-sm font-semibold text-forge-text">{cat.name}</span>
-                  </div>
-                  <div className="space-y-1.5">
-                    {cat.tweaks.map((t) => (
-                      <div key={t} className="flex items-center gap-2 text-xs text-forge-muted">
-                        <CheckCircle2 className="w-3 h-3 text-forge-accent/50" />
-                        {t}
-                      </div>
-                    ))}
+              {[...new Set(tweaks.map((t) => t.category))].map((cat) => {
+                const CatIcon = categoryIcons[cat] || Cpu;
+                return (
+                  <div key={cat} className="bg-forge-card border border-forge-border rounded-xl p-4">
+                    <div className="flex items-center gap-2 mb-3">
+                      <CatIcon className="w-4 h-4 text-forge-accent" />
+                      <span className="text-sm font-semibold text-forge-text">{categoryLabels[cat] || cat}</span>
+                    </div>
+                    <div className="space-y-1.5">
+                      {tweaks.filter((t) => t.category === cat).map((tweak) => {
+                        const on = tweak.applied;
+                        return (
+                          <div key={tweak.id} className="flex items-center gap-2 text-xs text-forge-muted" title={tweak.description}>
+                            <button
+                              onClick={() => toggleTweak(tweak)}
+                              disabled={togglingTweak !== null || applying || restoring}
+                              className={`w-7 h-3.5 rounded-full transition-colors relative shrink-0 disabled:opacity-50 ${
+                                on ? "bg-forge-accent" : "bg-forge-border"
+                              }`}
+                            >
+                              <motion.div
+                                animate={{ x: on ? 14 : 2 }}
+                                className="absolute top-0.5 w-2.5 h-2.5 bg-white rounded-full shadow"
+                              />
+                            </button>
+                            <span className="flex-1">{tweak.name}</span>
+                            {!on && tweak.revertible && togglingTweak !== tweak.id && (
+                              <button
+                                onClick={() => revertTweak(tweak)}
+                                disabled={togglingTweak !== null || applying || restoring}
+                                title="Partly applied: undo the remaining changes"
+                                className="text-forge-muted hover:text-forge-text disabled:opacity-50 shrink-0"
+                              >
+                                <RotateCcw className="w-3 h-3" />
+                              </button>
+                            )}
+                            {togglingTweak === tweak.id && <Loader2 className="w-3 h-3 text-forge-accent animate-spin shrink-0" />}
+                          </div>
+                        );
+                      })}
+                    </div>
                   </div>
-                </div>
-              ))}
+                );
+              })}
             </motion.div>
           )}
         </AnimatePresence>
@@ -1,5 +1,6 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {backup} from '../models';
 import {cleaner} from '../models';
 import {gaming} from '../models';
 import {startup} from '../models';
@@ -9,15 +10,25 @@ import {monitor} from '../models';
 import {network} from '../models';
 import {privacy} from '../models';
 import {system} from '../models';
+import {plan} from '../models';
+import {quarantine} from '../models';
+
+export function AnalyzeDiskUsage(arg1:string):Promise<cleaner.UsageReport>;
+
+export function AnalyzeLowDisks():Promise<Array<cleaner.UsageReport>>;
 
 export function ApplyAllPrivacy():Promise<void>;
 
 export function ApplyGameProfile(arg1:string):Promise<void>;
 
+export function CancelClean():Promise<void>;
+
 export function CleanSystem(arg1:Array<string>):Promise<cleaner.CleanResult>;
 
 export function DetectGPU():Promise<gaming.GPUInfo>;
 
+export function DiffRestorePoint(arg1:string):Promise<Array<backup.Change>>;
+
 export function DisableNagle():Promise<void>;
 
 export function DisableStartupItem(arg1:startup.StartupItem):Promise<void>;
@@ -26,18 +37,30 @@ export function EnableNagle():Promise<void>;
 
 export function EnableStartupItem(arg1:startup.StartupItem):Promise<void>;
 
+export function ExecutePlan(arg1:string):Promise<plan.Result>;
+
+export function ExportDiskUsage(arg1:cleaner.UsageReport,arg2:string):Promise<string>;
+
+export function FindDuplicates(arg1:Array<string>):Promise<cleaner.CleanCategory>;
+
 export function FlushMemory():Promise<void>;
 
 export function FlushNetwork():Promise<string>;
 
+export function GetAuditLog():Promise<boolean>;
+
 export function GetAvailableTweaks():Promise<Array<gaming.TweakInfo>>;
 
 export function GetBloatwareApps():Promise<Array<toolkit.BloatwareApp>>;
 
 export function GetBoostStatus():Promise<gaming.BoostStatus>;
 
+export function GetBrowsers():Promise<Array<cleaner.Browser>>;
+
 export function GetGameProfiles():Promise<Array<gaming.GameProfile>>;
 
+export function GetInterruptedBoost():Promise<gaming.BoostStatus>;
+
 export function GetIsAdmin():Promise<boolean>;
 
 export function GetMemoryStatus():Promise<memory.MemoryStatus>;
@@ -46,8 +69,18 @@ export function GetMonitorSnapshot():Promise<monitor.MonitorSnapshot>;
 
 export function GetNetworkStatus():Promise<network.NetworkStatus>;
 
+export function GetPendingDeletes():Promise<Array<cleaner.PendingFile>>;
+
 export function GetPrivacyTweaks():Promise<Array<privacy.PrivacyTweak>>;
 
+export function GetQuarantineMode():Promise<boolean>;
+
+export function GetQuarantineRuns():Promise<Array<quarantine.Run>>;
+
+export function GetRestorePoints():Promise<Array<backup.RestorePoint>>;
+
+export function GetSkipRunningBrowsers():Promise<boolean>;
+
 export function GetStartupItems():Promise<Array<startup.StartupItem>>;
 
 export function GetSystemInfo():Promise<system.SystemInfo>;
@@ -56,12 +89,30 @@ export function GetVersion():Promise<string>;
 
 export function HasBackup():Promise<boolean>;
 
+export function PickDuplicates(arg1:Array<string>):Promise<void>;
+
 export function PingTest(arg1:string):Promise<number>;
 
+export function PlanAllPrivacy():Promise<plan.Plan>;
+
+export function PlanClean(arg1:Array<string>):Promise<plan.Plan>;
+
+export function PlanDisableNagle():Promise<plan.Plan>;
+
+export function PlanGameProfile(arg1:string):Promise<plan.Plan>;
+
+export function PruneRestorePoints(arg1:number,arg2:number):Promise<number>;
+
+export function PurgeExpiredQuarantine():Promise<Array<string>>;
+
+export function PurgeQuarantineRun(arg1:string):Promise<void>;
+
 export function RebuildFontCache():Promise<toolkit.ToolResult>;
 
 export function RebuildIconCache():Promise<toolkit.ToolResult>;
 
+export function ReloadCleanerConfig():Promise<void>;
+
 export function RemoveBloatware(arg1:Array<string>):Promise<toolkit.ToolResult>;
 
 export function RepairWindowsUpdate():Promise<toolkit.ToolResult>;
@@ -70,12 +121,24 @@ export function ResetDNS():Promise<void>;
 
 export function ResetWindowsSearch():Promise<toolkit.ToolResult>;
 
+export function ResolveInterruptedBoost(arg1:boolean):Promise<void>;
+
 export function RestoreAllBackup():Promise<void>;
 
 export function RestoreAllPrivacy():Promise<void>;
 
 export function RestoreGameSettings():Promise<void>;
 
+export function RestoreQuarantineItem(arg1:string,arg2:number):Promise<void>;
+
+export function RestoreQuarantineRun(arg1:string):Promise<void>;
+
+export function RestoreToPoint(arg1:string):Promise<void>;
+
+export function RetryPendingDeletes():Promise<cleaner.CleanResult>;
+
+export function RevertGameTweak(arg1:string):Promise<void>;
+
 export function RunBenchmark():Promise<monitor.BenchmarkResult>;
 
 export function RunDISM():Promise<toolkit.ToolResult>;
@@ -84,6 +147,14 @@ export function RunSFC():Promise<toolkit.ToolResult>;
 
 export function ScanSystem():Promise<cleaner.ScanResult>;
 
+export function SetAuditLog(arg1:boolean):Promise<void>;
+
 export function SetDNS(arg1:network.DNSPreset):Promise<void>;
 
+export function SetQuarantineMode(arg1:boolean):Promise<void>;
+
+export function SetSkipRunningBrowsers(arg1:boolean):Promise<void>;
+
+export function ToggleGameTweak(arg1:string):Promise<void>;
+
 export function TogglePrivacyTweak(arg1:string):Promise<void>;
@@ -2,6 +2,14 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AnalyzeDiskUsage(arg1) {
+  return window['go']['main']['App']['AnalyzeDiskUsage'](arg1);
+}
+
+export function AnalyzeLowDisks() {
+  return window['go']['main']['App']['AnalyzeLowDisks']();
+}
+
 export function ApplyAllPrivacy() {
   return window['go']['main']['App']['ApplyAllPrivacy']();
 }
@@ -10,6 +18,10 @@ export function ApplyGameProfile(arg1) {
   return window['go']['main']['App']['ApplyGameProfile'](arg1);
 }
 
+export function CancelClean() {
+  return window['go']['main']['App']['CancelClean']();
+}
+
 export function CleanSystem(arg1) {
   return window['go']['main']['App']['CleanSystem'](arg1);
 }
@@ -18,6 +30,10 @@ export function DetectGPU() {
   return window['go']['main']['App']['DetectGPU']();
 }
 
+export function DiffRestorePoint(arg1) {
+  return window['go']['main']['App']['DiffRestorePoint'](arg1);
+}
+
 export function DisableNagle() {
   return window['go']['main']['App']['DisableNagle']();
 }
@@ -34,6 +50,18 @@ export function EnableStartupItem(arg1) {
   return window['go']['main']['App']['EnableStartupItem'](arg1);
 }
 
+export function ExecutePlan(arg1) {
+  return window['go']['main']['App']['ExecutePlan'](arg1);
+}
+
+export function ExportDiskUsage(arg1, arg2) {
+  return window['go']['main']['App']['ExportDiskUsage'](arg1, arg2);
+}
+
+export function FindDuplicates(arg1) {
+  return window['go']['main']['App']['FindDuplicates'](arg1);
+}
+
 export function FlushMemory() {
   return window['go']['main']['App']['FlushMemory']();
 }
@@ -42,6 +70,10 @@ export function FlushNetwork() {
   return window['go']['main']['App']['FlushNetwork']();
 }
 
+export function GetAuditLog() {
+  return window['go']['main']['App']['GetAuditLog']();
+}
+
 export function GetAvailableTweaks() {
   return window['go']['main']['App']['GetAvailableTweaks']();
 }
@@ -54,10 +86,18 @@ export function GetBoostStatus() {
   return window['go']['main']['App']['GetBoostStatus']();
 }
 
+export function GetBrowsers() {
+  return window['go']['main']['App']['GetBrowsers']();
+}
+
 export function GetGameProfiles() {
   return window['go']['main']['App']['GetGameProfiles']();
 }
 
+export function GetInterruptedBoost() {
+  return window['go']['main']['App']['GetInterruptedBoost']();
+}
+
 export function GetIsAdmin() {
   return window['go']['main']['App']['GetIsAdmin']();
 }
@@ -74,10 +114,30 @@ export function GetNetworkStatus() {
   return window['go']['main']['App']['GetNetworkStatus']();
 }
 
+export function GetPendingDeletes() {
+  return window['go']['main']['App']['GetPendingDeletes']();
+}
+
 export function GetPrivacyTweaks() {
   return window['go']['main']['App']['GetPrivacyTweaks']();
 }
 
+export function GetQuarantineMode() {
+  return window['go']['main']['App']['GetQuarantineMode']();
+}
+
+export function GetQuarantineRuns() {
+  return window['go']['main']['App']['GetQuarantineRuns']();
+}
+
+export function GetRestorePoints() {
+  return window['go']['main']['App']['GetRestorePoints']();
+}
+
+export function GetSkipRunningBrowsers() {
+  return window['go']['main']['App']['GetSkipRunningBrowsers']();
+}
+
 export function GetStartupItems() {
   return window['go']['main']['App']['GetStartupItems']();
 }
@@ -94,10 +154,42 @@ export function HasBackup() {
   return window['go']['main']['App']['HasBackup']();
 }
 
+export function PickDuplicates(arg1) {
+  return window['go']['main']['App']['PickDuplicates'](arg1);
+}
+
 export function PingTest(arg1) {
   return window['go']['main']['App']['PingTest'](arg1);
 }
 
+export function PlanAllPrivacy() {
+  return window['go']['main']['App']['PlanAllPrivacy']();
+}
+
+export function PlanClean(arg1) {
+  return window['go']['main']['App']['PlanClean'](arg1);
+}
+
+export function PlanDisableNagle() {
+  return window['go']['main']['App']['PlanDisableNagle']();
+}
+
+export function PlanGameProfile(arg1) {
+  return window['go']['main']['App']['PlanGameProfile'](arg1);
+}
+
+export function PruneRestorePoints(arg1, arg2) {
+  return window['go']['main']['App']['PruneRestorePoints'](arg1, arg2);
+}
+
+export function PurgeExpiredQuarantine() {
+  return window['go']['main']['App']['PurgeExpiredQuarantine']();
+}
+
+export function PurgeQuarantineRun(arg1) {
+  return window['go']['main']['App']['PurgeQuarantineRun'](arg1);
+}
+
 export function RebuildFontCache() {
   return window['go']['main']['App']['RebuildFontCache']();
 }
@@ -106,6 +198,10 @@ export function RebuildIconCache() {
   return window['go']['main']['App']['RebuildIconCache']();
 }
 
+export function ReloadCleanerConfig() {
+  return window['go']['main']['App']['ReloadCleanerConfig']();
+}
+
 export function RemoveBloatware(arg1) {
   return window['go']['main']['App']['RemoveBloatware'](arg1);
 }
@@ -122,6 +218,10 @@ export function ResetWindowsSearch() {
   return window['go']['main']['App']['ResetWindowsSearch']();
 }
 
+export function ResolveInterruptedBoost(arg1) {
+  return window['go']['main']['App']['ResolveInterruptedBoost'](arg1);
+}
+
 export function RestoreAllBackup() {
   return window['go']['main']['App']['RestoreAllBackup']();
 }
@@ -134,6 +234,26 @@ export function RestoreGameSettings() {
   return window['go']['main']['App']['RestoreGameSettings']();
 }
 
+export function RestoreQuarantineItem(arg1, arg2) {
+  return window['go']['main']['App']['RestoreQuarantineItem'](arg1, arg2);
+}
+
+export function RestoreQuarantineRun(arg1) {
+  return window['go']['main']['App']['RestoreQuarantineRun'](arg1);
+}
+
+export function RestoreToPoint(arg1) {
+  return window['go']['main']['App']['RestoreToPoint'](arg1);
+}
+
+export function RetryPendingDeletes() {
+  return window['go']['main']['App']['RetryPendingDeletes']();
+}
+
+export function RevertGameTweak(arg1) {
+  return window['go']['main']['App']['RevertGameTweak'](arg1);
+}
+
 export function RunBenchmark() {
   return window['go']['main']['App']['RunBenchmark']();
 }
@@ -150,10 +270,26 @@ export function ScanSystem() {
   return window['go']['main']['App']['ScanSystem']();
 }
 
+export function SetAuditLog(arg1) {
+  return window['go']['main']['App']['SetAuditLog'](arg1);
+}
+
 export function SetDNS(arg1) {
   return window['go']['main']['App']['SetDNS'](arg1);
 }
 
+export function SetQuarantineMode(arg1) {
+  return window['go']['main']['App']['SetQuarantineMode'](arg1);
+}
+
+export function SetSkipRunningBrowsers(arg1) {
+  return window['go']['main']['App']['SetSkipRunningBrowsers'](arg1);
+}
+
+export function ToggleGameTweak(arg1) {
+  return window['go']['main']['App']['ToggleGameTweak'](arg1);
+}
+
 export function TogglePrivacyTweak(arg1) {
   return window['go']['main']['App']['TogglePrivacyTweak'](arg1);
 }
@@ -1,13 +1,170 @@
+export namespace backup {
+	
+	export class Change {
+	    target: string;
+	    module: string;
+	    tweak: string;
+	    then: string;
+	    now: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Change(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.target = source["target"];
+	        this.module = source["module"];
+	        this.tweak = source["tweak"];
+	        this.then = source["then"];
+	        this.now = source["now"];
+	    }
+	}
+	export class RestorePoint {
+	    id: string;
+	    description: string;
+	    created: string;
+	    seq: number;
+	    pinned: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RestorePoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.description = source["description"];
+	        this.created = source["created"];
+	        this.seq = source["seq"];
+	        this.pinned = source["pinned"];
+	    }
+	}
+
+}
+
 export namespace cleaner {
 	
+	export class Browser {
+	    id: string;
+	    name: string;
+	    engine: string;
+	    profiles: BrowserProfile[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Browser(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.engine = source["engine"];
+	        this.profiles = this.convertValues(source["profiles"], BrowserProfile);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BrowserProfile {
+	    dir: string;
+	    name: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BrowserProfile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dir = source["dir"];
+	        this.name = source["name"];
+	    }
+	}
+	export class CategoryItem {
+	    id: string;
+	    name: string;
+	    size: number;
+	    fileCount: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CategoryItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.size = source["size"];
+	        this.fileCount = source["fileCount"];
+	    }
+	}
+	export class CategoryResult {
+	    id: string;
+	    freedSpace: number;
+	    deletedFiles: number;
+	    skipped: number;
+	    errors: CleanError[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CategoryResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.freedSpace = source["freedSpace"];
+	        this.deletedFiles = source["deletedFiles"];
+	        this.skipped = source["skipped"];
+	        this.errors = this.convertValues(source["errors"], CleanError);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class CleanCategory {
 	    id: string;
 	    name: string;
 	    description: string;
 	    icon: string;
 	    risk: string;
+	    type?: string;
 	    size: number;
 	    fileCount: number;
+	    keptSize?: number;
+	    keptFiles?: number;
+	    duplicates?: DuplicateSet[];
+	    items?: CategoryItem[];
+	    busy?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new CleanCategory(source);
@@ -20,14 +177,68 @@ export namespace cleaner {
 	        this.description = source["description"];
 	        this.icon = source["icon"];
 	        this.risk = source["risk"];
+	        this.type = source["type"];
 	        this.size = source["size"];
 	        this.fileCount = source["fileCount"];
+	        this.keptSize = source["keptSize"];
+	        this.keptFiles = source["keptFiles"];
+	        this.duplicates = this.convertValues(source["duplicates"], DuplicateSet);
+	        this.items = this.convertValues(source["items"], CategoryItem);
+	        this.busy = source["busy"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CleanError {
+	    path: string;
+	    category: string;
+	    class: string;
+	    message: string;
+	    pending?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CleanError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.category = source["category"];
+	        this.class = source["class"];
+	        this.message = source["message"];
+	        this.pending = source["pending"];
 	    }
 	}
 	export class CleanResult {
 	    freedSpace: number;
 	    deletedFiles: number;
 	    errors: string[];
+	    categories: CategoryResult[];
+	    // Go type: time
+	    started: any;
+	    // Go type: time
+	    finished: any;
+	    durationMs: number;
+	    failures?: CleanError[];
+	    errorCounts?: Record<string, number>;
+	    pending?: number;
+	    skips?: SkippedEntry[];
 	
 	    static createFrom(source: any = {}) {
 	        return new CleanResult(source);
@@ -38,6 +249,131 @@ export namespace cleaner {
 	        this.freedSpace = source["freedSpace"];
 	        this.deletedFiles = source["deletedFiles"];
 	        this.errors = source["errors"];
+	        this.categories = this.convertValues(source["categories"], CategoryResult);
+	        this.started = this.convertValues(source["started"], null);
+	        this.finished = this.convertValues(source["finished"], null);
+	        this.durationMs = source["durationMs"];
+	        this.failures = this.convertValues(source["failures"], CleanError);
+	        this.errorCounts = source["errorCounts"];
+	        this.pending = source["pending"];
+	        this.skips = this.convertValues(source["skips"], SkippedEntry);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DuplicateFile {
+	    path: string;
+	    // Go type: time
+	    modTime: any;
+	    remove: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.modTime = this.convertValues(source["modTime"], null);
+	        this.remove = source["remove"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DuplicateSet {
+	    hash: string;
+	    size: number;
+	    wasted: number;
+	    files: DuplicateFile[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateSet(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hash = source["hash"];
+	        this.size = source["size"];
+	        this.wasted = source["wasted"];
+	        this.files = this.convertValues(source["files"], DuplicateFile);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PendingFile {
+	    path: string;
+	    category: string;
+	    root?: string;
+	    dir: boolean;
+	    size: number;
+	    files: number;
+	    added: string;
+	    attempts: number;
+	    rebootScheduled?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new PendingFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.category = source["category"];
+	        this.root = source["root"];
+	        this.dir = source["dir"];
+	        this.size = source["size"];
+	        this.files = source["files"];
+	        this.added = source["added"];
+	        this.attempts = source["attempts"];
+	        this.rebootScheduled = source["rebootScheduled"];
 	    }
 	}
 	export class ScanResult {
@@ -74,6 +410,166 @@ export namespace cleaner {
 		    return a;
 		}
 	}
+	export class SkippedEntry {
+	    path: string;
+	    category: string;
+	    reason: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SkippedEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.category = source["category"];
+	        this.reason = source["reason"];
+	        this.message = source["message"];
+	    }
+	}
+	export class UsageEntry {
+	    path: string;
+	    size: number;
+	    files?: number;
+	    // Go type: time
+	    modTime?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new UsageEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.size = source["size"];
+	        this.files = source["files"];
+	        this.modTime = this.convertValues(source["modTime"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UsageGroup {
+	    name: string;
+	    size: number;
+	    files: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new UsageGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.size = source["size"];
+	        this.files = source["files"];
+	    }
+	}
+	export class UsageNode {
+	    name: string;
+	    path: string;
+	    size: number;
+	    files: number;
+	    children?: UsageNode[];
+	    otherSize?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new UsageNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.size = source["size"];
+	        this.files = source["files"];
+	        this.children = this.convertValues(source["children"], UsageNode);
+	        this.otherSize = source["otherSize"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UsageReport {
+	    root: string;
+	    // Go type: time
+	    scanned: any;
+	    size: number;
+	    files: number;
+	    tree: UsageNode;
+	    largestFiles: UsageEntry[];
+	    largestFolders: UsageEntry[];
+	    byExtension: UsageGroup[];
+	    byAge: UsageGroup[];
+	    warnings?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UsageReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.root = source["root"];
+	        this.scanned = this.convertValues(source["scanned"], null);
+	        this.size = source["size"];
+	        this.files = source["files"];
+	        this.tree = this.convertValues(source["tree"], UsageNode);
+	        this.largestFiles = this.convertValues(source["largestFiles"], UsageEntry);
+	        this.largestFolders = this.convertValues(source["largestFolders"], UsageEntry);
+	        this.byExtension = this.convertValues(source["byExtension"], UsageGroup);
+	        this.byAge = this.convertValues(source["byAge"], UsageGroup);
+	        this.warnings = source["warnings"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
 
@@ -84,6 +580,7 @@ export namespace gaming {
 	    profile: string;
 	    tweaksApplied: string[];
 	    startedAt: string;
+	    restorePoint?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new BoostStatus(source);
@@ -95,6 +592,7 @@ export namespace gaming {
 	        this.profile = source["profile"];
 	        this.tweaksApplied = source["tweaksApplied"];
 	        this.startedAt = source["startedAt"];
+	        this.restorePoint = source["restorePoint"];
 	    }
 	}
 	export class GPUInfo {
@@ -142,6 +640,7 @@ export namespace gaming {
 	    category: string;
 	    enabled: boolean;
 	    applied: boolean;
+	    revertible: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new TweakInfo(source);
@@ -155,6 +654,7 @@ export namespace gaming {
 	        this.category = source["category"];
 	        this.enabled = source["enabled"];
 	        this.applied = source["applied"];
+	        this.revertible = source["revertible"];
 	    }
 	}
 
@@ -317,6 +817,147 @@ export namespace network {
 
 }
 
+export namespace plan {
+	
+	export class Action {
+	    kind: string;
+	    module: string;
+	    tweak?: string;
+	    target: string;
+	    old?: string;
+	    new?: string;
+	    size?: number;
+	    files?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Action(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.module = source["module"];
+	        this.tweak = source["tweak"];
+	        this.target = source["target"];
+	        this.old = source["old"];
+	        this.new = source["new"];
+	        this.size = source["size"];
+	        this.files = source["files"];
+	    }
+	}
+	export class Failure {
+	    action: Action;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Failure(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action = this.convertValues(source["action"], Action);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Plan {
+	    id: string;
+	    description: string;
+	    actions: Action[];
+	    warnings?: string[];
+	    skipped?: Record<string, number>;
+	
+	    static createFrom(source: any = {}) {
+	        return new Plan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.description = source["description"];
+	        this.actions = this.convertValues(source["actions"], Action);
+	        this.warnings = source["warnings"];
+	        this.skipped = source["skipped"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Result {
+	    applied: Action[];
+	    failures: Failure[];
+	    warnings?: string[];
+	    // Go type: time
+	    started: any;
+	    // Go type: time
+	    finished: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Result(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.applied = this.convertValues(source["applied"], Action);
+	        this.failures = this.convertValues(source["failures"], Failure);
+	        this.warnings = source["warnings"];
+	        this.started = this.convertValues(source["started"], null);
+	        this.finished = this.convertValues(source["finished"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace privacy {
 	
 	export class PrivacyTweak {
@@ -344,6 +985,73 @@ export namespace privacy {
 
 }
 
+export namespace quarantine {
+	
+	export class Item {
+	    id: number;
+	    original: string;
+	    dir: boolean;
+	    size: number;
+	    files: number;
+	    sha256: string;
+	    moved: string;
+	    restored?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Item(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.original = source["original"];
+	        this.dir = source["dir"];
+	        this.size = source["size"];
+	        this.files = source["files"];
+	        this.sha256 = source["sha256"];
+	        this.moved = source["moved"];
+	        this.restored = source["restored"];
+	    }
+	}
+	export class Run {
+	    id: string;
+	    description: string;
+	    created: string;
+	    items: Item[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Run(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.description = source["description"];
+	        this.created = source["created"];
+	        this.items = this.convertValues(source["items"], Item);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace startup {
 	
 	export class StartupItem {
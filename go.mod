@@ -8,6 +8,7 @@ require (
 	github.com/shirou/gopsutil/v4 v4.26.1
 	github.com/wailsapp/wails/v2 v2.11.0
 	golang.org/x/sys v0.41.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -1,55 +1,139 @@
 package backup
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"cleanforge/internal/cmd"
-	"golang.org/x/sys/windows/registry"
+	"cleanforge/internal/reg"
 )
 
-// BackupState holds the complete system state snapshot used for backup and restore.
-type BackupState struct {
-	Timestamp    string                       `json:"timestamp"`
-	RegistryKeys map[string]RegistryBackup    `json:"registryKeys"`
-	Services     map[string]string            `json:"services"`  // service name -> original start type
-	PowerPlan    string                       `json:"powerPlan"` // original active power plan GUID
+// Entry kinds. Each kind has a matching field on Entry holding the state
+// captured before the change.
+const (
+	KindRegistry   = "registry"
+	KindService    = "service"
+	KindPowerPlan  = "powerplan"
+	KindDNS        = "dns"
+	KindBootOption = "bootoption"
+	KindFile       = "file"
+	KindRename     = "rename"
+	KindTask       = "task"
+)
+
+// Entry is a single journal record: the state of one resource captured
+// immediately before a module changed it.
+type Entry struct {
+	Seq    int    `json:"seq"`
+	Time   string `json:"time"`
+	Module string `json:"module"` // "gaming", "privacy", "network", "startup"
+	Tweak  string `json:"tweak"`  // tweak or operation that made the change
+	Kind   string `json:"kind"`
+	Target string `json:"target"` // identifies the resource; entries with equal targets touch the same thing
+
+	// Restored is set once a restore has put the resource back to this
+	// entry's state. Restored entries are kept so sequence numbers stay
+	// unique and restore points keep their meaning.
+	Restored bool `json:"restored,omitempty"`
+
+	Registry   *RegistryBackup   `json:"registry,omitempty"`
+	Service    *ServiceBackup    `json:"service,omitempty"`
+	PowerPlan  string            `json:"powerPlan,omitempty"` // previously active plan GUID
+	DNS        *DNSBackup        `json:"dns,omitempty"`
+	BootOption *BootOptionBackup `json:"bootOption,omitempty"`
+	File       *FileBackup       `json:"file,omitempty"`
+	Rename     *RenameBackup     `json:"rename,omitempty"`
+	Task       *TaskBackup       `json:"task,omitempty"`
 }
 
-// RegistryBackup holds a single registry value's backup information.
+// RegistryBackup holds a single registry value's previous contents.
 type RegistryBackup struct {
-	Path      string      `json:"path"`
-	ValueName string      `json:"valueName"`
-	Value     interface{} `json:"value"`
-	Type      string      `json:"type"`    // "string", "dword", "qword", "none"
-	Existed   bool        `json:"existed"` // if the key/value existed before we changed it
+	Root      reg.Root      `json:"root"`
+	Path      string        `json:"path"`
+	ValueName string        `json:"valueName"`
+	Type      reg.ValueType `json:"type"`
+	String    string        `json:"string,omitempty"`  // SZ, EXPAND_SZ
+	Integer   uint64        `json:"integer,omitempty"` // DWORD, QWORD
+	Strings   []string      `json:"strings,omitempty"` // MULTI_SZ
+	Binary    []byte        `json:"binary,omitempty"`  // everything else
+	Existed   bool          `json:"existed"`           // false if the value did not exist
+}
+
+// ServiceBackup holds a service's start type and whether it was running.
+type ServiceBackup struct {
+	Name      string `json:"name"`
+	StartType string `json:"startType"` // as reported by `sc qc`, e.g. "AUTO_START"
+	Running   bool   `json:"running"`
 }
 
-// backupFilename is the name of the backup state file.
-const backupFilename = "backup_state.json"
+// DNSBackup holds an adapter's statically configured DNS servers. An empty
+// list means the adapter was using DHCP-provided DNS.
+type DNSBackup struct {
+	Adapter string   `json:"adapter"`
+	Servers []string `json:"servers"`
+}
 
-// state holds the current in-memory backup state.
-var state *BackupState
+// BootOptionBackup holds a boot configuration (bcdedit) element.
+type BootOptionBackup struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Existed bool   `json:"existed"`
+}
 
-// init initializes the in-memory state.
-func init() {
-	state = newEmptyState()
+// FileBackup holds a file's previous contents.
+type FileBackup struct {
+	Path    string `json:"path"`
+	Content []byte `json:"content,omitempty"`
+	Existed bool   `json:"existed"`
 }
 
-// newEmptyState creates a fresh empty BackupState.
-func newEmptyState() *BackupState {
-	return &BackupState{
-		Timestamp:    time.Now().Format(time.RFC3339),
-		RegistryKeys: make(map[string]RegistryBackup),
-		Services:     make(map[string]string),
-		PowerPlan:    "",
-	}
+// RenameBackup records that a file was moved from From to To.
+type RenameBackup struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// TaskBackup holds whether a scheduled task was enabled.
+type TaskBackup struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// journalFilename is the name of the append-only journal file.
+const journalFilename = "journal.jsonl"
+
+// Journal is the single store every mutating operation records into before
+// it touches the system. Entries are appended to a JSON-lines file and are
+// only rewritten when a restore marks them restored, which also drops
+// restored entries that no restore point needs.
+type Journal struct {
+	mu       sync.Mutex
+	dir      string
+	registry reg.Registry
+	runner   cmd.Runner
+
+	// lastSeq is the newest sequence number in the journal file, valid
+	// while the file is still seqSize bytes long. A different size means
+	// the file changed behind our back, e.g. from another process.
+	lastSeq int
+	seqSize int64
+}
+
+// NewJournal returns a journal stored in dir that reads and restores state
+// through registry and runner. An empty dir means GetBackupPath().
+func NewJournal(dir string, registry reg.Registry, runner cmd.Runner) *Journal {
+	return &Journal{dir: dir, registry: registry, runner: runner, seqSize: -1}
 }
 
+// Default is the journal shared by all modules.
+var Default = NewJournal("", reg.Default, cmd.Default)
+
 // GetBackupPath returns the directory where backup files are stored.
 // Creates the directory if it does not exist.
 func GetBackupPath() string {
@@ -67,376 +151,238 @@ func GetBackupPath() string {
 	return backupDir
 }
 
-// getBackupFilePath returns the full path to the backup state JSON file.
-func getBackupFilePath() string {
-	return filepath.Join(GetBackupPath(), backupFilename)
-}
-
-// SaveRegistryValue reads the current value of a registry key and saves it to the
-// in-memory backup state. rootKey should be one of: "HKLM", "HKCU", "HKCR", "HKU", "HKCC".
-// path is the registry subkey path, and valueName is the specific value name to back up.
-func SaveRegistryValue(rootKey, path, valueName string) error {
-	root, err := parseRootKey(rootKey)
-	if err != nil {
-		return err
+// Dir returns the directory holding the journal file.
+func (j *Journal) Dir() string {
+	if j.dir != "" {
+		return j.dir
 	}
+	return GetBackupPath()
+}
 
-	// Construct a unique key for the map
-	mapKey := fmt.Sprintf("%s\\%s\\%s", rootKey, path, valueName)
+func (j *Journal) path() string {
+	return filepath.Join(j.Dir(), journalFilename)
+}
 
-	// Try to open the registry key
-	key, err := registry.OpenKey(root, path, registry.QUERY_VALUE)
+// Entries returns the journal entries that have not been restored yet, in
+// the order they were recorded.
+func (j *Journal) Entries() ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries, err := j.load()
 	if err != nil {
-		// Key doesn't exist; record that it didn't exist
-		state.RegistryKeys[mapKey] = RegistryBackup{
-			Path:      fmt.Sprintf("%s\\%s", rootKey, path),
-			ValueName: valueName,
-			Value:     nil,
-			Type:      "none",
-			Existed:   false,
-		}
-		return nil
+		return nil, err
 	}
-	defer key.Close()
+	return pending(entries), nil
+}
 
-	// Read the value
-	val, valType, err := key.GetValue(valueName, nil)
-	if err != nil {
-		// Value doesn't exist under the key
-		state.RegistryKeys[mapKey] = RegistryBackup{
-			Path:      fmt.Sprintf("%s\\%s", rootKey, path),
-			ValueName: valueName,
-			Value:     nil,
-			Type:      "none",
-			Existed:   false,
+// pending filters out restored entries.
+func pending(entries []Entry) []Entry {
+	var out []Entry
+	for _, e := range entries {
+		if !e.Restored {
+			out = append(out, e)
 		}
-		return nil
 	}
+	return out
+}
 
-	// Determine the type and read the actual value
-	backup := RegistryBackup{
-		Path:      fmt.Sprintf("%s\\%s", rootKey, path),
-		ValueName: valueName,
-		Existed:   true,
+// HasEntries reports whether the journal holds anything to restore.
+func (j *Journal) HasEntries() bool {
+	entries, err := j.Entries()
+	return err == nil && len(entries) > 0
+}
+
+// load reads the journal, migrating legacy backup_state.json files the first
+// time. Callers must hold j.mu.
+func (j *Journal) load() ([]Entry, error) {
+	f, err := os.Open(j.path())
+	if os.IsNotExist(err) {
+		j.cacheSeq(nil, -1)
+		return j.migrateLegacy()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
 	}
+	defer f.Close()
 
-	switch valType {
-	case registry.SZ, registry.EXPAND_SZ:
-		strVal, _, err := key.GetStringValue(valueName)
-		if err == nil {
-			backup.Value = strVal
-			backup.Type = "string"
-		}
-	case registry.DWORD:
-		dwordVal, _, err := key.GetIntegerValue(valueName)
-		if err == nil {
-			backup.Value = uint32(dwordVal)
-			backup.Type = "dword"
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
 		}
-	case registry.QWORD:
-		qwordVal, _, err := key.GetIntegerValue(valueName)
-		if err == nil {
-			backup.Value = qwordVal
-			backup.Type = "qword"
+		var e Entry
+		if err := json.Unmarshal([]byte(text), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse journal line %d: %w", line, err)
 		}
-	default:
-		// Store raw bytes for unsupported types
-		backup.Value = val
-		backup.Type = "binary"
+		entries = append(entries, e)
 	}
-
-	state.RegistryKeys[mapKey] = backup
-	return nil
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	if info, err := f.Stat(); err == nil {
+		j.cacheSeq(entries, info.Size())
+	}
+	return entries, nil
 }
 
-// SaveServiceState reads the current start type of a Windows service and saves it
-// to the in-memory backup state.
-func SaveServiceState(serviceName string) error {
-	out, err := cmd.Hidden("sc", "qc", serviceName).Output()
-	if err != nil {
-		return fmt.Errorf("failed to query service '%s': %w", serviceName, err)
+// cacheSeq remembers the newest sequence number in entries, which make up a
+// journal file of size bytes. Callers must hold j.mu.
+func (j *Journal) cacheSeq(entries []Entry, size int64) {
+	j.lastSeq = 0
+	if n := len(entries); n > 0 {
+		j.lastSeq = entries[n-1].Seq
 	}
+	j.seqSize = size
+}
 
-	startType := parseServiceStartType(string(out))
-	if startType == "" {
-		return fmt.Errorf("could not parse start type for service '%s'", serviceName)
+// nextSeq returns the sequence number for a new entry, reading the journal
+// only when the cached number may be stale. Callers must hold j.mu.
+func (j *Journal) nextSeq() (int, error) {
+	info, err := os.Stat(j.path())
+	if err != nil || info.Size() != j.seqSize {
+		if _, err := j.load(); err != nil {
+			return 0, err
+		}
 	}
-
-	state.Services[serviceName] = startType
-	return nil
+	return j.lastSeq + 1, nil
 }
 
-// SavePowerPlan reads the currently active power plan GUID and saves it to the
-// in-memory backup state.
-func SavePowerPlan() error {
-	out, err := cmd.Hidden("powercfg", "/getactivescheme").Output()
+// append assigns e the next sequence number and writes it to the journal.
+// Callers must hold j.mu.
+func (j *Journal) append(e Entry) error {
+	seq, err := j.nextSeq()
 	if err != nil {
-		return fmt.Errorf("failed to get active power plan: %w", err)
+		return err
 	}
-
-	guid := parsePowerPlanGUID(string(out))
-	if guid == "" {
-		return fmt.Errorf("could not parse power plan GUID from output: %s", string(out))
+	e.Seq = seq
+	if e.Time == "" {
+		e.Time = time.Now().Format(time.RFC3339)
 	}
 
-	state.PowerPlan = guid
-	return nil
-}
-
-// Save writes the current in-memory backup state to the JSON file on disk.
-// Uses MarshalIndent for human-readable output.
-func Save() error {
-	state.Timestamp = time.Now().Format(time.RFC3339)
-
-	data, err := json.MarshalIndent(state, "", "  ")
+	data, err := json.Marshal(e)
 	if err != nil {
-		return fmt.Errorf("failed to marshal backup state: %w", err)
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
 	}
-
-	filePath := getBackupFilePath()
-
-	// Ensure the directory exists
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := os.MkdirAll(j.Dir(), 0755); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
-
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write backup file: %w", err)
-	}
-
-	return nil
-}
-
-// Load reads the backup state from the JSON file on disk and returns it.
-// Also loads it into the in-memory state for subsequent operations.
-func Load() (*BackupState, error) {
-	filePath := getBackupFilePath()
-
-	data, err := os.ReadFile(filePath)
+	f, err := os.OpenFile(j.path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read backup file: %w", err)
+		return fmt.Errorf("failed to open journal: %w", err)
 	}
-
-	loaded := &BackupState{}
-	if err := json.Unmarshal(data, loaded); err != nil {
-		return nil, fmt.Errorf("failed to parse backup file: %w", err)
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		j.seqSize = -1
+		return fmt.Errorf("failed to write journal: %w", err)
 	}
-
-	// Ensure maps are initialized
-	if loaded.RegistryKeys == nil {
-		loaded.RegistryKeys = make(map[string]RegistryBackup)
+	if err := f.Close(); err != nil {
+		j.seqSize = -1
+		return err
 	}
-	if loaded.Services == nil {
-		loaded.Services = make(map[string]string)
+	j.lastSeq = e.Seq
+	if info, err := os.Stat(j.path()); err == nil {
+		j.seqSize = info.Size()
+	} else {
+		j.seqSize = -1
 	}
-
-	state = loaded
-	return loaded, nil
+	return nil
 }
 
-// RestoreRegistry reads the backup state and restores all registry values.
-// If a value did not exist before (Existed=false), the value is deleted.
-// If it did exist, it is set back to its original value.
-func RestoreRegistry() error {
-	if state == nil || len(state.RegistryKeys) == 0 {
-		return nil
+// rewrite atomically replaces the journal with entries. Callers must hold j.mu.
+func (j *Journal) rewrite(entries []Entry) error {
+	if err := os.MkdirAll(j.Dir(), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
-
-	var errors []string
-
-	for _, backup := range state.RegistryKeys {
-		err := restoreSingleRegistryValue(backup)
+	var b strings.Builder
+	for _, e := range entries {
+		data, err := json.Marshal(e)
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("%s\\%s: %v", backup.Path, backup.ValueName, err))
+			return fmt.Errorf("failed to marshal journal entry: %w", err)
 		}
+		b.Write(data)
+		b.WriteByte('\n')
 	}
-
-	if len(errors) > 0 {
-		return fmt.Errorf("some registry values could not be restored:\n%s", strings.Join(errors, "\n"))
+	tmp := j.path() + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
 	}
-
+	if err := os.Rename(tmp, j.path()); err != nil {
+		j.seqSize = -1
+		return fmt.Errorf("failed to replace journal: %w", err)
+	}
+	j.cacheSeq(entries, int64(b.Len()))
 	return nil
 }
 
-// restoreSingleRegistryValue restores a single registry value from its backup.
-func restoreSingleRegistryValue(backup RegistryBackup) error {
-	// Parse the root key and subpath from the full path
-	rootKeyStr, subPath, err := splitRegistryPath(backup.Path)
+// compact drops restored entries that nothing needs any more: those that
+// are not the position of a restore point. The newest entry is always kept
+// so sequence numbers keep increasing after the journal is reopened.
+// Callers must hold j.mu.
+func (j *Journal) compact(entries []Entry) ([]Entry, error) {
+	points, err := j.loadRestorePoints()
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	root, err := parseRootKey(rootKeyStr)
-	if err != nil {
-		return err
+	marked := make(map[int]bool, len(points))
+	for _, p := range points {
+		marked[p.Seq] = true
 	}
-
-	if !backup.Existed {
-		// The value didn't exist before; delete it
-		key, err := registry.OpenKey(root, subPath, registry.SET_VALUE)
-		if err != nil {
-			// Key doesn't exist anyway, nothing to delete
-			return nil
-		}
-		defer key.Close()
-
-		err = key.DeleteValue(backup.ValueName)
-		if err != nil {
-			// Value might not exist, which is fine
-			return nil
+	var kept []Entry
+	for i, e := range entries {
+		if !e.Restored || marked[e.Seq] || i == len(entries)-1 {
+			kept = append(kept, e)
 		}
-		return nil
-	}
-
-	// The value existed; restore it
-	key, err := registry.OpenKey(root, subPath, registry.SET_VALUE)
-	if err != nil {
-		// Try to create the key
-		key, _, err = registry.CreateKey(root, subPath, registry.SET_VALUE)
-		if err != nil {
-			return fmt.Errorf("failed to open/create registry key: %w", err)
-		}
-	}
-	defer key.Close()
-
-	switch backup.Type {
-	case "string":
-		strVal, ok := backup.Value.(string)
-		if !ok {
-			return fmt.Errorf("expected string value, got %T", backup.Value)
-		}
-		return key.SetStringValue(backup.ValueName, strVal)
-
-	case "dword":
-		var dwordVal uint32
-		switch v := backup.Value.(type) {
-		case float64:
-			// JSON unmarshals numbers as float64
-			dwordVal = uint32(v)
-		case uint32:
-			dwordVal = v
-		case int:
-			dwordVal = uint32(v)
-		default:
-			return fmt.Errorf("expected numeric value for DWORD, got %T", backup.Value)
-		}
-		return key.SetDWordValue(backup.ValueName, dwordVal)
-
-	case "qword":
-		var qwordVal uint64
-		switch v := backup.Value.(type) {
-		case float64:
-			qwordVal = uint64(v)
-		case uint64:
-			qwordVal = v
-		case int:
-			qwordVal = uint64(v)
-		default:
-			return fmt.Errorf("expected numeric value for QWORD, got %T", backup.Value)
-		}
-		return key.SetQWordValue(backup.ValueName, qwordVal)
-
-	case "none":
-		// Nothing to restore
-		return nil
-
-	default:
-		return fmt.Errorf("unsupported registry value type: %s", backup.Type)
 	}
+	return kept, nil
 }
 
-// RestoreServices restores all service start types from the backup state.
-func RestoreServices() error {
-	if state == nil || len(state.Services) == 0 {
-		return nil
-	}
-
-	var errors []string
-
-	for serviceName, startType := range state.Services {
-		scStartType := mapStartTypeToSC(startType)
-		if scStartType == "" {
-			errors = append(errors, fmt.Sprintf("%s: unknown start type '%s'", serviceName, startType))
-			continue
-		}
-
-		cmd := cmd.Hidden("sc", "config", serviceName, "start=", scStartType)
-		if out, err := cmd.CombinedOutput(); err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v (%s)", serviceName, err, strings.TrimSpace(string(out))))
-		}
-	}
-
-	if len(errors) > 0 {
-		return fmt.Errorf("some services could not be restored:\n%s", strings.Join(errors, "\n"))
-	}
-
-	return nil
+// For returns a Recorder that attributes entries on the default journal to
+// module and tweak.
+func For(module, tweak string) Recorder {
+	return Default.For(module, tweak)
 }
 
-// RestorePowerPlan restores the original active power plan from the backup state.
-func RestorePowerPlan() error {
-	if state == nil || state.PowerPlan == "" {
-		return nil
-	}
-
-	cmd := cmd.Hidden("powercfg", "/setactive", state.PowerPlan)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to restore power plan %s: %v (%s)", state.PowerPlan, err, strings.TrimSpace(string(out)))
-	}
-
-	return nil
+// RestoreAll undoes every change recorded in the default journal.
+func RestoreAll() error {
+	return Default.RestoreAll()
 }
 
-// RestoreAll performs a full restoration: registry values, services, and power plan.
-func RestoreAll() error {
-	var errors []string
+// HasBackup reports whether the default journal holds anything to restore.
+func HasBackup() bool {
+	return Default.HasEntries()
+}
 
-	if err := RestoreRegistry(); err != nil {
-		errors = append(errors, fmt.Sprintf("Registry: %v", err))
-	}
+// RestorePoints returns the default journal's restore points, oldest first.
+func RestorePoints() ([]RestorePoint, error) {
+	return Default.RestorePoints()
+}
 
-	if err := RestoreServices(); err != nil {
-		errors = append(errors, fmt.Sprintf("Services: %v", err))
-	}
+// CreateRestorePoint marks the current position in the default journal.
+func CreateRestorePoint(description string) (RestorePoint, error) {
+	return Default.CreateRestorePoint(description)
+}
 
-	if err := RestorePowerPlan(); err != nil {
-		errors = append(errors, fmt.Sprintf("PowerPlan: %v", err))
-	}
+// Diff compares a restore point in the default journal with the current state.
+func Diff(id string) ([]Change, error) {
+	return Default.Diff(id)
+}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("restore completed with errors:\n%s", strings.Join(errors, "\n"))
-	}
+// RestoreTo undoes every change in the default journal made after a restore point.
+func RestoreTo(id string) error {
+	return Default.RestoreTo(id)
+}
 
-	return nil
+// Prune removes old restore points from the default journal.
+func Prune(maxAge time.Duration, keep int) ([]RestorePoint, error) {
+	return Default.Prune(maxAge, keep)
 }
 
-// HasBackup checks whether a backup file exists on disk.
-func HasBackup() bool {
-	filePath := getBackupFilePath()
-	info, err := os.Stat(filePath)
-	if err != nil {
-		return false
-	}
-	return !info.IsDir() && info.Size() > 0
-}
-
-// parseRootKey converts a root key string to a registry.Key constant.
-func parseRootKey(rootKey string) (registry.Key, error) {
-	switch strings.ToUpper(rootKey) {
-	case "HKLM", "HKEY_LOCAL_MACHINE":
-		return registry.LOCAL_MACHINE, nil
-	case "HKCU", "HKEY_CURRENT_USER":
-		return registry.CURRENT_USER, nil
-	case "HKCR", "HKEY_CLASSES_ROOT":
-		return registry.CLASSES_ROOT, nil
-	case "HKU", "HKEY_USERS":
-		return registry.USERS, nil
-	case "HKCC", "HKEY_CURRENT_CONFIG":
-		return registry.CURRENT_CONFIG, nil
-	default:
-		return 0, fmt.Errorf("unknown registry root key: %s", rootKey)
-	}
+// parseRootKey converts a root key string to a reg.Root.
+func parseRootKey(rootKey string) (reg.Root, error) {
+	return reg.ParseRoot(rootKey)
 }
 
 // splitRegistryPath splits a full registry path like "HKLM\SOFTWARE\Test" into
@@ -4,10 +4,52 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"cleanforge/internal/cmd"
+	"cleanforge/internal/reg"
 )
 
+// newTestJournal returns a journal in a temp directory backed by a reg.Memory
+// seeded from the given .reg fixture and a cmd.Fake.
+func newTestJournal(t *testing.T, fixture string) (*Journal, *reg.Memory, *cmd.Fake) {
+	t.Helper()
+	mem, err := reg.FromReg(fixture)
+	if err != nil {
+		t.Fatalf("load registry fixture: %v", err)
+	}
+	fake := cmd.NewFake()
+	return NewJournal(t.TempDir(), mem, fake), mem, fake
+}
+
+// setString writes a string value, failing the test on error.
+func setString(t *testing.T, mem *reg.Memory, root reg.Root, path, name, value string) {
+	t.Helper()
+	key, _, err := mem.CreateKey(root, path, reg.SetValue)
+	if err != nil {
+		t.Fatalf("CreateKey(%s\\%s): %v", root, path, err)
+	}
+	defer key.Close()
+	if err := key.SetStringValue(name, value); err != nil {
+		t.Fatalf("SetStringValue(%s): %v", name, err)
+	}
+}
+
+// getString reads a string value, returning "" if it does not exist.
+func getString(mem *reg.Memory, root reg.Root, path, name string) string {
+	key, err := mem.OpenKey(root, path, reg.QueryValue)
+	if err != nil {
+		return ""
+	}
+	defer key.Close()
+	v, _, _ := key.GetStringValue(name)
+	return v
+}
+
 func TestGetBackupPath(t *testing.T) {
 	path := GetBackupPath()
 	if path == "" {
@@ -32,103 +74,6 @@ func TestGetBackupPath(t *testing.T) {
 	}
 }
 
-func TestNewEmptyState(t *testing.T) {
-	s := newEmptyState()
-	if s == nil {
-		t.Fatal("newEmptyState returned nil")
-	}
-
-	if s.Timestamp == "" {
-		t.Error("Timestamp is empty")
-	}
-
-	if s.RegistryKeys == nil {
-		t.Error("RegistryKeys map is nil")
-	}
-
-	if s.Services == nil {
-		t.Error("Services map is nil")
-	}
-
-	if len(s.RegistryKeys) != 0 {
-		t.Error("RegistryKeys should be empty")
-	}
-
-	if len(s.Services) != 0 {
-		t.Error("Services should be empty")
-	}
-}
-
-func TestSaveAndLoad(t *testing.T) {
-	// Use a temp directory for the test
-	tmpDir := t.TempDir()
-	tmpFile := filepath.Join(tmpDir, backupFilename)
-
-	// Override the state with test data
-	oldState := state
-	defer func() { state = oldState }()
-
-	state = &BackupState{
-		Timestamp:    "2026-01-01T00:00:00Z",
-		RegistryKeys: map[string]RegistryBackup{
-			"HKCU\\Test\\Value": {
-				Path:      "HKCU\\Test",
-				ValueName: "Value",
-				Value:     "test_data",
-				Type:      "string",
-				Existed:   true,
-			},
-		},
-		Services:  map[string]string{"TestService": "AUTO_START"},
-		PowerPlan: "381b4222-f694-41f0-9685-ff5bb260df2e",
-	}
-
-	// Save to temp file
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
-		t.Fatalf("marshal failed: %v", err)
-	}
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-		t.Fatalf("write failed: %v", err)
-	}
-
-	// Verify file exists and is not empty
-	info, err := os.Stat(tmpFile)
-	if err != nil {
-		t.Fatalf("stat failed: %v", err)
-	}
-	if info.Size() == 0 {
-		t.Error("backup file is empty")
-	}
-
-	// Read it back
-	readData, err := os.ReadFile(tmpFile)
-	if err != nil {
-		t.Fatalf("read failed: %v", err)
-	}
-
-	loaded := &BackupState{}
-	if err := json.Unmarshal(readData, loaded); err != nil {
-		t.Fatalf("unmarshal failed: %v", err)
-	}
-
-	if loaded.Timestamp != "2026-01-01T00:00:00Z" {
-		t.Errorf("expected timestamp %q, got %q", "2026-01-01T00:00:00Z", loaded.Timestamp)
-	}
-
-	if loaded.PowerPlan != "381b4222-f694-41f0-9685-ff5bb260df2e" {
-		t.Errorf("expected powerPlan %q, got %q", "381b4222-f694-41f0-9685-ff5bb260df2e", loaded.PowerPlan)
-	}
-
-	if len(loaded.RegistryKeys) != 1 {
-		t.Errorf("expected 1 registry key, got %d", len(loaded.RegistryKeys))
-	}
-
-	if loaded.Services["TestService"] != "AUTO_START" {
-		t.Errorf("expected service TestService=AUTO_START, got %q", loaded.Services["TestService"])
-	}
-}
-
 func TestParseRootKey(t *testing.T) {
 	tests := []struct {
 		input   string
@@ -325,6 +270,20 @@ func TestParsePowerPlanGUID(t *testing.T) {
 	}
 }
 
+func TestParseBootOption(t *testing.T) {
+	output := "Windows Boot Loader\n-------------------\nidentifier              {current}\nuseplatformclock        Yes\ndescription             Windows 11\n"
+
+	if v, ok := parseBootOption(output, "useplatformclock"); !ok || v != "Yes" {
+		t.Errorf("parseBootOption(useplatformclock) = %q, %v; want %q, true", v, ok, "Yes")
+	}
+	if v, ok := parseBootOption(output, "description"); !ok || v != "Windows 11" {
+		t.Errorf("parseBootOption(description) = %q, %v; want %q, true", v, ok, "Windows 11")
+	}
+	if _, ok := parseBootOption(output, "disabledynamictick"); ok {
+		t.Error("parseBootOption found an element that is not present")
+	}
+}
+
 func TestHasBackup(t *testing.T) {
 	// Simply verify it does not panic
 	defer func() {
@@ -337,81 +296,776 @@ func TestHasBackup(t *testing.T) {
 	t.Logf("HasBackup: %v", result)
 }
 
-func TestRestoreRegistryEmptyState(t *testing.T) {
-	// Restore with empty state should be a no-op
-	oldState := state
-	defer func() { state = oldState }()
+func TestRestoreAllEmptyJournal(t *testing.T) {
+	j, _, _ := newTestJournal(t, "")
+	if err := j.RestoreAll(); err != nil {
+		t.Errorf("RestoreAll with empty journal should not error: %v", err)
+	}
+	if j.HasEntries() {
+		t.Error("empty journal reports entries")
+	}
+}
+
+func TestRegistryRecordRestoreRoundTrip(t *testing.T) {
+	j, mem, _ := newTestJournal(t, `
+[HKEY_CURRENT_USER\Control Panel\Mouse]
+"MouseSpeed"="1"
+
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Control\PriorityControl]
+"Win32PrioritySeparation"=dword:00000002
+"Big"=hex(b):2a,00,00,00,00,00,00,00
+"Blob"=hex:01,02,03
+"List"=hex(7):61,00,00,00,62,00,00,00,00,00
+`)
+
+	rec := j.For("gaming", "test")
+	saves := []struct {
+		root       reg.Root
+		path, name string
+	}{
+		{reg.CurrentUser, `Control Panel\Mouse`, "MouseSpeed"},
+		{reg.CurrentUser, `Control Panel\Mouse`, "MouseThreshold1"},
+		{reg.LocalMachine, `SYSTEM\CurrentControlSet\Control\PriorityControl`, "Win32PrioritySeparation"},
+		{reg.LocalMachine, `SYSTEM\CurrentControlSet\Control\PriorityControl`, "Big"},
+		{reg.LocalMachine, `SYSTEM\CurrentControlSet\Control\PriorityControl`, "Blob"},
+		{reg.LocalMachine, `SYSTEM\CurrentControlSet\Control\PriorityControl`, "List"},
+		{reg.CurrentUser, `Software\DoesNotExist`, "Anything"},
+	}
+	for _, s := range saves {
+		if err := rec.RegistryValue(s.root, s.path, s.name); err != nil {
+			t.Fatalf("RegistryValue(%s, %s, %s): %v", s.root, s.path, s.name, err)
+		}
+	}
+
+	// Simulate tweaks overwriting and adding values.
+	mouse, _, _ := mem.CreateKey(reg.CurrentUser, `Control Panel\Mouse`, reg.AllAccess)
+	_ = mouse.SetStringValue("MouseSpeed", "0")
+	_ = mouse.SetStringValue("MouseThreshold1", "0")
+	prio, _, _ := mem.CreateKey(reg.LocalMachine, `SYSTEM\CurrentControlSet\Control\PriorityControl`, reg.AllAccess)
+	_ = prio.SetDWordValue("Win32PrioritySeparation", 0x26)
+	_ = prio.SetQWordValue("Big", 1)
+	_ = prio.SetBinaryValue("Blob", []byte{9})
+	_ = prio.SetStringsValue("List", []string{"x"})
+	setString(t, mem, reg.CurrentUser, `Software\DoesNotExist`, "Anything", "x")
+
+	if err := j.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+
+	if v, _, _ := mouse.GetStringValue("MouseSpeed"); v != "1" {
+		t.Errorf("MouseSpeed = %q, want %q", v, "1")
+	}
+	if _, _, err := mouse.GetStringValue("MouseThreshold1"); err == nil {
+		t.Error("MouseThreshold1 did not exist at backup time and should be deleted")
+	}
+	if v, typ, _ := prio.GetIntegerValue("Win32PrioritySeparation"); v != 2 || typ != reg.DWord {
+		t.Errorf("Win32PrioritySeparation = %d (type %d), want 2 (DWORD)", v, typ)
+	}
+	if v, typ, _ := prio.GetIntegerValue("Big"); v != 42 || typ != reg.QWord {
+		t.Errorf("Big = %d (type %d), want 42 (QWORD)", v, typ)
+	}
+	if v, _, _ := prio.GetValue("Blob"); !reflect.DeepEqual(v, []byte{1, 2, 3}) {
+		t.Errorf("Blob = %v, want [1 2 3]", v)
+	}
+	if v, typ, _ := prio.GetStringsValue("List"); !reflect.DeepEqual(v, []string{"a", "b"}) || typ != reg.MultiSZ {
+		t.Errorf("List = %q (type %d), want [a b] (MULTI_SZ)", v, typ)
+	}
+	if v := getString(mem, reg.CurrentUser, `Software\DoesNotExist`, "Anything"); v != "" {
+		t.Errorf("Anything = %q, want it deleted", v)
+	}
+	if j.HasEntries() {
+		t.Error("journal should be empty after a successful restore")
+	}
+}
+
+func TestRegistryValueRefusesUnrestorableTypes(t *testing.T) {
+	j, _, _ := newTestJournal(t, `
+[HKEY_LOCAL_MACHINE\SYSTEM\Test]
+"Marker"=hex(0):01,02
+"Resources"=hex(8):00,00
+`)
+	for _, name := range []string{"Marker", "Resources"} {
+		if err := j.For("gaming", "test").RegistryValue(reg.LocalMachine, `SYSTEM\Test`, name); err == nil {
+			t.Errorf("RegistryValue(%s) should refuse a type it cannot restore", name)
+		}
+	}
+	if j.HasEntries() {
+		t.Error("nothing should be recorded for values that cannot be restored")
+	}
+}
+
+func TestRestoreUsesEarliestState(t *testing.T) {
+	j, mem, _ := newTestJournal(t, `
+[HKEY_CURRENT_USER\Control Panel\Desktop]
+"SmoothScroll"="1"
+`)
+	const path = `Control Panel\Desktop`
 
-	state = newEmptyState()
-	err := RestoreRegistry()
+	for _, v := range []string{"2", "3"} {
+		if err := j.For("gaming", "smooth").RegistryValue(reg.CurrentUser, path, "SmoothScroll"); err != nil {
+			t.Fatal(err)
+		}
+		setString(t, mem, reg.CurrentUser, path, "SmoothScroll", v)
+	}
+
+	if err := j.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	if v := getString(mem, reg.CurrentUser, path, "SmoothScroll"); v != "1" {
+		t.Errorf("SmoothScroll = %q, want %q", v, "1")
+	}
+}
+
+func TestRestoreModule(t *testing.T) {
+	j, mem, _ := newTestJournal(t, `
+[HKEY_CURRENT_USER\Software\Test]
+"Shared"="original"
+"Gaming"="original"
+"Privacy"="original"
+`)
+	const path = `Software\Test`
+	change := func(module, name, value string) {
+		t.Helper()
+		if err := j.For(module, "tweak").RegistryValue(reg.CurrentUser, path, name); err != nil {
+			t.Fatal(err)
+		}
+		setString(t, mem, reg.CurrentUser, path, name, value)
+	}
+
+	change("privacy", "Shared", "privacy")
+	change("privacy", "Privacy", "privacy")
+	change("gaming", "Shared", "gaming")
+	change("gaming", "Gaming", "gaming")
+
+	if err := j.RestoreModule("gaming"); err != nil {
+		t.Fatalf("RestoreModule: %v", err)
+	}
+
+	want := map[string]string{
+		"Shared":  "privacy", // back to how gaming found it
+		"Gaming":  "original",
+		"Privacy": "privacy", // untouched by gaming
+	}
+	for name, w := range want {
+		if v := getString(mem, reg.CurrentUser, path, name); v != w {
+			t.Errorf("%s = %q, want %q", name, v, w)
+		}
+	}
+
+	entries, err := j.Entries()
 	if err != nil {
-		t.Errorf("RestoreRegistry with empty state should not error: %v", err)
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Module != "privacy" {
+			t.Errorf("entry %d (%s) from module %q should have been consumed", e.Seq, e.Target, e.Module)
+		}
+	}
+	if len(entries) != 2 {
+		t.Errorf("got %d entries left, want 2", len(entries))
+	}
+
+	if err := j.RestoreModule("privacy"); err != nil {
+		t.Fatalf("RestoreModule: %v", err)
+	}
+	for _, name := range []string{"Shared", "Gaming", "Privacy"} {
+		if v := getString(mem, reg.CurrentUser, path, name); v != "original" {
+			t.Errorf("%s = %q, want %q", name, v, "original")
+		}
 	}
 }
 
-func TestRestoreServicesEmptyState(t *testing.T) {
-	oldState := state
-	defer func() { state = oldState }()
+func TestRestoreTweak(t *testing.T) {
+	j, mem, _ := newTestJournal(t, `
+[HKEY_CURRENT_USER\Software\Test]
+"Mouse"="original"
+"Keyboard"="original"
+`)
+	const path = `Software\Test`
+	for _, name := range []string{"Mouse", "Keyboard"} {
+		if err := j.For("gaming", strings.ToLower(name)).RegistryValue(reg.CurrentUser, path, name); err != nil {
+			t.Fatal(err)
+		}
+		setString(t, mem, reg.CurrentUser, path, name, "boosted")
+	}
+
+	if err := j.RestoreTweak("gaming", "mouse"); err != nil {
+		t.Fatalf("RestoreTweak: %v", err)
+	}
+	if v := getString(mem, reg.CurrentUser, path, "Mouse"); v != "original" {
+		t.Errorf("Mouse = %q, want %q", v, "original")
+	}
+	if v := getString(mem, reg.CurrentUser, path, "Keyboard"); v != "boosted" {
+		t.Errorf("Keyboard = %q, want %q", v, "boosted")
+	}
 
-	state = newEmptyState()
-	err := RestoreServices()
+	entries, err := j.Entries()
 	if err != nil {
-		t.Errorf("RestoreServices with empty state should not error: %v", err)
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Tweak != "keyboard" {
+		t.Errorf("entries left = %+v, want only the keyboard tweak's", entries)
 	}
 }
 
-func TestRestorePowerPlanEmptyState(t *testing.T) {
-	oldState := state
-	defer func() { state = oldState }()
+func TestRestoreTweakKeepsLaterTweaks(t *testing.T) {
+	j, mem, _ := newTestJournal(t, `
+[HKEY_CURRENT_USER\Control Panel\Mouse]
+"MouseSpeed"="1"
+"MouseThreshold1"="6"
+`)
+	const path = `Control Panel\Mouse`
+	change := func(tweak, name, value string) {
+		t.Helper()
+		if err := j.For("gaming", tweak).RegistryValue(reg.CurrentUser, path, name); err != nil {
+			t.Fatal(err)
+		}
+		setString(t, mem, reg.CurrentUser, path, name, value)
+	}
 
-	state = newEmptyState()
-	err := RestorePowerPlan()
+	change("raw_input", "MouseSpeed", "0")
+	change("no_accel", "MouseSpeed", "0")
+	change("no_accel", "MouseThreshold1", "0")
+
+	// no_accel still wants MouseSpeed at 0, so reverting raw_input leaves it
+	if err := j.RestoreTweak("gaming", "raw_input"); err != nil {
+		t.Fatalf("RestoreTweak(raw_input): %v", err)
+	}
+	for name, want := range map[string]string{"MouseSpeed": "0", "MouseThreshold1": "0"} {
+		if v := getString(mem, reg.CurrentUser, path, name); v != want {
+			t.Errorf("%s = %q after reverting raw_input, want %q", name, v, want)
+		}
+	}
+	entries, err := j.Entries()
 	if err != nil {
-		t.Errorf("RestorePowerPlan with empty state should not error: %v", err)
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Tweak != "no_accel" {
+			t.Errorf("entry %d (%s) of %s should have been taken out", e.Seq, e.Target, e.Tweak)
+		}
+	}
+	if len(entries) != 2 || entries[0].Registry.String != "1" {
+		t.Fatalf("entries = %+v, want no_accel's two, MouseSpeed holding the original \"1\"", entries)
+	}
+
+	if err := j.RestoreTweak("gaming", "no_accel"); err != nil {
+		t.Fatalf("RestoreTweak(no_accel): %v", err)
+	}
+	for name, want := range map[string]string{"MouseSpeed": "1", "MouseThreshold1": "6"} {
+		if v := getString(mem, reg.CurrentUser, path, name); v != want {
+			t.Errorf("%s = %q after reverting both, want %q", name, v, want)
+		}
+	}
+	if j.HasEntries() {
+		t.Error("nothing should be left to restore")
+	}
+}
+
+func TestServiceRecordRestore(t *testing.T) {
+	j, _, fake := newTestJournal(t, "")
+	fake.On("sc", "qc", "SysMain").Returns("        START_TYPE         : 2   AUTO_START\n")
+	fake.On("sc", "query", "SysMain").Returns("        STATE              : 4  RUNNING\n")
+	fake.On("sc", "config", "SysMain").Returns("[SC] ChangeServiceConfig SUCCESS")
+	fake.On("sc", "start", "SysMain").Returns("")
+
+	if err := j.For("gaming", "disable_sysmain").Service("SysMain"); err != nil {
+		t.Fatalf("Service: %v", err)
+	}
+	entries, _ := j.Entries()
+	if len(entries) != 1 || entries[0].Service == nil {
+		t.Fatalf("entries = %+v, want one service entry", entries)
+	}
+	if got := *entries[0].Service; got != (ServiceBackup{Name: "SysMain", StartType: "AUTO_START", Running: true}) {
+		t.Errorf("Service backup = %+v", got)
+	}
+
+	if err := j.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	if !fake.Called("sc", "config", "SysMain", "start=", "auto") {
+		t.Error("restore did not reset the start type")
+	}
+	if !fake.Called("sc", "start", "SysMain") {
+		t.Error("restore did not restart the service")
 	}
 }
 
-func TestRestoreAllEmptyState(t *testing.T) {
-	oldState := state
-	defer func() { state = oldState }()
+func TestFailedRestoreKeepsEntry(t *testing.T) {
+	j, _, fake := newTestJournal(t, "")
+	fake.On("powercfg", "/getactivescheme").Returns("Power Scheme GUID: 381b4222-f694-41f0-9685-ff5bb260df2e  (Balanced)")
+	fake.On("powercfg", "/setactive").Fails(1, "access denied")
 
-	state = newEmptyState()
-	err := RestoreAll()
+	if err := j.For("gaming", "ultimate_power_plan").PowerPlan(); err != nil {
+		t.Fatalf("PowerPlan: %v", err)
+	}
+	err := j.RestoreAll()
+	if err == nil || !strings.Contains(err.Error(), "powerplan") {
+		t.Fatalf("RestoreAll error = %v, want failure naming the power plan", err)
+	}
+	if !fake.Called("powercfg", "/setactive", "381b4222-f694-41f0-9685-ff5bb260df2e") {
+		t.Error("restore did not try to reactivate the original plan")
+	}
+	if !j.HasEntries() {
+		t.Error("failed entry should stay in the journal for a retry")
+	}
+}
+
+func TestBootOptionRestore(t *testing.T) {
+	j, _, fake := newTestJournal(t, "")
+	fake.On("bcdedit", "/enum", "{current}").Returns("identifier              {current}\n")
+	fake.On("bcdedit", "/deletevalue").Returns("The operation completed successfully.")
+
+	if err := j.For("gaming", "disable_hpet").BootOption("useplatformclock"); err != nil {
+		t.Fatalf("BootOption: %v", err)
+	}
+	if err := j.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	if !fake.Called("bcdedit", "/deletevalue", "useplatformclock") {
+		t.Error("option absent at backup time should be deleted on restore")
+	}
+}
+
+func TestDNSRecordRestore(t *testing.T) {
+	j, _, fake := newTestJournal(t, `
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{1234}]
+"NameServer"="9.9.9.9,149.112.112.112"
+`)
+	fake.On("powershell").Returns("{1234}\r\n")
+
+	if err := j.For("network", "set_dns").DNS("Ethernet"); err != nil {
+		t.Fatalf("DNS: %v", err)
+	}
+	entries, _ := j.Entries()
+	if want := []string{"9.9.9.9", "149.112.112.112"}; len(entries) != 1 || !reflect.DeepEqual(entries[0].DNS.Servers, want) {
+		t.Fatalf("entries = %+v, want DNS servers %v", entries, want)
+	}
+
+	if err := j.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	calls := fake.Calls()
+	last := calls[len(calls)-1]
+	if !strings.Contains(last.String(), "-ServerAddresses @('9.9.9.9','149.112.112.112')") {
+		t.Errorf("restore ran %q", last)
+	}
+}
+
+func TestFileAndRenameRestore(t *testing.T) {
+	j, _, _ := newTestJournal(t, "")
+	dir := t.TempDir()
+	hosts := filepath.Join(dir, "hosts")
+	created := filepath.Join(dir, "created")
+	from := filepath.Join(dir, "app.lnk")
+	to := from + ".disabled"
+	for path, content := range map[string]string{hosts: "original", from: "shortcut"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := j.For("test", "files")
+	if err := rec.File(hosts); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.File(created); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Rename(from, to); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(hosts, []byte("changed"), 0644)
+	_ = os.WriteFile(created, []byte("new"), 0644)
+	_ = os.Rename(from, to)
+
+	if err := j.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	if data, _ := os.ReadFile(hosts); string(data) != "original" {
+		t.Errorf("hosts = %q, want %q", data, "original")
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Error("file that did not exist at backup time should be removed")
+	}
+	if _, err := os.Stat(from); err != nil {
+		t.Errorf("renamed file was not moved back: %v", err)
+	}
+}
+
+func TestEntriesPersistAcrossJournals(t *testing.T) {
+	j, mem, fake := newTestJournal(t, `
+[HKEY_CURRENT_USER\Software\Test]
+"Value"="1"
+`)
+	if err := j.For("privacy", "telemetry").RegistryValue(reg.CurrentUser, `Software\Test`, "Value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.For("startup", "disable_item").Task(`\Vendor\Updater`, true); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := NewJournal(j.Dir(), mem, fake)
+	entries, err := reopened.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	first, second := entries[0], entries[1]
+	if first.Seq != 1 || first.Module != "privacy" || first.Tweak != "telemetry" || first.Kind != KindRegistry {
+		t.Errorf("first entry = %+v", first)
+	}
+	if first.Registry == nil || first.Registry.String != "1" || first.Registry.Type != reg.SZ || !first.Registry.Existed {
+		t.Errorf("first registry backup = %+v", first.Registry)
+	}
+	if second.Seq != 2 || second.Task == nil || !second.Task.Enabled {
+		t.Errorf("second entry = %+v", second)
+	}
+	if first.Time == "" {
+		t.Error("entry has no timestamp")
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "backups")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	gaming := `{
+  "createdAt": "2025-01-01T00:00:00Z",
+  "entries": [
+    {"type": "registry", "root": "HKCU", "keyPath": "Control Panel\\Mouse", "valueName": "MouseSpeed", "value": "1", "valueType": 1},
+    {"type": "registry", "root": "HKLM", "keyPath": "SYSTEM\\Test", "valueName": "Flag", "value": "38", "valueType": 4},
+    {"type": "registry", "root": "HKCU", "keyPath": "Software\\Test", "valueName": "Gone", "missing": true},
+    {"type": "service", "serviceName": "SysMain", "serviceState": "running"}
+  ]
+}`
+	legacy := `{
+  "timestamp": "2025-02-01T00:00:00Z",
+  "registryKeys": {
+    "HKLM\\SOFTWARE\\Test\\Value": {"path": "HKLM\\SOFTWARE\\Test", "valueName": "Value", "value": 42, "type": "dword", "existed": true}
+  },
+  "services": {"WSearch": "AUTO_START"},
+  "powerPlan": "381b4222-f694-41f0-9685-ff5bb260df2e"
+}`
+	gamingPath := filepath.Join(root, legacyFilename)
+	legacyPath := filepath.Join(dir, legacyFilename)
+	_ = os.WriteFile(gamingPath, []byte(gaming), 0644)
+	_ = os.WriteFile(legacyPath, []byte(legacy), 0644)
+
+	j := NewJournal(dir, reg.NewMemory(), cmd.NewFake())
+	entries, err := j.Entries()
 	if err != nil {
-		t.Errorf("RestoreAll with empty state should not error: %v", err)
+		t.Fatalf("Entries: %v", err)
+	}
+
+	var targets []string
+	for _, e := range entries {
+		targets = append(targets, e.Module+" "+e.Target)
+	}
+	want := []string{
+		`gaming HKCU\Control Panel\Mouse\MouseSpeed`,
+		`gaming HKLM\SYSTEM\Test\Flag`,
+		`gaming HKCU\Software\Test\Gone`,
+		`gaming service:SysMain`,
+		`legacy HKLM\SOFTWARE\Test\Value`,
+		`legacy service:WSearch`,
+		`legacy powerplan`,
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("migrated targets = %q, want %q", targets, want)
+	}
+	if b := entries[1].Registry; b.Type != reg.DWord || b.Integer != 38 {
+		t.Errorf("Flag backup = %+v, want DWORD 38", b)
+	}
+	if b := entries[2].Registry; b.Existed {
+		t.Error("missing legacy value should migrate as not existing")
+	}
+	if b := entries[4].Registry; b.Type != reg.DWord || b.Integer != 42 {
+		t.Errorf("Value backup = %+v, want DWORD 42", b)
+	}
+
+	for _, p := range []string{gamingPath, legacyPath} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should have been renamed", p)
+		}
+		if _, err := os.Stat(p + ".migrated"); err != nil {
+			t.Errorf("%s.migrated missing: %v", p, err)
+		}
+	}
+
+	// A second load reads the journal rather than migrating again.
+	again, err := j.Entries()
+	if err != nil || len(again) != len(entries) {
+		t.Errorf("reloaded %d entries (err %v), want %d", len(again), err, len(entries))
 	}
 }
 
-func TestBackupStateJSON(t *testing.T) {
-	s := &BackupState{
-		Timestamp: "2026-01-01T00:00:00Z",
-		RegistryKeys: map[string]RegistryBackup{
-			"test": {
-				Path:      "HKCU\\Test",
-				ValueName: "TestVal",
-				Value:     uint32(42),
-				Type:      "dword",
-				Existed:   true,
-			},
+func TestEntryJSON(t *testing.T) {
+	e := Entry{
+		Seq:    3,
+		Time:   "2026-01-01T00:00:00Z",
+		Module: "gaming",
+		Tweak:  "disable_hpet",
+		Kind:   KindBootOption,
+		Target: "bootoption:useplatformclock",
+		BootOption: &BootOptionBackup{
+			Name:    "useplatformclock",
+			Value:   "Yes",
+			Existed: true,
 		},
-		Services:  map[string]string{"svc1": "AUTO_START"},
-		PowerPlan: "abc-123",
 	}
 
-	data, err := json.Marshal(s)
+	data, err := json.Marshal(e)
 	if err != nil {
 		t.Fatalf("marshal failed: %v", err)
 	}
+	if strings.Contains(string(data), `"registry"`) {
+		t.Errorf("unused kinds should be omitted: %s", data)
+	}
 
-	var loaded BackupState
+	var loaded Entry
 	if err := json.Unmarshal(data, &loaded); err != nil {
 		t.Fatalf("unmarshal failed: %v", err)
 	}
+	if !reflect.DeepEqual(loaded, e) {
+		t.Errorf("round trip = %+v, want %+v", loaded, e)
+	}
+}
+
+func TestPristineRestorePointIsPinned(t *testing.T) {
+	j, _, _ := newTestJournal(t, "")
+
+	points, err := j.RestorePoints()
+	if err != nil {
+		t.Fatalf("RestorePoints: %v", err)
+	}
+	if len(points) != 1 || points[0].ID != PristineID || !points[0].Pinned || points[0].Seq != 0 {
+		t.Fatalf("points = %+v, want only the pinned pristine point", points)
+	}
 
-	if loaded.Timestamp != s.Timestamp {
-		t.Errorf("timestamp mismatch: %q != %q", loaded.Timestamp, s.Timestamp)
+	// No changes yet, so a new point would be identical to pristine.
+	p, err := j.CreateRestorePoint("Before tweak")
+	if err != nil {
+		t.Fatalf("CreateRestorePoint: %v", err)
+	}
+	if p.ID != PristineID {
+		t.Errorf("CreateRestorePoint with no changes returned %q, want %q", p.ID, PristineID)
+	}
+
+	removed, err := j.Prune(time.Nanosecond, 0)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Prune removed %+v, pristine must never be pruned", removed)
+	}
+}
+
+func TestRestoreToPoint(t *testing.T) {
+	j, mem, _ := newTestJournal(t, `
+[HKEY_CURRENT_USER\Software\Test]
+"Value"="original"
+`)
+	const path = `Software\Test`
+	change := func(value string) {
+		t.Helper()
+		if err := j.For("gaming", "tweak").RegistryValue(reg.CurrentUser, path, "Value"); err != nil {
+			t.Fatal(err)
+		}
+		setString(t, mem, reg.CurrentUser, path, "Value", value)
+	}
+
+	first, _ := j.CreateRestorePoint("Before first profile")
+	change("first")
+	second, err := j.CreateRestorePoint("Before second profile")
+	if err != nil {
+		t.Fatalf("CreateRestorePoint: %v", err)
+	}
+	if second.ID == first.ID || second.Seq != 1 {
+		t.Fatalf("second point = %+v, want a new point after entry 1", second)
+	}
+	change("second")
+
+	_, after, err := j.Inspect(second.ID)
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if len(after) != 1 || after[0].Registry.String != "first" {
+		t.Errorf("Inspect = %+v, want the one change made after the point", after)
+	}
+
+	changes, err := j.Diff(PristineID)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	want := []Change{{Target: `HKCU\Software\Test\Value`, Module: "gaming", Tweak: "tweak", Then: `"original"`, Now: `"second"`}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Diff = %+v, want %+v", changes, want)
+	}
+
+	if err := j.RestoreTo(second.ID); err != nil {
+		t.Fatalf("RestoreTo(second): %v", err)
+	}
+	if v := getString(mem, reg.CurrentUser, path, "Value"); v != "first" {
+		t.Errorf("Value = %q after restoring the second point, want %q", v, "first")
+	}
+
+	// The true original survives applying two profiles in a row.
+	if err := j.RestoreTo(PristineID); err != nil {
+		t.Fatalf("RestoreTo(pristine): %v", err)
+	}
+	if v := getString(mem, reg.CurrentUser, path, "Value"); v != "original" {
+		t.Errorf("Value = %q after restoring pristine, want %q", v, "original")
+	}
+	if j.HasEntries() {
+		t.Error("nothing should be left to restore")
+	}
+
+	// Sequence numbers keep increasing after a restore.
+	change("third")
+	third, _ := j.CreateRestorePoint("After restore")
+	if third.Seq != 3 {
+		t.Errorf("point after restore has seq %d, want 3", third.Seq)
+	}
+}
+
+func TestRestoreCompactsJournal(t *testing.T) {
+	j, mem, _ := newTestJournal(t, `
+[HKEY_CURRENT_USER\Software\Test]
+"Value"="original"
+`)
+	const path = `Software\Test`
+	change := func(value string) {
+		t.Helper()
+		if err := j.For("gaming", "tweak").RegistryValue(reg.CurrentUser, path, "Value"); err != nil {
+			t.Fatal(err)
+		}
+		setString(t, mem, reg.CurrentUser, path, "Value", value)
+	}
+
+	change("first")
+	point, err := j.CreateRestorePoint("Before second")
+	if err != nil {
+		t.Fatalf("CreateRestorePoint: %v", err)
+	}
+	for _, v := range []string{"second", "third", "fourth"} {
+		change(v)
+	}
+	if err := j.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+
+	j.mu.Lock()
+	entries, err := j.load()
+	j.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seqs []int
+	for _, e := range entries {
+		seqs = append(seqs, e.Seq)
+	}
+	// Entry 1 marks the restore point and entry 4 holds the newest sequence.
+	if want := []int{point.Seq, 4}; !reflect.DeepEqual(seqs, want) {
+		t.Errorf("journal after restore holds entries %v, want %v", seqs, want)
+	}
+
+	// A journal reopened from disk carries on from the newest sequence.
+	reopened := NewJournal(j.Dir(), mem, cmd.NewFake())
+	if err := reopened.For("gaming", "tweak").RegistryValue(reg.CurrentUser, path, "Value"); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := reopened.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Seq != 5 {
+		t.Errorf("entries after reopening = %+v, want one entry with seq 5", pending)
+	}
+
+	// The first journal notices the other writer instead of reusing 5.
+	change("fifth")
+	pending, _ = j.Entries()
+	if n := len(pending); n != 2 || pending[n-1].Seq != 6 {
+		t.Errorf("entries = %+v, want a second entry with seq 6", pending)
+	}
+}
+
+func TestRestoreToUnknownPoint(t *testing.T) {
+	j, _, _ := newTestJournal(t, "")
+	if err := j.RestoreTo("nope"); err == nil {
+		t.Error("RestoreTo should fail for an unknown restore point")
+	}
+	if _, err := j.Diff("nope"); err == nil {
+		t.Error("Diff should fail for an unknown restore point")
+	}
+}
+
+func TestPruneRestorePoints(t *testing.T) {
+	j, _, _ := newTestJournal(t, "")
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		if err := j.For("startup", "disable_item").Task(`\Task`+strconv.Itoa(i), true); err != nil {
+			t.Fatal(err)
+		}
+		p, err := j.CreateRestorePoint("point " + strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, p.ID)
+	}
+
+	removed, err := j.Prune(0, 2)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if len(removed) != 2 || removed[0].ID != ids[1] || removed[1].ID != ids[0] {
+		t.Errorf("Prune removed %+v, want the two oldest unpinned points", removed)
+	}
+
+	ids = ids[2:]
+	if got := restorePointIDs(t, j); !reflect.DeepEqual(got, []string{PristineID, ids[0], ids[1]}) {
+		t.Errorf("remaining points = %v", got)
+	}
+
+	// Backdate one point so age-based pruning picks it up.
+	points, _ := j.RestorePoints()
+	points[1].Created = time.Now().Add(-48 * time.Hour).Format(time.RFC3339)
+	if err := j.saveRestorePoints(points); err != nil {
+		t.Fatal(err)
+	}
+	removed, err = j.Prune(24*time.Hour, -1)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if len(removed) != 1 || removed[0].ID != ids[0] {
+		t.Errorf("Prune removed %+v, want only %s", removed, ids[0])
+	}
+	if got := restorePointIDs(t, j); !reflect.DeepEqual(got, []string{PristineID, ids[1]}) {
+		t.Errorf("remaining points = %v", got)
+	}
+
+	entries, _ := j.Entries()
+	if len(entries) != 4 {
+		t.Errorf("pruning restore points must keep journal entries, have %d", len(entries))
+	}
+}
+
+func restorePointIDs(t *testing.T, j *Journal) []string {
+	t.Helper()
+	points, err := j.RestorePoints()
+	if err != nil {
+		t.Fatalf("RestorePoints: %v", err)
 	}
-	if loaded.PowerPlan != s.PowerPlan {
-		t.Errorf("powerPlan mismatch: %q != %q", loaded.PowerPlan, s.PowerPlan)
+	var ids []string
+	for _, p := range points {
+		ids = append(ids, p.ID)
 	}
+	return ids
 }
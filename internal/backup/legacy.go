@@ -0,0 +1,239 @@
+package backup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"cleanforge/internal/reg"
+)
+
+// legacyFilename is the single-snapshot file written by earlier versions.
+// This package kept one in the backups directory and the game booster kept
+// another one level up, in ~/.cleanforge.
+const legacyFilename = "backup_state.json"
+
+// legacyState covers both legacy snapshot schemas. The backup package wrote
+// Timestamp/RegistryKeys/Services/PowerPlan; the game booster wrote
+// CreatedAt/Entries.
+type legacyState struct {
+	Timestamp    string                         `json:"timestamp"`
+	RegistryKeys map[string]legacyRegistryValue `json:"registryKeys"`
+	Services     map[string]string              `json:"services"`
+	PowerPlan    string                         `json:"powerPlan"`
+
+	CreatedAt string              `json:"createdAt"`
+	Entries   []legacyGamingEntry `json:"entries"`
+}
+
+type legacyRegistryValue struct {
+	Path      string      `json:"path"` // includes the root, e.g. "HKLM\SOFTWARE\Test"
+	ValueName string      `json:"valueName"`
+	Value     interface{} `json:"value"`
+	Type      string      `json:"type"` // "string", "dword", "qword", "binary", "none"
+	Existed   bool        `json:"existed"`
+}
+
+type legacyGamingEntry struct {
+	Type         string        `json:"type"` // "registry" or "service"
+	Root         string        `json:"root"`
+	KeyPath      string        `json:"keyPath"`
+	ValueName    string        `json:"valueName"`
+	Value        string        `json:"value"`
+	ValueType    reg.ValueType `json:"valueType"`
+	ServiceName  string        `json:"serviceName"`
+	ServiceState string        `json:"serviceState"`
+	Missing      bool          `json:"missing"`
+}
+
+// migrateLegacy converts any legacy snapshot files into a new journal and
+// renames them with a ".migrated" suffix so they are only imported once.
+// It returns nil when there is nothing to migrate. Callers must hold j.mu.
+func (j *Journal) migrateLegacy() ([]Entry, error) {
+	paths := []string{
+		filepath.Join(filepath.Dir(j.Dir()), legacyFilename),
+		filepath.Join(j.Dir(), legacyFilename),
+	}
+
+	var entries []Entry
+	var migrated []string
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var state legacyState
+		if err := json.Unmarshal(data, &state); err != nil {
+			// Leave unreadable files alone rather than lose them.
+			continue
+		}
+		entries = append(entries, state.convert()...)
+		migrated = append(migrated, p)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	for i := range entries {
+		entries[i].Seq = i + 1
+	}
+	if err := j.rewrite(entries); err != nil {
+		return nil, err
+	}
+	for _, p := range migrated {
+		_ = os.Rename(p, p+".migrated")
+	}
+	return entries, nil
+}
+
+// convert turns a legacy snapshot into journal entries.
+func (s *legacyState) convert() []Entry {
+	var entries []Entry
+
+	for _, e := range s.Entries {
+		switch e.Type {
+		case "registry":
+			root, err := reg.ParseRoot(e.Root)
+			if err != nil {
+				continue
+			}
+			b := &RegistryBackup{Root: root, Path: e.KeyPath, ValueName: e.ValueName, Existed: !e.Missing}
+			if b.Existed {
+				b.Type = e.ValueType
+				if e.ValueType == reg.DWord || e.ValueType == reg.QWord {
+					b.Integer, _ = strconv.ParseUint(e.Value, 10, 64)
+				} else {
+					b.Type = reg.SZ
+					if e.ValueType == reg.ExpandSZ {
+						b.Type = reg.ExpandSZ
+					}
+					b.String = e.Value
+				}
+			}
+			entries = append(entries, Entry{
+				Time:     s.CreatedAt,
+				Module:   "gaming",
+				Tweak:    "legacy",
+				Kind:     KindRegistry,
+				Target:   registryTarget(b),
+				Registry: b,
+			})
+		case "service":
+			entries = append(entries, Entry{
+				Time:    s.CreatedAt,
+				Module:  "gaming",
+				Tweak:   "legacy",
+				Kind:    KindService,
+				Target:  "service:" + e.ServiceName,
+				Service: &ServiceBackup{Name: e.ServiceName, Running: e.ServiceState == "running"},
+			})
+		}
+	}
+
+	// Map iteration order is random; sort so migration is deterministic.
+	keys := make([]string, 0, len(s.RegistryKeys))
+	for k := range s.RegistryKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b, ok := s.RegistryKeys[k].convert()
+		if !ok {
+			continue
+		}
+		entries = append(entries, Entry{
+			Time:     s.Timestamp,
+			Module:   "legacy",
+			Tweak:    "legacy",
+			Kind:     KindRegistry,
+			Target:   registryTarget(b),
+			Registry: b,
+		})
+	}
+
+	services := make([]string, 0, len(s.Services))
+	for name := range s.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	for _, name := range services {
+		entries = append(entries, Entry{
+			Time:    s.Timestamp,
+			Module:  "legacy",
+			Tweak:   "legacy",
+			Kind:    KindService,
+			Target:  "service:" + name,
+			Service: &ServiceBackup{Name: name, StartType: s.Services[name]},
+		})
+	}
+
+	if s.PowerPlan != "" {
+		entries = append(entries, Entry{
+			Time:      s.Timestamp,
+			Module:    "legacy",
+			Tweak:     "legacy",
+			Kind:      KindPowerPlan,
+			Target:    "powerplan",
+			PowerPlan: s.PowerPlan,
+		})
+	}
+
+	return entries
+}
+
+// convert turns a legacy registry value into a RegistryBackup.
+func (v legacyRegistryValue) convert() (*RegistryBackup, bool) {
+	rootStr, subPath, err := splitRegistryPath(v.Path)
+	if err != nil {
+		return nil, false
+	}
+	root, err := parseRootKey(rootStr)
+	if err != nil {
+		return nil, false
+	}
+
+	b := &RegistryBackup{Root: root, Path: subPath, ValueName: v.ValueName, Existed: v.Existed}
+	if !v.Existed {
+		return b, true
+	}
+	switch v.Type {
+	case "string":
+		s, ok := v.Value.(string)
+		if !ok {
+			return nil, false
+		}
+		b.Type, b.String = reg.SZ, s
+	case "dword", "qword":
+		// JSON numbers decode as float64.
+		f, ok := v.Value.(float64)
+		if !ok {
+			return nil, false
+		}
+		b.Type, b.Integer = reg.DWord, uint64(f)
+		if v.Type == "qword" {
+			b.Type = reg.QWord
+		}
+	case "binary":
+		// []byte values were marshaled as base64 strings.
+		s, ok := v.Value.(string)
+		if !ok {
+			return nil, false
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, false
+		}
+		b.Type, b.Binary = reg.Binary, data
+	default:
+		return nil, false
+	}
+	return b, true
+}
+
+// registryTarget is the journal target for a registry value.
+func registryTarget(b *RegistryBackup) string {
+	return string(b.Root) + `\` + b.Path + `\` + b.ValueName
+}
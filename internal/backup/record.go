@@ -0,0 +1,269 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"cleanforge/internal/reg"
+)
+
+// tcpInterfacesPath holds per-adapter TCP/IP settings, including static DNS.
+const tcpInterfacesPath = `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces`
+
+// Recorder captures the current state of a resource into a Journal so the
+// change that follows can be undone. Every entry it writes is attributed to
+// the module and tweak the Recorder was created for.
+//
+// Call a Recorder method immediately before the change it protects and
+// abort the change if it returns an error.
+type Recorder struct {
+	journal *Journal
+	module  string
+	tweak   string
+}
+
+// For returns a Recorder that attributes entries to module and tweak.
+func (j *Journal) For(module, tweak string) Recorder {
+	return Recorder{journal: j, module: module, tweak: tweak}
+}
+
+func (r Recorder) record(e Entry) error {
+	e.Module = r.module
+	e.Tweak = r.tweak
+	r.journal.mu.Lock()
+	defer r.journal.mu.Unlock()
+	return r.journal.append(e)
+}
+
+// RegistryValue records a registry value, or the fact that it does not exist.
+func (r Recorder) RegistryValue(root reg.Root, path, name string) error {
+	b, err := r.journal.readRegistryValue(root, path, name)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s\\%s\\%s: %w", root, path, name, err)
+	}
+	return r.record(Entry{Kind: KindRegistry, Target: registryTarget(b), Registry: b})
+}
+
+// Service records a service's start type and whether it is running.
+func (r Recorder) Service(name string) error {
+	b := r.journal.readService(name)
+	return r.record(Entry{Kind: KindService, Target: "service:" + name, Service: b})
+}
+
+// PowerPlan records the active power plan.
+func (r Recorder) PowerPlan() error {
+	guid, err := r.journal.readPowerPlan()
+	if err != nil {
+		return err
+	}
+	return r.record(Entry{Kind: KindPowerPlan, Target: "powerplan", PowerPlan: guid})
+}
+
+// DNS records the statically configured DNS servers of an adapter.
+func (r Recorder) DNS(adapter string) error {
+	servers, err := r.journal.readDNSServers(adapter)
+	if err != nil {
+		return fmt.Errorf("failed to back up DNS for %s: %w", adapter, err)
+	}
+	return r.record(Entry{
+		Kind:   KindDNS,
+		Target: "dns:" + adapter,
+		DNS:    &DNSBackup{Adapter: adapter, Servers: servers},
+	})
+}
+
+// BootOption records a boot configuration element of the current boot entry.
+func (r Recorder) BootOption(name string) error {
+	value, existed, err := r.journal.readBootOption(name)
+	if err != nil {
+		return fmt.Errorf("failed to back up boot option %s: %w", name, err)
+	}
+	return r.record(Entry{
+		Kind:       KindBootOption,
+		Target:     "bootoption:" + name,
+		BootOption: &BootOptionBackup{Name: name, Value: value, Existed: existed},
+	})
+}
+
+// File records the contents of a file, or the fact that it does not exist.
+func (r Recorder) File(path string) error {
+	b, err := readFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return r.record(Entry{Kind: KindFile, Target: "file:" + path, File: b})
+}
+
+// Rename records that a file is about to be moved from from to to.
+func (r Recorder) Rename(from, to string) error {
+	return r.record(Entry{
+		Kind:   KindRename,
+		Target: "rename:" + from,
+		Rename: &RenameBackup{From: from, To: to},
+	})
+}
+
+// Task records whether a scheduled task is enabled. The caller already knows
+// the state, so it is passed in rather than queried.
+func (r Recorder) Task(name string, enabled bool) error {
+	return r.record(Entry{
+		Kind:   KindTask,
+		Target: "task:" + name,
+		Task:   &TaskBackup{Name: name, Enabled: enabled},
+	})
+}
+
+// readRegistryValue captures a value in a form that can be written back.
+func (j *Journal) readRegistryValue(root reg.Root, path, name string) (*RegistryBackup, error) {
+	b := &RegistryBackup{Root: root, Path: path, ValueName: name}
+
+	key, err := j.registry.OpenKey(root, path, reg.QueryValue)
+	if errors.Is(err, reg.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	data, typ, err := key.GetValue(name)
+	if errors.Is(err, reg.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.Existed = true
+	b.Type = typ
+	switch typ {
+	case reg.SZ, reg.ExpandSZ:
+		b.String, _, err = key.GetStringValue(name)
+	case reg.DWord, reg.QWord:
+		b.Integer, _, err = key.GetIntegerValue(name)
+	case reg.MultiSZ:
+		b.Strings, _, err = key.GetStringsValue(name)
+	case reg.Binary:
+		b.Binary = data
+	default:
+		// Values of other types could not be written back, so the change
+		// must not go ahead
+		return nil, fmt.Errorf("cannot back up registry value of type %d", typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// readService captures a service's start type (best effort) and whether it
+// is running.
+func (j *Journal) readService(name string) *ServiceBackup {
+	b := &ServiceBackup{Name: name}
+	if out, err := j.runner.Command(context.Background(), "sc", "qc", name).Output(); err == nil {
+		b.StartType = parseServiceStartType(string(out))
+	}
+	b.Running = j.serviceRunning(name)
+	return b
+}
+
+// readPowerPlan returns the GUID of the active power plan.
+func (j *Journal) readPowerPlan() (string, error) {
+	out, err := j.runner.Command(context.Background(), "powercfg", "/getactivescheme").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get active power plan: %w", err)
+	}
+	guid := parsePowerPlanGUID(string(out))
+	if guid == "" {
+		return "", fmt.Errorf("could not parse power plan GUID from output: %s", string(out))
+	}
+	return guid, nil
+}
+
+// readFile captures a file's contents, or the fact that it does not exist.
+func readFile(path string) (*FileBackup, error) {
+	b := &FileBackup{Path: path}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		b.Content = data
+		b.Existed = true
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	return b, nil
+}
+
+// readTaskEnabled reports whether a scheduled task is enabled, from the
+// status column of `schtasks /Query`.
+func (j *Journal) readTaskEnabled(name string) (bool, error) {
+	out, err := j.runner.Command(context.Background(), "schtasks", "/Query", "/TN", name, "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to query task: %w", err)
+	}
+	return !strings.Contains(string(out), `"Disabled"`), nil
+}
+
+// serviceRunning reports whether `sc query` shows the service as running.
+func (j *Journal) serviceRunning(name string) bool {
+	out, err := j.runner.Command(context.Background(), "sc", "query", name).Output()
+	return err == nil && strings.Contains(string(out), "RUNNING")
+}
+
+// readDNSServers returns the static DNS servers configured on an adapter, or
+// nil when the adapter takes DNS from DHCP. The servers live in the adapter's
+// TCP/IP interface key, which is found through the adapter's GUID.
+func (j *Journal) readDNSServers(adapter string) ([]string, error) {
+	ps := fmt.Sprintf(`(Get-NetAdapter -Name '%s' -ErrorAction Stop).InterfaceGuid`, strings.ReplaceAll(adapter, "'", "''"))
+	out, err := j.runner.Command(context.Background(), "powershell", "-NoProfile", "-Command", ps).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up adapter: %w", err)
+	}
+	guid := strings.TrimSpace(string(out))
+	if guid == "" {
+		return nil, fmt.Errorf("adapter %q has no interface GUID", adapter)
+	}
+
+	key, err := j.registry.OpenKey(reg.LocalMachine, tcpInterfacesPath+`\`+guid, reg.QueryValue)
+	if errors.Is(err, reg.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	value, _, err := key.GetStringValue("NameServer")
+	if errors.Is(err, reg.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }), nil
+}
+
+// readBootOption looks up an element of the current boot entry in the
+// output of `bcdedit /enum {current}`.
+func (j *Journal) readBootOption(name string) (string, bool, error) {
+	out, err := j.runner.Command(context.Background(), "bcdedit", "/enum", "{current}").Output()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := parseBootOption(string(out), name)
+	return value, ok, nil
+}
+
+// parseBootOption finds "name   value" in bcdedit output.
+func parseBootOption(output, name string) (string, bool) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && strings.EqualFold(fields[0], name) {
+			return strings.Join(fields[1:], " "), true
+		}
+	}
+	return "", false
+}
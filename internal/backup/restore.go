@@ -0,0 +1,269 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"cleanforge/internal/reg"
+)
+
+// RestoreAll undoes every change in the journal, returning each resource to
+// the state it was in before CleanForge first touched it.
+func (j *Journal) RestoreAll() error {
+	return j.restore(func(Entry) bool { return true })
+}
+
+// RestoreModule undoes the changes recorded by one module.
+//
+// A resource the module changed last is put back to the state it had before
+// the module's first change to it; earlier changes by other modules are
+// kept. A resource another module changed since is left as it is, and that
+// module's entry takes over the state from before this module's change, so
+// undoing it later still returns the resource to where it was.
+func (j *Journal) RestoreModule(module string) error {
+	return j.restore(func(e Entry) bool { return e.Module == module })
+}
+
+// RestoreTweak undoes the changes recorded under one tweak of a module,
+// following the same rules as RestoreModule.
+func (j *Journal) RestoreTweak(module, tweak string) error {
+	return j.restore(func(e Entry) bool { return e.Module == module && e.Tweak == tweak })
+}
+
+// restore takes the pending matching entries out of each resource's history.
+//
+// The pending entries of a resource form a chain, each holding the state
+// the one before it left. A run of matching entries followed by an entry
+// that does not match is dropped from the chain by handing the state held
+// in the run's first entry on to that later entry; the resource itself is
+// not touched. A run at the end of the chain is undone by restoring the
+// resource to the state held in the run's first entry, newest resource
+// first. The entries taken out are marked restored; entries that fail to
+// restore stay pending so the restore can be retried.
+func (j *Journal) restore(match func(Entry) bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.load()
+	if err != nil {
+		return err
+	}
+
+	var order []string
+	chains := make(map[string][]int)
+	for i, e := range entries {
+		if e.Restored {
+			continue
+		}
+		target := strings.ToLower(e.Target)
+		if _, ok := chains[target]; !ok {
+			order = append(order, target)
+		}
+		chains[target] = append(chains[target], i)
+	}
+
+	// trailing runs of matching entries, undone on the system below
+	type run struct{ first, n int }
+	var undo []run
+	changed := false
+	for _, target := range order {
+		chain := chains[target]
+		start := -1
+		for k, i := range chain {
+			switch {
+			case match(entries[i]) && start < 0:
+				start = k
+			case !match(entries[i]) && start >= 0:
+				entries[i].takeState(entries[chain[start]])
+				for _, r := range chain[start:k] {
+					entries[r].Restored = true
+				}
+				start, changed = -1, true
+			}
+		}
+		if start >= 0 {
+			undo = append(undo, run{first: chain[start], n: len(chain) - start})
+		}
+	}
+	if len(undo) == 0 && !changed {
+		return nil
+	}
+	sort.Slice(undo, func(a, b int) bool { return entries[undo[a].first].Seq > entries[undo[b].first].Seq })
+
+	var errs []string
+	for _, r := range undo {
+		e := entries[r.first]
+		if err := j.restoreEntry(e); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", e.Target, err))
+			continue
+		}
+		chain := chains[strings.ToLower(e.Target)]
+		for _, i := range chain[len(chain)-r.n:] {
+			entries[i].Restored = true
+		}
+	}
+	kept, err := j.compact(entries)
+	if err != nil {
+		errs = append(errs, err.Error())
+		kept = entries
+	}
+	if err := j.rewrite(kept); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("restore completed with errors:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// takeState replaces the state held in e with the one held in from, an
+// earlier entry for the same resource.
+func (e *Entry) takeState(from Entry) {
+	e.Registry, e.Service, e.PowerPlan = from.Registry, from.Service, from.PowerPlan
+	e.DNS, e.BootOption, e.File = from.DNS, from.BootOption, from.File
+	e.Rename, e.Task = from.Rename, from.Task
+}
+
+// restoreEntry puts a single resource back to the state held in e.
+func (j *Journal) restoreEntry(e Entry) error {
+	switch {
+	case e.Kind == KindRegistry && e.Registry != nil:
+		return j.restoreRegistry(e.Registry)
+	case e.Kind == KindService && e.Service != nil:
+		return j.restoreService(e.Service)
+	case e.Kind == KindPowerPlan && e.PowerPlan != "":
+		return j.run("powercfg", "/setactive", e.PowerPlan)
+	case e.Kind == KindDNS && e.DNS != nil:
+		return j.restoreDNS(e.DNS)
+	case e.Kind == KindBootOption && e.BootOption != nil:
+		return j.restoreBootOption(e.BootOption)
+	case e.Kind == KindFile && e.File != nil:
+		return restoreFile(e.File)
+	case e.Kind == KindRename && e.Rename != nil:
+		return restoreRename(e.Rename)
+	case e.Kind == KindTask && e.Task != nil:
+		flag := "/Disable"
+		if e.Task.Enabled {
+			flag = "/Enable"
+		}
+		return j.run("schtasks", "/Change", "/TN", e.Task.Name, flag)
+	}
+	return fmt.Errorf("malformed %q journal entry", e.Kind)
+}
+
+// run executes a command and folds its output into the error on failure.
+func (j *Journal) run(name string, args ...string) error {
+	out, err := j.runner.Command(context.Background(), name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// restoreRegistry writes a value back, or deletes it if it did not exist.
+func (j *Journal) restoreRegistry(b *RegistryBackup) error {
+	if !b.Existed {
+		key, err := j.registry.OpenKey(b.Root, b.Path, reg.SetValue)
+		if errors.Is(err, reg.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer key.Close()
+		if err := key.DeleteValue(b.ValueName); err != nil && !errors.Is(err, reg.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	key, _, err := j.registry.CreateKey(b.Root, b.Path, reg.SetValue)
+	if err != nil {
+		return fmt.Errorf("failed to open/create registry key: %w", err)
+	}
+	defer key.Close()
+
+	switch b.Type {
+	case reg.SZ:
+		return key.SetStringValue(b.ValueName, b.String)
+	case reg.ExpandSZ:
+		return key.SetExpandStringValue(b.ValueName, b.String)
+	case reg.DWord:
+		return key.SetDWordValue(b.ValueName, uint32(b.Integer))
+	case reg.QWord:
+		return key.SetQWordValue(b.ValueName, b.Integer)
+	case reg.MultiSZ:
+		return key.SetStringsValue(b.ValueName, b.Strings)
+	case reg.Binary:
+		return key.SetBinaryValue(b.ValueName, b.Binary)
+	default:
+		return fmt.Errorf("unsupported registry value type: %d", b.Type)
+	}
+}
+
+// restoreService resets the start type and restarts the service if it was
+// running. Services CleanForge found stopped are left alone.
+func (j *Journal) restoreService(b *ServiceBackup) error {
+	if startType := mapStartTypeToSC(b.StartType); startType != "" {
+		if err := j.run("sc", "config", b.Name, "start=", startType); err != nil {
+			return err
+		}
+	}
+	if b.Running {
+		if err := j.run("sc", "start", b.Name); err != nil && !j.serviceRunning(b.Name) {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreDNS puts back static servers, or returns the adapter to DHCP.
+func (j *Journal) restoreDNS(b *DNSBackup) error {
+	adapter := strings.ReplaceAll(b.Adapter, "'", "''")
+	ps := fmt.Sprintf(`Set-DnsClientServerAddress -InterfaceAlias '%s' -ResetServerAddresses`, adapter)
+	if len(b.Servers) > 0 {
+		ps = fmt.Sprintf(`Set-DnsClientServerAddress -InterfaceAlias '%s' -ServerAddresses @('%s')`,
+			adapter, strings.Join(b.Servers, "','"))
+	}
+	return j.run("powershell", "-NoProfile", "-Command", ps)
+}
+
+// restoreBootOption sets the element back, or deletes it if it was absent.
+func (j *Journal) restoreBootOption(b *BootOptionBackup) error {
+	if b.Existed {
+		return j.run("bcdedit", "/set", b.Name, b.Value)
+	}
+	err := j.run("bcdedit", "/deletevalue", b.Name)
+	if err == nil {
+		return nil
+	}
+	// bcdedit fails when the element is already absent, which is the goal.
+	if _, present, qerr := j.readBootOption(b.Name); qerr == nil && !present {
+		return nil
+	}
+	return err
+}
+
+// restoreFile writes the old contents back, or removes a file that did not exist.
+func restoreFile(b *FileBackup) error {
+	if !b.Existed {
+		if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(b.Path, b.Content, 0644)
+}
+
+// restoreRename moves a file back to where it was, unless it is already there.
+func restoreRename(b *RenameBackup) error {
+	if _, err := os.Stat(b.From); err == nil {
+		return nil
+	}
+	return os.Rename(b.To, b.From)
+}
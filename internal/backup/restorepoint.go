@@ -0,0 +1,401 @@
+package backup
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cleanforge/internal/reg"
+)
+
+// restorePointsFilename holds the restore point list next to the journal.
+const restorePointsFilename = "restore_points.json"
+
+// PristineID identifies the pinned restore point that marks the state of the
+// system before CleanForge changed anything. It is never pruned.
+const PristineID = "pristine"
+
+// RestorePoint marks a position in the journal. Restoring to it undoes every
+// change recorded after it, leaving earlier changes in place.
+type RestorePoint struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Created     string `json:"created"`
+	Seq         int    `json:"seq"`    // last journal entry recorded before the point
+	Pinned      bool   `json:"pinned"` // pinned points are never pruned
+}
+
+// Change describes one resource that differs from a restore point.
+type Change struct {
+	Target string `json:"target"`
+	Module string `json:"module"` // module of the first change since the point
+	Tweak  string `json:"tweak"`
+	Then   string `json:"then"` // state at the restore point
+	Now    string `json:"now"`  // current state
+}
+
+func (j *Journal) restorePointsPath() string {
+	return filepath.Join(j.Dir(), restorePointsFilename)
+}
+
+// loadRestorePoints reads the restore point list, creating the pinned
+// pristine point the first time. Callers must hold j.mu.
+func (j *Journal) loadRestorePoints() ([]RestorePoint, error) {
+	var points []RestorePoint
+	data, err := os.ReadFile(j.restorePointsPath())
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &points); err != nil {
+			return nil, fmt.Errorf("failed to parse restore points: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read restore points: %w", err)
+	}
+
+	for _, p := range points {
+		if p.ID == PristineID {
+			return points, nil
+		}
+	}
+	pristine := RestorePoint{
+		ID:          PristineID,
+		Description: "Original system state",
+		Created:     time.Now().Format(time.RFC3339),
+		Pinned:      true,
+	}
+	points = append([]RestorePoint{pristine}, points...)
+	if err := j.saveRestorePoints(points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// saveRestorePoints writes the restore point list. Callers must hold j.mu.
+func (j *Journal) saveRestorePoints(points []RestorePoint) error {
+	if err := os.MkdirAll(j.Dir(), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	data, err := json.MarshalIndent(points, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal restore points: %w", err)
+	}
+	tmp := j.restorePointsPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write restore points: %w", err)
+	}
+	if err := os.Rename(tmp, j.restorePointsPath()); err != nil {
+		return fmt.Errorf("failed to replace restore points: %w", err)
+	}
+	return nil
+}
+
+// findRestorePoint looks up a point by ID. Callers must hold j.mu.
+func (j *Journal) findRestorePoint(id string) (RestorePoint, error) {
+	points, err := j.loadRestorePoints()
+	if err != nil {
+		return RestorePoint{}, err
+	}
+	for _, p := range points {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return RestorePoint{}, fmt.Errorf("unknown restore point: %s", id)
+}
+
+// RestorePoints returns every restore point, oldest first. The pinned
+// pristine point is always present.
+func (j *Journal) RestorePoints() ([]RestorePoint, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.loadRestorePoints()
+}
+
+// CreateRestorePoint marks the current position in the journal. If nothing
+// has been recorded since the newest point, that point is returned instead
+// of creating an identical one.
+func (j *Journal) CreateRestorePoint(description string) (RestorePoint, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	points, err := j.loadRestorePoints()
+	if err != nil {
+		return RestorePoint{}, err
+	}
+	entries, err := j.load()
+	if err != nil {
+		return RestorePoint{}, err
+	}
+	seq := 0
+	if n := len(entries); n > 0 {
+		seq = entries[n-1].Seq
+	}
+
+	newest := points[0]
+	for _, p := range points {
+		if p.Seq >= newest.Seq {
+			newest = p
+		}
+	}
+	if newest.Seq == seq {
+		return newest, nil
+	}
+
+	now := time.Now()
+	p := RestorePoint{
+		ID:          uniqueID(points, now.UTC().Format("20060102T150405Z")),
+		Description: description,
+		Created:     now.Format(time.RFC3339),
+		Seq:         seq,
+	}
+	if err := j.saveRestorePoints(append(points, p)); err != nil {
+		return RestorePoint{}, err
+	}
+	return p, nil
+}
+
+// uniqueID returns base, or base with a numeric suffix if a point already uses it.
+func uniqueID(points []RestorePoint, base string) string {
+	taken := make(map[string]bool, len(points))
+	for _, p := range points {
+		taken[p.ID] = true
+	}
+	id := base
+	for n := 2; taken[id]; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// Inspect returns a restore point and the pending entries recorded after it,
+// which are the changes restoring to it would undo.
+func (j *Journal) Inspect(id string) (RestorePoint, []Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	p, err := j.findRestorePoint(id)
+	if err != nil {
+		return RestorePoint{}, nil, err
+	}
+	entries, err := j.load()
+	if err != nil {
+		return RestorePoint{}, nil, err
+	}
+	var after []Entry
+	for _, e := range pending(entries) {
+		if e.Seq > p.Seq {
+			after = append(after, e)
+		}
+	}
+	return p, after, nil
+}
+
+// Diff compares the state recorded at a restore point with the current state
+// of every resource changed since then. The current state is read live, so
+// resources that cannot be queried are reported with an error description.
+func (j *Journal) Diff(id string) ([]Change, error) {
+	_, after, err := j.Inspect(id)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var changes []Change
+	for _, e := range after {
+		target := strings.ToLower(e.Target)
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		changes = append(changes, Change{
+			Target: e.Target,
+			Module: e.Module,
+			Tweak:  e.Tweak,
+			Then:   describe(e),
+			Now:    j.describeCurrent(e),
+		})
+	}
+	return changes, nil
+}
+
+// RestoreTo undoes every change recorded after a restore point. Changes
+// recorded before it, and the restore points themselves, are kept.
+func (j *Journal) RestoreTo(id string) error {
+	j.mu.Lock()
+	p, err := j.findRestorePoint(id)
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return j.restore(func(e Entry) bool { return e.Seq > p.Seq })
+}
+
+// Prune deletes unpinned restore points older than maxAge, then all but the
+// newest keep of the remaining unpinned points. A zero maxAge or a negative
+// keep disables that limit. It returns the points that were removed.
+//
+// Pruning only forgets positions in the journal; the entries themselves are
+// kept because earlier points and RestoreAll still need them.
+func (j *Journal) Prune(maxAge time.Duration, keep int) ([]RestorePoint, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	points, err := j.loadRestorePoints()
+	if err != nil {
+		return nil, err
+	}
+
+	// Newest first so the count limit keeps the most recent points.
+	sort.SliceStable(points, func(a, b int) bool { return points[a].Seq > points[b].Seq })
+
+	cutoff := time.Now().Add(-maxAge)
+	var kept, removed []RestorePoint
+	unpinned := 0
+	for _, p := range points {
+		if p.Pinned {
+			kept = append(kept, p)
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, p.Created)
+		tooOld := maxAge > 0 && err == nil && created.Before(cutoff)
+		tooMany := keep >= 0 && unpinned >= keep
+		if tooOld || tooMany {
+			removed = append(removed, p)
+			continue
+		}
+		unpinned++
+		kept = append(kept, p)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(kept, func(a, b int) bool { return kept[a].Seq < kept[b].Seq })
+	if err := j.saveRestorePoints(kept); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// describe renders the state held in an entry for display.
+func describe(e Entry) string {
+	switch {
+	case e.Registry != nil:
+		return describeRegistry(e.Registry)
+	case e.Service != nil:
+		state := "stopped"
+		if e.Service.Running {
+			state = "running"
+		}
+		if e.Service.StartType == "" {
+			return state
+		}
+		return e.Service.StartType + ", " + state
+	case e.Kind == KindPowerPlan:
+		return e.PowerPlan
+	case e.DNS != nil:
+		if len(e.DNS.Servers) == 0 {
+			return "DHCP"
+		}
+		return strings.Join(e.DNS.Servers, ", ")
+	case e.BootOption != nil:
+		if !e.BootOption.Existed {
+			return "not set"
+		}
+		return e.BootOption.Value
+	case e.File != nil:
+		if !e.File.Existed {
+			return "missing"
+		}
+		return fmt.Sprintf("%d bytes", len(e.File.Content))
+	case e.Rename != nil:
+		return "at " + e.Rename.From
+	case e.Task != nil:
+		if e.Task.Enabled {
+			return "enabled"
+		}
+		return "disabled"
+	}
+	return ""
+}
+
+func describeRegistry(b *RegistryBackup) string {
+	if !b.Existed {
+		return "not set"
+	}
+	switch b.Type {
+	case reg.SZ, reg.ExpandSZ:
+		return strconv.Quote(b.String)
+	case reg.DWord, reg.QWord:
+		return strconv.FormatUint(b.Integer, 10)
+	case reg.MultiSZ:
+		quoted := make([]string, len(b.Strings))
+		for i, v := range b.Strings {
+			quoted[i] = strconv.Quote(v)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	default:
+		return "hex:" + hex.EncodeToString(b.Binary)
+	}
+}
+
+// describeCurrent reads the live state of the resource e refers to and
+// renders it like describe.
+func (j *Journal) describeCurrent(e Entry) string {
+	unknown := func(err error) string { return "unknown (" + err.Error() + ")" }
+
+	switch {
+	case e.Registry != nil:
+		b, err := j.readRegistryValue(e.Registry.Root, e.Registry.Path, e.Registry.ValueName)
+		if err != nil {
+			return unknown(err)
+		}
+		return describeRegistry(b)
+	case e.Service != nil:
+		return describe(Entry{Service: j.readService(e.Service.Name)})
+	case e.Kind == KindPowerPlan:
+		guid, err := j.readPowerPlan()
+		if err != nil {
+			return unknown(err)
+		}
+		return guid
+	case e.DNS != nil:
+		servers, err := j.readDNSServers(e.DNS.Adapter)
+		if err != nil {
+			return unknown(err)
+		}
+		return describe(Entry{DNS: &DNSBackup{Servers: servers}})
+	case e.BootOption != nil:
+		value, existed, err := j.readBootOption(e.BootOption.Name)
+		if err != nil {
+			return unknown(err)
+		}
+		return describe(Entry{BootOption: &BootOptionBackup{Value: value, Existed: existed}})
+	case e.File != nil:
+		b, err := readFile(e.File.Path)
+		if err != nil {
+			return unknown(err)
+		}
+		return describe(Entry{File: b})
+	case e.Rename != nil:
+		for _, path := range []string{e.Rename.From, e.Rename.To} {
+			if _, err := os.Stat(path); err == nil {
+				return "at " + path
+			}
+		}
+		return "missing"
+	case e.Task != nil:
+		enabled, err := j.readTaskEnabled(e.Task.Name)
+		if err != nil {
+			return unknown(err)
+		}
+		return describe(Entry{Task: &TaskBackup{Enabled: enabled}})
+	}
+	return ""
+}
@@ -0,0 +1,242 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cleanforge/internal/guard"
+	"cleanforge/internal/plan"
+)
+
+// AuditRecord is one line of an audit log: what happened to one entry. The
+// files in a directory entry get a record each, with Entry set to the
+// directory, so a directory removed only in part shows which files went.
+type AuditRecord struct {
+	Time     string       `json:"time"` // RFC 3339, when the entry was handled
+	Plan     string       `json:"plan"` // ID of the cleanup plan
+	Host     string       `json:"host"`
+	User     string       `json:"user"`
+	Category string       `json:"category"`
+	Action   plan.Kind    `json:"action"`
+	Path     string       `json:"path"`            // file, directory or command line
+	Entry    string       `json:"entry,omitempty"` // directory entry the file was removed with
+	Size     int64        `json:"size"`
+	Files    int          `json:"files"`
+	Result   string       `json:"result"` // "deleted", "quarantined", "ran", "failed", "pending" or "skipped"
+	Class    ErrorClass   `json:"class,omitempty"`
+	Reason   guard.Reason `json:"reason,omitempty"` // why the deletion guard skipped the entry
+	Error    string       `json:"error,omitempty"`
+}
+
+// AuditLog is a directory of JSON Lines files, one per cleanup, recording
+// every entry the cleanup removed or failed to remove.
+type AuditLog struct {
+	dir string
+}
+
+// NewAuditLog returns a log kept in dir. An empty dir means AuditDir().
+func NewAuditLog(dir string) *AuditLog {
+	return &AuditLog{dir: dir}
+}
+
+// DefaultAudit is the log the app and the CLI write to when auditing is on.
+var DefaultAudit = NewAuditLog("")
+
+// AuditDir returns ~/.cleanforge/logs.
+func AuditDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(homeDir, ".cleanforge", "logs")
+}
+
+// Dir returns the directory holding the log files.
+func (l *AuditLog) Dir() string {
+	if l.dir != "" {
+		return l.dir
+	}
+	return AuditDir()
+}
+
+// Path returns the file the cleanup with the given plan ID is logged to.
+func (l *AuditLog) Path(planID string) string {
+	return filepath.Join(l.Dir(), "clean-"+planID+".jsonl")
+}
+
+// SetAudit makes cleanups record every entry they remove, or fail to remove,
+// in l. Pass nil to stop auditing.
+func (c *Cleaner) SetAudit(l *AuditLog) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.audit = l
+}
+
+// auditFiles lists the files in each directory entry as it is removed, so
+// the audit log can record them one by one.
+type auditFiles struct {
+	mu    sync.Mutex
+	byDir map[string][]auditFile
+}
+
+type auditFile struct {
+	path string
+	size int64
+	gone bool // no longer there after the removal
+}
+
+// track wraps remove so that the files under a directory entry are listed
+// before it is removed, and checked for afterwards.
+func (a *auditFiles) track(remove func(entry) error) func(entry) error {
+	return func(e entry) error {
+		if !e.dir {
+			return remove(e)
+		}
+		var files []auditFile
+		_ = filepath.WalkDir(e.path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			f := auditFile{path: path}
+			if info, err := d.Info(); err == nil {
+				f.size = info.Size()
+			}
+			files = append(files, f)
+			return nil
+		})
+
+		err := remove(e)
+		for i := range files {
+			_, statErr := os.Lstat(files[i].path)
+			files[i].gone = os.IsNotExist(statErr)
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.byDir == nil {
+			a.byDir = make(map[string][]auditFile)
+		}
+		a.byDir[e.path] = files
+		return err
+	}
+}
+
+// take returns and forgets the files listed for the directory entry at path.
+func (a *auditFiles) take(path string) []auditFile {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	files := a.byDir[path]
+	delete(a.byDir, path)
+	return files
+}
+
+// attach writes a record for every action of p as it is tried, or for every
+// file of a directory entry tracked by files. The file is created with the
+// first record and closed once p has run. A log that cannot be written adds
+// a warning to the result rather than stopping the cleanup.
+func (l *AuditLog) attach(p *plan.Plan, files *auditFiles) {
+	host, _ := os.Hostname()
+	username := ""
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+
+	var mu sync.Mutex
+	var f *os.File
+	var failed error
+	p.Observe(func(a plan.Action, err error) {
+		rec := AuditRecord{
+			Time:     time.Now().Format(time.RFC3339Nano),
+			Plan:     p.ID,
+			Host:     host,
+			User:     username,
+			Category: a.Tweak,
+			Action:   a.Kind,
+			Path:     a.Target,
+			Size:     a.Size,
+			Files:    a.Files,
+		}
+		var ge *guard.Error
+		switch {
+		case errors.As(err, &ge):
+			rec.Result, rec.Reason, rec.Error = "skipped", ge.Reason, err.Error()
+		case err != nil:
+			rec.Result, rec.Class, rec.Error = "failed", Classify(err), err.Error()
+			if errors.Is(err, errPending) {
+				rec.Result = "pending"
+			}
+		default:
+			rec.Result = auditDone(a.Kind)
+		}
+
+		recs := []AuditRecord{rec}
+		if dir := files.take(a.Target); len(dir) > 0 {
+			recs = recs[:0]
+			for _, file := range dir {
+				r := rec
+				r.Path, r.Entry, r.Size, r.Files = file.path, a.Target, file.size, 1
+				if file.gone {
+					r.Result, r.Class, r.Reason, r.Error = auditDone(a.Kind), "", "", ""
+				}
+				recs = append(recs, r)
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if failed != nil {
+			return
+		}
+		if f == nil {
+			if failed = os.MkdirAll(l.Dir(), 0755); failed != nil {
+				return
+			}
+			if f, failed = os.OpenFile(l.Path(p.ID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); failed != nil {
+				return
+			}
+		}
+		for _, r := range recs {
+			data, _ := json.Marshal(r)
+			if _, failed = f.Write(append(data, '\n')); failed != nil {
+				return
+			}
+		}
+	})
+	p.After(func(r *plan.Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		if f != nil {
+			if err := f.Sync(); err != nil && failed == nil {
+				failed = err
+			}
+			if err := f.Close(); err != nil && failed == nil {
+				failed = err
+			}
+		}
+		if failed != nil {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("audit log %s: %v", l.Path(p.ID), failed))
+		}
+	})
+}
+
+// auditDone is the result recorded for an action of the given kind that
+// succeeded.
+func auditDone(kind plan.Kind) string {
+	switch kind {
+	case plan.KindQuarantine:
+		return "quarantined"
+	case plan.KindCommand:
+		return "ran"
+	}
+	return "deleted"
+}
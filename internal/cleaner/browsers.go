@@ -0,0 +1,261 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// Browser engines, which decide where a browser keeps its profiles' caches.
+const (
+	EngineChromium = "chromium"
+	EngineGecko    = "gecko"
+)
+
+// Browser is an installed web browser and its profiles.
+type Browser struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Engine    string           `json:"engine"`
+	Profiles  []BrowserProfile `json:"profiles"`
+	Processes []string         `json:"-"` // executable names without .exe
+}
+
+// BrowserProfile is one profile of a browser.
+type BrowserProfile struct {
+	Dir    string   `json:"dir"`  // folder name, e.g. "Profile 1"
+	Name   string   `json:"name"` // name the browser shows, e.g. "Work"
+	Caches []string `json:"-"`    // cache folders of the profile
+}
+
+// browserDef is where a browser keeps its profiles. For Chromium browsers
+// root holds the profiles' caches (or is the only profile, as with Opera)
+// and state holds the Local State file naming them. For Gecko browsers root
+// holds the profiles.
+type browserDef struct {
+	id, name, engine string
+	root, state      string
+	processes        []string
+}
+
+// chromiumCaches are the cache folders of a Chromium profile.
+var chromiumCaches = []string{"Cache", "Code Cache", "GPUCache"}
+
+// browserDefs lists the browsers p knows of, installed or not.
+func browserDefs(p *Paths) []browserDef {
+	type chromium struct {
+		id, name  string
+		win       string // under %LOCALAPPDATA%
+		winState  string // under %APPDATA% when it is not win
+		xdg       string // under the cache and config folders, "" if not on Linux
+		processes []string
+	}
+	chromiums := []chromium{
+		{"chrome", "Chrome", `Google\Chrome\User Data`, "", "google-chrome", []string{"chrome"}},
+		{"edge", "Edge", `Microsoft\Edge\User Data`, "", "microsoft-edge", []string{"msedge"}},
+		{"brave", "Brave", `BraveSoftware\Brave-Browser\User Data`, "", "BraveSoftware/Brave-Browser", []string{"brave"}},
+		{"opera", "Opera", `Opera Software\Opera Stable`, `Opera Software\Opera Stable`, "opera", []string{"opera"}},
+		{"opera_gx", "Opera GX", `Opera Software\Opera GX Stable`, `Opera Software\Opera GX Stable`, "", []string{"opera"}},
+		{"vivaldi", "Vivaldi", `Vivaldi\User Data`, "", "vivaldi", []string{"vivaldi", "vivaldi-bin"}},
+		{"chromium", "Chromium", `Chromium\User Data`, "", "chromium", []string{"chromium", "chrome"}},
+	}
+
+	var defs []browserDef
+	for _, b := range chromiums {
+		d := browserDef{id: b.id, name: b.name, engine: EngineChromium, processes: b.processes}
+		switch {
+		case p.Windows():
+			d.root = p.join(p.LocalAppData(), b.win)
+			d.state = d.root
+			if b.winState != "" {
+				d.state = p.join(p.RoamingAppData(), b.winState)
+			}
+		case b.xdg != "":
+			d.root = p.join(p.Cache(), b.xdg)
+			d.state = p.join(p.Config(), b.xdg)
+		default:
+			continue
+		}
+		defs = append(defs, d)
+	}
+
+	firefox, librewolf := p.join(p.Cache(), "mozilla", "firefox"), p.join(p.Cache(), "librewolf")
+	if p.Windows() {
+		firefox = p.join(p.LocalAppData(), "Mozilla", "Firefox", "Profiles")
+		librewolf = p.join(p.LocalAppData(), "librewolf", "Profiles")
+	}
+	return append(defs,
+		browserDef{id: "firefox", name: "Firefox", engine: EngineGecko, root: firefox, processes: []string{"firefox"}},
+		browserDef{id: "librewolf", name: "LibreWolf", engine: EngineGecko, root: librewolf, processes: []string{"librewolf"}},
+	)
+}
+
+// DiscoverBrowsers returns the browsers p knows of that have at least one
+// profile with a cache.
+func DiscoverBrowsers(p *Paths) []Browser {
+	var found []Browser
+	for _, d := range browserDefs(p) {
+		if b := d.discover(p); len(b.Profiles) > 0 {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+// discover finds the profiles of d, sorted with the default profile first.
+func (d browserDef) discover(p *Paths) Browser {
+	b := Browser{ID: d.id, Name: d.name, Engine: d.engine, Processes: d.processes}
+	if d.engine == EngineGecko {
+		for _, cache := range resolveGlobPaths(p.join(d.root, "*", "cache2")) {
+			dir := filepath.Base(filepath.Dir(cache))
+			name := dir
+			if i := strings.Index(dir, "."); i >= 0 {
+				name = dir[i+1:]
+			}
+			b.Profiles = append(b.Profiles, BrowserProfile{Dir: dir, Name: name, Caches: []string{cache}})
+		}
+		return b
+	}
+
+	names := chromiumProfileNames(p.join(d.state, "Local State"))
+	if hasChromiumCache(p, d.root) {
+		b.Profiles = append(b.Profiles, chromiumProfile(p, d.root, "", d.name))
+	}
+	entries, _ := os.ReadDir(d.root)
+	for _, e := range entries {
+		dir := p.join(d.root, e.Name())
+		if !e.IsDir() || !hasChromiumCache(p, dir) {
+			continue
+		}
+		name := names[e.Name()]
+		if name == "" {
+			name = e.Name()
+		}
+		b.Profiles = append(b.Profiles, chromiumProfile(p, dir, e.Name(), name))
+	}
+	sort.SliceStable(b.Profiles, func(i, j int) bool {
+		return b.Profiles[i].Dir == "Default" && b.Profiles[j].Dir != "Default"
+	})
+	return b
+}
+
+// hasChromiumCache reports whether dir is a Chromium profile with a cache.
+func hasChromiumCache(p *Paths, dir string) bool {
+	for _, c := range chromiumCaches {
+		if info, err := os.Stat(p.join(dir, c)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+func chromiumProfile(p *Paths, dir, folder, name string) BrowserProfile {
+	profile := BrowserProfile{Dir: folder, Name: name}
+	for _, c := range chromiumCaches {
+		profile.Caches = append(profile.Caches, p.join(dir, c))
+	}
+	return profile
+}
+
+// chromiumProfileNames reads the names of a Chromium browser's profiles by
+// folder from its Local State file. A missing or unreadable file names none.
+func chromiumProfileNames(path string) map[string]string {
+	var state struct {
+		Profile struct {
+			InfoCache map[string]struct {
+				Name string `json:"name"`
+			} `json:"info_cache"`
+		} `json:"profile"`
+	}
+	names := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &state) != nil {
+		return names
+	}
+	for dir, info := range state.Profile.InfoCache {
+		names[dir] = info.Name
+	}
+	return names
+}
+
+// browserCategories returns a cache category for every browser p knows of,
+// with a CategoryItem for each profile found.
+func browserCategories(p *Paths) []CleanCategory {
+	var categories []CleanCategory
+	for _, d := range browserDefs(p) {
+		b := d.discover(p)
+		cat := CleanCategory{
+			ID:          "browser_cache_" + b.ID,
+			Name:        b.Name + " Cache",
+			Description: "Cache files of every " + b.Name + " profile",
+			Icon:        "globe",
+			Risk:        "safe",
+			Paths:       []string{},
+			Processes:   b.Processes,
+		}
+		for _, profile := range b.Profiles {
+			id := profile.Dir
+			if id == "" {
+				id = b.ID
+			}
+			cat.Items = append(cat.Items, CategoryItem{ID: id, Name: profile.Name, Paths: profile.Caches})
+			cat.Paths = append(cat.Paths, profile.Caches...)
+		}
+		categories = append(categories, cat)
+	}
+	return categories
+}
+
+// runningProcesses returns the lower-case names, without .exe, of the
+// running processes. Tests replace it.
+var runningProcesses = func() (map[string]bool, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	names := make(map[string]bool, len(procs))
+	for _, proc := range procs {
+		if name, err := proc.Name(); err == nil {
+			names[strings.TrimSuffix(strings.ToLower(name), ".exe")] = true
+		}
+	}
+	return names, nil
+}
+
+// SetSkipRunning makes scans and cleanups leave out categories whose
+// Processes are running, such as the cache of an open browser.
+func (c *Cleaner) SetSkipRunning(skip bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skipRunning = skip
+}
+
+// busyCategories returns why each category left out by SetSkipRunning is
+// left out, by ID. If the processes cannot be listed none is.
+func (c *Cleaner) busyCategories() map[string]string {
+	c.mu.Lock()
+	skip := c.skipRunning
+	c.mu.Unlock()
+	if !skip {
+		return nil
+	}
+	running, err := runningProcesses()
+	if err != nil {
+		return nil
+	}
+	busy := make(map[string]string)
+	for _, cat := range c.categoryList() {
+		for _, name := range cat.Processes {
+			if running[name] {
+				busy[cat.ID] = name + " is running"
+				break
+			}
+		}
+	}
+	return busy
+}
@@ -1,27 +1,78 @@
 package cleaner
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"cleanforge/internal/cmd"
+	"cleanforge/internal/guard"
+	"cleanforge/internal/plan"
+	"cleanforge/internal/quarantine"
 )
 
+// runner executes external commands. Tests replace it with a cmd.Fake.
+var runner cmd.Runner = cmd.Default
+
 // CleanCategory represents a category of files that can be cleaned.
 type CleanCategory struct {
 	ID          string   `json:"id"`
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Icon        string   `json:"icon"`
-	Risk        string   `json:"risk"` // "safe", "low", "medium"
+	Risk        string   `json:"risk"`           // "safe", "low", "medium"
+	Type        string   `json:"type,omitempty"` // "" to empty Paths, or TypeDuplicates
 	Size        int64    `json:"size"`
 	FileCount   int      `json:"fileCount"`
 	Paths       []string `json:"-"`
+
+	// KeptSize and KeptFiles count what the filters below kept out of Size
+	// and FileCount.
+	KeptSize  int64 `json:"keptSize,omitempty"`
+	KeptFiles int   `json:"keptFiles,omitempty"`
+
+	// Duplicates lists the sets a duplicate category's scan found. Size and
+	// FileCount cover the copies picked for removal.
+	Duplicates []DuplicateSet `json:"duplicates,omitempty"`
+
+	// Items breaks Size and FileCount down by part, such as browser profile.
+	Items []CategoryItem `json:"items,omitempty"`
+
+	// Processes are the executables, without .exe, that use Paths. Busy says
+	// why the category was left out of a scan; see SetSkipRunning.
+	Processes []string `json:"-"`
+	Busy      string   `json:"busy,omitempty"`
+
+	// Filters narrow what is cleaned under Paths; see filter.
+	MaxAge     time.Duration `json:"-"` // only files last modified longer ago than this
+	SkipRecent time.Duration `json:"-"` // keep files modified within this long, e.g. by a running installer
+	MinSize    int64         `json:"-"` // only files at least this many bytes
+	MaxSize    int64         `json:"-"` // only files at most this many bytes; 0 means no limit
+	Include    []string      `json:"-"` // if set, only files matching one of these patterns
+	Exclude    []string      `json:"-"` // patterns for entries that are never cleaned
+}
+
+// CategoryItem is a part of a category measured on its own, such as the
+// cache of one browser profile.
+type CategoryItem struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Size      int64    `json:"size"`
+	FileCount int      `json:"fileCount"`
+	Paths     []string `json:"-"` // the category's paths that belong to the item
 }
 
+// tempGrace keeps recently written temp files, which often belong to an
+// installer or update that is still running.
+const tempGrace = time.Hour
+
 // ScanResult contains the results of scanning all cleanup categories.
 type ScanResult struct {
 	Categories []CleanCategory `json:"categories"`
@@ -34,10 +85,63 @@ type CleanResult struct {
 	FreedSpace   int64    `json:"freedSpace"`
 	DeletedFiles int      `json:"deletedFiles"`
 	Errors       []string `json:"errors"`
+
+	Categories []CategoryResult `json:"categories"` // in the order they were cleaned
+	Started    time.Time        `json:"started"`
+	Finished   time.Time        `json:"finished"`
+	DurationMs int64            `json:"durationMs"`
+
+	// Failures details the entries that could not be cleaned, and
+	// ErrorCounts counts them by class.
+	Failures    []CleanError       `json:"failures,omitempty"`
+	ErrorCounts map[ErrorClass]int `json:"errorCounts,omitempty"`
+	Pending     int                `json:"pending,omitempty"` // in-use entries left for a later run
+
+	// Skips lists the entries the deletion guard left in place. They are
+	// not errors, and count towards their category's Skipped.
+	Skips []SkippedEntry `json:"skips,omitempty"`
+}
+
+// CategoryResult is what a cleanup did in one category.
+type CategoryResult struct {
+	ID           string `json:"id"`
+	FreedSpace   int64  `json:"freedSpace"`
+	DeletedFiles int    `json:"deletedFiles"`
+	// Skipped counts files kept by the category's filters, entries the
+	// deletion guard refused and entries not reached because the cleanup
+	// was cancelled.
+	Skipped int          `json:"skipped"`
+	Errors  []CleanError `json:"errors"`
+}
+
+// category returns the result of the category with the given ID, adding it
+// if it is not there yet.
+func (r *CleanResult) category(id string) *CategoryResult {
+	for i := range r.Categories {
+		if r.Categories[i].ID == id {
+			return &r.Categories[i]
+		}
+	}
+	r.Categories = append(r.Categories, CategoryResult{ID: id, Errors: []CleanError{}})
+	return &r.Categories[len(r.Categories)-1]
+}
+
+// addError records an entry that could not be cleaned.
+func (r *CleanResult) addError(e CleanError) {
+	r.Errors = append(r.Errors, e.Error())
+	r.Failures = append(r.Failures, e)
+	if r.ErrorCounts == nil {
+		r.ErrorCounts = make(map[ErrorClass]int)
+	}
+	r.ErrorCounts[e.Class]++
+	if e.Pending {
+		r.Pending++
+	}
 }
 
 // CleanProgress reports progress during a cleanup operation.
 type CleanProgress struct {
+	Phase       string  `json:"phase"` // "scan" or "clean"
 	Category    string  `json:"category"`
 	Current     int     `json:"current"`
 	Total       int     `json:"total"`
@@ -45,18 +149,140 @@ type CleanProgress struct {
 	CurrentFile string  `json:"currentFile"`
 }
 
+// ProgressFunc receives progress updates from ScanContext and CleanContext.
+// It is called on the goroutine doing the work, so it should return quickly.
+type ProgressFunc func(CleanProgress)
+
+// progressInterval limits how often per-file progress is reported, so walking
+// a large cache does not flood the receiver.
+var progressInterval = 100 * time.Millisecond
+
+// progress tracks and reports how far an operation has got. It is safe for
+// concurrent use. A nil *progress reports nothing.
+type progress struct {
+	mu   sync.Mutex
+	fn   ProgressFunc
+	p    CleanProgress
+	last time.Time
+}
+
+func newProgress(fn ProgressFunc, phase string, total int) *progress {
+	if fn == nil {
+		return nil
+	}
+	return &progress{fn: fn, p: CleanProgress{Phase: phase, Total: total}}
+}
+
+// emit sends the current state to the receiver.
+func (pr *progress) emit() {
+	if pr.p.Total > 0 {
+		pr.p.Percentage = float64(pr.p.Current) / float64(pr.p.Total) * 100
+	}
+	pr.fn(pr.p)
+	pr.last = time.Now()
+}
+
+// category reports that work on a category has started.
+func (pr *progress) category(id string) {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.p.Category = id
+	pr.p.CurrentFile = ""
+	pr.emit()
+}
+
+// file reports the file being worked on, at most once per progressInterval.
+func (pr *progress) file(path string) {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	if time.Since(pr.last) < progressInterval {
+		return
+	}
+	pr.p.CurrentFile = path
+	pr.emit()
+}
+
+// step reports that one more unit of work is done. The final step is always
+// reported; the others at most once per progressInterval.
+func (pr *progress) step(category, path string) {
+	if pr == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.p.Current++
+	if pr.p.Current < pr.p.Total && time.Since(pr.last) < progressInterval {
+		return
+	}
+	pr.p.Category = category
+	pr.p.CurrentFile = path
+	pr.emit()
+}
+
 // Cleaner manages file scanning and cleanup operations.
 type Cleaner struct {
 	username   string
 	categories []CleanCategory
+
+	workers     int           // paths scanned at once, see SetWorkers
+	pathTimeout time.Duration // limit for scanning one path, see SetPathTimeout
+
+	quarantine  *quarantine.Store // where removed entries go instead of being deleted, see SetQuarantine
+	pending     *PendingList      // where entries in use are recorded, see SetPending
+	audit       *AuditLog         // where every removed entry is recorded, see SetAudit
+	skipRunning bool              // leave out categories whose processes run, see SetSkipRunning
+	guard       *guard.Guard      // checks every entry before it is removed; guard.Default if nil
+	minAge      time.Duration     // keep files modified more recently in every category, see SetMinAge
+
+	// mu guards categories, quarantine, pending, audit and skipRunning,
+	// which may change while a scan or cleanup runs, and the fields below. cache holds
+	// what the last Scan found for each scanUnit, so a clean right after
+	// it does not walk the same trees again.
+	mu    sync.Mutex
+	cache map[scanUnit]*scanned
+	picks map[string][]string // duplicate copies to remove by category, see PickDuplicates
+}
+
+// windowsOnly lists the built-in categories that only exist on Windows.
+var windowsOnly = map[string]bool{
+	"windows_temp":   true,
+	"user_temp":      true,
+	"recycle_bin":    true,
+	"windows_update": true,
+	"windows_logs":   true,
+	"prefetch":       true,
 }
 
 // NewCleaner creates a new Cleaner instance with all category definitions populated
-// using the provided Windows username for user-specific paths.
+// using the running system's folders; username is only used where the
+// environment does not name the user's profile folder.
 func NewCleaner(username string) *Cleaner {
-	userHome := filepath.Join("C:\\Users", username)
-	appDataLocal := filepath.Join(userHome, "AppData", "Local")
-	appDataRoaming := filepath.Join(userHome, "AppData", "Roaming")
+	return NewCleanerFor(SystemPaths(username))
+}
+
+// NewCleanerFor is like NewCleaner, but resolves folders with p. Categories
+// that only exist on Windows are left out for other systems.
+func NewCleanerFor(p *Paths) *Cleaner {
+	systemRoot := p.SystemRoot()
+	thumbnails := p.join(p.LocalAppData(), "Microsoft", "Windows", "Explorer", "thumbcache_*.db")
+	npm := p.join(p.RoamingAppData(), "npm-cache")
+	if !p.Windows() {
+		thumbnails = p.join(p.Cache(), "thumbnails", "*")
+		npm = p.join(p.Home(), ".npm")
+	}
+	if dir := p.env("npm_config_cache"); dir != "" {
+		npm = dir
+	}
+	gradle := p.join(p.Home(), ".gradle")
+	if dir := p.env("GRADLE_USER_HOME"); dir != "" {
+		gradle = dir
+	}
 
 	categories := []CleanCategory{
 		{
@@ -65,7 +291,8 @@ func NewCleaner(username string) *Cleaner {
 			Description: "Temporary files created by Windows and applications",
 			Icon:        "trash",
 			Risk:        "safe",
-			Paths:       []string{`C:\Windows\Temp`},
+			Paths:       []string{p.join(systemRoot, "Temp")},
+			SkipRecent:  tempGrace,
 		},
 		{
 			ID:          "user_temp",
@@ -73,7 +300,8 @@ func NewCleaner(username string) *Cleaner {
 			Description: "Temporary files in your user profile",
 			Icon:        "trash",
 			Risk:        "safe",
-			Paths:       []string{filepath.Join(appDataLocal, "Temp")},
+			Paths:       []string{p.Temp()},
+			SkipRecent:  tempGrace,
 		},
 		{
 			ID:          "recycle_bin",
@@ -83,43 +311,13 @@ func NewCleaner(username string) *Cleaner {
 			Risk:        "safe",
 			Paths:       []string{}, // handled via PowerShell
 		},
-		{
-			ID:          "browser_cache_chrome",
-			Name:        "Chrome Cache",
-			Description: "Google Chrome browser cache files",
-			Icon:        "globe",
-			Risk:        "safe",
-			Paths: []string{
-				filepath.Join(appDataLocal, "Google", "Chrome", "User Data", "Default", "Cache"),
-				filepath.Join(appDataLocal, "Google", "Chrome", "User Data", "Default", "Code Cache"),
-			},
-		},
-		{
-			ID:          "browser_cache_edge",
-			Name:        "Edge Cache",
-			Description: "Microsoft Edge browser cache files",
-			Icon:        "globe",
-			Risk:        "safe",
-			Paths: []string{
-				filepath.Join(appDataLocal, "Microsoft", "Edge", "User Data", "Default", "Cache"),
-				filepath.Join(appDataLocal, "Microsoft", "Edge", "User Data", "Default", "Code Cache"),
-			},
-		},
-		{
-			ID:          "browser_cache_firefox",
-			Name:        "Firefox Cache",
-			Description: "Mozilla Firefox browser cache files",
-			Icon:        "globe",
-			Risk:        "safe",
-			Paths:       resolveGlobPaths(filepath.Join(appDataLocal, "Mozilla", "Firefox", "Profiles", "*", "cache2")),
-		},
 		{
 			ID:          "npm_cache",
 			Name:        "npm Cache",
 			Description: "Node.js package manager cache",
 			Icon:        "package",
 			Risk:        "low",
-			Paths:       []string{filepath.Join(appDataRoaming, "npm-cache")},
+			Paths:       []string{npm},
 		},
 		{
 			ID:          "maven_cache",
@@ -127,7 +325,7 @@ func NewCleaner(username string) *Cleaner {
 			Description: "Apache Maven local repository cache",
 			Icon:        "package",
 			Risk:        "low",
-			Paths:       []string{filepath.Join(userHome, ".m2", "repository")},
+			Paths:       []string{p.join(p.Home(), ".m2", "repository")},
 		},
 		{
 			ID:          "gradle_cache",
@@ -135,7 +333,7 @@ func NewCleaner(username string) *Cleaner {
 			Description: "Gradle build system cache",
 			Icon:        "package",
 			Risk:        "low",
-			Paths:       []string{filepath.Join(userHome, ".gradle", "caches")},
+			Paths:       []string{p.join(gradle, "caches")},
 		},
 		{
 			ID:          "go_cache",
@@ -151,7 +349,7 @@ func NewCleaner(username string) *Cleaner {
 			Description: "Downloaded Windows Update files",
 			Icon:        "download",
 			Risk:        "low",
-			Paths:       []string{`C:\Windows\SoftwareDistribution\Download`},
+			Paths:       []string{p.join(systemRoot, "SoftwareDistribution", "Download")},
 		},
 		{
 			ID:          "windows_logs",
@@ -159,7 +357,7 @@ func NewCleaner(username string) *Cleaner {
 			Description: "Windows system log files",
 			Icon:        "file-text",
 			Risk:        "low",
-			Paths:       []string{`C:\Windows\Logs`},
+			Paths:       []string{p.join(systemRoot, "Logs")},
 		},
 		{
 			ID:          "prefetch",
@@ -167,56 +365,120 @@ func NewCleaner(username string) *Cleaner {
 			Description: "Windows application prefetch data",
 			Icon:        "zap",
 			Risk:        "low",
-			Paths:       []string{`C:\Windows\Prefetch`},
+			Paths:       []string{p.join(systemRoot, "Prefetch")},
 		},
 		{
 			ID:          "thumbnails",
 			Name:        "Thumbnail Cache",
-			Description: "Windows Explorer thumbnail cache files",
+			Description: "Thumbnail cache files kept by the file manager",
 			Icon:        "image",
 			Risk:        "safe",
-			Paths:       resolveGlobPaths(filepath.Join(appDataLocal, "Microsoft", "Windows", "Explorer", "thumbcache_*.db")),
+			Paths:       resolveGlobPaths(thumbnails),
 		},
 	}
 
+	// Browser caches go after the Recycle Bin, other developer tool caches
+	// after Go's
+	for i := len(categories) - 1; i >= 0; i-- {
+		switch categories[i].ID {
+		case "recycle_bin":
+			categories = slices.Insert(categories, i+1, browserCategories(p)...)
+		case "go_cache":
+			categories = slices.Insert(categories, i+1, devCategories(p)...)
+		}
+	}
+	if !p.Windows() {
+		categories = slices.DeleteFunc(categories, func(cat CleanCategory) bool {
+			return windowsOnly[cat.ID]
+		})
+	}
+
 	return &Cleaner{
-		username:   username,
+		username:   p.Username,
 		categories: categories,
+		guard:      p.deletionGuard(),
 	}
 }
 
+// Categories returns the cleanup categories without scanning them.
+func (c *Cleaner) Categories() []CleanCategory {
+	return slices.Clone(c.categoryList())
+}
+
+// categoryList returns the categories. The slice is replaced, never changed
+// in place, so it can be read without holding c.mu.
+func (c *Cleaner) categoryList() []CleanCategory {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.categories
+}
+
 // Scan examines all cleanup categories and calculates the total size and file count
 // for each category. Returns a ScanResult with the findings.
 func (c *Cleaner) Scan() (*ScanResult, error) {
+	return c.ScanContext(context.Background(), nil)
+}
+
+// ScanContext is like Scan, but reports progress to fn (which may be nil) and
+// stops with ctx's error as soon as ctx is cancelled. Paths are scanned
+// concurrently; see SetWorkers and SetPathTimeout.
+func (c *Cleaner) ScanContext(ctx context.Context, fn ProgressFunc) (*ScanResult, error) {
+	return c.scanCategories(ctx, func(string) bool { return true }, fn)
+}
+
+// ScanCategoriesContext is like ScanContext, but only scans the categories
+// with the given IDs.
+func (c *Cleaner) ScanCategoriesContext(ctx context.Context, categoryIDs []string, fn ProgressFunc) (*ScanResult, error) {
+	return c.scanCategories(ctx, func(id string) bool { return slices.Contains(categoryIDs, id) }, fn)
+}
+
+// scanCategories scans the categories accepted by include. A scanned
+// duplicate category loses the copies picked from its previous scan.
+func (c *Cleaner) scanCategories(ctx context.Context, include func(id string) bool, fn ProgressFunc) (*ScanResult, error) {
+	categories := c.categoryList()
 	result := &ScanResult{
-		Categories: make([]CleanCategory, 0, len(c.categories)),
+		Categories: make([]CleanCategory, 0, len(categories)),
+	}
+
+	busy := c.busyCategories()
+	units, n := c.units(func(id string) bool { return include(id) && busy[id] == "" })
+	scans := c.scanUnits(ctx, units, false, newProgress(fn, "scan", n))
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+	c.remember(scans)
 
-	for _, cat := range c.categories {
+	for _, cat := range categories {
+		if !include(cat.ID) {
+			continue
+		}
+		if cat.Type == TypeDuplicates {
+			c.mu.Lock()
+			delete(c.picks, cat.ID)
+			c.mu.Unlock()
+		}
 		scannedCat := cat
 		scannedCat.Size = 0
 		scannedCat.FileCount = 0
-
-		switch cat.ID {
-		case "recycle_bin":
-			size, count := scanRecycleBin()
-			scannedCat.Size = size
-			scannedCat.FileCount = count
-
-		case "go_cache":
-			size, count := scanGoCache()
-			scannedCat.Size = size
-			scannedCat.FileCount = count
-
-		default:
-			for _, p := range cat.Paths {
-				size, count, err := scanDirectory(p)
-				if err != nil {
-					// Skip directories we can't access
-					continue
+		scannedCat.Busy = busy[cat.ID]
+		scannedCat.Items = slices.Clone(cat.Items)
+
+		for i, u := range units {
+			if u.category == cat.ID {
+				scannedCat.Size += scans[i].size
+				scannedCat.FileCount += scans[i].files
+				scannedCat.KeptSize += scans[i].keptSize
+				scannedCat.KeptFiles += scans[i].keptFiles
+				scannedCat.Duplicates = scans[i].sets
+			}
+		}
+		for j := range scannedCat.Items {
+			item := &scannedCat.Items[j]
+			for i, u := range units {
+				if u.category == cat.ID && slices.Contains(item.Paths, u.path) {
+					item.Size += scans[i].size
+					item.FileCount += scans[i].files
 				}
-				scannedCat.Size += size
-				scannedCat.FileCount += count
 			}
 		}
 
@@ -231,57 +493,231 @@ func (c *Cleaner) Scan() (*ScanResult, error) {
 // Clean deletes files in the specified category IDs. Pass an empty slice to skip cleaning.
 // Returns a CleanResult with statistics about the operation.
 func (c *Cleaner) Clean(categoryIDs []string) (*CleanResult, error) {
+	return c.CleanContext(context.Background(), categoryIDs, nil)
+}
+
+// CleanContext is like Clean, but reports progress to fn (which may be nil):
+// first a "scan" phase while the categories are listed, then a "clean" phase
+// as entries are deleted. Once ctx is cancelled nothing more is deleted; what
+// was freed up to that point is returned along with ctx's error.
+func (c *Cleaner) CleanContext(ctx context.Context, categoryIDs []string, fn ProgressFunc) (*CleanResult, error) {
 	if len(categoryIDs) == 0 {
 		return &CleanResult{}, nil
 	}
 
+	p, err := c.PlanContext(ctx, categoryIDs, fn)
+	if err != nil {
+		return nil, err
+	}
+	return ExecuteContext(ctx, p, fn)
+}
+
+// Plan lists every file, directory and command Clean would delete or run for
+// the specified category IDs, without deleting anything. Paths measured by a
+// Scan in the last few minutes are not walked again.
+func (c *Cleaner) Plan(categoryIDs []string) (*plan.Plan, error) {
+	return c.PlanContext(context.Background(), categoryIDs, nil)
+}
+
+// PlanContext is like Plan, but reports "scan" progress to fn (which may be
+// nil) and stops with ctx's error once ctx is cancelled.
+func (c *Cleaner) PlanContext(ctx context.Context, categoryIDs []string, fn ProgressFunc) (*plan.Plan, error) {
 	// Build a lookup set for the requested categories
 	requested := make(map[string]bool, len(categoryIDs))
 	for _, id := range categoryIDs {
 		requested[id] = true
 	}
 
-	result := &CleanResult{
-		Errors: make([]string, 0),
+	c.mu.Lock()
+	q, pending, audit, minAge := c.quarantine, c.pending, c.audit, c.minAge
+	c.mu.Unlock()
+
+	busy := c.busyCategories()
+	units, n := c.units(func(id string) bool { return requested[id] && busy[id] == "" })
+	scans := c.scanUnits(ctx, units, true, newProgress(fn, "scan", n))
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	for _, cat := range c.categories {
-		if !requested[cat.ID] {
-			continue
+	p := plan.New("Clean " + strings.Join(categoryIDs, ", "))
+	for _, id := range categoryIDs {
+		if reason := busy[id]; reason != "" {
+			p.Warn("%s left out: %s", id, reason)
 		}
+	}
 
-		switch cat.ID {
+	kind, remove := plan.KindDelete, deleteEntry
+	if q != nil {
+		kind, remove = c.planQuarantine(p, q)
+	}
+	var files *auditFiles
+	if audit != nil {
+		files = &auditFiles{}
+		remove = files.track(remove)
+	}
+	remove = guarded(c.deletionGuard(), remove)
+	for _, s := range scans {
+		remove := remove
+		if pending != nil && kind == plan.KindDelete {
+			remove = deferLocked(pending, s.unit.category, remove)
+		}
+		if minAge > 0 && measuredByCommand(s.unit.category) {
+			p.Warn("%s left out: it cannot be cleaned by age", s.unit.category)
+			continue
+		}
+		switch s.unit.category {
 		case "recycle_bin":
-			freed, deleted, errs := cleanRecycleBin()
-			result.FreedSpace += freed
-			result.DeletedFiles += deleted
-			result.Errors = append(result.Errors, errs...)
-
+			planRecycleBin(p, s)
 		case "go_cache":
-			freed, deleted, errs := cleanGoCache()
-			result.FreedSpace += freed
-			result.DeletedFiles += deleted
-			result.Errors = append(result.Errors, errs...)
-
+			planGoCache(p, s)
+		case DockerBuildCacheID:
+			planDockerBuildCache(p, s)
 		default:
-			for _, p := range cat.Paths {
-				freed, deleted, errs := cleanPath(p)
-				result.FreedSpace += freed
-				result.DeletedFiles += deleted
-				result.Errors = append(result.Errors, errs...)
+			skipped := s.keptFiles
+			if _, t, ok := c.devCache(s.unit.category); ok && s.tool != nil {
+				if minAge <= 0 {
+					// The tool cleans its folder whatever the filters keep
+					planDevTool(p, s.tool, t)
+					skipped -= s.tool.keptFiles
+				} else {
+					planPath(p, s.tool, kind, remove)
+				}
+			}
+			p.Skip(s.unit.category, skipped)
+			if _, ok := c.duplicateRoots(s.unit.category); ok {
+				if err := c.pickDuplicates(s); err != nil {
+					return nil, err
+				}
+				planPath(p, s, kind, func(e entry) error {
+					if err := unchangedDuplicate(e); err != nil {
+						return err
+					}
+					return remove(e)
+				})
+				continue
 			}
+			planPath(p, s, kind, remove)
 		}
 	}
+	if audit != nil {
+		audit.attach(p, files)
+	}
 
-	return result, nil
+	return p, nil
+}
+
+// SetQuarantine makes cleanups move files and directories into q instead of
+// deleting them, so they can be restored until q purges them. Pass nil to
+// delete permanently again. Categories cleaned by a command, such as the
+// Recycle Bin, are unaffected.
+func (c *Cleaner) SetQuarantine(q *quarantine.Store) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.quarantine = q
+}
+
+// SetPending makes cleanups that delete permanently record entries that are
+// in use into l, for l.Retry to delete on a later run. Pass nil to only
+// report them as errors.
+func (c *Cleaner) SetPending(l *PendingList) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = l
+}
+
+// planQuarantine starts a quarantine run when p is executed and closes it
+// afterwards, returning the action kind and remover that move entries into it.
+func (c *Cleaner) planQuarantine(p *plan.Plan, q *quarantine.Store) (plan.Kind, func(entry) error) {
+	var run *quarantine.Run
+	p.Before(func() error {
+		var err error
+		if run, err = q.Begin(p.Description); err != nil {
+			return fmt.Errorf("failed to start quarantine: %w", err)
+		}
+		return nil
+	})
+	p.After(func(*plan.Result) {
+		run.Close()
+	})
+	return plan.KindQuarantine, func(e entry) error {
+		_, err := run.Move(e.path)
+		return err
+	}
+}
+
+// Execute applies a cleanup plan from Plan and tallies what it freed.
+func Execute(p *plan.Plan) (*CleanResult, error) {
+	return ExecuteContext(context.Background(), p, nil)
+}
+
+// ExecuteContext is like Execute, but reports "clean" progress to fn (which
+// may be nil) and stops deleting once ctx is cancelled, returning what was
+// freed so far along with ctx's error.
+func ExecuteContext(ctx context.Context, p *plan.Plan, fn ProgressFunc) (*CleanResult, error) {
+	pr := newProgress(fn, "clean", len(p.Actions))
+	r, err := plan.ExecuteContext(ctx, p, func(_ int, a plan.Action) {
+		pr.step(a.Tweak, a.Target)
+	})
+	if r == nil {
+		return nil, err
+	}
+	result := &CleanResult{
+		Errors:     append([]string{}, r.Warnings...),
+		Categories: []CategoryResult{},
+		Started:    r.Started,
+		Finished:   r.Finished,
+		DurationMs: r.Finished.Sub(r.Started).Milliseconds(),
+	}
+
+	// Actions not tried were cut off by cancellation
+	untried := make(map[string]int)
+	for _, a := range p.Actions {
+		result.category(a.Tweak)
+		untried[a.Tweak]++
+	}
+	for _, a := range r.Applied {
+		cat := result.category(a.Tweak)
+		cat.FreedSpace += a.Size
+		cat.DeletedFiles += a.Files
+		untried[a.Tweak]--
+	}
+	for _, f := range r.Failures {
+		if skip, ok := guardSkip(f); ok {
+			result.Skips = append(result.Skips, skip)
+			result.category(f.Action.Tweak).Skipped++
+			untried[f.Action.Tweak]--
+			continue
+		}
+		e := failureError(f)
+		result.addError(e)
+		cat := result.category(f.Action.Tweak)
+		cat.Errors = append(cat.Errors, e)
+		untried[f.Action.Tweak]--
+	}
+	skipped := make([]string, 0, len(p.Skipped))
+	for id := range p.Skipped {
+		skipped = append(skipped, id)
+	}
+	sort.Strings(skipped)
+	for _, id := range skipped {
+		result.category(id).Skipped += p.Skipped[id]
+	}
+	for id, n := range untried {
+		result.category(id).Skipped += n
+	}
+
+	result.FreedSpace, result.DeletedFiles = r.Freed()
+	return result, err
 }
 
 // scanDirectory recursively walks a directory and returns total size in bytes and file count.
 // Permission errors and inaccessible files are silently skipped.
 func scanDirectory(path string) (int64, int, error) {
-	var totalSize int64
-	var fileCount int
+	return walkDirectory(context.Background(), path, nil)
+}
 
+// walkDirectory is scanDirectory with cancellation and per-file progress.
+func walkDirectory(ctx context.Context, path string, pr *progress) (int64, int, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -295,18 +731,7 @@ func scanDirectory(path string) (int64, int, error) {
 		return info.Size(), 1, nil
 	}
 
-	err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
-		if err != nil {
-			// Skip files/dirs we can't access
-			return nil
-		}
-		if !info.IsDir() {
-			totalSize += info.Size()
-			fileCount++
-		}
-		return nil
-	})
-
+	totalSize, fileCount, err := walkTree(ctx, path, pr)
 	if err != nil {
 		return totalSize, fileCount, fmt.Errorf("error walking %s: %w", path, err)
 	}
@@ -316,9 +741,9 @@ func scanDirectory(path string) (int64, int, error) {
 
 // scanRecycleBin estimates the size and item count of the Windows Recycle Bin
 // using a PowerShell command.
-func scanRecycleBin() (int64, int) {
+func scanRecycleBin(ctx context.Context) (int64, int) {
 	// Get total size
-	sizeOut, err := cmd.Hidden("powershell", "-Command",
+	sizeOut, err := runner.Command(ctx, "powershell", "-Command",
 		"(New-Object -ComObject Shell.Application).NameSpace(10).Items() | Measure-Object -Property Size -Sum | Select-Object -ExpandProperty Sum").Output()
 	if err != nil {
 		return 0, 0
@@ -335,7 +760,7 @@ func scanRecycleBin() (int64, int) {
 	}
 
 	// Get item count
-	countOut, err := cmd.Hidden("powershell", "-Command",
+	countOut, err := runner.Command(ctx, "powershell", "-Command",
 		"(New-Object -ComObject Shell.Application).NameSpace(10).Items() | Measure-Object | Select-Object -ExpandProperty Count").Output()
 	if err != nil {
 		return size, 0
@@ -351,8 +776,8 @@ func scanRecycleBin() (int64, int) {
 }
 
 // scanGoCache estimates the size of the Go build cache by checking the cache directory.
-func scanGoCache() (int64, int) {
-	out, err := cmd.Hidden("go", "env", "GOCACHE").Output()
+func scanGoCache(ctx context.Context, pr *progress) (int64, int) {
+	out, err := runner.Command(ctx, "go", "env", "GOCACHE").Output()
 	if err != nil {
 		return 0, 0
 	}
@@ -362,7 +787,7 @@ func scanGoCache() (int64, int) {
 		return 0, 0
 	}
 
-	size, count, err := scanDirectory(cacheDir)
+	size, count, err := walkDirectory(ctx, cacheDir, pr)
 	if err != nil {
 		return 0, 0
 	}
@@ -370,105 +795,113 @@ func scanGoCache() (int64, int) {
 	return size, count
 }
 
+// planRecycleBin adds emptying the Windows Recycle Bin to a plan.
+func planRecycleBin(p *plan.Plan, s *scanned) {
+	a := plan.Command("powershell", "-Command", "Clear-RecycleBin -Force -ErrorAction SilentlyContinue")
+	a.Module, a.Tweak = "cleaner", "recycle_bin"
+	a.Size, a.Files = s.size, s.files
+	p.Add(a, func() error {
+		if err := runner.Command(context.Background(), "powershell", "-Command",
+			"Clear-RecycleBin -Force -ErrorAction SilentlyContinue").Run(); err != nil {
+			return fmt.Errorf("failed to clear recycle bin: %w", err)
+		}
+		return nil
+	})
+}
+
 // cleanRecycleBin empties the Windows Recycle Bin using PowerShell.
 func cleanRecycleBin() (int64, int, []string) {
-	var errs []string
-
-	// Get current size before cleaning
-	sizeBefore, countBefore := scanRecycleBin()
-
-	err := cmd.Hidden("powershell", "-Command",
-		"Clear-RecycleBin -Force -ErrorAction SilentlyContinue").Run()
-	if err != nil {
-		errs = append(errs, fmt.Sprintf("recycle_bin: failed to clear recycle bin: %v", err))
-		return 0, 0, errs
-	}
+	u := scanUnit{category: "recycle_bin"}
+	p := plan.New("Empty the Recycle Bin")
+	planRecycleBin(p, scanUnitNow(context.Background(), u, nil, nil))
+	return cleanNow(p)
+}
 
-	return sizeBefore, countBefore, errs
+// planGoCache adds `go clean -cache` to a plan.
+func planGoCache(p *plan.Plan, s *scanned) {
+	a := plan.Command("go", "clean", "-cache")
+	a.Module, a.Tweak = "cleaner", "go_cache"
+	a.Size, a.Files = s.size, s.files
+	p.Add(a, func() error {
+		if err := runner.Command(context.Background(), "go", "clean", "-cache").Run(); err != nil {
+			return fmt.Errorf("failed to clean go cache: %w", err)
+		}
+		return nil
+	})
 }
 
 // cleanGoCache runs `go clean -cache` to clear the Go build cache.
 func cleanGoCache() (int64, int, []string) {
-	var errs []string
-
-	// Get current size before cleaning
-	sizeBefore, countBefore := scanGoCache()
+	u := scanUnit{category: "go_cache"}
+	p := plan.New("Clean the Go build cache")
+	planGoCache(p, scanUnitNow(context.Background(), u, nil, nil))
+	return cleanNow(p)
+}
 
-	err := cmd.Hidden("go", "clean", "-cache").Run()
-	if err != nil {
-		errs = append(errs, fmt.Sprintf("go_cache: failed to clean go cache: %v", err))
-		return 0, 0, errs
+// planPath adds removing the entries a scan found under a path to a plan,
+// one action of the given kind per entry. The top-level directory itself is
+// preserved. Entries that could not be read are recorded as plan warnings.
+func planPath(p *plan.Plan, s *scanned, kind plan.Kind, remove func(entry) error) {
+	p.Warnings = append(p.Warnings, s.warnings...)
+	for _, e := range s.entries {
+		p.Add(plan.Action{
+			Kind:   kind,
+			Module: "cleaner",
+			Tweak:  s.unit.category,
+			Target: e.path,
+			Size:   e.size,
+			Files:  e.files,
+		}, func() error { return remove(e) })
 	}
-
-	return sizeBefore, countBefore, errs
 }
 
-// cleanPath deletes all files and subdirectories within a given path.
-// The top-level directory itself is preserved. Permission errors are collected
-// but do not stop the operation.
-func cleanPath(path string) (int64, int, []string) {
-	var freedSpace int64
-	var deletedFiles int
-	var errs []string
-
-	info, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, 0, nil
-		}
-		errs = append(errs, fmt.Sprintf("%s: cannot access: %v", path, err))
-		return 0, 0, errs
+// deletionGuard returns the guard that checks what c removes.
+func (c *Cleaner) deletionGuard() *guard.Guard {
+	if c.guard != nil {
+		return c.guard
 	}
+	return guard.Default
+}
 
-	// If it's a single file, delete it directly
-	if !info.IsDir() {
-		size := info.Size()
-		err := os.Remove(path)
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
-			return 0, 0, errs
+// guarded wraps remove so that g checks every entry just before it is
+// removed, when a link swapped in since the scan would be caught too. An
+// entry g refuses fails with a *guard.Error, which ExecuteContext reports
+// as a skip rather than an error.
+func guarded(g *guard.Guard, remove func(entry) error) func(entry) error {
+	return func(e entry) error {
+		if err := g.Check(e.root, e.path); err != nil {
+			return err
 		}
-		return size, 1, errs
+		return remove(e)
 	}
+}
 
-	// Collect all entries in the directory
-	entries, err := os.ReadDir(path)
-	if err != nil {
-		errs = append(errs, fmt.Sprintf("%s: cannot read directory: %v", path, err))
-		return 0, 0, errs
+// deleteEntry removes an entry permanently. os.RemoveAll removes the links
+// in a directory without following them.
+func deleteEntry(e entry) error {
+	if e.dir {
+		return os.RemoveAll(e.path)
 	}
+	return os.Remove(e.path)
+}
 
-	for _, entry := range entries {
-		entryPath := filepath.Join(path, entry.Name())
+// cleanPath deletes all files and subdirectories within a given path.
+// The top-level directory itself is preserved. Permission errors are collected
+// but do not stop the operation.
+func cleanPath(path string) (int64, int, []string) {
+	p := plan.New("Clean " + path)
+	planPath(p, scanPath(context.Background(), scanUnit{path: path}, nil, nil), plan.KindDelete, guarded(guard.Default, deleteEntry))
+	return cleanNow(p)
+}
 
-		if entry.IsDir() {
-			// Calculate size before removal
-			size, count, _ := scanDirectory(entryPath)
-			err := os.RemoveAll(entryPath)
-			if err != nil {
-				errs = append(errs, fmt.Sprintf("%s: %v", entryPath, err))
-				continue
-			}
-			freedSpace += size
-			deletedFiles += count
-		} else {
-			entryInfo, err := entry.Info()
-			if err != nil {
-				errs = append(errs, fmt.Sprintf("%s: cannot get file info: %v", entryPath, err))
-				continue
-			}
-			size := entryInfo.Size()
-			err = os.Remove(entryPath)
-			if err != nil {
-				errs = append(errs, fmt.Sprintf("%s: %v", entryPath, err))
-				continue
-			}
-			freedSpace += size
-			deletedFiles++
-		}
+// cleanNow executes a single-purpose plan and returns freed bytes, deleted
+// files and errors.
+func cleanNow(p *plan.Plan) (int64, int, []string) {
+	result, err := Execute(p)
+	if err != nil {
+		return 0, 0, []string{err.Error()}
 	}
-
-	return freedSpace, deletedFiles, errs
+	return result.FreedSpace, result.DeletedFiles, result.Errors
 }
 
 // resolveGlobPaths expands a glob pattern into matching file paths.
@@ -1,11 +1,35 @@
 package cleaner
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"cleanforge/internal/cmd"
+	"cleanforge/internal/guard"
+	"cleanforge/internal/plan"
+	"cleanforge/internal/quarantine"
 )
 
+// useFakeRunner swaps the package runner for a cmd.Fake for the duration of the test.
+func useFakeRunner(t *testing.T) *cmd.Fake {
+	t.Helper()
+	fake := cmd.NewFake()
+	prev := runner
+	runner = fake
+	t.Cleanup(func() { runner = prev })
+	return fake
+}
+
 // createTempFiles is a helper that creates a temporary directory containing
 // the specified number of files, each of the given size. It returns the
 // directory path and the total size of all files created.
@@ -56,8 +80,13 @@ func createTempDirWithSubdirs(t *testing.T) (string, int64, int) {
 	return dir, 700, 5
 }
 
+// windowsPaths returns Paths for Windows with only the given environment.
+func windowsPaths(username string, env map[string]string) *Paths {
+	return &Paths{OS: "windows", Username: username, Getenv: func(key string) string { return env[key] }}
+}
+
 func TestNewCleaner(t *testing.T) {
-	c := NewCleaner("TestUser")
+	c := NewCleanerFor(windowsPaths("TestUser", nil))
 
 	if c == nil {
 		t.Fatal("NewCleaner returned nil")
@@ -102,23 +131,11 @@ func TestNewCleaner(t *testing.T) {
 	})
 
 	t.Run("UserTempPathContainsUsername", func(t *testing.T) {
+		want := `C:\Users\TestUser\AppData\Local\Temp`
 		for _, cat := range c.categories {
 			if cat.ID == "user_temp" {
-				if len(cat.Paths) == 0 {
-					t.Error("user_temp category has no paths")
-					return
-				}
-				found := false
-				for _, p := range cat.Paths {
-					if filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(p))))) == "TestUser" ||
-						// Check if the path contains TestUser anywhere
-						containsPath(p, "TestUser") {
-						found = true
-						break
-					}
-				}
-				if !found {
-					t.Errorf("user_temp path does not contain username; paths: %v", cat.Paths)
+				if len(cat.Paths) != 1 || cat.Paths[0] != want {
+					t.Errorf("user_temp paths = %v, want [%s]", cat.Paths, want)
 				}
 				return
 			}
@@ -127,32 +144,6 @@ func TestNewCleaner(t *testing.T) {
 	})
 }
 
-func containsPath(path, segment string) bool {
-	for _, part := range filepath.SplitList(path) {
-		if part == segment {
-			return true
-		}
-	}
-	// Fallback: check if the segment appears in the path string
-	return filepath.Clean(path) != "" && len(path) > 0 && findInPath(path, segment)
-}
-
-func findInPath(path, segment string) bool {
-	dir := path
-	for {
-		base := filepath.Base(dir)
-		if base == segment {
-			return true
-		}
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			break
-		}
-		dir = parent
-	}
-	return false
-}
-
 func TestScanDirectory(t *testing.T) {
 	dir := createTempFiles(t, 5, 1024)
 
@@ -505,3 +496,1467 @@ func TestResolveGlobPaths(t *testing.T) {
 		}
 	})
 }
+
+func TestScanRecycleBin(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("powershell").Returns("2048\r\n").Returns("3\r\n")
+
+	size, count := scanRecycleBin(context.Background())
+	if size != 2048 || count != 3 {
+		t.Errorf("scanRecycleBin = (%d, %d), want (2048, 3)", size, count)
+	}
+}
+
+func TestScanRecycleBinEmpty(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("powershell").Returns("\r\n")
+
+	size, count := scanRecycleBin(context.Background())
+	if size != 0 || count != 0 {
+		t.Errorf("scanRecycleBin = (%d, %d), want (0, 0)", size, count)
+	}
+}
+
+func TestCleanGoCache(t *testing.T) {
+	cacheDir := createTempFiles(t, 4, 64)
+
+	fake := useFakeRunner(t)
+	fake.On("go", "env", "GOCACHE").Returns(cacheDir + "\n")
+	fake.On("go", "clean", "-cache").Returns("")
+
+	freed, deleted, errs := cleanGoCache()
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if freed != 4*64 || deleted != 4 {
+		t.Errorf("cleanGoCache = (%d, %d), want (%d, 4)", freed, deleted, 4*64)
+	}
+	if !fake.Called("go", "clean", "-cache") {
+		t.Error("expected `go clean -cache` to be run")
+	}
+}
+
+func TestCleanGoCacheFailure(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("go", "env", "GOCACHE").Returns(t.TempDir())
+	fake.On("go", "clean", "-cache").Fails(1, "permission denied")
+
+	freed, deleted, errs := cleanGoCache()
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	if freed != 0 || deleted != 0 {
+		t.Errorf("nothing should be reported freed on failure, got (%d, %d)", freed, deleted)
+	}
+}
+
+func TestSetMinAge(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "old.log"), []byte("12345"), time.Now().Add(-48*time.Hour))
+	writeFile(t, filepath.Join(dir, "new.log"), []byte("123"), time.Now())
+	useFakeRunner(t).On("powershell").Returns("2048\r\n").Returns("3\r\n")
+
+	c := &Cleaner{categories: []CleanCategory{{ID: "logs", Paths: []string{dir}}, {ID: "recycle_bin"}}}
+	c.SetMinAge(24 * time.Hour)
+	p, err := c.Plan([]string{"logs", "recycle_bin"})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(p.Actions) != 1 || filepath.Base(p.Actions[0].Target) != "old.log" {
+		t.Errorf("actions = %v, want only old.log", p.Actions)
+	}
+	if len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0], "recycle_bin") {
+		t.Errorf("warnings = %v, want the Recycle Bin left out", p.Warnings)
+	}
+}
+
+func TestSettingsChangeDuringPlan(t *testing.T) {
+	dir := createTempFiles(t, 3, 10)
+	c := &Cleaner{categories: []CleanCategory{{ID: "logs", Paths: []string{dir}}}}
+
+	// Run with -race: the settings change while plans read them.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Plan([]string{"logs"}); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			c.SetMinAge(time.Duration(i) * time.Hour)
+			c.SetWorkers(i)
+			c.SetPathTimeout(time.Duration(i) * time.Minute)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestScanCategoriesContext(t *testing.T) {
+	a, b := createTempFiles(t, 2, 10), createTempFiles(t, 3, 10)
+	c := &Cleaner{categories: []CleanCategory{{ID: "a", Paths: []string{a}}, {ID: "b", Paths: []string{b}}}}
+
+	result, err := c.ScanCategoriesContext(context.Background(), []string{"b"}, nil)
+	if err != nil {
+		t.Fatalf("ScanCategoriesContext: %v", err)
+	}
+	if len(result.Categories) != 1 || result.Categories[0].ID != "b" || result.TotalFiles != 3 {
+		t.Errorf("result = %+v, want only category b with 3 files", result)
+	}
+}
+
+func TestDevCacheCleanedByTool(t *testing.T) {
+	fallback := t.TempDir()
+	cacheDir := filepath.Join(fallback, "cache")
+	for _, name := range []string{"a", "b", "c"} {
+		writeFile(t, filepath.Join(cacheDir, name), make([]byte, 100), time.Now())
+	}
+	writeFile(t, filepath.Join(fallback, "selfcheck.json"), make([]byte, 50), time.Now())
+
+	fake := useFakeRunner(t)
+	fake.On("pip", "cache", "dir").Returns("WARNING: something\n" + cacheDir + "\n")
+	fake.On("pip", "cache", "purge").Returns("")
+
+	c := &Cleaner{categories: []CleanCategory{{ID: "pip_cache", Paths: []string{fallback}}}}
+	p, err := c.Plan([]string{"pip_cache"})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(p.Actions) != 1 || p.Actions[0].Kind != plan.KindCommand || p.Actions[0].Size != 300 {
+		t.Fatalf("actions = %v, want one pip command freeing 300 bytes", p.Actions)
+	}
+
+	result, err := Execute(p)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if result.FreedSpace != 300 || result.DeletedFiles != 3 {
+		t.Errorf("freed (%d, %d), want (300, 3)", result.FreedSpace, result.DeletedFiles)
+	}
+	if !fake.Called("pip", "cache", "purge") {
+		t.Error("expected `pip cache purge` to be run")
+	}
+	if _, err := os.Stat(filepath.Join(fallback, "selfcheck.json")); err != nil {
+		t.Error("a folder holding the tool's cache folder should be left to the tool")
+	}
+}
+
+func TestDevCacheDeletesPathsTheToolDoesNotManage(t *testing.T) {
+	cacheDir := createTempFiles(t, 3, 100)
+	berry := createTempFiles(t, 2, 50)
+
+	fake := useFakeRunner(t)
+	fake.On("yarn", "cache", "dir").Returns(cacheDir + "\n")
+	fake.On("yarn", "cache", "clean").Returns("")
+
+	c := &Cleaner{categories: []CleanCategory{{ID: "yarn_cache", Paths: []string{cacheDir, berry}}}}
+	scan, err := c.ScanCategoriesContext(context.Background(), []string{"yarn_cache"}, nil)
+	if err != nil {
+		t.Fatalf("ScanCategoriesContext: %v", err)
+	}
+	if scan.TotalSize != 400 || scan.TotalFiles != 5 {
+		t.Errorf("scanned (%d, %d), want (400, 5)", scan.TotalSize, scan.TotalFiles)
+	}
+
+	p, err := c.Plan([]string{"yarn_cache"})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(p.Actions) != 3 || p.Actions[0].Kind != plan.KindCommand || p.Actions[0].Size != 300 {
+		t.Fatalf("actions = %v, want the yarn command freeing 300 bytes and the two Berry files", p.Actions)
+	}
+	result, err := Execute(p)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if result.FreedSpace != 400 || result.DeletedFiles != 5 {
+		t.Errorf("freed (%d, %d), want (400, 5)", result.FreedSpace, result.DeletedFiles)
+	}
+	if entries, _ := os.ReadDir(berry); len(entries) != 0 {
+		t.Errorf("%d entries left in the Berry cache, want it emptied", len(entries))
+	}
+}
+
+func TestPartialDevToolReportsWhatItFreed(t *testing.T) {
+	store := createTempFiles(t, 3, 100)
+	fake := useFakeRunner(t)
+	fake.On("pnpm", "store", "path").Returns(store + "\n")
+	fake.On("pnpm", "store", "prune").Returns("")
+
+	c := &Cleaner{categories: []CleanCategory{{ID: "pnpm_store", Paths: []string{t.TempDir()}}}}
+	p, err := c.Plan([]string{"pnpm_store"})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(p.Actions) != 1 || p.Actions[0].Size != 0 {
+		t.Fatalf("actions = %v, want one pnpm command without a planned size", p.Actions)
+	}
+
+	// Stands in for the prune removing the one package no project uses
+	entries, _ := os.ReadDir(store)
+	if err := os.Remove(filepath.Join(store, entries[0].Name())); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Execute(p)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if result.FreedSpace != 100 || result.DeletedFiles != 1 {
+		t.Errorf("freed (%d, %d), want (100, 1)", result.FreedSpace, result.DeletedFiles)
+	}
+}
+
+func TestDevCacheDeletedWithoutTool(t *testing.T) {
+	dir := createTempFiles(t, 2, 100)
+	fake := useFakeRunner(t) // no stubs, so dotnet is not installed
+
+	c := &Cleaner{categories: []CleanCategory{{ID: "nuget_cache", Paths: []string{dir}}}}
+	result, err := c.Clean([]string{"nuget_cache"})
+	if err != nil {
+		t.Fatalf("Clean: %v", err)
+	}
+	if result.FreedSpace != 200 || result.DeletedFiles != 2 {
+		t.Errorf("freed (%d, %d), want (200, 2)", result.FreedSpace, result.DeletedFiles)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d entries left, want the folder emptied", len(entries))
+	}
+	if fake.Called("dotnet", "nuget") {
+		t.Error("dotnet should not be asked to clean")
+	}
+}
+
+func TestScanDockerBuildCache(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("docker", "system", "df").Returns("Images\t4\t2.5GB\nBuild Cache\t12\t1.5GB\n")
+
+	size, count := scanDockerBuildCache(context.Background())
+	if size != 3<<29 || count != 12 {
+		t.Errorf("scanDockerBuildCache = (%d, %d), want (%d, 12)", size, count, 3<<29)
+	}
+
+	useFakeRunner(t).On("docker", "system", "df").Fails(1, "Cannot connect to the Docker daemon")
+	if size, count := scanDockerBuildCache(context.Background()); size != 0 || count != 0 {
+		t.Errorf("without docker = (%d, %d), want (0, 0)", size, count)
+	}
+}
+
+func TestPlanListsEntriesWithoutDeleting(t *testing.T) {
+	dir, totalSize, totalFiles := createTempDirWithSubdirs(t)
+
+	c := &Cleaner{
+		username:   "test",
+		categories: []CleanCategory{{ID: "cat_a", Name: "Category A", Paths: []string{dir}}},
+	}
+
+	p, err := c.Plan([]string{"cat_a"})
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+
+	// 3 root files plus the subdirectory, which is removed as a whole.
+	if len(p.Actions) != 4 {
+		t.Fatalf("expected 4 actions, got %d: %v", len(p.Actions), p.Actions)
+	}
+	var size int64
+	var files int
+	for _, a := range p.Actions {
+		if a.Kind != plan.KindDelete || a.Tweak != "cat_a" {
+			t.Errorf("unexpected action %+v", a)
+		}
+		if _, err := os.Stat(a.Target); err != nil {
+			t.Errorf("%s should still exist after planning: %v", a.Target, err)
+		}
+		size += a.Size
+		files += a.Files
+	}
+	if size != totalSize || files != totalFiles {
+		t.Errorf("plan covers (%d bytes, %d files), want (%d, %d)", size, files, totalSize, totalFiles)
+	}
+
+	result, err := Execute(p)
+	if err != nil {
+		t.Fatalf("execute returned error: %v", err)
+	}
+	if result.FreedSpace != totalSize || result.DeletedFiles != totalFiles {
+		t.Errorf("freed (%d, %d), want (%d, %d)", result.FreedSpace, result.DeletedFiles, totalSize, totalFiles)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("directory should be empty after executing the plan, has %d entries", len(entries))
+	}
+}
+
+func TestScanContextReportsProgress(t *testing.T) {
+	c := &Cleaner{
+		username: "test",
+		categories: []CleanCategory{
+			{ID: "cat_a", Name: "Category A", Paths: []string{createTempFiles(t, 2, 100)}},
+			{ID: "cat_b", Name: "Category B", Paths: []string{createTempFiles(t, 3, 100)}},
+		},
+	}
+
+	var events []CleanProgress
+	result, err := c.ScanContext(context.Background(), func(p CleanProgress) {
+		events = append(events, p)
+	})
+	if err != nil {
+		t.Fatalf("ScanContext returned error: %v", err)
+	}
+	if result.TotalFiles != 5 {
+		t.Errorf("expected 5 files, got %d", result.TotalFiles)
+	}
+	if len(events) == 0 {
+		t.Fatal("no progress reported")
+	}
+	if events[0].Phase != "scan" || events[0].Category != "cat_a" || events[0].Total != 2 {
+		t.Errorf("first event = %+v, want scan of cat_a out of 2", events[0])
+	}
+	last := events[len(events)-1]
+	if last.Current != 2 || last.Percentage != 100 {
+		t.Errorf("last event = %+v, want 2/2 at 100%%", last)
+	}
+}
+
+func TestScanContextCancelled(t *testing.T) {
+	c := &Cleaner{
+		username: "test",
+		categories: []CleanCategory{
+			{ID: "cat_a", Name: "Category A", Paths: []string{createTempFiles(t, 2, 100)}},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.ScanContext(ctx, nil); err != context.Canceled {
+		t.Errorf("ScanContext error = %v, want context.Canceled", err)
+	}
+}
+
+func TestCleanContextStopsWhenCancelled(t *testing.T) {
+	dir := createTempFiles(t, 4, 100)
+	c := &Cleaner{
+		username: "test",
+		categories: []CleanCategory{
+			{ID: "cat_a", Name: "Category A", Paths: []string{dir}},
+		},
+	}
+	prev := progressInterval
+	progressInterval = 0
+	t.Cleanup(func() { progressInterval = prev })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cleaned []CleanProgress
+	result, err := c.CleanContext(ctx, []string{"cat_a"}, func(p CleanProgress) {
+		if p.Phase != "clean" {
+			return
+		}
+		cleaned = append(cleaned, p)
+		if p.Current == 2 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("CleanContext error = %v, want context.Canceled", err)
+	}
+	if result == nil || result.DeletedFiles != 2 || result.FreedSpace != 200 {
+		t.Fatalf("result = %+v, want the 2 files deleted before cancelling", result)
+	}
+	if len(cleaned) != 2 || cleaned[0].Total != 4 || cleaned[0].Category != "cat_a" || cleaned[0].CurrentFile == "" {
+		t.Errorf("clean progress = %+v", cleaned)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("%d files left, want 2", len(entries))
+	}
+}
+
+func TestCleanReusesScan(t *testing.T) {
+	dir := createTempFiles(t, 2, 100)
+	c := &Cleaner{
+		username:   "test",
+		categories: []CleanCategory{{ID: "cat_a", Name: "Category A", Paths: []string{dir}}},
+	}
+
+	if _, err := c.Scan(); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	// A file created after the scan is not in the plan built from it.
+	late := filepath.Join(dir, "late.tmp")
+	if err := os.WriteFile(late, make([]byte, 50), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.Clean([]string{"cat_a"})
+	if err != nil {
+		t.Fatalf("Clean returned error: %v", err)
+	}
+	if result.DeletedFiles != 2 || result.FreedSpace != 200 {
+		t.Errorf("freed (%d, %d), want the 2 scanned files", result.FreedSpace, result.DeletedFiles)
+	}
+	if _, err := os.Stat(late); err != nil {
+		t.Errorf("file created after the scan should be kept: %v", err)
+	}
+
+	// The scan is used once; the next plan walks the directory again.
+	p, err := c.Plan([]string{"cat_a"})
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+	if len(p.Actions) != 1 || p.Actions[0].Target != late {
+		t.Errorf("second plan = %v, want only %s", p.Actions, late)
+	}
+}
+
+func TestScanPathTimeout(t *testing.T) {
+	dir, _, _ := createTempDirWithSubdirs(t)
+	c := &Cleaner{
+		username:   "test",
+		categories: []CleanCategory{{ID: "cat_a", Name: "Category A", Paths: []string{dir}}},
+	}
+	c.SetPathTimeout(time.Nanosecond)
+
+	p, err := c.Plan([]string{"cat_a"})
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+	if len(p.Warnings) != 1 || !strings.Contains(p.Warnings[0], "scan incomplete") {
+		t.Errorf("warnings = %v, want the timed out path reported", p.Warnings)
+	}
+	for _, a := range p.Actions {
+		if filepath.Base(a.Target) == "subdir" {
+			t.Errorf("partly scanned subdirectory should not be planned: %v", a)
+		}
+	}
+}
+
+func TestForEachLimitsWorkers(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	seen := make([]bool, 20)
+
+	forEach(context.Background(), 3, len(seen), func(i int) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		seen[i] = true
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	if peak > 3 {
+		t.Errorf("%d calls ran at once, want at most 3", peak)
+	}
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("index %d was not visited", i)
+		}
+	}
+}
+
+// writeConfig writes a category config file and returns its path.
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "categories.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"game_a/Library", "game_b/Library", "game_b/Assets"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("WORKSPACE", root)
+	path := writeConfig(t, `{"categories": [{
+		"id": "unity_library",
+		"name": "Unity Library",
+		"risk": "low",
+		"paths": ["$WORKSPACE/bazel-out"],
+		"globs": ["$WORKSPACE/*/Library"],
+		"maxAge": "14d",
+		"exclude": ["*.keep"]
+	}]}`)
+
+	c := NewCleaner("TestUser")
+	builtins := len(c.categories)
+	if err := c.LoadConfig(path); err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(c.categories) != builtins+1 {
+		t.Fatalf("expected %d categories, got %d", builtins+1, len(c.categories))
+	}
+
+	cat := c.categories[builtins]
+	if cat.ID != "unity_library" || cat.Icon != "folder" || cat.MaxAge != 14*24*time.Hour {
+		t.Errorf("unexpected category %+v", cat)
+	}
+	want := []string{
+		filepath.Join(root, "bazel-out"),
+		filepath.Join(root, "game_a", "Library"),
+		filepath.Join(root, "game_b", "Library"),
+	}
+	if strings.Join(cat.Paths, "|") != strings.Join(want, "|") {
+		t.Errorf("paths = %v, want %v", cat.Paths, want)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	c := NewCleaner("TestUser")
+	builtins := len(c.categories)
+	if err := c.LoadConfig(filepath.Join(t.TempDir(), "categories.json")); err != nil {
+		t.Fatalf("missing config should not be an error: %v", err)
+	}
+	if len(c.categories) != builtins {
+		t.Errorf("categories changed without a config file")
+	}
+}
+
+func TestLoadConfigRejectsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, `{"categories": [
+		{"id": "ok_one", "name": "Fine", "risk": "low", "paths": ["`+filepath.ToSlash(dir)+`"]},
+		{"id": "npm_cache", "name": "Clash", "risk": "low", "paths": ["`+filepath.ToSlash(dir)+`"]},
+		{"id": "Bad-ID", "risk": "extreme", "paths": ["relative/dir", "/"], "maxAge": "soon", "exclude": ["[x"]},
+		{"id": "dupes", "name": "Dupes", "risk": "medium", "type": "twins", "paths": ["`+filepath.ToSlash(dir)+`"]}
+	]}`)
+
+	c := NewCleaner("TestUser")
+	builtins := len(c.categories)
+	err := c.LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected an error for an invalid config")
+	}
+	for _, want := range []string{
+		"id is already used",
+		"id must be lowercase",
+		"name is required",
+		"risk must be",
+		"must be absolute",
+		"filesystem root",
+		`maxAge "soon"`,
+		`exclude pattern "[x"`,
+		`type must be empty or "duplicates"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+	if len(c.categories) != builtins {
+		t.Errorf("an invalid config should add no categories, got %d extra", len(c.categories)-builtins)
+	}
+
+	if err := c.LoadConfig(writeConfig(t, `{"categories": [], "extra": true}`)); err == nil {
+		t.Error("unknown fields should be rejected")
+	}
+}
+
+func TestPlanAppliesMaxAgeAndExclude(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	files := map[string]bool{ // path relative to dir -> expected to be cleaned
+		"old.log":           true,
+		"new.log":           false,
+		"keep.keep":         false,
+		"sub/old.tmp":       true,
+		"sub/new.tmp":       false,
+		"protected/old.tmp": false,
+	}
+	for rel, clean := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, 10), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if clean || rel == "keep.keep" || rel == "protected/old.tmp" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	c := &Cleaner{
+		username: "test",
+		categories: []CleanCategory{{
+			ID:      "cat_a",
+			Name:    "Category A",
+			Paths:   []string{dir},
+			MaxAge:  7 * 24 * time.Hour,
+			Exclude: []string{"*.keep", "protected"},
+		}},
+	}
+	p, err := c.Plan([]string{"cat_a"})
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+
+	planned := make(map[string]bool)
+	for _, a := range p.Actions {
+		rel, _ := filepath.Rel(dir, a.Target)
+		planned[filepath.ToSlash(rel)] = true
+	}
+	for rel, clean := range files {
+		if planned[rel] != clean {
+			t.Errorf("%s: planned = %v, want %v", rel, planned[rel], clean)
+		}
+	}
+
+	if _, err := Execute(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "new.tmp")); err != nil {
+		t.Errorf("directory holding a recent file should be kept: %v", err)
+	}
+}
+
+func TestScanReportsFilteredFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	write := func(name string, size int, modified time.Time) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("big.log", 5000, old)          // cleaned
+	write("small.log", 10, old)          // below MinSize
+	write("huge.log", 90000, old)        // above MaxSize
+	write("big.dat", 5000, old)          // not included
+	write("fresh.log", 5000, time.Now()) // modified within SkipRecent
+
+	c := &Cleaner{
+		username: "test",
+		categories: []CleanCategory{{
+			ID:         "cat_a",
+			Name:       "Category A",
+			Paths:      []string{dir},
+			SkipRecent: time.Hour,
+			MinSize:    1000,
+			MaxSize:    10000,
+			Include:    []string{"*.log"},
+		}},
+	}
+	result, err := c.Scan()
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	cat := result.Categories[0]
+	if cat.Size != 5000 || cat.FileCount != 1 {
+		t.Errorf("to clean = (%d bytes, %d files), want only big.log", cat.Size, cat.FileCount)
+	}
+	if cat.KeptSize != 10+90000+5000+5000 || cat.KeptFiles != 4 {
+		t.Errorf("kept = (%d bytes, %d files), want the other 4 files", cat.KeptSize, cat.KeptFiles)
+	}
+}
+
+func TestPathsFollowEnvironment(t *testing.T) {
+	categoryPaths := func(c *Cleaner) map[string][]string {
+		paths := make(map[string][]string)
+		for _, cat := range c.categories {
+			paths[cat.ID] = cat.Paths
+		}
+		return paths
+	}
+
+	t.Run("Windows", func(t *testing.T) {
+		c := NewCleanerFor(windowsPaths("TestUser", map[string]string{
+			"USERPROFILE":  `E:\Profiles\tuser`,
+			"LOCALAPPDATA": `E:\Profiles\tuser\Local`,
+			"APPDATA":      `\\server\roaming\tuser`,
+			"TEMP":         `E:\Temp`,
+			"SystemRoot":   `D:\WINNT`,
+		}))
+		paths := categoryPaths(c)
+		want := map[string]string{
+			"windows_temp": `D:\WINNT\Temp`,
+			"user_temp":    `E:\Temp`,
+			"pip_cache":    `E:\Profiles\tuser\Local\pip\Cache`,
+			"npm_cache":    `\\server\roaming\tuser\npm-cache`,
+			"maven_cache":  `E:\Profiles\tuser\.m2\repository`,
+			"prefetch":     `D:\WINNT\Prefetch`,
+		}
+		for id, p := range want {
+			if len(paths[id]) == 0 || paths[id][0] != p {
+				t.Errorf("%s paths = %v, want %s first", id, paths[id], p)
+			}
+		}
+	})
+
+	t.Run("Linux", func(t *testing.T) {
+		env := map[string]string{"HOME": "/home/builder", "XDG_CACHE_HOME": "/var/cache/builder"}
+		c := NewCleanerFor(&Paths{OS: "linux", Getenv: func(key string) string { return env[key] }})
+		paths := categoryPaths(c)
+		for id := range windowsOnly {
+			if _, ok := paths[id]; ok {
+				t.Errorf("Windows-only category %s offered on Linux", id)
+			}
+		}
+		want := map[string]string{
+			"npm_cache":    "/home/builder/.npm",
+			"maven_cache":  "/home/builder/.m2/repository",
+			"gradle_cache": "/home/builder/.gradle/caches",
+			"pip_cache":    "/var/cache/builder/pip",
+		}
+		for id, p := range want {
+			if len(paths[id]) == 0 || paths[id][0] != p {
+				t.Errorf("%s paths = %v, want %s first", id, paths[id], p)
+			}
+		}
+		if _, ok := paths["go_cache"]; !ok {
+			t.Error("go_cache missing on Linux")
+		}
+	})
+
+	t.Run("Fallbacks", func(t *testing.T) {
+		p := windowsPaths("TestUser", map[string]string{"SystemDrive": "D:"})
+		if got := p.Home(); got != `D:\Users\TestUser` {
+			t.Errorf("Home() = %q, want D:\\Users\\TestUser", got)
+		}
+		if got := p.SystemRoot(); got != `D:\Windows` {
+			t.Errorf("SystemRoot() = %q, want D:\\Windows", got)
+		}
+		l := &Paths{OS: "linux", Username: "builder", Getenv: func(string) string { return "" }}
+		if got := l.Cache(); got != "/home/builder/.cache" {
+			t.Errorf("Cache() = %q, want /home/builder/.cache", got)
+		}
+	})
+
+	t.Run("NativeWindowsFallbacks", func(t *testing.T) {
+		if runtime.GOOS != "windows" {
+			t.Skip("joins with filepath only when resolving for the running system")
+		}
+		p := &Paths{OS: runtime.GOOS, Username: "TestUser", Getenv: func(key string) string {
+			return map[string]string{"SystemDrive": "D:"}[key]
+		}}
+		if got := p.Home(); got != `D:\Users\TestUser` {
+			t.Errorf("Home() = %q, want D:\\Users\\TestUser", got)
+		}
+		if got := p.SystemRoot(); got != `D:\Windows` {
+			t.Errorf("SystemRoot() = %q, want D:\\Windows", got)
+		}
+	})
+}
+
+func TestDiscoverBrowsers(t *testing.T) {
+	root := t.TempDir()
+	env := map[string]string{
+		"HOME":            root,
+		"XDG_CACHE_HOME":  filepath.Join(root, "cache"),
+		"XDG_CONFIG_HOME": filepath.Join(root, "config"),
+	}
+	p := &Paths{OS: "linux", Getenv: func(key string) string { return env[key] }}
+
+	now := time.Now()
+	chrome := filepath.Join(root, "cache", "google-chrome")
+	writeFile(t, filepath.Join(chrome, "Profile 1", "Code Cache", "js"), []byte("aaaa"), now)
+	writeFile(t, filepath.Join(chrome, "Default", "Cache", "data_0"), []byte("bb"), now)
+	writeFile(t, filepath.Join(chrome, "ShaderCache", "index"), []byte("c"), now)
+	writeFile(t, filepath.Join(root, "config", "google-chrome", "Local State"),
+		[]byte(`{"profile": {"info_cache": {"Default": {"name": "Person 1"}, "Profile 1": {"name": "Work"}}}}`), now)
+	writeFile(t, filepath.Join(root, "cache", "mozilla", "firefox", "x1y2.default-release", "cache2", "entries"), []byte("ddd"), now)
+
+	browsers := DiscoverBrowsers(p)
+	if len(browsers) != 2 || browsers[0].ID != "chrome" || browsers[1].ID != "firefox" {
+		t.Fatalf("DiscoverBrowsers = %+v, want chrome and firefox", browsers)
+	}
+	profiles := browsers[0].Profiles
+	if len(profiles) != 2 || profiles[0].Dir != "Default" || profiles[0].Name != "Person 1" || profiles[1].Name != "Work" {
+		t.Errorf("chrome profiles = %+v, want Default (Person 1) then Profile 1 (Work)", profiles)
+	}
+	if ff := browsers[1].Profiles; len(ff) != 1 || ff[0].Name != "default-release" {
+		t.Errorf("firefox profiles = %+v, want default-release", ff)
+	}
+
+	useFakeRunner(t) // no developer tools are installed
+	c := NewCleanerFor(p)
+	result, err := c.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	for _, cat := range result.Categories {
+		if cat.ID != "browser_cache_chrome" {
+			continue
+		}
+		if cat.Size != 6 || len(cat.Items) != 2 || cat.Items[0].Size != 2 || cat.Items[1].Size != 4 {
+			t.Errorf("chrome = %d bytes in items %+v, want 6 split 2 and 4", cat.Size, cat.Items)
+		}
+		return
+	}
+	t.Error("browser_cache_chrome not scanned")
+}
+
+func TestSkipRunningBrowsers(t *testing.T) {
+	dir := createTempFiles(t, 2, 100)
+	prev := runningProcesses
+	runningProcesses = func() (map[string]bool, error) { return map[string]bool{"chrome": true}, nil }
+	t.Cleanup(func() { runningProcesses = prev })
+
+	c := &Cleaner{categories: []CleanCategory{{ID: "browser_cache_chrome", Paths: []string{dir}, Processes: []string{"chrome"}}}}
+	result, err := c.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if cat := result.Categories[0]; cat.Size != 200 || cat.Busy != "" {
+		t.Errorf("without SetSkipRunning = (%d bytes, busy %q), want 200 bytes", cat.Size, cat.Busy)
+	}
+
+	c.SetSkipRunning(true)
+	result, err = c.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if cat := result.Categories[0]; cat.Size != 0 || cat.Busy != "chrome is running" {
+		t.Errorf("while running = (%d bytes, busy %q), want it left out", cat.Size, cat.Busy)
+	}
+	p, err := c.Plan([]string{"browser_cache_chrome"})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(p.Actions) != 0 || len(p.Warnings) != 1 {
+		t.Errorf("plan = %d actions, warnings %v; want none and a warning", len(p.Actions), p.Warnings)
+	}
+}
+
+func TestTempCategoriesSkipRecentFiles(t *testing.T) {
+	c := NewCleanerFor(windowsPaths("TestUser", nil))
+	for _, cat := range c.categories {
+		if (cat.ID == "user_temp" || cat.ID == "windows_temp") && cat.SkipRecent <= 0 {
+			t.Errorf("%s should keep recently modified files", cat.ID)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"4096":  4096,
+		"512KB": 512 << 10,
+		"1.5gb": 3 << 29,
+		"2 MB":  2 << 20,
+		"10B":   10,
+	}
+	for in, want := range tests {
+		got, err := parseSize(in)
+		if err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "-1MB", "lots", "0"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) should fail", in)
+		}
+	}
+}
+
+func TestCleanIntoQuarantine(t *testing.T) {
+	dir, totalSize, totalFiles := createTempDirWithSubdirs(t)
+	store := quarantine.NewStore(t.TempDir())
+	c := &Cleaner{
+		username:   "test",
+		categories: []CleanCategory{{ID: "cat_a", Name: "Category A", Paths: []string{dir}}},
+	}
+	c.SetQuarantine(store)
+
+	p, err := c.Plan([]string{"cat_a"})
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+	for _, a := range p.Actions {
+		if a.Kind != plan.KindQuarantine {
+			t.Errorf("action %s should quarantine", a)
+		}
+	}
+	result, err := Execute(p)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if result.FreedSpace != totalSize || result.DeletedFiles != totalFiles || len(result.Errors) != 0 {
+		t.Errorf("result = %+v, want (%d, %d) freed", result, totalSize, totalFiles)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d entries left in the cleaned directory", len(entries))
+	}
+
+	runs, err := store.Runs()
+	if err != nil || len(runs) != 1 || len(runs[0].Items) != len(p.Actions) {
+		t.Fatalf("runs = %+v, %v; want one run with %d items", runs, err, len(p.Actions))
+	}
+	if err := store.RestoreRun(runs[0].ID); err != nil {
+		t.Fatalf("RestoreRun: %v", err)
+	}
+	if size, files, _ := scanDirectory(dir); size != totalSize || files != totalFiles {
+		t.Errorf("restored (%d, %d), want (%d, %d)", size, files, totalSize, totalFiles)
+	}
+}
+
+// writeFile creates a file with the given content and modification time.
+func writeFile(t *testing.T, path string, data []byte, mod time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// duplicateTree lays out two roots holding one set of three identical
+// copies, and files that only look alike: one of the same size, and two
+// large files that share their first partialHashSize bytes.
+func duplicateTree(t *testing.T) (string, string, []string) {
+	t.Helper()
+	downloads, documents := t.TempDir(), t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	same := []byte(strings.Repeat("report", 100))
+
+	copies := []string{
+		filepath.Join(documents, "report.pdf"),
+		filepath.Join(downloads, "report (1).pdf"),
+		filepath.Join(downloads, "nested", "report.pdf"),
+	}
+	for i, p := range copies {
+		writeFile(t, p, same, old.Add(time.Duration(i)*time.Hour))
+	}
+	writeFile(t, filepath.Join(downloads, "other.pdf"), []byte(strings.Repeat("other!", 100)), old)
+
+	big := make([]byte, partialHashSize+100)
+	writeFile(t, filepath.Join(downloads, "big_a.bin"), big, old)
+	big[len(big)-1] = 1
+	writeFile(t, filepath.Join(documents, "big_b.bin"), big, old)
+	writeFile(t, filepath.Join(documents, "empty_a"), nil, old)
+	writeFile(t, filepath.Join(downloads, "empty_b"), nil, old)
+	return downloads, documents, copies
+}
+
+func TestFindDuplicates(t *testing.T) {
+	downloads, documents, copies := duplicateTree(t)
+	c := &Cleaner{username: "test"}
+
+	cat, err := c.FindDuplicates([]string{downloads, documents})
+	if err != nil {
+		t.Fatalf("FindDuplicates returned error: %v", err)
+	}
+	if len(cat.Duplicates) != 1 {
+		t.Fatalf("found %d sets, want 1: %+v", len(cat.Duplicates), cat.Duplicates)
+	}
+	set := cat.Duplicates[0]
+	if len(set.Files) != 3 || set.Size != 600 || set.Wasted != 1200 {
+		t.Errorf("set = %+v, want 3 copies of 600 bytes wasting 1200", set)
+	}
+	for i, f := range set.Files {
+		if f.Path != copies[i] || f.Remove != (i > 0) {
+			t.Errorf("copy %d = %+v, want %s, removed unless oldest", i, f, copies[i])
+		}
+	}
+	if cat.Size != 1200 || cat.FileCount != 2 || cat.Risk != "medium" {
+		t.Errorf("category = %+v, want 1200 bytes in 2 files at medium risk", cat)
+	}
+
+	result, err := c.Clean([]string{DuplicatesID})
+	if err != nil || result.FreedSpace != 1200 || result.DeletedFiles != 2 {
+		t.Fatalf("Clean = %+v, %v; want 1200 bytes in 2 files", result, err)
+	}
+	if _, err := os.Stat(copies[0]); err != nil {
+		t.Errorf("oldest copy was removed: %v", err)
+	}
+	for _, p := range copies[1:] {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", p)
+		}
+	}
+}
+
+func TestPickDuplicates(t *testing.T) {
+	downloads, documents, copies := duplicateTree(t)
+	c := &Cleaner{username: "test"}
+	if _, err := c.FindDuplicates([]string{downloads, documents}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.PickDuplicates(DuplicatesID, copies); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Plan([]string{DuplicatesID}); err == nil {
+		t.Error("Plan should refuse to remove every copy")
+	}
+
+	if err := c.PickDuplicates(DuplicatesID, copies[:1]); err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.Plan([]string{DuplicatesID})
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+	if len(p.Actions) != 1 || p.Actions[0].Target != copies[0] {
+		t.Fatalf("plan = %+v, want only %s removed", p.Actions, copies[0])
+	}
+	if err := c.PickDuplicates("npm_cache", nil); err == nil {
+		t.Error("picking copies of a non-duplicate category should fail")
+	}
+}
+
+func TestDuplicateChangedAfterScanIsKept(t *testing.T) {
+	downloads, documents, copies := duplicateTree(t)
+	c := &Cleaner{username: "test"}
+	if _, err := c.FindDuplicates([]string{downloads, documents}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.Plan([]string{DuplicatesID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, copies[1], []byte("edited since"), time.Now())
+	if err := os.Remove(copies[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Execute(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DeletedFiles != 0 || len(result.Errors) != 2 {
+		t.Errorf("result = %+v, want nothing removed and 2 errors", result)
+	}
+	for _, path := range copies[1:] {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+}
+
+func TestFindDuplicatesDuringScan(t *testing.T) {
+	downloads, documents, _ := duplicateTree(t)
+	c := &Cleaner{username: "test"}
+
+	// Run with -race: the category list changes while scans read it.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := c.FindDuplicates([]string{downloads, documents}); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := c.Scan(); err != nil {
+				t.Error(err)
+			}
+			c.Categories()
+		}()
+	}
+	wg.Wait()
+
+	if cats := c.Categories(); len(cats) != 1 || cats[0].ID != DuplicatesID {
+		t.Errorf("categories = %+v, want the one duplicate category", cats)
+	}
+}
+
+func TestFindDuplicatesCancelled(t *testing.T) {
+	downloads, documents, _ := duplicateTree(t)
+	c := &Cleaner{username: "test"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.FindDuplicatesContext(ctx, []string{downloads, documents}, nil); err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestAnalyzeUsage(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-400 * 24 * time.Hour)
+	writeFile(t, filepath.Join(root, "notes.txt"), make([]byte, 10), time.Now())
+	writeFile(t, filepath.Join(root, "videos", "a.mp4"), make([]byte, 5000), old)
+	writeFile(t, filepath.Join(root, "videos", "b.MP4"), make([]byte, 3000), old)
+	writeFile(t, filepath.Join(root, "games", "x", "y", "z", "deep.pak"), make([]byte, 2000), time.Now())
+	writeFile(t, filepath.Join(root, "games", "x", "save.dat"), make([]byte, 100), time.Now())
+
+	r, err := AnalyzeUsage(context.Background(), root, UsageOptions{Top: 2, Depth: 2}, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeUsage returned error: %v", err)
+	}
+	if r.Size != 10110 || r.Files != 5 {
+		t.Errorf("report = (%d, %d), want (10110, 5)", r.Size, r.Files)
+	}
+	if len(r.LargestFiles) != 2 || r.LargestFiles[0].Size != 5000 || r.LargestFiles[1].Size != 3000 {
+		t.Errorf("largest files = %+v", r.LargestFiles)
+	}
+	if len(r.LargestFolders) != 2 || r.LargestFolders[0].Path != filepath.Join(root, "videos") ||
+		r.LargestFolders[1].Path != filepath.Join(root, "games", "x", "y", "z") {
+		t.Errorf("largest folders = %+v", r.LargestFolders)
+	}
+	if len(r.ByExtension) != 3 || r.ByExtension[0] != (UsageGroup{Name: ".mp4", Size: 8000, Files: 2}) ||
+		r.ByExtension[2] != (UsageGroup{Name: "(other)", Size: 110, Files: 2}) {
+		t.Errorf("by extension = %+v", r.ByExtension)
+	}
+	if len(r.ByAge) != 2 || r.ByAge[0].Files != 3 || r.ByAge[1] != (UsageGroup{Name: "1-2 years", Size: 8000, Files: 2}) {
+		t.Errorf("by age = %+v", r.ByAge)
+	}
+
+	games := r.Tree.Children[1]
+	if games.Name != "games" || games.Size != 2100 || len(games.Children) != 1 {
+		t.Fatalf("games node = %+v", games)
+	}
+	if x := games.Children[0]; len(x.Children) != 0 || x.OtherSize != 2100 {
+		t.Errorf("nodes below depth 2 should be summed into OtherSize, got %+v", x)
+	}
+
+	var buf strings.Builder
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"largestFiles"`) {
+		t.Errorf("JSON export is missing fields:\n%s", buf.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := AnalyzeUsage(ctx, root, UsageOptions{}, nil); err != context.Canceled {
+		t.Errorf("cancelled analysis returned %v, want context.Canceled", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{nil, ""},
+		{&os.PathError{Op: "remove", Path: "x", Err: errInUse}, ErrorInUse},
+		{&os.PathError{Op: "remove", Path: "x", Err: os.ErrNotExist}, ErrorNotFound},
+		{&os.PathError{Op: "remove", Path: "x", Err: os.ErrPermission}, ErrorAccessDenied},
+		{errors.New("disk on fire"), ErrorOther},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		err := os.Remove(filepath.Join(t.TempDir(), strings.Repeat("n", 300)))
+		if got := Classify(err); got != ErrorPathTooLong {
+			t.Errorf("Classify(%v) = %q, want %q", err, got, ErrorPathTooLong)
+		}
+	}
+}
+
+func TestLockedFilesArePending(t *testing.T) {
+	dir := t.TempDir()
+	locked := filepath.Join(dir, "locked.log")
+	writeFile(t, locked, make([]byte, 100), time.Now())
+	writeFile(t, filepath.Join(dir, "free.log"), make([]byte, 50), time.Now())
+	l := NewPendingList(filepath.Join(t.TempDir(), "pending.json"))
+
+	remove := deferLocked(l, "windows_temp", func(e entry) error {
+		if e.path == locked {
+			return &os.PathError{Op: "remove", Path: e.path, Err: errInUse}
+		}
+		return deleteEntry(e)
+	})
+	p := plan.New("Clean")
+	planPath(p, scanPath(context.Background(), scanUnit{category: "windows_temp", path: dir}, nil, nil), plan.KindDelete, remove)
+	result, err := Execute(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FreedSpace != 50 || result.Pending != 1 || result.ErrorCounts[ErrorInUse] != 1 {
+		t.Errorf("result = %+v, want 50 bytes freed and 1 pending in-use error", result)
+	}
+	if len(result.Failures) != 1 || !result.Failures[0].Pending || result.Failures[0].Category != "windows_temp" {
+		t.Errorf("failures = %+v", result.Failures)
+	}
+
+	files, err := l.Files()
+	if err != nil || len(files) != 1 || files[0].Path != locked || files[0].Size != 100 {
+		t.Fatalf("pending = %+v, %v; want %s", files, err, locked)
+	}
+	if err := l.Add(PendingFile{Path: filepath.Join(dir, "already-gone.log"), Size: 7, Files: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing holds the file any more, so the retry deletes it
+	retried, err := l.Retry()
+	if err != nil {
+		t.Fatalf("Retry returned error: %v", err)
+	}
+	if retried.FreedSpace != 107 || retried.DeletedFiles != 2 || len(retried.Errors) != 0 {
+		t.Errorf("retry = %+v, want 107 bytes in 2 files", retried)
+	}
+	if _, err := os.Stat(locked); !os.IsNotExist(err) {
+		t.Errorf("pending file was not deleted: %v", err)
+	}
+	if files, _ := l.Files(); len(files) != 0 {
+		t.Errorf("pending list should be empty, got %+v", files)
+	}
+}
+
+func TestCleanResultPerCategoryAndAuditLog(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(dirA, "a1.tmp"), make([]byte, 100), time.Now())
+	writeFile(t, filepath.Join(dirA, "a2.tmp"), make([]byte, 200), time.Now())
+	writeFile(t, filepath.Join(dirB, "b.log"), make([]byte, 50), time.Now())
+	writeFile(t, filepath.Join(dirB, "b.keep"), make([]byte, 70), time.Now())
+	logs := NewAuditLog(t.TempDir())
+
+	c := &Cleaner{
+		username: "test",
+		categories: []CleanCategory{
+			{ID: "cat_a", Name: "Category A", Paths: []string{dirA}},
+			{ID: "cat_b", Name: "Category B", Paths: []string{dirB}, Exclude: []string{"*.keep"}},
+		},
+	}
+	c.SetAudit(logs)
+	p, err := c.Plan([]string{"cat_a", "cat_b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Gone before the plan runs, so removing it fails
+	if err := os.Remove(filepath.Join(dirA, "a2.tmp")); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Execute(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Categories) != 2 {
+		t.Fatalf("categories = %+v, want cat_a and cat_b", result.Categories)
+	}
+	a, b := result.Categories[0], result.Categories[1]
+	if a.ID != "cat_a" || a.FreedSpace != 100 || a.DeletedFiles != 1 || len(a.Errors) != 1 || a.Errors[0].Class != ErrorNotFound {
+		t.Errorf("cat_a = %+v, want 100 bytes freed and one not-found error", a)
+	}
+	if b.ID != "cat_b" || b.FreedSpace != 50 || b.Skipped != 1 || len(b.Errors) != 0 {
+		t.Errorf("cat_b = %+v, want 50 bytes freed and 1 skipped", b)
+	}
+	if result.Started.IsZero() || result.Finished.Before(result.Started) || result.DurationMs < 0 {
+		t.Errorf("times = %v .. %v (%d ms)", result.Started, result.Finished, result.DurationMs)
+	}
+
+	data, err := os.ReadFile(logs.Path(p.ID))
+	if err != nil {
+		t.Fatalf("audit log not written: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("audit log has %d lines, want 3:\n%s", len(lines), data)
+	}
+	results := map[string]string{}
+	for _, line := range lines {
+		var rec AuditRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("bad audit line %q: %v", line, err)
+		}
+		if rec.Plan != p.ID || rec.Time == "" || rec.Action != plan.KindDelete {
+			t.Errorf("incomplete record %+v", rec)
+		}
+		results[filepath.Base(rec.Path)] = rec.Result + " " + string(rec.Class)
+	}
+	want := map[string]string{"a1.tmp": "deleted ", "a2.tmp": "failed not_found", "b.log": "deleted "}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("audit results = %v, want %v", results, want)
+	}
+}
+
+func TestAuditLogRecordsFilesOfDirectories(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	first := filepath.Join(dir, "a.tmp")
+	writeFile(t, first, make([]byte, 10), time.Now())
+	writeFile(t, filepath.Join(dir, "b.tmp"), make([]byte, 20), time.Now())
+	writeFile(t, filepath.Join(dir, "nested", "c.tmp"), make([]byte, 30), time.Now())
+	logs := NewAuditLog(t.TempDir())
+
+	// A removal that stops after the first file
+	files := &auditFiles{}
+	remove := files.track(func(e entry) error {
+		if err := os.Remove(first); err != nil {
+			return err
+		}
+		return errors.New("access is denied")
+	})
+	p := plan.New("Clean cache")
+	p.Add(plan.Action{Kind: plan.KindDelete, Module: "cleaner", Tweak: "cache", Target: dir, Size: 60, Files: 3},
+		func() error { return remove(entry{path: dir, dir: true}) })
+	logs.attach(p, files)
+	if _, err := Execute(p); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(logs.Path(p.ID))
+	if err != nil {
+		t.Fatalf("audit log not written: %v", err)
+	}
+	results := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var rec AuditRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("bad audit line %q: %v", line, err)
+		}
+		if rec.Entry != dir || rec.Files != 1 {
+			t.Errorf("record %+v should be one file of %s", rec, dir)
+		}
+		rel, _ := filepath.Rel(dir, rec.Path)
+		results[filepath.ToSlash(rel)] = fmt.Sprintf("%s %d", rec.Result, rec.Size)
+	}
+	want := map[string]string{"a.tmp": "deleted 10", "b.tmp": "failed 20", "nested/c.tmp": "failed 30"}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("audit results = %v, want %v", results, want)
+	}
+}
+
+// linkedTree lays out a category folder holding junk, a link to a folder
+// outside it, a folder reached through such a link and a tree with a link
+// inside, and returns the folder and the outside file that must survive.
+func linkedTree(t *testing.T) (string, string) {
+	t.Helper()
+	base := t.TempDir()
+	dir, outside := filepath.Join(base, "temp"), filepath.Join(base, "Documents")
+	kept := filepath.Join(outside, "thesis.docx")
+	writeFile(t, kept, make([]byte, 500), time.Now())
+	writeFile(t, filepath.Join(dir, "junk.tmp"), make([]byte, 10), time.Now())
+	writeFile(t, filepath.Join(dir, "tree", "junk.tmp"), make([]byte, 20), time.Now())
+	for link, target := range map[string]string{
+		filepath.Join(dir, "docs"):         outside,
+		filepath.Join(dir, "tree", "docs"): outside,
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	return dir, kept
+}
+
+func TestCleanSkipsLinksOutsideRoot(t *testing.T) {
+	for _, quarantined := range []bool{false, true} {
+		dir, kept := linkedTree(t)
+		c := &Cleaner{
+			username:   "test",
+			categories: []CleanCategory{{ID: "user_temp", Name: "User Temp Files", Paths: []string{dir}}},
+		}
+		if quarantined {
+			c.SetQuarantine(quarantine.NewStore(t.TempDir()))
+		}
+
+		result, err := c.Clean([]string{"user_temp"})
+		if err != nil {
+			t.Fatalf("Clean returned error: %v", err)
+		}
+		if len(result.Errors) != 0 || result.Categories[0].Skipped != 1 {
+			t.Errorf("quarantine %v: result = %+v, want no errors and 1 skipped", quarantined, result)
+		}
+		want := []SkippedEntry{{Path: filepath.Join(dir, "docs"), Category: "user_temp", Reason: guard.LinkOutsideRoot}}
+		for i := range result.Skips {
+			result.Skips[i].Message = ""
+		}
+		if !reflect.DeepEqual(result.Skips, want) {
+			t.Errorf("quarantine %v: skips = %+v, want %+v", quarantined, result.Skips, want)
+		}
+		if _, err := os.Stat(kept); err != nil {
+			t.Errorf("quarantine %v: the file the links led to is gone: %v", quarantined, err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 || entries[0].Name() != "docs" {
+			t.Errorf("quarantine %v: %d entries left, want only the refused link", quarantined, len(entries))
+		}
+	}
+}
+
+func TestGuardRefusesProtectedAndSwappedFolders(t *testing.T) {
+	base := t.TempDir()
+	home, app := filepath.Join(base, "users", "me"), filepath.Join(base, "app")
+	kept := filepath.Join(base, "Documents", "cache", "thesis.docx")
+	writeFile(t, filepath.Join(home, "notes.txt"), []byte("keep"), time.Now())
+	writeFile(t, kept, []byte("keep"), time.Now())
+	writeFile(t, filepath.Join(app, "cache", "thesis.docx"), []byte("junk"), time.Now())
+	c := NewCleanerFor(&Paths{OS: runtime.GOOS, Getenv: func(key string) string {
+		if key == "HOME" || key == "USERPROFILE" {
+			return home
+		}
+		return ""
+	}})
+	c.categories = []CleanCategory{
+		{ID: "users", Name: "Users", Paths: []string{filepath.Dir(home)}},
+		{ID: "app_cache", Name: "App Cache", Paths: []string{filepath.Join(app, "cache")}},
+	}
+
+	p, err := c.Plan([]string{"users", "app_cache"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Between the plan and the cleanup the app folder becomes a link
+	if err := os.RemoveAll(app); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Dir(filepath.Dir(kept)), app); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	result, err := Execute(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SkippedEntry{
+		{Path: home, Category: "users", Reason: guard.Protected},
+		{Path: filepath.Join(app, "cache", "thesis.docx"), Category: "app_cache", Reason: guard.OutsideRoot},
+	}
+	for i := range result.Skips {
+		result.Skips[i].Message = ""
+	}
+	if !reflect.DeepEqual(result.Skips, want) || len(result.Errors) != 0 {
+		t.Errorf("skips = %+v, errors = %v; want %+v", result.Skips, result.Errors, want)
+	}
+	for _, path := range []string{filepath.Join(home, "notes.txt"), kept} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+}
+
+func TestRetrySkipsGuardedEntries(t *testing.T) {
+	dir, kept := linkedTree(t)
+	l := NewPendingList(filepath.Join(t.TempDir(), "pending.json"))
+	link := filepath.Join(dir, "docs")
+	if err := l.Add(PendingFile{Path: link, Category: "user_temp", Root: guard.Root(dir), Dir: true, Size: 500, Files: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := l.Retry()
+	if err != nil {
+		t.Fatalf("Retry returned error: %v", err)
+	}
+	if len(result.Skips) != 1 || result.Skips[0].Reason != guard.LinkOutsideRoot || result.FreedSpace != 0 {
+		t.Errorf("retry = %+v, want the link skipped", result)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("the file the link led to is gone: %v", err)
+	}
+	if files, _ := l.Files(); len(files) != 0 {
+		t.Errorf("a skipped entry should leave the pending list, got %+v", files)
+	}
+}
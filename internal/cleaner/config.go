@@ -0,0 +1,284 @@
+package cleaner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configFilename is the file under ~/.cleanforge that user-defined cleanup
+// categories are read from.
+const configFilename = "categories.json"
+
+// ConfigPath returns the path of the user's category config file.
+func ConfigPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(homeDir, ".cleanforge", configFilename)
+}
+
+// config is the layout of the category config file, e.g.
+//
+//	{
+//	  "categories": [
+//	    {
+//	      "id": "unity_library",
+//	      "name": "Unity Library folders",
+//	      "risk": "low",
+//	      "globs": ["D:\\Projects\\*\\Library"],
+//	      "maxAge": "14d",
+//	      "minSize": "1MB",
+//	      "exclude": ["*.asset"]
+//	    }
+//	  ]
+//	}
+type config struct {
+	Categories []categoryConfig `json:"categories"`
+}
+
+// categoryConfig is a user-defined category as written in the config file.
+type categoryConfig struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Icon        string   `json:"icon"`
+	Risk        string   `json:"risk"`
+	Type        string   `json:"type"`       // "" or "duplicates" to search paths for duplicate files
+	Paths       []string `json:"paths"`      // directories or files, "~" and $VAR are expanded
+	Globs       []string `json:"globs"`      // expanded with filepath.Glob when loaded
+	MaxAge      string   `json:"maxAge"`     // only clean files older than this, e.g. "72h" or "14d"
+	SkipRecent  string   `json:"skipRecent"` // keep files modified within this long, e.g. "30m"
+	MinSize     string   `json:"minSize"`    // only clean files at least this big, e.g. "100MB"
+	MaxSize     string   `json:"maxSize"`    // only clean files at most this big
+	Include     []string `json:"include"`    // if set, only clean files matching these patterns
+	Exclude     []string `json:"exclude"`    // never clean entries matching these patterns
+}
+
+// validID restricts category IDs to the form the built-in ones use.
+var validID = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// LoadConfig reads user-defined categories from path and adds them after the
+// built-in ones. A missing file is not an error. If any category is invalid
+// the whole file is rejected and no category is added.
+func (c *Cleaner) LoadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read category config: %w", err)
+	}
+
+	var cfg config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return fmt.Errorf("failed to parse category config %s: %w", path, err)
+	}
+
+	taken := map[string]bool{DuplicatesID: true} // reserved for FindDuplicates
+	for _, cat := range c.categoryList() {
+		taken[cat.ID] = true
+	}
+
+	var cats []CleanCategory
+	var problems []string
+	for i, cc := range cfg.Categories {
+		cat, errs := cc.category(taken)
+		for _, e := range errs {
+			problems = append(problems, fmt.Sprintf("category %d (%q): %s", i+1, cc.ID, e))
+		}
+		taken[cc.ID] = true
+		cats = append(cats, cat)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid category config %s:\n%s", path, strings.Join(problems, "\n"))
+	}
+
+	c.mu.Lock()
+	c.categories = append(slices.Clone(c.categories), cats...)
+	c.mu.Unlock()
+	return nil
+}
+
+// category validates a configured category and converts it, listing every
+// problem found. taken holds the IDs already in use.
+func (cc categoryConfig) category(taken map[string]bool) (CleanCategory, []string) {
+	var errs []string
+	cat := CleanCategory{
+		ID:          cc.ID,
+		Name:        cc.Name,
+		Description: cc.Description,
+		Icon:        cc.Icon,
+		Risk:        cc.Risk,
+		Type:        cc.Type,
+		Paths:       []string{},
+		Include:     cc.Include,
+		Exclude:     cc.Exclude,
+	}
+
+	switch {
+	case !validID.MatchString(cc.ID):
+		errs = append(errs, "id must be lowercase letters, digits and underscores")
+	case taken[cc.ID]:
+		errs = append(errs, "id is already used")
+	}
+	if cc.Name == "" {
+		errs = append(errs, "name is required")
+	}
+	if cat.Icon == "" {
+		cat.Icon = "folder"
+	}
+	switch cc.Risk {
+	case "safe", "low", "medium":
+	default:
+		errs = append(errs, `risk must be "safe", "low" or "medium"`)
+	}
+	if cc.Type != "" && cc.Type != TypeDuplicates {
+		errs = append(errs, `type must be empty or "duplicates"`)
+	}
+
+	if len(cc.Paths) == 0 && len(cc.Globs) == 0 {
+		errs = append(errs, "at least one path or glob is required")
+	}
+	for _, p := range cc.Paths {
+		p = expandPath(p)
+		if err := checkCleanRoot(p); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		cat.Paths = append(cat.Paths, p)
+	}
+	for _, g := range cc.Globs {
+		g = expandPath(g)
+		if _, err := filepath.Match(g, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("glob %q: %v", g, err))
+			continue
+		}
+		if err := checkCleanRoot(g); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		cat.Paths = append(cat.Paths, resolveGlobPaths(g)...)
+	}
+
+	var err error
+	if cc.MaxAge != "" {
+		if cat.MaxAge, err = ParseAge(cc.MaxAge); err != nil {
+			errs = append(errs, "maxAge "+err.Error())
+		}
+	}
+	if cc.SkipRecent != "" {
+		if cat.SkipRecent, err = ParseAge(cc.SkipRecent); err != nil {
+			errs = append(errs, "skipRecent "+err.Error())
+		}
+	}
+	if cc.MinSize != "" {
+		if cat.MinSize, err = parseSize(cc.MinSize); err != nil {
+			errs = append(errs, "minSize "+err.Error())
+		}
+	}
+	if cc.MaxSize != "" {
+		if cat.MaxSize, err = parseSize(cc.MaxSize); err != nil {
+			errs = append(errs, "maxSize "+err.Error())
+		}
+	}
+	if cat.MaxSize > 0 && cat.MinSize > cat.MaxSize {
+		errs = append(errs, "minSize is larger than maxSize")
+	}
+	for _, pattern := range cc.Include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("include pattern %q: %v", pattern, err))
+		}
+	}
+	for _, pattern := range cc.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("exclude pattern %q: %v", pattern, err))
+		}
+	}
+
+	return cat, errs
+}
+
+// expandPath expands a leading "~" to the home directory and $VAR or ${VAR}
+// references to environment variables.
+func expandPath(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = home + p[1:]
+		}
+	}
+	return filepath.Clean(os.ExpandEnv(p))
+}
+
+// checkCleanRoot rejects paths that would be disastrous to empty: relative
+// paths, drive or filesystem roots, and the home directory itself.
+func checkCleanRoot(p string) error {
+	if !filepath.IsAbs(p) {
+		return fmt.Errorf("path %q must be absolute", p)
+	}
+	if filepath.Dir(p) == p {
+		return fmt.Errorf("path %q is a filesystem root", p)
+	}
+	if home, err := os.UserHomeDir(); err == nil && strings.EqualFold(filepath.Clean(home), p) {
+		return fmt.Errorf("path %q is the home directory", p)
+	}
+	return nil
+}
+
+// ParseAge parses a duration, also accepting a whole number of days such as
+// "14d".
+func ParseAge(s string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%q: invalid number of days", s)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("%q: %v", s, err)
+		}
+		age = d
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("%q must be positive", s)
+	}
+	return age, nil
+}
+
+// sizeUnits are the suffixes parseSize accepts, longest first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+}
+
+// parseSize parses a byte count such as "4096", "512KB" or "1.5GB". Units
+// are powers of 1024 and case-insensitive.
+func parseSize(s string) (int64, error) {
+	num, mult := strings.TrimSpace(s), 1.0
+	for _, u := range sizeUnits {
+		if len(num) >= len(u.suffix) && strings.EqualFold(num[len(num)-len(u.suffix):], u.suffix) {
+			num, mult = strings.TrimSpace(num[:len(num)-len(u.suffix)]), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q must be a positive size such as 512KB or 1.5GB", s)
+	}
+	return int64(n * mult), nil
+}
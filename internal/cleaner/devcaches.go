@@ -0,0 +1,357 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"cleanforge/internal/plan"
+)
+
+// DockerBuildCacheID is the category of Docker's build cache, which lives
+// inside the Docker engine and is measured and pruned through the docker CLI.
+const DockerBuildCacheID = "docker_build_cache"
+
+// devTool is the clean command of a developer tool whose cache is a built-in
+// category. When the tool is installed, the category is measured where the
+// tool says its cache is and cleaned with the command, the way go_cache is
+// cleaned with `go clean -cache`. Otherwise the category's paths are deleted
+// like any other.
+type devTool struct {
+	name  string   // executable
+	dir   []string // arguments printing the cache folder; nil to run --version and measure the category's paths
+	clean []string // arguments cleaning the cache
+
+	// partial is set when clean only removes what nothing uses any more,
+	// so what it frees is measured after it runs rather than planned.
+	partial bool
+}
+
+// devTools are the clean commands of the developer cache categories, by ID.
+var devTools = map[string]devTool{
+	"yarn_cache":     {name: "yarn", dir: []string{"cache", "dir"}, clean: []string{"cache", "clean"}},
+	"pnpm_store":     {name: "pnpm", dir: []string{"store", "path"}, clean: []string{"store", "prune"}, partial: true},
+	"pip_cache":      {name: "pip", dir: []string{"cache", "dir"}, clean: []string{"cache", "purge"}},
+	"nuget_cache":    {name: "dotnet", clean: []string{"nuget", "locals", "all", "--clear"}},
+	"composer_cache": {name: "composer", dir: []string{"config", "cache-dir"}, clean: []string{"clear-cache"}},
+	"conda_pkgs":     {name: "conda", clean: []string{"clean", "--all", "--yes"}, partial: true},
+}
+
+// devCategories returns the built-in categories of developer tool caches
+// beyond npm, Maven, Gradle and Go. The Cargo target directory is only one
+// of them when CARGO_TARGET_DIR is set.
+func devCategories(p *Paths) []CleanCategory {
+	yarn := p.join(p.Cache(), "yarn")
+	pnpm := p.join(p.Data(), "pnpm", "store")
+	nugetHTTP := p.join(p.Data(), "NuGet", "v3-cache")
+	composer := p.join(p.Cache(), "composer")
+	vscode := p.join(p.Config(), "Code")
+	jetbrains := p.join(p.Cache(), "JetBrains")
+	if p.Windows() {
+		yarn = p.join(p.LocalAppData(), "Yarn", "Cache")
+		nugetHTTP = p.join(p.LocalAppData(), "NuGet", "v3-cache")
+		composer = p.join(p.LocalAppData(), "Composer")
+	}
+	pip := p.envOr("PIP_CACHE_DIR", p.join(p.Cache(), "pip"))
+	poetry := p.envOr("POETRY_CACHE_DIR", p.join(p.Cache(), "pypoetry"))
+	if p.Windows() {
+		pip = p.envOr("PIP_CACHE_DIR", p.join(p.LocalAppData(), "pip", "Cache"))
+		poetry = p.envOr("POETRY_CACHE_DIR", p.join(p.LocalAppData(), "pypoetry", "Cache"))
+	}
+	composer = p.envOr("COMPOSER_CACHE_DIR", composer)
+	cargo := p.envOr("CARGO_HOME", p.join(p.Home(), ".cargo"))
+	nuget := p.envOr("NUGET_PACKAGES", p.join(p.Home(), ".nuget", "packages"))
+
+	// Package archives can always go; the extracted packages may be linked
+	// into environments, so only `conda clean` removes those
+	var conda []string
+	for _, dir := range p.condaPkgs() {
+		conda = append(conda, resolveGlobPaths(p.join(dir, "*.tar.bz2"))...)
+		conda = append(conda, resolveGlobPaths(p.join(dir, "*.conda"))...)
+	}
+
+	categories := []CleanCategory{
+		{
+			ID:          "yarn_cache",
+			Name:        "Yarn Cache",
+			Description: "Yarn package cache",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       []string{yarn, p.join(p.Home(), ".yarn", "berry", "cache")},
+		},
+		{
+			ID:          "pnpm_store",
+			Name:        "pnpm Store",
+			Description: "pnpm content-addressable package store",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       []string{pnpm},
+		},
+		{
+			ID:          "pip_cache",
+			Name:        "pip Cache",
+			Description: "Python pip download and wheel cache",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       []string{pip},
+		},
+		{
+			ID:          "poetry_cache",
+			Name:        "Poetry Cache",
+			Description: "Poetry package and artifact cache (virtualenvs are kept)",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       []string{p.join(poetry, "cache"), p.join(poetry, "artifacts")},
+		},
+		{
+			ID:          "cargo_registry",
+			Name:        "Cargo Registry",
+			Description: "Downloaded Rust crates and their unpacked sources",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       []string{p.join(cargo, "registry", "cache"), p.join(cargo, "registry", "src")},
+		},
+	}
+	// Without a shared CARGO_TARGET_DIR build output stays in each
+	// project's target folder, so there is nothing to offer
+	if dir := p.env("CARGO_TARGET_DIR"); dir != "" {
+		categories = append(categories, CleanCategory{
+			ID:          "cargo_target",
+			Name:        "Cargo Target Directory",
+			Description: "Rust build output in the shared CARGO_TARGET_DIR",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       []string{dir},
+		})
+	}
+	return append(categories, []CleanCategory{
+		{
+			ID:          "nuget_cache",
+			Name:        "NuGet Cache",
+			Description: "NuGet global packages and HTTP cache",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       []string{nuget, nugetHTTP},
+		},
+		{
+			ID:          DockerBuildCacheID,
+			Name:        "Docker Build Cache",
+			Description: "Docker BuildKit cache of image layers",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       []string{}, // handled via `docker builder prune`
+		},
+		{
+			ID:          "composer_cache",
+			Name:        "Composer Cache",
+			Description: "PHP Composer package cache",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       []string{composer},
+		},
+		{
+			ID:          "conda_pkgs",
+			Name:        "Conda Packages",
+			Description: "Conda package archives and unused packages",
+			Icon:        "package",
+			Risk:        "low",
+			Paths:       conda,
+		},
+		{
+			ID:          "vscode_cache",
+			Name:        "VS Code Cache",
+			Description: "Visual Studio Code caches and downloaded extension packages",
+			Icon:        "code",
+			Risk:        "safe",
+			Paths: []string{
+				p.join(vscode, "Cache"),
+				p.join(vscode, "CachedData"),
+				p.join(vscode, "Code Cache"),
+				p.join(vscode, "CachedExtensionVSIXs"),
+			},
+		},
+		{
+			ID:          "jetbrains_cache",
+			Name:        "JetBrains Caches",
+			Description: "Caches and indexes of JetBrains IDEs, rebuilt on the next start",
+			Icon:        "code",
+			Risk:        "safe",
+			Paths:       resolveGlobPaths(p.join(jetbrains, "*", "caches")),
+		},
+	}...)
+}
+
+// condaPkgs returns the folders conda keeps packages in: $CONDA_PKGS_DIRS,
+// or the pkgs folder of the usual installs under the home folder.
+func (p *Paths) condaPkgs() []string {
+	if dirs := p.env("CONDA_PKGS_DIRS"); dirs != "" {
+		return strings.Split(dirs, ",")
+	}
+	var dirs []string
+	for _, install := range []string{"miniconda3", "anaconda3", "miniforge3"} {
+		dirs = append(dirs, p.join(p.Home(), install, "pkgs"))
+	}
+	return dirs
+}
+
+// devCache returns the category with the given ID's paths and clean command,
+// and whether it has one.
+func (c *Cleaner) devCache(id string) ([]string, devTool, bool) {
+	t, ok := devTools[id]
+	if !ok {
+		return nil, devTool{}, false
+	}
+	for _, cat := range c.categoryList() {
+		if cat.ID == id {
+			return cat.Paths, t, true
+		}
+	}
+	return nil, devTool{}, false
+}
+
+// scanDevCache measures a developer cache category. If t's tool runs, the
+// folder it reports (or else all of paths) is measured into s.tool, so the
+// plan cleans it with the tool. Paths the tool does not manage, such as Yarn
+// Berry's cache next to Yarn's own, and all of paths if the tool does not
+// run, are scanned into s for deletion.
+func scanDevCache(ctx context.Context, u scanUnit, paths []string, t devTool, f *filter, pr *progress) *scanned {
+	s := &scanned{unit: u, at: time.Now()}
+	args := t.dir
+	if args == nil {
+		args = []string{"--version"}
+	}
+	out, err := runner.Command(ctx, t.name, args...).Output()
+	if err != nil {
+		s.scanPaths(ctx, paths, f, pr)
+		return s
+	}
+
+	managed, rest := paths, []string(nil)
+	if t.dir != nil {
+		if dir := lastLine(string(out)); filepath.IsAbs(dir) && checkCleanRoot(filepath.Clean(dir)) == nil {
+			dir = filepath.Clean(dir)
+			managed = []string{dir}
+			// A path holding the tool's folder is left to the tool too
+			for _, p := range paths {
+				if !within(p, dir) && !within(dir, p) {
+					rest = append(rest, p)
+				}
+			}
+		}
+	}
+	s.tool = &scanned{unit: u, at: s.at}
+	s.tool.scanPaths(ctx, managed, f, pr)
+	s.scanPaths(ctx, rest, f, pr)
+	s.size += s.tool.size
+	s.files += s.tool.files
+	s.keep(s.tool.keptSize, s.tool.keptFiles)
+	return s
+}
+
+// scanPaths scans each of paths and adds what it finds to s.
+func (s *scanned) scanPaths(ctx context.Context, paths []string, f *filter, pr *progress) {
+	for _, p := range paths {
+		ps := scanPath(ctx, scanUnit{category: s.unit.category, path: p}, f, pr)
+		for _, e := range ps.entries {
+			s.add(e)
+		}
+		s.keep(ps.keptSize, ps.keptFiles)
+		s.warnings = append(s.warnings, ps.warnings...)
+	}
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// lastLine returns the last non-empty line of out, trimmed. Tools may print
+// notices before the value asked for.
+func lastLine(out string) string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// planDevTool adds running t's clean command to a plan, freeing what the
+// scan s of the tool's folder measured. If t only cleans in part, the plan leaves the size out and
+// the result reports how much smaller the scanned entries are afterwards.
+func planDevTool(p *plan.Plan, s *scanned, t devTool) {
+	a := plan.Command(t.name, t.clean...)
+	a.Module, a.Tweak = "cleaner", s.unit.category
+	a.Size, a.Files = s.size, s.files
+
+	var freed int64
+	var files int
+	if t.partial {
+		a.Size, a.Files = 0, 0
+		p.After(func(r *plan.Result) {
+			for i, applied := range r.Applied {
+				if applied.Kind == plan.KindCommand && applied.Tweak == a.Tweak {
+					r.Applied[i].Size, r.Applied[i].Files = freed, files
+				}
+			}
+		})
+	}
+	p.Add(a, func() error {
+		if err := runner.Command(context.Background(), t.name, t.clean...).Run(); err != nil {
+			return fmt.Errorf("failed to clean %s cache: %w", t.name, err)
+		}
+		if t.partial {
+			freed, files = shrunk(s)
+		}
+		return nil
+	})
+}
+
+// shrunk returns how many bytes and files the entries of s lost since they
+// were scanned.
+func shrunk(s *scanned) (int64, int) {
+	var size int64
+	var files int
+	for _, e := range s.entries {
+		n, f, _ := walkDirectory(context.Background(), e.path, nil)
+		size += n
+		files += f
+	}
+	return max(s.size-size, 0), max(s.files-files, 0)
+}
+
+// scanDockerBuildCache reads the size and entry count of Docker's build
+// cache from `docker system df`. Without a running Docker engine it is empty.
+func scanDockerBuildCache(ctx context.Context) (int64, int) {
+	out, err := runner.Command(ctx, "docker", "system", "df", "--format", "{{.Type}}\t{{.TotalCount}}\t{{.Size}}").Output()
+	if err != nil {
+		return 0, 0
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) != 3 || fields[0] != "Build Cache" {
+			continue
+		}
+		count, _ := strconv.Atoi(fields[1])
+		size, err := parseSize(fields[2])
+		if err != nil {
+			return 0, count
+		}
+		return size, count
+	}
+	return 0, 0
+}
+
+// planDockerBuildCache adds `docker builder prune` to a plan.
+func planDockerBuildCache(p *plan.Plan, s *scanned) {
+	args := []string{"builder", "prune", "--all", "--force"}
+	a := plan.Command("docker", args...)
+	a.Module, a.Tweak = "cleaner", DockerBuildCacheID
+	a.Size, a.Files = s.size, s.files
+	p.Add(a, func() error {
+		if err := runner.Command(context.Background(), "docker", args...).Run(); err != nil {
+			return fmt.Errorf("failed to prune docker build cache: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,441 @@
+package cleaner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"cleanforge/internal/guard"
+)
+
+// TypeDuplicates marks a category whose Paths are roots searched for
+// duplicate files rather than directories to empty.
+const TypeDuplicates = "duplicates"
+
+// DuplicatesID is the ID of the category FindDuplicates searches with.
+const DuplicatesID = "duplicates"
+
+// partialHashSize is how much of each file is hashed before deciding whether
+// the whole file is worth reading.
+const partialHashSize = 16 << 10
+
+// DuplicateSet is a group of files with identical content.
+type DuplicateSet struct {
+	Hash   string          `json:"hash"`   // SHA-256 of the content
+	Size   int64           `json:"size"`   // size of each copy
+	Wasted int64           `json:"wasted"` // bytes taken by all copies but one
+	Files  []DuplicateFile `json:"files"`  // oldest first
+}
+
+// DuplicateFile is one copy in a DuplicateSet.
+type DuplicateFile struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"modTime"`
+	Remove  bool      `json:"remove"` // picked for removal; by default every copy but the oldest
+
+	root string // the searched root the copy was found under, resolved by guard.Root
+}
+
+// candidate is a file that may have a duplicate.
+type candidate struct {
+	path    string
+	root    string // as in DuplicateFile
+	size    int64
+	mod     time.Time
+	partial string
+	full    string
+}
+
+// FindDuplicates searches roots for duplicate files, replacing the roots of
+// any earlier search, and returns the "duplicates" category with the sets it
+// found. Clean or Plan it like any other category to remove the copies
+// picked with PickDuplicates.
+func (c *Cleaner) FindDuplicates(roots []string) (*CleanCategory, error) {
+	return c.FindDuplicatesContext(context.Background(), roots, nil)
+}
+
+// FindDuplicatesContext is like FindDuplicates, but reports progress to fn
+// (which may be nil) and stops with ctx's error once ctx is cancelled.
+func (c *Cleaner) FindDuplicatesContext(ctx context.Context, roots []string, fn ProgressFunc) (*CleanCategory, error) {
+	cat := CleanCategory{
+		ID:          DuplicatesID,
+		Name:        "Duplicate Files",
+		Description: "Identical copies of files in the chosen folders",
+		Icon:        "copy",
+		Risk:        "medium",
+		Type:        TypeDuplicates,
+		Paths:       []string{},
+	}
+	for _, r := range roots {
+		r = expandPath(r)
+		if err := checkCleanRoot(r); err != nil {
+			return nil, err
+		}
+		cat.Paths = append(cat.Paths, r)
+	}
+	if len(cat.Paths) == 0 {
+		return nil, errors.New("no folders to search for duplicates")
+	}
+
+	// Scans may be reading the current slice, so it is replaced rather
+	// than changed in place.
+	c.mu.Lock()
+	cats := slices.Clone(c.categories)
+	replaced := false
+	for i := range cats {
+		if cats[i].ID == DuplicatesID {
+			cats[i], replaced = cat, true
+		}
+	}
+	if !replaced {
+		cats = append(cats, cat)
+	}
+	c.categories = cats
+	c.mu.Unlock()
+
+	result, err := c.scanCategories(ctx, func(id string) bool { return id == DuplicatesID }, fn)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Categories[0], nil
+}
+
+// PickDuplicates chooses which copies a clean of the duplicate category id
+// removes, replacing the default of keeping the oldest copy in each set.
+// When the plan is made, every path must be a copy found by the scan and at
+// least one copy of every set must be kept. Pass nil to restore the default.
+// A new scan of the category also restores it.
+func (c *Cleaner) PickDuplicates(id string, remove []string) error {
+	if _, ok := c.duplicateRoots(id); !ok {
+		return fmt.Errorf("%q is not a duplicate file category", id)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.picks == nil {
+		c.picks = make(map[string][]string)
+	}
+	if remove == nil {
+		delete(c.picks, id)
+	} else {
+		c.picks[id] = remove
+	}
+	return nil
+}
+
+// duplicateRoots returns the roots of the category with the given ID and
+// whether it is a duplicate file category.
+func (c *Cleaner) duplicateRoots(id string) ([]string, bool) {
+	for _, cat := range c.categoryList() {
+		if cat.ID == id {
+			return cat.Paths, cat.Type == TypeDuplicates
+		}
+	}
+	return nil, false
+}
+
+// scanDuplicates finds the duplicate files under roots that f lets through.
+// Candidates are grouped by size, then by a hash of their first bytes, and
+// only then read in full, so files with a unique size are never opened. The
+// entries are the copies picked by default. Up to workers files are read at
+// once.
+func (c *Cleaner) scanDuplicates(ctx context.Context, u scanUnit, roots []string, f *filter, workers int, pr *progress) *scanned {
+	s := &scanned{unit: u, at: time.Now()}
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	bySize := make(map[int64][]*candidate)
+	forEach(ctx, workers, len(roots), func(i int) {
+		found, warnings := collectCandidates(ctx, roots[i], f, pr)
+		mu.Lock()
+		defer mu.Unlock()
+		s.warnings = append(s.warnings, warnings...)
+		for _, cd := range found {
+			if !seen[cd.path] {
+				seen[cd.path] = true
+				bySize[cd.size] = append(bySize[cd.size], cd)
+			}
+		}
+	})
+
+	groups := multiples(bySize)
+	groups = regroup(ctx, s, workers, groups, pr, func(cd *candidate) (string, error) {
+		var err error
+		cd.partial, err = hashFile(ctx, cd.path, partialHashSize)
+		return cd.partial, err
+	})
+	groups = regroup(ctx, s, workers, groups, pr, func(cd *candidate) (string, error) {
+		if cd.size <= partialHashSize {
+			cd.full = cd.partial
+			return cd.full, nil
+		}
+		var err error
+		cd.full, err = hashFile(ctx, cd.path, -1)
+		return cd.full, err
+	})
+	if err := ctx.Err(); err != nil {
+		s.warn("duplicate search incomplete: %v", err)
+		return s
+	}
+
+	for _, g := range groups {
+		g = withoutLinks(g)
+		if len(g) < 2 {
+			continue
+		}
+		sort.Slice(g, func(i, j int) bool {
+			if !g[i].mod.Equal(g[j].mod) {
+				return g[i].mod.Before(g[j].mod)
+			}
+			return g[i].path < g[j].path
+		})
+		set := DuplicateSet{Hash: g[0].full, Size: g[0].size, Wasted: g[0].size * int64(len(g)-1)}
+		for i, cd := range g {
+			set.Files = append(set.Files, DuplicateFile{Path: cd.path, ModTime: cd.mod, Remove: i > 0, root: cd.root})
+		}
+		s.sets = append(s.sets, set)
+	}
+	sort.Slice(s.sets, func(i, j int) bool {
+		if s.sets[i].Wasted != s.sets[j].Wasted {
+			return s.sets[i].Wasted > s.sets[j].Wasted
+		}
+		return s.sets[i].Files[0].Path < s.sets[j].Files[0].Path
+	})
+
+	for _, e := range duplicateEntries(s.sets) {
+		s.add(e)
+	}
+	return s
+}
+
+// collectCandidates lists the regular, non-empty files under root that f
+// lets through. Symbolic links are not followed.
+func collectCandidates(ctx context.Context, root string, f *filter, pr *progress) ([]*candidate, []string) {
+	var found []*candidate
+	var warnings []string
+	resolved := guard.Root(root)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if path != root || !os.IsNotExist(err) {
+				warnings = append(warnings, fmt.Sprintf("%s: cannot access: %v", path, err))
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && f != nil && f.excluded(root, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: cannot get file info: %v", path, err))
+			return nil
+		}
+		pr.file(path)
+		if info.Size() == 0 || (f != nil && !f.cleans(root, path, info)) {
+			return nil
+		}
+		found = append(found, &candidate{path: path, root: resolved, size: info.Size(), mod: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("%s: scan incomplete, remaining entries skipped: %v", root, err))
+	}
+	return found, warnings
+}
+
+// multiples returns the groups that hold more than one candidate.
+func multiples[K comparable](groups map[K][]*candidate) [][]*candidate {
+	var out [][]*candidate
+	for _, g := range groups {
+		if len(g) > 1 {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// regroup splits every group by the key hash computes for its candidates,
+// hashing concurrently, and keeps the groups that still hold more than one.
+// Candidates that cannot be read are dropped with a warning.
+func regroup(ctx context.Context, s *scanned, workers int, groups [][]*candidate, pr *progress, hash func(*candidate) (string, error)) [][]*candidate {
+	var all []*candidate
+	for _, g := range groups {
+		all = append(all, g...)
+	}
+	keys := make([]string, len(all))
+	failed := make([]error, len(all))
+	forEach(ctx, workers, len(all), func(i int) {
+		pr.file(all[i].path)
+		keys[i], failed[i] = hash(all[i])
+	})
+
+	type key struct {
+		size int64
+		hash string
+	}
+	split := make(map[key][]*candidate)
+	for i, cd := range all {
+		switch {
+		case failed[i] != nil:
+			if ctx.Err() == nil {
+				s.warn("%s: cannot read: %v", cd.path, failed[i])
+			}
+		case keys[i] != "":
+			k := key{cd.size, keys[i]}
+			split[k] = append(split[k], cd)
+		}
+	}
+	return multiples(split)
+}
+
+// hashFile returns the hex SHA-256 of the first limit bytes of the file at
+// path, or of all of it if limit is negative. Reading stops once ctx is
+// cancelled.
+func hashFile(ctx context.Context, path string, limit int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if limit >= 0 {
+		r = io.LimitReader(file, limit)
+	}
+	h := sha256.New()
+	buf := make([]byte, 256<<10)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, err := r.Read(buf)
+		h.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// withoutLinks drops candidates that are hard links to, or the same file
+// as, an earlier one in g, since removing them frees nothing.
+func withoutLinks(g []*candidate) []*candidate {
+	var out []*candidate
+	var infos []os.FileInfo
+next:
+	for _, cd := range g {
+		info, err := os.Stat(cd.path)
+		if err != nil {
+			continue
+		}
+		for _, other := range infos {
+			if os.SameFile(info, other) {
+				continue next
+			}
+		}
+		out = append(out, cd)
+		infos = append(infos, info)
+	}
+	return out
+}
+
+// duplicateEntries returns an entry for every copy marked for removal in
+// sets, each remembering the copies of its set that are kept.
+func duplicateEntries(sets []DuplicateSet) []entry {
+	var entries []entry
+	for _, set := range sets {
+		var kept []string
+		for _, df := range set.Files {
+			if !df.Remove {
+				kept = append(kept, df.Path)
+			}
+		}
+		for _, df := range set.Files {
+			if df.Remove {
+				entries = append(entries, entry{path: df.Path, root: df.root, size: set.Size, files: 1, mod: df.ModTime, kept: kept})
+			}
+		}
+	}
+	return entries
+}
+
+// pickDuplicates applies the copies picked with PickDuplicates to a scan of
+// a duplicate category, returning an error if a pick is not one of the
+// copies found or would remove every copy of a set.
+func (c *Cleaner) pickDuplicates(s *scanned) error {
+	c.mu.Lock()
+	picked, ok := c.picks[s.unit.category]
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	remove := make(map[string]bool, len(picked))
+	for _, p := range picked {
+		remove[filepath.Clean(p)] = true
+	}
+	sets := make([]DuplicateSet, len(s.sets))
+	for i, set := range s.sets {
+		set.Files = append([]DuplicateFile(nil), set.Files...)
+		kept := 0
+		for j := range set.Files {
+			set.Files[j].Remove = remove[set.Files[j].Path]
+			delete(remove, set.Files[j].Path)
+			if !set.Files[j].Remove {
+				kept++
+			}
+		}
+		if kept == 0 {
+			return fmt.Errorf("every copy of %s would be removed; keep at least one", set.Files[0].Path)
+		}
+		sets[i] = set
+	}
+	for p := range remove {
+		return fmt.Errorf("%s is not a duplicate found by the last scan", p)
+	}
+
+	s.sets, s.entries, s.size, s.files = sets, nil, 0, 0
+	for _, e := range duplicateEntries(sets) {
+		s.add(e)
+	}
+	return nil
+}
+
+// unchangedDuplicate checks, just before a copy is removed, that it still
+// has the size and modification time it was hashed with and that a copy
+// being kept is still there.
+func unchangedDuplicate(e entry) error {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != e.size || !info.ModTime().Equal(e.mod) {
+		return errors.New("changed since it was scanned; not removed")
+	}
+	for _, k := range e.kept {
+		if ki, err := os.Stat(k); err == nil && ki.Size() == e.size {
+			return nil
+		}
+	}
+	return errors.New("no kept copy remains; not removed")
+}
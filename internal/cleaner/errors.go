@@ -0,0 +1,94 @@
+package cleaner
+
+import (
+	"errors"
+	"io/fs"
+
+	"cleanforge/internal/guard"
+	"cleanforge/internal/plan"
+)
+
+// ErrorClass says why an entry could not be cleaned.
+type ErrorClass string
+
+const (
+	ErrorInUse        ErrorClass = "in_use"        // another process has the file open
+	ErrorAccessDenied ErrorClass = "access_denied" // missing permission, or a read-only file
+	ErrorNotFound     ErrorClass = "not_found"     // already gone
+	ErrorPathTooLong  ErrorClass = "path_too_long" // beyond what the file APIs accept
+	ErrorOther        ErrorClass = "other"
+)
+
+// CleanError is an entry that could not be cleaned.
+type CleanError struct {
+	Path     string     `json:"path"`
+	Category string     `json:"category"`
+	Class    ErrorClass `json:"class"`
+	Message  string     `json:"message"`
+	// Pending is set when the entry was in use and has been recorded in a
+	// PendingList to be deleted later.
+	Pending bool `json:"pending,omitempty"`
+
+	err error
+}
+
+func (e *CleanError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+func (e *CleanError) Unwrap() error {
+	return e.err
+}
+
+// SkippedEntry is an entry the deletion guard refused to remove: a protected
+// folder, or a link leading out of the folder its category cleans.
+type SkippedEntry struct {
+	Path     string       `json:"path"`
+	Category string       `json:"category"`
+	Reason   guard.Reason `json:"reason"`
+	Message  string       `json:"message"`
+}
+
+// Classify sorts an error from deleting or moving a file into an ErrorClass.
+func Classify(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return ""
+	case isInUse(err):
+		return ErrorInUse
+	case isPathTooLong(err):
+		return ErrorPathTooLong
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrorNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return ErrorAccessDenied
+	default:
+		return ErrorOther
+	}
+}
+
+// failureError turns a failed plan action into a CleanError.
+func failureError(f plan.Failure) CleanError {
+	cause := f.Cause
+	if cause == nil {
+		cause = errors.New(f.Err)
+	}
+	return CleanError{
+		Path:     f.Action.Target,
+		Category: f.Action.Tweak,
+		Class:    Classify(cause),
+		Message:  f.Err,
+		Pending:  errors.Is(cause, errPending),
+		err:      cause,
+	}
+}
+
+// guardSkip turns a plan action the deletion guard refused into a
+// SkippedEntry, reporting false for any other failure.
+func guardSkip(f plan.Failure) (SkippedEntry, bool) {
+	var ge *guard.Error
+	if !errors.As(f.Cause, &ge) {
+		return SkippedEntry{}, false
+	}
+	return SkippedEntry{Path: f.Action.Target, Category: f.Action.Tweak, Reason: ge.Reason, Message: ge.Detail}, true
+}
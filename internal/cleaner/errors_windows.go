@@ -0,0 +1,18 @@
+package cleaner
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// errInUse is what deleting a file another process has open fails with.
+var errInUse error = windows.ERROR_SHARING_VIOLATION
+
+func isInUse(err error) bool {
+	return errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+}
+
+func isPathTooLong(err error) bool {
+	return errors.Is(err, windows.ERROR_FILENAME_EXCED_RANGE)
+}
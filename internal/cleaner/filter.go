@@ -0,0 +1,99 @@
+package cleaner
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// filter narrows what is cleaned under a category's paths. Include and
+// exclude patterns are matched against an entry's name and its path relative
+// to the category path, with forward slashes. A nil filter cleans every entry.
+type filter struct {
+	minAge  time.Duration // the largest of MaxAge, SkipRecent and the cleaner's SetMinAge
+	minSize int64
+	maxSize int64
+	include []string
+	exclude []string
+	now     time.Time
+}
+
+// SetMinAge makes every category keep files modified within d, on top of its
+// own filters. Categories cleaned by a command, such as the Recycle Bin, are
+// left out while it is set, and developer caches are deleted file by file
+// instead of with their tool. Zero restores the categories' own filters.
+func (c *Cleaner) SetMinAge(d time.Duration) {
+	c.mu.Lock()
+	c.minAge = d
+	c.cache = nil
+	c.mu.Unlock()
+}
+
+// filterFor returns the filter of the category with the given ID, or nil if
+// it has none. minAge is the cleaner's SetMinAge, read by the caller.
+func (c *Cleaner) filterFor(id string, minAge time.Duration) *filter {
+	for _, cat := range c.categoryList() {
+		if cat.ID != id {
+			continue
+		}
+		if cat.MaxAge <= 0 && cat.SkipRecent <= 0 && minAge <= 0 && cat.MinSize <= 0 && cat.MaxSize <= 0 &&
+			len(cat.Include) == 0 && len(cat.Exclude) == 0 {
+			return nil
+		}
+		return &filter{
+			minAge:  max(cat.MaxAge, cat.SkipRecent, minAge),
+			minSize: cat.MinSize,
+			maxSize: cat.MaxSize,
+			include: cat.Include,
+			exclude: cat.Exclude,
+			now:     time.Now(),
+		}
+	}
+	return nil
+}
+
+// matches reports whether the entry at path under root matches any of
+// patterns.
+func matches(patterns []string, root, path string) bool {
+	name := filepath.Base(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = name
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(filepath.ToSlash(pattern), rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether the entry at path under root matches an exclude
+// pattern. An excluded directory is kept with everything in it.
+func (f *filter) excluded(root, path string) bool {
+	return matches(f.exclude, root, path)
+}
+
+// cleans reports whether the file at path under root should be cleaned.
+func (f *filter) cleans(root, path string, info fs.FileInfo) bool {
+	if f.excluded(root, path) {
+		return false
+	}
+	if len(f.include) > 0 && !matches(f.include, root, path) {
+		return false
+	}
+	if f.minAge > 0 && f.now.Sub(info.ModTime()) < f.minAge {
+		return false
+	}
+	if info.Size() < f.minSize {
+		return false
+	}
+	if f.maxSize > 0 && info.Size() > f.maxSize {
+		return false
+	}
+	return true
+}
@@ -467,12 +467,15 @@ func TestScanNestedDirectories(t *testing.T) {
 // TestCleanerNewCreatesCategories verifies that NewCleaner initializes all
 // expected category IDs.
 func TestCleanerNewCreatesCategories(t *testing.T) {
-	c := NewCleaner("testuser")
+	c := NewCleanerFor(windowsPaths("testuser", nil))
 
 	expectedIDs := []string{
 		"windows_temp", "user_temp", "recycle_bin",
 		"browser_cache_chrome", "browser_cache_edge", "browser_cache_firefox",
 		"npm_cache", "maven_cache", "gradle_cache", "go_cache",
+		"yarn_cache", "pnpm_store", "pip_cache", "poetry_cache",
+		"cargo_registry", "nuget_cache", "docker_build_cache",
+		"composer_cache", "conda_pkgs", "vscode_cache", "jetbrains_cache",
 		"windows_update", "windows_logs", "prefetch", "thumbnails",
 	}
 
@@ -490,6 +493,18 @@ func TestCleanerNewCreatesCategories(t *testing.T) {
 			t.Errorf("missing category ID: %s", id)
 		}
 	}
+
+	// The Cargo target directory is only offered when one is shared
+	c = NewCleanerFor(windowsPaths("testuser", map[string]string{"CARGO_TARGET_DIR": `D:\cargo-target`}))
+	found := false
+	for _, cat := range c.categories {
+		if cat.ID == "cargo_target" {
+			found = len(cat.Paths) == 1 && cat.Paths[0] == `D:\cargo-target`
+		}
+	}
+	if !found {
+		t.Error(`cargo_target should clean D:\cargo-target when CARGO_TARGET_DIR names it`)
+	}
 }
 
 // TestCleanEmptyCategoryIDs verifies that Clean with an empty slice is a no-op.
@@ -0,0 +1,181 @@
+package cleaner
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"cleanforge/internal/guard"
+)
+
+// Paths resolves the folders the built-in categories clean from the
+// environment, so redirected or roaming profiles and Windows installed on
+// another drive are found where they really are. Where a variable is unset
+// the usual default under the user's profile is used.
+type Paths struct {
+	OS       string                  // runtime.GOOS value to resolve for
+	Username string                  // only used when the environment names no home folder
+	Getenv   func(key string) string // looks up environment variables
+}
+
+// SystemPaths returns Paths for the running system and its environment.
+func SystemPaths(username string) *Paths {
+	return &Paths{OS: runtime.GOOS, Username: username, Getenv: os.Getenv}
+}
+
+// Windows reports whether the paths are resolved for Windows.
+func (p *Paths) Windows() bool {
+	return p.OS == "windows"
+}
+
+// Home returns the user's profile folder: %USERPROFILE% on Windows and
+// $HOME elsewhere.
+func (p *Paths) Home() string {
+	if p.Windows() {
+		if home := p.env("USERPROFILE"); home != "" {
+			return home
+		}
+		if drive, dir := p.env("HOMEDRIVE"), p.env("HOMEPATH"); drive != "" && dir != "" {
+			return p.join(drive, dir)
+		}
+		return p.join(p.systemDriveRoot(), "Users", p.Username)
+	}
+	if home := p.env("HOME"); home != "" {
+		return home
+	}
+	return p.join("/home", p.Username)
+}
+
+// LocalAppData returns %LOCALAPPDATA%. It only makes sense on Windows.
+func (p *Paths) LocalAppData() string {
+	if dir := p.env("LOCALAPPDATA"); dir != "" {
+		return dir
+	}
+	return p.join(p.Home(), "AppData", "Local")
+}
+
+// RoamingAppData returns %APPDATA%. It only makes sense on Windows.
+func (p *Paths) RoamingAppData() string {
+	if dir := p.env("APPDATA"); dir != "" {
+		return dir
+	}
+	return p.join(p.Home(), "AppData", "Roaming")
+}
+
+// SystemRoot returns the Windows folder, %SystemRoot%. It only makes sense
+// on Windows.
+func (p *Paths) SystemRoot() string {
+	for _, key := range []string{"SystemRoot", "windir"} {
+		if dir := p.env(key); dir != "" {
+			return dir
+		}
+	}
+	return p.join(p.systemDriveRoot(), "Windows")
+}
+
+// Temp returns the user's temporary folder: %TEMP% on Windows and $TMPDIR
+// or /tmp elsewhere.
+func (p *Paths) Temp() string {
+	if p.Windows() {
+		for _, key := range []string{"TEMP", "TMP"} {
+			if dir := p.env(key); dir != "" {
+				return dir
+			}
+		}
+		return p.join(p.LocalAppData(), "Temp")
+	}
+	if dir := p.env("TMPDIR"); dir != "" {
+		return dir
+	}
+	return "/tmp"
+}
+
+// Cache returns the folder applications keep caches in: %LOCALAPPDATA% on
+// Windows and $XDG_CACHE_HOME or ~/.cache elsewhere.
+func (p *Paths) Cache() string {
+	if p.Windows() {
+		return p.LocalAppData()
+	}
+	return p.envOr("XDG_CACHE_HOME", p.join(p.Home(), ".cache"))
+}
+
+// Data returns the folder applications keep their data in: %LOCALAPPDATA%
+// on Windows and $XDG_DATA_HOME or ~/.local/share elsewhere.
+func (p *Paths) Data() string {
+	if p.Windows() {
+		return p.LocalAppData()
+	}
+	return p.envOr("XDG_DATA_HOME", p.join(p.Home(), ".local", "share"))
+}
+
+// Config returns the folder applications keep settings in: %APPDATA% on
+// Windows and $XDG_CONFIG_HOME or ~/.config elsewhere.
+func (p *Paths) Config() string {
+	if p.Windows() {
+		return p.RoamingAppData()
+	}
+	return p.envOr("XDG_CONFIG_HOME", p.join(p.Home(), ".config"))
+}
+
+// envOr returns the value of key, or def if it is unset.
+func (p *Paths) envOr(key, def string) string {
+	if v := p.env(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// env looks up key, treating a missing Getenv as an empty environment.
+func (p *Paths) env(key string) string {
+	if p.Getenv == nil {
+		return ""
+	}
+	return p.Getenv(key)
+}
+
+// systemDrive returns %SystemDrive%, usually "C:".
+func (p *Paths) systemDrive() string {
+	if drive := p.env("SystemDrive"); drive != "" {
+		return drive
+	}
+	return "C:"
+}
+
+// systemDriveRoot returns the root folder of the system drive, usually
+// "C:\". Joining onto the bare drive would give a path relative to the
+// drive's current folder, such as "C:Windows".
+func (p *Paths) systemDriveRoot() string {
+	return p.systemDrive() + `\`
+}
+
+// deletionGuard returns a guard protecting the user's profile folder and,
+// on Windows, the Windows folder and System32.
+func (p *Paths) deletionGuard() *guard.Guard {
+	if !p.Windows() {
+		return guard.New([]string{p.Home()}, nil)
+	}
+	systemRoot := p.SystemRoot()
+	return guard.New([]string{p.Home(), systemRoot}, []string{p.join(systemRoot, "System32")})
+}
+
+// join joins path elements with the separator of p.OS, which need not be
+// the one of the running system.
+func (p *Paths) join(elem ...string) string {
+	if p.OS == runtime.GOOS {
+		return filepath.Join(elem...)
+	}
+	if !p.Windows() {
+		return path.Join(elem...)
+	}
+	unc := len(elem) > 0 && strings.HasPrefix(elem[0], `\\`)
+	for i, e := range elem {
+		elem[i] = strings.ReplaceAll(e, `\`, "/")
+	}
+	joined := strings.ReplaceAll(path.Join(elem...), "/", `\`)
+	if unc {
+		joined = `\` + joined
+	}
+	return joined
+}
@@ -0,0 +1,193 @@
+package cleaner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cleanforge/internal/guard"
+)
+
+// maxPendingAttempts is how many runs a file may still be in use before it
+// is dropped from the pending list.
+const maxPendingAttempts = 10
+
+// errPending marks the error of an entry that was recorded in a PendingList.
+var errPending = errors.New("will be deleted on the next run")
+
+// PendingFile is a file or directory that was in use when a cleanup tried to
+// delete it.
+type PendingFile struct {
+	Path     string `json:"path"`
+	Category string `json:"category"`
+	Root     string `json:"root,omitempty"` // the folder it was found in, see guard.Guard.Check
+	Dir      bool   `json:"dir"`
+	Size     int64  `json:"size"`
+	Files    int    `json:"files"`
+	Added    string `json:"added"` // RFC 3339
+	Attempts int    `json:"attempts"`
+	// RebootScheduled is set when Windows was also asked to delete the file
+	// at the next restart, which needs administrator rights.
+	RebootScheduled bool `json:"rebootScheduled,omitempty"`
+}
+
+// PendingList is a file of entries to delete once they are no longer in use.
+type PendingList struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewPendingList returns a list kept in the file at path. An empty path
+// means PendingPath().
+func NewPendingList(path string) *PendingList {
+	return &PendingList{path: path}
+}
+
+// DefaultPending is the list the app and the CLI retry at startup.
+var DefaultPending = NewPendingList("")
+
+// PendingPath returns ~/.cleanforge/pending.json.
+func PendingPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(homeDir, ".cleanforge", "pending.json")
+}
+
+func (l *PendingList) file() string {
+	if l.path != "" {
+		return l.path
+	}
+	return PendingPath()
+}
+
+// Files returns the entries waiting to be deleted.
+func (l *PendingList) Files() ([]PendingFile, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.load()
+}
+
+// Add records an entry, replacing any earlier record of the same path.
+func (l *PendingList) Add(f PendingFile) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	files, err := l.load()
+	if err != nil {
+		return err
+	}
+	if f.Added == "" {
+		f.Added = time.Now().Format(time.RFC3339)
+	}
+	for i := range files {
+		if files[i].Path == f.Path {
+			files[i] = f
+			return l.save(files)
+		}
+	}
+	return l.save(append(files, f))
+}
+
+// Retry tries to delete every entry again. Entries that are gone, deleted or
+// refused by the deletion guard leave the list; those still in use stay
+// until maxPendingAttempts runs have failed. What was freed is returned as a
+// CleanResult.
+func (l *PendingList) Retry() (*CleanResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	files, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CleanResult{Errors: []string{}}
+	var keep []PendingFile
+	for _, f := range files {
+		err := guarded(guard.Default, deleteEntry)(entry{path: f.Path, root: f.Root, dir: f.Dir})
+		var ge *guard.Error
+		if errors.As(err, &ge) {
+			result.Skips = append(result.Skips, SkippedEntry{Path: f.Path, Category: f.Category, Reason: ge.Reason, Message: ge.Detail})
+			continue
+		}
+		class := Classify(err)
+		switch {
+		case err == nil || class == ErrorNotFound:
+			// A file deleted at reboot counts as freed by the cleanup too
+			result.FreedSpace += f.Size
+			result.DeletedFiles += f.Files
+			continue
+		case class == ErrorInUse && f.Attempts+1 < maxPendingAttempts:
+			f.Attempts++
+			keep = append(keep, f)
+			err = fmt.Errorf("%w (%w)", err, errPending)
+		}
+		result.addError(CleanError{Path: f.Path, Category: f.Category, Class: class, Message: err.Error(), Pending: errors.Is(err, errPending), err: err})
+	}
+	return result, l.save(keep)
+}
+
+// load reads the list. Callers must hold l.mu.
+func (l *PendingList) load() ([]PendingFile, error) {
+	data, err := os.ReadFile(l.file())
+	if os.IsNotExist(err) {
+		return []PendingFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending deletes: %w", err)
+	}
+	var files []PendingFile
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse pending deletes: %w", err)
+	}
+	return files, nil
+}
+
+// save atomically replaces the list, removing the file once it is empty.
+// Callers must hold l.mu.
+func (l *PendingList) save(files []PendingFile) error {
+	path := l.file()
+	if len(files) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear pending deletes: %w", err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending deletes: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create pending deletes directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pending deletes: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace pending deletes: %w", err)
+	}
+	return nil
+}
+
+// deferLocked wraps remove so that an entry found in use is recorded in l,
+// and scheduled for deletion at the next restart where possible, instead of
+// only failing.
+func deferLocked(l *PendingList, category string, remove func(entry) error) func(entry) error {
+	return func(e entry) error {
+		err := remove(e)
+		if Classify(err) != ErrorInUse {
+			return err
+		}
+		f := PendingFile{Path: e.path, Category: category, Root: e.root, Dir: e.dir, Size: e.size, Files: e.files}
+		f.RebootScheduled = !e.dir && deleteOnReboot(e.path) == nil
+		if addErr := l.Add(f); addErr != nil {
+			return fmt.Errorf("%w; %v", err, addErr)
+		}
+		return fmt.Errorf("%w (%w)", err, errPending)
+	}
+}
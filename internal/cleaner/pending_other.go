@@ -0,0 +1,11 @@
+//go:build !windows
+
+package cleaner
+
+import "errors"
+
+// deleteOnReboot is unsupported outside Windows; pending files are only
+// retried by the next run.
+func deleteOnReboot(path string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,13 @@
+package cleaner
+
+import "golang.org/x/sys/windows"
+
+// deleteOnReboot asks Windows to delete the file at path during the next
+// restart. It needs administrator rights.
+func deleteOnReboot(path string) error {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	return windows.MoveFileEx(p, nil, windows.MOVEFILE_DELAY_UNTIL_REBOOT)
+}
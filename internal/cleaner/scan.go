@@ -0,0 +1,407 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cleanforge/internal/guard"
+)
+
+// defaultPathTimeout bounds how long a single path may be scanned before its
+// remaining entries are skipped.
+const defaultPathTimeout = 2 * time.Minute
+
+// scanReuse is how long the entries found by Scan are trusted by a following
+// Plan or Clean before the paths are walked again.
+const scanReuse = 10 * time.Minute
+
+// SetWorkers sets how many paths are scanned at once. Zero or less restores
+// the default, the number of CPUs (at least 4).
+func (c *Cleaner) SetWorkers(n int) {
+	c.mu.Lock()
+	c.workers = n
+	c.mu.Unlock()
+}
+
+// SetPathTimeout sets how long a single path may be scanned. The entries
+// found before it expires are kept and a warning is added to the plan. Zero
+// or less restores the default of two minutes.
+func (c *Cleaner) SetPathTimeout(d time.Duration) {
+	c.mu.Lock()
+	c.pathTimeout = d
+	c.mu.Unlock()
+}
+
+// workerCount and scanTimeout return the settings in effect. The caller
+// holds c.mu unless c is not shared yet.
+func (c *Cleaner) workerCount() int {
+	if c.workers > 0 {
+		return c.workers
+	}
+	return max(runtime.NumCPU(), 4)
+}
+
+func (c *Cleaner) scanTimeout() time.Duration {
+	if c.pathTimeout > 0 {
+		return c.pathTimeout
+	}
+	return defaultPathTimeout
+}
+
+// entry is a file or directory tree directly under a scanned path. Cleaning
+// removes each entry as a whole.
+type entry struct {
+	path  string
+	root  string // the folder the entry was found in, resolved by guard.Root
+	size  int64
+	files int
+	dir   bool
+
+	// mod and kept are set for duplicate copies; see unchangedDuplicate.
+	mod  time.Time
+	kept []string
+}
+
+// scanned is what scanning one unit found.
+type scanned struct {
+	unit     scanUnit
+	root     string  // the unit's folder resolved by guard.Root, the root of entries added
+	entries  []entry // top-level entries, or the path itself if it is a file
+	size     int64
+	files    int
+	warnings []string // entries that could not be read
+	at       time.Time
+
+	// keptSize and keptFiles count files a filter kept out of entries.
+	keptSize  int64
+	keptFiles int
+
+	sets []DuplicateSet // for duplicate categories, the sets found
+
+	// tool is set for developer caches whose tool runs: what the folder it
+	// manages holds, cleaned with its command. It counts towards the sizes
+	// and file counts, but not entries, which are the category's other paths.
+	tool *scanned
+}
+
+func (s *scanned) add(e entry) {
+	if e.root == "" {
+		e.root = s.root
+	}
+	s.entries = append(s.entries, e)
+	s.size += e.size
+	s.files += e.files
+}
+
+func (s *scanned) keep(size int64, files int) {
+	s.keptSize += size
+	s.keptFiles += files
+}
+
+func (s *scanned) warn(format string, args ...interface{}) {
+	s.warnings = append(s.warnings, fmt.Sprintf(format, args...))
+}
+
+// scanUnit is one independently scanned piece of a category: one of its
+// paths, or the whole category when it is measured by a command.
+type scanUnit struct {
+	category string
+	path     string // empty for categories measured by a command, duplicate and developer cache categories
+}
+
+// measuredByCommand reports whether the category with the given ID is
+// measured and cleaned by a command rather than from paths.
+func measuredByCommand(id string) bool {
+	return id == "recycle_bin" || id == "go_cache" || id == DockerBuildCacheID
+}
+
+// units lists the scan units of the categories accepted by include, in
+// category order, and how many categories they cover.
+func (c *Cleaner) units(include func(id string) bool) ([]scanUnit, int) {
+	var units []scanUnit
+	categories := 0
+	for _, cat := range c.categoryList() {
+		_, tool := devTools[cat.ID]
+		if !include(cat.ID) || (len(cat.Paths) == 0 && !measuredByCommand(cat.ID) && !tool) {
+			continue
+		}
+		categories++
+		switch {
+		case measuredByCommand(cat.ID) || cat.Type == TypeDuplicates || tool:
+			// Copies of a file can be in different roots, so a duplicate
+			// category's roots are searched together, and a developer
+			// cache may be measured where its tool says it is instead
+			units = append(units, scanUnit{category: cat.ID})
+		default:
+			for _, p := range cat.Paths {
+				units = append(units, scanUnit{category: cat.ID, path: p})
+			}
+		}
+	}
+	return units, categories
+}
+
+// scanUnits scans units concurrently on at most workerCount goroutines, each
+// unit limited to scanTimeout except for duplicate searches, which read
+// whole files and can take much longer. Units found in the cache from a recent Scan are
+// taken from it instead when reuse is set. Once every unit of a category is
+// done, a progress step is reported for it. Results are indexed like units.
+func (c *Cleaner) scanUnits(ctx context.Context, units []scanUnit, reuse bool, pr *progress) []*scanned {
+	results := make([]*scanned, len(units))
+	remaining := make(map[string]*int32)
+	for _, u := range units {
+		if remaining[u.category] == nil {
+			remaining[u.category] = new(int32)
+		}
+		*remaining[u.category]++
+	}
+
+	c.mu.Lock()
+	workers, timeout, minAge := c.workerCount(), c.scanTimeout(), c.minAge
+	c.mu.Unlock()
+
+	forEach(ctx, workers, len(units), func(i int) {
+		u := units[i]
+		s := (*scanned)(nil)
+		f := c.filterFor(u.category, minAge)
+		if reuse {
+			s = c.recall(u)
+		}
+		if s == nil {
+			pr.category(u.category)
+			if roots, ok := c.duplicateRoots(u.category); ok {
+				s = c.scanDuplicates(ctx, u, roots, f, workers, pr)
+			} else if paths, t, ok := c.devCache(u.category); ok {
+				uctx, cancel := context.WithTimeout(ctx, timeout)
+				s = scanDevCache(uctx, u, paths, t, f, pr)
+				cancel()
+			} else {
+				uctx, cancel := context.WithTimeout(ctx, timeout)
+				s = scanUnitNow(uctx, u, f, pr)
+				cancel()
+			}
+		}
+		results[i] = s
+		if atomic.AddInt32(remaining[u.category], -1) == 0 {
+			pr.step(u.category, "")
+		}
+	})
+	return results
+}
+
+// scanUnitNow measures a single unit, keeping only what f lets through.
+func scanUnitNow(ctx context.Context, u scanUnit, f *filter, pr *progress) *scanned {
+	switch u.category {
+	case "recycle_bin":
+		s := &scanned{unit: u, at: time.Now()}
+		s.size, s.files = scanRecycleBin(ctx)
+		return s
+	case "go_cache":
+		s := &scanned{unit: u, at: time.Now()}
+		s.size, s.files = scanGoCache(ctx, pr)
+		return s
+	case DockerBuildCacheID:
+		s := &scanned{unit: u, at: time.Now()}
+		s.size, s.files = scanDockerBuildCache(ctx)
+		return s
+	default:
+		return scanPath(ctx, u, f, pr)
+	}
+}
+
+// forEach calls fn for every index in [0, n) on at most workers goroutines
+// and waits for them to finish. Once ctx is cancelled no further calls start.
+func forEach(ctx context.Context, workers, n int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// scanPath lists the entries directly under a unit's path and measures each
+// one, stat'ing every file once. If ctx expires part way, the entries
+// measured so far are kept and a warning records the rest were skipped.
+// With a filter, the entries are the individual files it lets through, so
+// directories holding files it keeps are never removed as a whole. Links are
+// entries of their own and never followed.
+func scanPath(ctx context.Context, u scanUnit, f *filter, pr *progress) *scanned {
+	s := &scanned{unit: u, at: time.Now()}
+	path := u.path
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.warn("%s: cannot access: %v", path, err)
+		}
+		return s
+	}
+	s.root = guard.Root(path)
+
+	// A single file (e.g. thumbcache_*.db matched by a glob) is its own
+	// entry, found in the folder holding it
+	if !info.IsDir() {
+		s.root = guard.Root(filepath.Dir(path))
+		if f == nil || f.cleans(path, path, info) {
+			s.add(entry{path: path, size: info.Size(), files: 1})
+		} else {
+			s.keep(info.Size(), 1)
+		}
+		return s
+	}
+	if f != nil {
+		scanFiltered(ctx, s, f, pr)
+		return s
+	}
+
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		s.warn("%s: cannot read directory: %v", path, err)
+		return s
+	}
+
+	for _, de := range dirEntries {
+		entryPath := filepath.Join(path, de.Name())
+		if de.IsDir() {
+			size, files, err := walkTree(ctx, entryPath, pr)
+			if err != nil {
+				s.warn("%s: scan incomplete, remaining entries skipped: %v", path, err)
+				return s
+			}
+			s.add(entry{path: entryPath, size: size, files: files, dir: true})
+			continue
+		}
+
+		entryInfo, err := de.Info()
+		if err != nil {
+			s.warn("%s: cannot get file info: %v", entryPath, err)
+			continue
+		}
+		pr.file(entryPath)
+		s.add(entry{path: entryPath, size: entryInfo.Size(), files: 1})
+	}
+	return s
+}
+
+// scanFiltered adds every file under s's path that f lets through and counts
+// the rest as kept. Excluded directories are kept as a whole.
+func scanFiltered(ctx context.Context, s *scanned, f *filter, pr *progress) {
+	root := s.unit.path
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			s.warn("%s: cannot access: %v", path, err)
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if d.IsDir() {
+			if f.excluded(root, path) {
+				size, files, err := walkTree(ctx, path, pr)
+				s.keep(size, files)
+				if err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			s.warn("%s: cannot get file info: %v", path, err)
+			return nil
+		}
+		pr.file(path)
+		if f.cleans(root, path, info) {
+			s.add(entry{path: path, size: info.Size(), files: 1})
+		} else {
+			s.keep(info.Size(), 1)
+		}
+		return nil
+	})
+	if err != nil {
+		s.warn("%s: scan incomplete, remaining entries skipped: %v", root, err)
+	}
+}
+
+// walkTree sums the size and number of files under root. Entries that cannot
+// be read are skipped; the only error returned is ctx's.
+func walkTree(ctx context.Context, root string, pr *progress) (int64, int, error) {
+	var size int64
+	var files int
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || d.IsDir() {
+			// Skip files/dirs we can't access
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		pr.file(path)
+		size += info.Size()
+		files++
+		return nil
+	})
+	return size, files, err
+}
+
+// remember caches scan results so a Plan shortly after can reuse them.
+func (c *Cleaner) remember(results []*scanned) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache == nil {
+		c.cache = make(map[scanUnit]*scanned)
+	}
+	for _, s := range results {
+		if s != nil {
+			c.cache[s.unit] = s
+		}
+	}
+}
+
+// recall takes a unit's result out of the cache if it is recent enough. Each
+// result is used once, so a later plan sees the effect of cleaning.
+func (c *Cleaner) recall(u scanUnit) *scanned {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.cache[u]
+	if !ok {
+		return nil
+	}
+	delete(c.cache, u)
+	if time.Since(s.at) > scanReuse {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,439 @@
+package cleaner
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UsageOptions controls how much detail AnalyzeUsage keeps.
+type UsageOptions struct {
+	Top     int // largest files, folders and extensions listed; default 20
+	Depth   int // levels of the folder tree kept below the root; default 3
+	Workers int // folders under the root walked at once; default as for SetWorkers
+}
+
+// UsageReport describes what takes up the space under a root directory.
+type UsageReport struct {
+	Root    string     `json:"root"`
+	Scanned time.Time  `json:"scanned"`
+	Size    int64      `json:"size"`
+	Files   int        `json:"files"`
+	Tree    *UsageNode `json:"tree"`
+
+	LargestFiles   []UsageEntry `json:"largestFiles"`
+	LargestFolders []UsageEntry `json:"largestFolders"` // by the files directly in them, so nested folders do not crowd each other out
+	ByExtension    []UsageGroup `json:"byExtension"`    // largest first, the rest summed as "(other)"
+	ByAge          []UsageGroup `json:"byAge"`          // by last modification, newest first
+
+	Warnings []string `json:"warnings,omitempty"` // folders that could not be read
+}
+
+// UsageNode is a folder in the size tree of a UsageReport.
+type UsageNode struct {
+	Name     string       `json:"name"`
+	Path     string       `json:"path"`
+	Size     int64        `json:"size"`
+	Files    int          `json:"files"`
+	Children []*UsageNode `json:"children,omitempty"` // largest first, at most Top
+	// OtherSize is what the subfolders left out of Children and the files
+	// directly in this folder take up.
+	OtherSize int64 `json:"otherSize,omitempty"`
+}
+
+// UsageEntry is a file or folder in a top-N list.
+type UsageEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Files   int       `json:"files,omitempty"` // for folders
+	ModTime time.Time `json:"modTime,omitempty"`
+}
+
+// UsageGroup sums the files sharing an extension or age.
+type UsageGroup struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	Files int    `json:"files"`
+}
+
+// ageBuckets are the ByAge groups, each holding files last modified less
+// than max ago.
+var ageBuckets = []struct {
+	name string
+	max  time.Duration
+}{
+	{"Last 30 days", 30 * 24 * time.Hour},
+	{"1-6 months", 182 * 24 * time.Hour},
+	{"6-12 months", 365 * 24 * time.Hour},
+	{"1-2 years", 2 * 365 * 24 * time.Hour},
+	{"Older than 2 years", 1<<63 - 1},
+}
+
+// maxUsageWarnings caps the warnings in a report; the rest are only counted.
+const maxUsageWarnings = 50
+
+// WriteJSON writes the report as indented JSON.
+func (r *UsageReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// DefaultPath returns where the report is saved by default, under
+// ~/.cleanforge/reports and named after the time it was made.
+func (r *UsageReport) DefaultPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = os.Getenv("USERPROFILE")
+	}
+	name := "usage-" + r.Scanned.Format("20060102-150405") + ".json"
+	return filepath.Join(homeDir, ".cleanforge", "reports", name)
+}
+
+// SaveJSON writes the report as JSON to path, creating its directory.
+func (r *UsageReport) SaveJSON(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create usage report: %w", err)
+	}
+	if err := r.WriteJSON(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write usage report: %w", err)
+	}
+	return f.Close()
+}
+
+// AnalyzeUsage walks everything under root and reports where the space
+// goes. Folders directly under root are walked concurrently. Symbolic links
+// and junctions are not followed. It reports "scan" progress to fn (which
+// may be nil) with one step per folder under root, and stops with ctx's
+// error once ctx is cancelled.
+func AnalyzeUsage(ctx context.Context, root string, opts UsageOptions, fn ProgressFunc) (*UsageReport, error) {
+	if opts.Top <= 0 {
+		opts.Top = 20
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = 3
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = (&Cleaner{}).workerCount()
+	}
+
+	root = filepath.Clean(root)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot analyze %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("cannot analyze %s: not a directory", root)
+	}
+	dirEntries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot analyze %s: %w", root, err)
+	}
+
+	now := time.Now()
+	var subdirs []string
+	top := newUsageTally(opts, now)
+	for _, de := range dirEntries {
+		path := filepath.Join(root, de.Name())
+		switch {
+		case de.IsDir():
+			subdirs = append(subdirs, path)
+		case de.Type().IsRegular():
+			if fi, err := de.Info(); err == nil {
+				top.file(path, fi)
+			}
+		}
+	}
+
+	pr := newProgress(fn, "scan", len(subdirs))
+	pr.category(root)
+	tallies := make([]*usageTally, len(subdirs))
+	nodes := make([]*UsageNode, len(subdirs))
+	forEach(ctx, opts.Workers, len(subdirs), func(i int) {
+		tallies[i] = newUsageTally(opts, now)
+		nodes[i] = tallies[i].walk(ctx, subdirs[i], 1, pr)
+		pr.step(root, subdirs[i])
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tree := &UsageNode{Name: root, Path: root, Size: top.ownSize, Files: top.ownFiles}
+	tree.OtherSize = top.ownSize
+	for i, t := range tallies {
+		top.merge(t)
+		tree.Size += nodes[i].Size
+		tree.Files += nodes[i].Files
+		tree.Children = append(tree.Children, nodes[i])
+	}
+	tree.OtherSize += trimChildren(tree, opts.Top)
+	top.folder(root, top.ownSize, top.ownFiles)
+
+	report := &UsageReport{
+		Root:           root,
+		Scanned:        now,
+		Size:           tree.Size,
+		Files:          tree.Files,
+		Tree:           tree,
+		LargestFiles:   top.files.sorted(),
+		LargestFolders: top.folders.sorted(),
+		ByExtension:    top.extensions(opts.Top),
+		ByAge:          top.ages(),
+		Warnings:       top.warnings,
+	}
+	if top.dropped > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("… and %d more folders could not be read", top.dropped))
+	}
+	return report, nil
+}
+
+// usageTally accumulates what one goroutine of AnalyzeUsage has seen.
+type usageTally struct {
+	opts     UsageOptions
+	now      time.Time
+	files    *topEntries
+	folders  *topEntries
+	byExt    map[string]*UsageGroup
+	byAge    []UsageGroup
+	warnings []string
+	dropped  int
+
+	// ownSize and ownFiles count the files added with file since the last
+	// call to walk, i.e. those directly in the folder being walked.
+	ownSize  int64
+	ownFiles int
+}
+
+func newUsageTally(opts UsageOptions, now time.Time) *usageTally {
+	t := &usageTally{
+		opts:    opts,
+		now:     now,
+		files:   &topEntries{max: opts.Top},
+		folders: &topEntries{max: opts.Top},
+		byExt:   make(map[string]*UsageGroup),
+		byAge:   make([]UsageGroup, len(ageBuckets)),
+	}
+	for i, b := range ageBuckets {
+		t.byAge[i].Name = b.name
+	}
+	return t
+}
+
+// file counts a regular file.
+func (t *usageTally) file(path string, info os.FileInfo) {
+	size := info.Size()
+	t.ownSize += size
+	t.ownFiles++
+	t.files.add(UsageEntry{Path: path, Size: size, ModTime: info.ModTime()})
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		ext = "(none)"
+	}
+	g := t.byExt[ext]
+	if g == nil {
+		g = &UsageGroup{Name: ext}
+		t.byExt[ext] = g
+	}
+	g.Size += size
+	g.Files++
+
+	age := t.now.Sub(info.ModTime())
+	for i, b := range ageBuckets {
+		if age < b.max {
+			t.byAge[i].Size += size
+			t.byAge[i].Files++
+			break
+		}
+	}
+}
+
+// folder records the files directly in a folder for LargestFolders.
+func (t *usageTally) folder(path string, size int64, files int) {
+	if files > 0 {
+		t.folders.add(UsageEntry{Path: path, Size: size, Files: files})
+	}
+}
+
+func (t *usageTally) warn(format string, args ...interface{}) {
+	if len(t.warnings) >= maxUsageWarnings {
+		t.dropped++
+		return
+	}
+	t.warnings = append(t.warnings, fmt.Sprintf(format, args...))
+}
+
+// walk sizes the folder at path, depth levels below the root, and returns
+// its node. Below opts.Depth, nodes have no children.
+func (t *usageTally) walk(ctx context.Context, path string, depth int, pr *progress) *UsageNode {
+	node := &UsageNode{Name: filepath.Base(path), Path: path}
+	if ctx.Err() != nil {
+		return node
+	}
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		t.warn("%s: cannot read directory: %v", path, err)
+	}
+
+	t.ownSize, t.ownFiles = 0, 0
+	var subdirs []string
+	for _, de := range dirEntries {
+		p := filepath.Join(path, de.Name())
+		switch {
+		case de.IsDir():
+			subdirs = append(subdirs, p)
+		case de.Type().IsRegular():
+			info, err := de.Info()
+			if err != nil {
+				continue
+			}
+			pr.file(p)
+			t.file(p, info)
+		}
+	}
+	node.Size, node.Files = t.ownSize, t.ownFiles
+	node.OtherSize = t.ownSize
+	t.folder(path, t.ownSize, t.ownFiles)
+
+	for _, sub := range subdirs {
+		child := t.walk(ctx, sub, depth+1, pr)
+		node.Size += child.Size
+		node.Files += child.Files
+		if depth < t.opts.Depth {
+			node.Children = append(node.Children, child)
+		} else {
+			node.OtherSize += child.Size
+		}
+	}
+	node.OtherSize += trimChildren(node, t.opts.Top)
+	return node
+}
+
+// merge adds what other has seen to t.
+func (t *usageTally) merge(other *usageTally) {
+	for _, e := range other.files.entries {
+		t.files.add(e)
+	}
+	for _, e := range other.folders.entries {
+		t.folders.add(e)
+	}
+	for ext, g := range other.byExt {
+		if mine := t.byExt[ext]; mine != nil {
+			mine.Size += g.Size
+			mine.Files += g.Files
+		} else {
+			t.byExt[ext] = g
+		}
+	}
+	for i := range t.byAge {
+		t.byAge[i].Size += other.byAge[i].Size
+		t.byAge[i].Files += other.byAge[i].Files
+	}
+	for _, w := range other.warnings {
+		t.warn("%s", w)
+	}
+	t.dropped += other.dropped
+}
+
+// extensions returns the top largest extensions, and the rest as "(other)".
+func (t *usageTally) extensions(top int) []UsageGroup {
+	groups := make([]UsageGroup, 0, len(t.byExt))
+	for _, g := range t.byExt {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	if len(groups) <= top {
+		return groups
+	}
+	other := UsageGroup{Name: "(other)"}
+	for _, g := range groups[top:] {
+		other.Size += g.Size
+		other.Files += g.Files
+	}
+	return append(groups[:top], other)
+}
+
+// ages returns the age groups that hold any files.
+func (t *usageTally) ages() []UsageGroup {
+	groups := []UsageGroup{}
+	for _, g := range t.byAge {
+		if g.Files > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// trimChildren sorts n's children largest first and drops all but the top
+// ones, returning the size of those dropped.
+func trimChildren(n *UsageNode, top int) int64 {
+	sort.Slice(n.Children, func(i, j int) bool {
+		if n.Children[i].Size != n.Children[j].Size {
+			return n.Children[i].Size > n.Children[j].Size
+		}
+		return n.Children[i].Path < n.Children[j].Path
+	})
+	var dropped int64
+	if len(n.Children) > top {
+		for _, c := range n.Children[top:] {
+			dropped += c.Size
+		}
+		n.Children = n.Children[:top]
+	}
+	return dropped
+}
+
+// topEntries keeps the max largest entries added to it, in a min-heap.
+type topEntries struct {
+	max     int
+	entries []UsageEntry
+}
+
+func (h *topEntries) Len() int           { return len(h.entries) }
+func (h *topEntries) Less(i, j int) bool { return h.entries[i].Size < h.entries[j].Size }
+func (h *topEntries) Swap(i, j int)      { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+func (h *topEntries) Push(x any)         { h.entries = append(h.entries, x.(UsageEntry)) }
+func (h *topEntries) Pop() any {
+	e := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return e
+}
+
+// add offers e, keeping it if it is among the largest so far.
+func (h *topEntries) add(e UsageEntry) {
+	if len(h.entries) < h.max {
+		heap.Push(h, e)
+	} else if e.Size > h.entries[0].Size {
+		h.entries[0] = e
+		heap.Fix(h, 0)
+	}
+}
+
+// sorted returns the entries largest first.
+func (h *topEntries) sorted() []UsageEntry {
+	out := append([]UsageEntry{}, h.entries...)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Size != out[j].Size {
+			return out[i].Size > out[j].Size
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
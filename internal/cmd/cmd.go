@@ -3,17 +3,42 @@ package cmd
 import (
 	"context"
 	"os/exec"
-	"syscall"
 )
 
+// Cmd is the subset of *exec.Cmd that CleanForge modules rely on.
+// *exec.Cmd satisfies it directly.
+type Cmd interface {
+	Run() error
+	Start() error
+	Output() ([]byte, error)
+	CombinedOutput() ([]byte, error)
+}
+
+// Runner creates commands for external programs. Modules hold a Runner
+// instead of calling os/exec directly so tests can substitute a Fake that
+// replays recorded output.
+type Runner interface {
+	Command(ctx context.Context, name string, args ...string) Cmd
+}
+
+// Default is the Runner used by all modules unless replaced. It starts real
+// processes without a visible console window.
+var Default Runner = execRunner{}
+
+// execRunner runs commands through os/exec.
+type execRunner struct{}
+
+// Command returns a hidden, context-aware *exec.Cmd.
+func (execRunner) Command(ctx context.Context, name string, args ...string) Cmd {
+	return HiddenContext(ctx, name, args...)
+}
+
 // Hidden creates an exec.Cmd with the CREATE_NO_WINDOW flag set,
 // preventing a visible console window from appearing when running
 // subprocesses from a GUI application.
 func Hidden(name string, args ...string) *exec.Cmd {
 	cmd := exec.Command(name, args...)
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		CreationFlags: 0x08000000, // CREATE_NO_WINDOW
-	}
+	hideWindow(cmd)
 	return cmd
 }
 
@@ -21,8 +46,6 @@ func Hidden(name string, args ...string) *exec.Cmd {
 // The command will be killed when the context deadline is exceeded.
 func HiddenContext(ctx context.Context, name string, args ...string) *exec.Cmd {
 	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		CreationFlags: 0x08000000, // CREATE_NO_WINDOW
-	}
+	hideWindow(cmd)
 	return cmd
 }
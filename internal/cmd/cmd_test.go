@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFakeReturnsStubbedOutput(t *testing.T) {
+	f := NewFake()
+	f.On("powercfg", "/list").Returns("Power Scheme GUID: abc")
+
+	out, err := f.Command(context.Background(), "powercfg", "/list").Output()
+	if err != nil {
+		t.Fatalf("Output returned error: %v", err)
+	}
+	if string(out) != "Power Scheme GUID: abc" {
+		t.Errorf("Output = %q, want stubbed stdout", out)
+	}
+}
+
+func TestFakeFailsWithExitError(t *testing.T) {
+	f := NewFake()
+	f.On("sc", "query").Fails(1060, "service does not exist")
+
+	out, err := f.Command(context.Background(), "sc", "query", "Nope").CombinedOutput()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected *ExitError, got %T (%v)", err, err)
+	}
+	if exitErr.Code != 1060 {
+		t.Errorf("exit code = %d, want 1060", exitErr.Code)
+	}
+	if string(out) != "service does not exist" {
+		t.Errorf("CombinedOutput = %q, want stderr included", out)
+	}
+}
+
+func TestFakeUnmatchedCommandFails(t *testing.T) {
+	f := NewFake()
+
+	if err := f.Command(context.Background(), "bcdedit").Run(); err == nil {
+		t.Fatal("expected unmatched command to fail")
+	}
+	if !f.Called("bcdedit") {
+		t.Error("unmatched command should still be recorded")
+	}
+}
+
+func TestFakeMostSpecificStubWins(t *testing.T) {
+	f := NewFake()
+	f.On("netsh").Returns("generic")
+	f.On("netsh", "winsock", "reset").Returns("specific")
+
+	out, _ := f.Command(context.Background(), "netsh", "winsock", "reset").Output()
+	if string(out) != "specific" {
+		t.Errorf("Output = %q, want %q", out, "specific")
+	}
+
+	out, _ = f.Command(context.Background(), "netsh", "int", "ip", "reset").Output()
+	if string(out) != "generic" {
+		t.Errorf("Output = %q, want %q", out, "generic")
+	}
+}
+
+func TestFakeResponsesAreQueued(t *testing.T) {
+	f := NewFake()
+	f.On("powercfg", "/duplicatescheme").Fails(1, "already exists").Returns("second")
+
+	if _, err := f.Command(context.Background(), "powercfg", "/duplicatescheme", "x").Output(); err == nil {
+		t.Error("first call should fail")
+	}
+	for i := 0; i < 2; i++ {
+		out, err := f.Command(context.Background(), "powercfg", "/duplicatescheme", "x").Output()
+		if err != nil || string(out) != "second" {
+			t.Errorf("call %d: got (%q, %v), want (%q, nil)", i+2, out, err, "second")
+		}
+	}
+}
+
+func TestFakeHonoursCancelledContext(t *testing.T) {
+	f := NewFake()
+	f.On("ping").Returns("pong")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := f.Command(ctx, "ping").Run(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run error = %v, want context.Canceled", err)
+	}
+}
+
+func TestFakeCalls(t *testing.T) {
+	f := NewFake()
+	f.On("ipconfig").Returns("")
+
+	_ = f.Command(context.Background(), "ipconfig", "/flushdns").Run()
+	_ = f.Command(context.Background(), "ipconfig", "/release").Run()
+
+	calls := f.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if calls[1].String() != "ipconfig /release" {
+		t.Errorf("second call = %q, want %q", calls[1].String(), "ipconfig /release")
+	}
+	if !f.Called("ipconfig", "/flushdns") {
+		t.Error("Called should match recorded command")
+	}
+	if f.Called("ipconfig", "/renew") {
+		t.Error("Called should not match a command that was never issued")
+	}
+}
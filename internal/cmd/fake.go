@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Response is a canned result replayed by a Fake for a matching command.
+type Response struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Err      error // returned as-is instead of an ExitError when set
+}
+
+// Call records a single command issued through a Fake.
+type Call struct {
+	Name string
+	Args []string
+}
+
+// String renders the call as a single command line.
+func (c Call) String() string {
+	return strings.TrimSpace(c.Name + " " + strings.Join(c.Args, " "))
+}
+
+// ExitError is returned by a Fake when a response has a non-zero exit code.
+type ExitError struct {
+	Code   int
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Fake is a scriptable Runner that replays recorded stdout, stderr and exit
+// codes instead of starting processes.
+//
+// A stub registered with On matches any command with the same name whose
+// arguments begin with the stub's arguments; the most specific stub wins.
+// Commands with no matching stub fail with exit code 1 so unexpected calls
+// surface as errors rather than silently succeeding.
+type Fake struct {
+	mu    sync.Mutex
+	stubs []*Stub
+	calls []Call
+}
+
+// Stub holds the queued responses for one command pattern. Responses are
+// consumed in order; the last one repeats once the queue is exhausted.
+type Stub struct {
+	name      string
+	args      []string
+	responses []Response
+}
+
+// NewFake returns an empty Fake.
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+// On registers (or extends) a stub for the given command name and argument prefix.
+func (f *Fake) On(name string, args ...string) *Stub {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, s := range f.stubs {
+		if strings.EqualFold(s.name, name) && equalArgs(s.args, args) {
+			return s
+		}
+	}
+	s := &Stub{name: name, args: args}
+	f.stubs = append(f.stubs, s)
+	return s
+}
+
+// Returns queues a successful response with the given stdout.
+func (s *Stub) Returns(stdout string) *Stub {
+	return s.Respond(Response{Stdout: stdout})
+}
+
+// Fails queues a failing response with the given exit code and stderr.
+func (s *Stub) Fails(exitCode int, stderr string) *Stub {
+	return s.Respond(Response{ExitCode: exitCode, Stderr: stderr})
+}
+
+// Respond queues an arbitrary response.
+func (s *Stub) Respond(r Response) *Stub {
+	s.responses = append(s.responses, r)
+	return s
+}
+
+// Calls returns every command issued so far, in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]Call, len(f.calls))
+	copy(out, f.calls)
+	return out
+}
+
+// Called reports whether a command with the given name and argument prefix was issued.
+func (f *Fake) Called(name string, args ...string) bool {
+	for _, c := range f.Calls() {
+		if strings.EqualFold(c.Name, name) && hasArgPrefix(c.Args, args) {
+			return true
+		}
+	}
+	return false
+}
+
+// Command implements Runner.
+func (f *Fake) Command(ctx context.Context, name string, args ...string) Cmd {
+	return &fakeCmd{fake: f, ctx: ctx, call: Call{Name: name, Args: args}}
+}
+
+// respond records the call and pops the next response for it.
+func (f *Fake) respond(call Call) Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, call)
+
+	var best *Stub
+	for _, s := range f.stubs {
+		if !strings.EqualFold(s.name, call.Name) || !hasArgPrefix(call.Args, s.args) {
+			continue
+		}
+		if best == nil || len(s.args) > len(best.args) {
+			best = s
+		}
+	}
+	if best == nil || len(best.responses) == 0 {
+		return Response{ExitCode: 1, Stderr: "cmd.Fake: no stub for " + call.String()}
+	}
+
+	r := best.responses[0]
+	if len(best.responses) > 1 {
+		best.responses = best.responses[1:]
+	}
+	return r
+}
+
+// fakeCmd is the Cmd returned by Fake.Command.
+type fakeCmd struct {
+	fake *Fake
+	ctx  context.Context
+	call Call
+}
+
+func (c *fakeCmd) exec() (Response, error) {
+	if c.ctx != nil {
+		if err := c.ctx.Err(); err != nil {
+			c.fake.respond(c.call)
+			return Response{}, err
+		}
+	}
+	r := c.fake.respond(c.call)
+	if r.Err != nil {
+		return r, r.Err
+	}
+	if r.ExitCode != 0 {
+		return r, &ExitError{Code: r.ExitCode, Stderr: []byte(r.Stderr)}
+	}
+	return r, nil
+}
+
+func (c *fakeCmd) Run() error {
+	_, err := c.exec()
+	return err
+}
+
+func (c *fakeCmd) Start() error {
+	_, err := c.exec()
+	return err
+}
+
+func (c *fakeCmd) Output() ([]byte, error) {
+	r, err := c.exec()
+	return []byte(r.Stdout), err
+}
+
+func (c *fakeCmd) CombinedOutput() ([]byte, error) {
+	r, err := c.exec()
+	return []byte(r.Stdout + r.Stderr), err
+}
+
+func equalArgs(a, b []string) bool {
+	return len(a) == len(b) && hasArgPrefix(a, b)
+}
+
+func hasArgPrefix(args, prefix []string) bool {
+	if len(prefix) > len(args) {
+		return false
+	}
+	for i, p := range prefix {
+		if !strings.EqualFold(args[i], p) {
+			return false
+		}
+	}
+	return true
+}
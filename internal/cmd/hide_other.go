@@ -0,0 +1,8 @@
+//go:build !windows
+
+package cmd
+
+import "os/exec"
+
+// hideWindow is a no-op outside Windows; there is no console to hide.
+func hideWindow(cmd *exec.Cmd) {}
@@ -0,0 +1,13 @@
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hideWindow sets CREATE_NO_WINDOW so no console flashes up for the child.
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: 0x08000000, // CREATE_NO_WINDOW
+	}
+}
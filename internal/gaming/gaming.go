@@ -1,20 +1,31 @@
 package gaming
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"cleanforge/internal/gaming/profiles"
+	"cleanforge/internal/backup"
 	"cleanforge/internal/cmd"
-
-	"golang.org/x/sys/windows/registry"
+	"cleanforge/internal/gaming/profiles"
+	"cleanforge/internal/plan"
+	"cleanforge/internal/reg"
 )
 
+// runner executes external commands. Tests replace it with a cmd.Fake.
+var runner cmd.Runner = cmd.Default
+
+// registry is the Windows registry. Tests replace it with a reg.Memory.
+var registry reg.Registry = reg.Default
+
+// journal records every change this package makes so it can be undone.
+var journal = backup.Default
+
 // ---------- Types ----------
 
 // GPUInfo holds detected GPU information.
@@ -31,6 +42,7 @@ type BoostStatus struct {
 	Profile       string   `json:"profile"`
 	TweaksApplied []string `json:"tweaksApplied"`
 	StartedAt     string   `json:"startedAt"`
+	RestorePoint  string   `json:"restorePoint,omitempty"` // journal restore point made before the boost
 }
 
 // TweakInfo describes a single tweak that can be toggled.
@@ -41,6 +53,7 @@ type TweakInfo struct {
 	Category    string `json:"category"`
 	Enabled     bool   `json:"enabled"`
 	Applied     bool   `json:"applied"`
+	Revertible  bool   `json:"revertible"` // the journal holds changes RevertTweak can undo
 }
 
 // GameProfile mirrors the profile type for JSON serialization to the frontend.
@@ -52,25 +65,6 @@ type GameProfile struct {
 	Tweaks      map[string]bool `json:"tweaks"`
 }
 
-// BackupEntry stores a single registry or service state for restore.
-type BackupEntry struct {
-	Type         string `json:"type"`          // "registry" or "service"
-	Root         string `json:"root"`          // "HKCU" or "HKLM"
-	KeyPath      string `json:"keyPath"`       // registry key path
-	ValueName    string `json:"valueName"`     // registry value name
-	Value        string `json:"value"`         // original value as string
-	ValueType    uint32 `json:"valueType"`     // registry value type constant
-	ServiceName  string `json:"serviceName"`   // for service entries
-	ServiceState string `json:"serviceState"`  // "running" or "stopped"
-	Missing      bool   `json:"missing"`       // true if value did not exist before
-}
-
-// BackupState is the complete backup persisted to disk.
-type BackupState struct {
-	CreatedAt string        `json:"createdAt"`
-	Entries   []BackupEntry `json:"entries"`
-}
-
 // ---------- GPU class GUID constant ----------
 
 const gpuClassGUID = `SYSTEM\CurrentControlSet\Control\Class\{4d36e968-e325-11ce-bfc1-08002be10318}\0000`
@@ -106,38 +100,217 @@ var lightBloatware = []string{
 
 // ---------- Tweak catalog ----------
 
+// tweakValue is a registry value a tweak sets, as a string or a DWORD.
+type tweakValue struct {
+	root     reg.Root
+	path     string
+	name     string
+	str      string
+	dword    uint32
+	isString bool
+}
+
+func regString(root reg.Root, path, name, value string) tweakValue {
+	return tweakValue{root: root, path: path, name: name, str: value, isString: true}
+}
+
+func regDWORD(root reg.Root, path, name string, value uint32) tweakValue {
+	return tweakValue{root: root, path: path, name: name, dword: value}
+}
+
+// tweakDef describes a tweak as data: the registry values it sets, the
+// services it stops and the commands it runs. Planning and applying the
+// tweak, and backing up what it changes, all follow from it; the tweak is
+// undone by restoring what its steps recorded in the journal. Changes that
+// cannot be listed up front, such as one per network interface, are planned
+// by Steps, and IsApplied then reads whether they are in effect.
 type tweakDef struct {
 	ID          string
 	Name        string
 	Description string
 	Category    string
+
+	Values   []tweakValue                             // registry values to set
+	GPU      map[string][]tweakValue                  // further values by GPU vendor, see DetectGPU
+	Services []string                                 // services to stop
+	Commands [][]string                               // best-effort commands, which cannot be undone
+	Steps    func(g *GameBooster, s tweakSteps) error // any other changes
+
+	IsApplied func(g *GameBooster) bool // whether the changes of Steps are in effect
 }
 
+// mouseKey holds the per-user mouse settings.
+const mouseKey = `Control Panel\Mouse`
+
+// coreParkingKey is the processor power setting for the minimum of unparked cores.
+const coreParkingKey = `SYSTEM\CurrentControlSet\Control\Power\PowerSettings\54533251-82be-4824-96c1-47b60b740d00\0cc5b647-c1df-4637-891a-dec35c318583`
+
 var tweakCatalog = []tweakDef{
-	{"mouse_raw_input", "Raw Mouse Input", "Enable raw input for precise mouse movement", "mouse"},
-	{"mouse_disable_acceleration", "Disable Mouse Acceleration", "Set MouseSpeed, Threshold1, Threshold2 to 0", "mouse"},
-	{"disable_smooth_scrolling", "Disable Smooth Scrolling", "Turn off smooth scrolling in system settings", "mouse"},
-	{"keyboard_repeat_max", "Max Keyboard Repeat Rate", "Set KeyboardDelay=0 and KeyboardSpeed=31", "keyboard"},
-	{"disable_sticky_keys", "Disable Sticky Keys", "Prevent sticky keys popup during gaming", "keyboard"},
-	{"disable_filter_keys", "Disable Filter Keys", "Prevent filter keys popup during gaming", "keyboard"},
-	{"disable_toggle_keys", "Disable Toggle Keys", "Prevent toggle keys sound during gaming", "keyboard"},
-	{"gpu_low_latency", "GPU Low Latency Mode", "Vendor-specific low latency rendering", "gpu"},
-	{"gpu_max_performance", "GPU Max Performance", "Vendor-specific maximum power/performance", "gpu"},
-	{"disable_game_dvr", "Disable Game DVR", "Turn off background game recording", "display"},
-	{"disable_game_bar", "Disable Game Bar", "Turn off Xbox Game Bar overlay", "display"},
-	{"disable_game_mode", "Disable Game Mode", "Turn off Windows Game Mode", "display"},
-	{"disable_fullscreen_optimize", "Disable Fullscreen Optimizations", "Prevent DWM fullscreen optimizations", "display"},
-	{"ultimate_power_plan", "Ultimate Performance Power Plan", "Activate Windows Ultimate Performance plan", "power"},
-	{"core_parking_off", "Disable Core Parking", "Keep all CPU cores active", "power"},
-	{"disable_hpet", "Disable HPET", "Remove platform clock for lower timer latency", "system"},
-	{"timer_resolution", "High Timer Resolution", "Request 0.5ms timer resolution", "system"},
-	{"disable_sysmain", "Disable SysMain/SuperFetch", "Stop SysMain service temporarily", "system"},
-	{"disable_indexing", "Disable Windows Search Indexing", "Stop WSearch service temporarily", "system"},
-	{"kill_bloatware", "Kill Bloatware Processes", "Terminate known background bloatware", "system"},
-	{"disable_nagle", "Disable Nagle Algorithm", "Turn off TCP packet batching for lower latency", "network"},
-	{"dns_optimize", "Optimize DNS Settings", "Flush DNS cache and set fast lookup", "network"},
-	{"flush_network", "Flush Network Stack", "Reset Winsock and flush DNS/ARP", "network"},
-	{"cpu_priority_high", "High CPU Priority", "Set foreground process priority boost", "system"},
+	{
+		ID: "mouse_raw_input", Name: "Raw Mouse Input", Description: "Enable raw input for precise mouse movement", Category: "mouse",
+		// Enable raw input hint via registry. Applications read HID_Usage flags;
+		// the primary user-facing toggle is disabling acceleration (see below).
+		Values: []tweakValue{regString(reg.CurrentUser, mouseKey, "MouseSpeed", "0")},
+	},
+	{
+		ID: "mouse_disable_acceleration", Name: "Disable Mouse Acceleration", Description: "Set MouseSpeed, Threshold1, Threshold2 to 0", Category: "mouse",
+		Values: []tweakValue{
+			regString(reg.CurrentUser, mouseKey, "MouseSpeed", "0"),
+			regString(reg.CurrentUser, mouseKey, "MouseThreshold1", "0"),
+			regString(reg.CurrentUser, mouseKey, "MouseThreshold2", "0"),
+		},
+	},
+	{
+		ID: "disable_smooth_scrolling", Name: "Disable Smooth Scrolling", Description: "Turn off smooth scrolling in system settings", Category: "mouse",
+		Values: []tweakValue{regDWORD(reg.CurrentUser, `Control Panel\Desktop`, "SmoothScroll", 0)},
+	},
+	{
+		ID: "keyboard_repeat_max", Name: "Max Keyboard Repeat Rate", Description: "Set KeyboardDelay=0 and KeyboardSpeed=31", Category: "keyboard",
+		Values: []tweakValue{
+			regString(reg.CurrentUser, `Control Panel\Keyboard`, "KeyboardDelay", "0"),
+			regString(reg.CurrentUser, `Control Panel\Keyboard`, "KeyboardSpeed", "31"),
+		},
+	},
+	{
+		ID: "disable_sticky_keys", Name: "Disable Sticky Keys", Description: "Prevent sticky keys popup during gaming", Category: "keyboard",
+		Values: []tweakValue{regString(reg.CurrentUser, `Control Panel\Accessibility\StickyKeys`, "Flags", "506")},
+	},
+	{
+		ID: "disable_filter_keys", Name: "Disable Filter Keys", Description: "Prevent filter keys popup during gaming", Category: "keyboard",
+		Values: []tweakValue{regString(reg.CurrentUser, `Control Panel\Accessibility\Keyboard Response`, "Flags", "122")},
+	},
+	{
+		ID: "disable_toggle_keys", Name: "Disable Toggle Keys", Description: "Prevent toggle keys sound during gaming", Category: "keyboard",
+		Values: []tweakValue{regString(reg.CurrentUser, `Control Panel\Accessibility\ToggleKeys`, "Flags", "58")},
+	},
+	{
+		ID: "gpu_low_latency", Name: "GPU Low Latency Mode", Description: "Vendor-specific low latency rendering", Category: "gpu",
+		GPU: map[string][]tweakValue{
+			// NVIDIA Low Latency Mode: set LowLatencyMode value
+			"nvidia": {regDWORD(reg.LocalMachine, gpuClassGUID, "KMD_EnableGPUTaskScheduler", 1)},
+			// AMD Anti-Lag toggle through driver registry
+			"amd": {regDWORD(reg.LocalMachine, gpuClassGUID, "DisableDMACopy", 1)},
+		},
+	},
+	{
+		ID: "gpu_max_performance", Name: "GPU Max Performance", Description: "Vendor-specific maximum power/performance", Category: "gpu",
+		GPU: map[string][]tweakValue{
+			// Prefer Maximum Performance power management
+			"nvidia": {
+				regDWORD(reg.LocalMachine, gpuClassGUID, "PerfLevelSrc", 0x2222),
+				regDWORD(reg.LocalMachine, gpuClassGUID, "PowerMizerEnable", 1),
+			},
+			// Disable ULPS (Ultra Low Power State) and set performance profile
+			"amd": {
+				regDWORD(reg.LocalMachine, gpuClassGUID, "UlpsEnable", 0),
+				regDWORD(reg.LocalMachine, gpuClassGUID, "PP_ThermalAutoThrottlingEnable", 0),
+			},
+			// Intel max performance mode
+			"intel": {regDWORD(reg.LocalMachine, gpuClassGUID, "FeatureTestControl", 0x9240)},
+		},
+	},
+	{
+		ID: "disable_game_dvr", Name: "Disable Game DVR", Description: "Turn off background game recording", Category: "display",
+		Values: []tweakValue{regDWORD(reg.CurrentUser, `System\GameConfigStore`, "GameDVR_Enabled", 0)},
+	},
+	{
+		ID: "disable_game_bar", Name: "Disable Game Bar", Description: "Turn off Xbox Game Bar overlay", Category: "display",
+		Values: []tweakValue{
+			regDWORD(reg.CurrentUser, `SOFTWARE\Microsoft\Windows\CurrentVersion\GameDVR`, "AppCaptureEnabled", 0),
+			regDWORD(reg.CurrentUser, `Software\Microsoft\GameBar`, "UseNexusForGameBarEnabled", 0),
+		},
+	},
+	{
+		ID: "disable_game_mode", Name: "Disable Game Mode", Description: "Turn off Windows Game Mode", Category: "display",
+		Values: []tweakValue{
+			regDWORD(reg.CurrentUser, `Software\Microsoft\GameBar`, "AllowAutoGameMode", 0),
+			regDWORD(reg.CurrentUser, `Software\Microsoft\GameBar`, "AutoGameModeEnabled", 0),
+		},
+	},
+	{
+		ID: "disable_fullscreen_optimize", Name: "Disable Fullscreen Optimizations", Description: "Prevent DWM fullscreen optimizations", Category: "display",
+		Values: []tweakValue{
+			regDWORD(reg.CurrentUser, `System\GameConfigStore`, "GameDVR_FSEBehaviorMode", 2),
+			regDWORD(reg.CurrentUser, `System\GameConfigStore`, "GameDVR_HonorUserFSEBehaviorMode", 1),
+			regDWORD(reg.CurrentUser, `System\GameConfigStore`, "GameDVR_FSEBehavior", 2),
+			regDWORD(reg.CurrentUser, `System\GameConfigStore`, "GameDVR_DXGIHonorFSEWindowsCompatible", 1),
+		},
+	},
+	{
+		ID: "ultimate_power_plan", Name: "Ultimate Performance Power Plan", Description: "Activate Windows Ultimate Performance plan", Category: "power",
+		Steps:     (*GameBooster).planUltimatePowerPlan,
+		IsApplied: (*GameBooster).isUltimatePowerPlanActive,
+	},
+	{
+		ID: "core_parking_off", Name: "Disable Core Parking", Description: "Keep all CPU cores active", Category: "power",
+		// Disable core parking by setting ValueMax to 0 (0% cores parked)
+		Values: []tweakValue{regDWORD(reg.LocalMachine, coreParkingKey, "ValueMax", 0)},
+	},
+	{
+		ID: "disable_hpet", Name: "Disable HPET", Description: "Remove platform clock for lower timer latency", Category: "system",
+		Steps:     (*GameBooster).planDisableHPET,
+		IsApplied: (*GameBooster).isHPETDisabled,
+	},
+	{
+		ID: "timer_resolution", Name: "High Timer Resolution", Description: "Request 0.5ms timer resolution", Category: "system",
+		// Use powershell to call NtSetTimerResolution for 0.5ms (5000 * 100ns = 0.5ms)
+		// This is a best-effort runtime tweak. Persist by setting the global timer.
+		Values: []tweakValue{regDWORD(reg.LocalMachine, `SYSTEM\CurrentControlSet\Control\Session Manager\kernel`, "GlobalTimerResolutionRequests", 1)},
+	},
+	{
+		ID: "disable_sysmain", Name: "Disable SysMain/SuperFetch", Description: "Stop SysMain service temporarily", Category: "system",
+		Services: []string{"SysMain"},
+	},
+	{
+		ID: "disable_indexing", Name: "Disable Windows Search Indexing", Description: "Stop WSearch service temporarily", Category: "system",
+		Services: []string{"WSearch"},
+	},
+	{
+		ID: "kill_bloatware", Name: "Kill Bloatware Processes", Description: "Terminate known background bloatware", Category: "system",
+		Commands: taskkills(heavyBloatware),
+	},
+	{
+		ID: "disable_nagle", Name: "Disable Nagle Algorithm", Description: "Turn off TCP packet batching for lower latency", Category: "network",
+		Steps:     (*GameBooster).planDisableNagle,
+		IsApplied: (*GameBooster).isNagleDisabled,
+	},
+	{
+		ID: "dns_optimize", Name: "Optimize DNS Settings", Description: "Flush DNS cache and set fast lookup", Category: "network",
+		Commands: [][]string{{"ipconfig", "/flushdns"}},
+	},
+	{
+		ID: "flush_network", Name: "Flush Network Stack", Description: "Reset Winsock and flush DNS/ARP", Category: "network",
+		Commands: [][]string{
+			{"ipconfig", "/flushdns"},
+			{"nbtstat", "-R"},
+			{"netsh", "winsock", "reset"},
+			{"netsh", "int", "ip", "reset"},
+		},
+	},
+	{
+		ID: "cpu_priority_high", Name: "High CPU Priority", Description: "Set foreground process priority boost", Category: "system",
+		// Win32PrioritySeparation = 38 (0x26) -> foreground apps get max priority boost
+		Values: []tweakValue{regDWORD(reg.LocalMachine, `SYSTEM\CurrentControlSet\Control\PriorityControl`, "Win32PrioritySeparation", 0x26)},
+	},
+}
+
+// taskkills returns a command forcibly ending each of the processes.
+func taskkills(processes []string) [][]string {
+	commands := make([][]string, len(processes))
+	for i, proc := range processes {
+		commands[i] = []string{"taskkill", "/F", "/IM", proc}
+	}
+	return commands
+}
+
+// findTweak returns the catalog entry with the given ID, or nil.
+func findTweak(id string) *tweakDef {
+	for i := range tweakCatalog {
+		if tweakCatalog[i].ID == id {
+			return &tweakCatalog[i]
+		}
+	}
+	return nil
 }
 
 // ---------- GameBooster ----------
@@ -147,18 +320,12 @@ type GameBooster struct {
 	mu            sync.Mutex
 	status        BoostStatus
 	appliedTweaks map[string]bool
-	backupPath    string
 }
 
 // NewGameBooster creates and initializes a GameBooster instance.
 func NewGameBooster() *GameBooster {
-	home, _ := os.UserHomeDir()
-	backupDir := filepath.Join(home, ".cleanforge")
-	_ = os.MkdirAll(backupDir, 0o755)
-
 	return &GameBooster{
 		appliedTweaks: make(map[string]bool),
-		backupPath:    filepath.Join(backupDir, "backup_state.json"),
 	}
 }
 
@@ -166,7 +333,7 @@ func NewGameBooster() *GameBooster {
 
 // DetectGPU uses wmic to detect the primary GPU and determine its vendor.
 func (g *GameBooster) DetectGPU() (*GPUInfo, error) {
-	out, err := cmd.Hidden("wmic", "path", "win32_VideoController", "get", "Name,DriverVersion", "/format:csv").CombinedOutput()
+	out, err := runner.Command(context.Background(), "wmic", "path", "win32_VideoController", "get", "Name,DriverVersion", "/format:csv").CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("wmic failed: %w — output: %s", err, string(out))
 	}
@@ -211,225 +378,14 @@ func (g *GameBooster) DetectGPU() (*GPUInfo, error) {
 	return info, nil
 }
 
-// ---------- Backup & Restore ----------
-
-func (g *GameBooster) readBackup() (*BackupState, error) {
-	data, err := os.ReadFile(g.backupPath)
-	if err != nil {
-		return nil, err
-	}
-	var state BackupState
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, err
-	}
-	return &state, nil
-}
+// ---------- Registry helpers ----------
 
-func (g *GameBooster) writeBackup(state *BackupState) error {
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
+// setRegString records the current value in the journal and then sets it.
+func setRegString(rec backup.Recorder, root reg.Root, keyPath, name, value string) error {
+	if err := rec.RegistryValue(root, keyPath, name); err != nil {
 		return err
 	}
-	return os.WriteFile(g.backupPath, data, 0o644)
-}
-
-func rootKey(name string) registry.Key {
-	if name == "HKLM" {
-		return registry.LOCAL_MACHINE
-	}
-	return registry.CURRENT_USER
-}
-
-// backupRegistryValue reads the current value from the registry and appends it to the backup state.
-// If the value does not exist, it marks the entry as missing so restore can delete it.
-func (g *GameBooster) backupRegistryValue(state *BackupState, root string, keyPath, valueName string) {
-	rk := rootKey(root)
-	key, err := registry.OpenKey(rk, keyPath, registry.QUERY_VALUE)
-	if err != nil {
-		state.Entries = append(state.Entries, BackupEntry{
-			Type:      "registry",
-			Root:      root,
-			KeyPath:   keyPath,
-			ValueName: valueName,
-			Missing:   true,
-		})
-		return
-	}
-	defer key.Close()
-
-	val, valType, err := key.GetStringValue(valueName)
-	if err != nil {
-		// Try reading as integer
-		ival, _, ierr := key.GetIntegerValue(valueName)
-		if ierr != nil {
-			state.Entries = append(state.Entries, BackupEntry{
-				Type:      "registry",
-				Root:      root,
-				KeyPath:   keyPath,
-				ValueName: valueName,
-				Missing:   true,
-			})
-			return
-		}
-		state.Entries = append(state.Entries, BackupEntry{
-			Type:      "registry",
-			Root:      root,
-			KeyPath:   keyPath,
-			ValueName: valueName,
-			Value:     fmt.Sprintf("%d", ival),
-			ValueType: registry.DWORD,
-		})
-		return
-	}
-
-	state.Entries = append(state.Entries, BackupEntry{
-		Type:      "registry",
-		Root:      root,
-		KeyPath:   keyPath,
-		ValueName: valueName,
-		Value:     val,
-		ValueType: valType,
-	})
-}
-
-// backupServiceState saves whether a service is running.
-func (g *GameBooster) backupServiceState(state *BackupState, serviceName string) {
-	out, err := cmd.Hidden("sc", "query", serviceName).CombinedOutput()
-	svcState := "stopped"
-	if err == nil && strings.Contains(string(out), "RUNNING") {
-		svcState = "running"
-	}
-	state.Entries = append(state.Entries, BackupEntry{
-		Type:         "service",
-		ServiceName:  serviceName,
-		ServiceState: svcState,
-	})
-}
-
-// BackupCurrentState captures the current state of all tweakable settings.
-func (g *GameBooster) BackupCurrentState() error {
-	state := &BackupState{
-		CreatedAt: time.Now().Format(time.RFC3339),
-		Entries:   []BackupEntry{},
-	}
-
-	// Mouse settings
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Mouse`, "MouseSpeed")
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Mouse`, "MouseThreshold1")
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Mouse`, "MouseThreshold2")
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Mouse`, "SmoothMouseXCurve")
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Mouse`, "SmoothMouseYCurve")
-
-	// Keyboard settings
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Keyboard`, "KeyboardDelay")
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Keyboard`, "KeyboardSpeed")
-
-	// Accessibility
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Accessibility\StickyKeys`, "Flags")
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Accessibility\Keyboard Response`, "Flags")
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Accessibility\ToggleKeys`, "Flags")
-
-	// Game DVR / Game Bar / Game Mode
-	g.backupRegistryValue(state, "HKCU", `System\GameConfigStore`, "GameDVR_Enabled")
-	g.backupRegistryValue(state, "HKCU", `SOFTWARE\Microsoft\Windows\CurrentVersion\GameDVR`, "AppCaptureEnabled")
-	g.backupRegistryValue(state, "HKCU", `Software\Microsoft\GameBar`, "AllowAutoGameMode")
-	g.backupRegistryValue(state, "HKCU", `Software\Microsoft\GameBar`, "AutoGameModeEnabled")
-
-	// Fullscreen optimization
-	g.backupRegistryValue(state, "HKCU", `System\GameConfigStore`, "GameDVR_FSEBehaviorMode")
-	g.backupRegistryValue(state, "HKCU", `System\GameConfigStore`, "GameDVR_HonorUserFSEBehaviorMode")
-	g.backupRegistryValue(state, "HKCU", `System\GameConfigStore`, "GameDVR_FSEBehavior")
-	g.backupRegistryValue(state, "HKCU", `System\GameConfigStore`, "GameDVR_DXGIHonorFSEWindowsCompatible")
-
-	// Nagle
-	g.backupRegistryValue(state, "HKLM", `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces`, "TcpAckFrequency")
-	g.backupRegistryValue(state, "HKLM", `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces`, "TCPNoDelay")
-
-	// Global TCP parameters
-	g.backupRegistryValue(state, "HKLM", `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters`, "TcpAckFrequency")
-	g.backupRegistryValue(state, "HKLM", `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters`, "TCPNoDelay")
-
-	// GPU class key
-	g.backupRegistryValue(state, "HKLM", gpuClassGUID, "UlpsEnable")
-	g.backupRegistryValue(state, "HKLM", gpuClassGUID, "PP_ThermalAutoThrottlingEnable")
-	g.backupRegistryValue(state, "HKLM", gpuClassGUID, "DisableDMACopy")
-	g.backupRegistryValue(state, "HKLM", gpuClassGUID, "KMD_EnableGPUTaskScheduler")
-
-	// Core parking
-	g.backupRegistryValue(state, "HKLM", `SYSTEM\CurrentControlSet\Control\Power\PowerSettings\54533251-82be-4824-96c1-47b60b740d00\0cc5b647-c1df-4637-891a-dec35c318583`, "ValueMax")
-
-	// CPU priority
-	g.backupRegistryValue(state, "HKLM", `SYSTEM\CurrentControlSet\Control\PriorityControl`, "Win32PrioritySeparation")
-
-	// Smooth scrolling
-	g.backupRegistryValue(state, "HKCU", `Control Panel\Desktop`, "SmoothScroll")
-
-	// Services
-	g.backupServiceState(state, "SysMain")
-	g.backupServiceState(state, "WSearch")
-
-	return g.writeBackup(state)
-}
-
-// RestoreOriginalState reads the backup and restores all saved values.
-func (g *GameBooster) RestoreOriginalState() error {
-	state, err := g.readBackup()
-	if err != nil {
-		return fmt.Errorf("no backup found: %w", err)
-	}
-
-	var errs []string
-
-	for _, entry := range state.Entries {
-		switch entry.Type {
-		case "registry":
-			if entry.Missing {
-				// Value did not exist before; delete it.
-				rk := rootKey(entry.Root)
-				key, kerr := registry.OpenKey(rk, entry.KeyPath, registry.SET_VALUE)
-				if kerr == nil {
-					_ = key.DeleteValue(entry.ValueName)
-					key.Close()
-				}
-				continue
-			}
-			rk := rootKey(entry.Root)
-			key, _, kerr := registry.CreateKey(rk, entry.KeyPath, registry.ALL_ACCESS)
-			if kerr != nil {
-				errs = append(errs, fmt.Sprintf("open %s\\%s: %v", entry.Root, entry.KeyPath, kerr))
-				continue
-			}
-			switch entry.ValueType {
-			case registry.DWORD:
-				var n uint64
-				fmt.Sscanf(entry.Value, "%d", &n)
-				kerr = key.SetDWordValue(entry.ValueName, uint32(n))
-			default:
-				kerr = key.SetStringValue(entry.ValueName, entry.Value)
-			}
-			key.Close()
-			if kerr != nil {
-				errs = append(errs, fmt.Sprintf("set %s\\%s\\%s: %v", entry.Root, entry.KeyPath, entry.ValueName, kerr))
-			}
-
-		case "service":
-			if entry.ServiceState == "running" {
-				_ = cmd.Hidden("sc", "start", entry.ServiceName).Run()
-			}
-		}
-	}
-
-	if len(errs) > 0 {
-		return fmt.Errorf("restore completed with errors: %s", strings.Join(errs, "; "))
-	}
-
-	return nil
-}
-
-// ---------- Registry helpers ----------
-
-func setRegString(root registry.Key, keyPath, name, value string) error {
-	key, _, err := registry.CreateKey(root, keyPath, registry.ALL_ACCESS)
+	key, _, err := registry.CreateKey(root, keyPath, reg.AllAccess)
 	if err != nil {
 		return fmt.Errorf("create key %s: %w", keyPath, err)
 	}
@@ -437,8 +393,12 @@ func setRegString(root registry.Key, keyPath, name, value string) error {
 	return key.SetStringValue(name, value)
 }
 
-func setRegDWORD(root registry.Key, keyPath, name string, value uint32) error {
-	key, _, err := registry.CreateKey(root, keyPath, registry.ALL_ACCESS)
+// setRegDWORD records the current value in the journal and then sets it.
+func setRegDWORD(rec backup.Recorder, root reg.Root, keyPath, name string, value uint32) error {
+	if err := rec.RegistryValue(root, keyPath, name); err != nil {
+		return err
+	}
+	key, _, err := registry.CreateKey(root, keyPath, reg.AllAccess)
 	if err != nil {
 		return fmt.Errorf("create key %s: %w", keyPath, err)
 	}
@@ -446,127 +406,88 @@ func setRegDWORD(root registry.Key, keyPath, name string, value uint32) error {
 	return key.SetDWordValue(name, value)
 }
 
-// ---------- Individual tweak implementations ----------
-
-func (g *GameBooster) applyMouseRawInput() error {
-	// Enable raw input hint via registry. Applications read HID_Usage flags;
-	// the primary user-facing toggle is disabling acceleration (see below).
-	return setRegString(registry.CURRENT_USER, `Control Panel\Mouse`, "MouseSpeed", "0")
-}
-
-func (g *GameBooster) applyMouseDisableAcceleration() error {
-	if err := setRegString(registry.CURRENT_USER, `Control Panel\Mouse`, "MouseSpeed", "0"); err != nil {
-		return err
-	}
-	if err := setRegString(registry.CURRENT_USER, `Control Panel\Mouse`, "MouseThreshold1", "0"); err != nil {
-		return err
-	}
-	return setRegString(registry.CURRENT_USER, `Control Panel\Mouse`, "MouseThreshold2", "0")
-}
-
-func (g *GameBooster) applyDisableSmoothScrolling() error {
-	return setRegDWORD(registry.CURRENT_USER, `Control Panel\Desktop`, "SmoothScroll", 0)
-}
+// ---------- Tweak steps ----------
 
-func (g *GameBooster) applyKeyboardRepeatMax() error {
-	if err := setRegString(registry.CURRENT_USER, `Control Panel\Keyboard`, "KeyboardDelay", "0"); err != nil {
-		return err
-	}
-	return setRegString(registry.CURRENT_USER, `Control Panel\Keyboard`, "KeyboardSpeed", "31")
+// tweakSteps adds the changes one tweak makes to a plan. Each step records
+// the state it replaces in the journal, under the tweak's ID, right before
+// it is applied.
+type tweakSteps struct {
+	plan *plan.Plan
+	id   string
+	rec  backup.Recorder
 }
 
-func (g *GameBooster) applyDisableStickyKeys() error {
-	return setRegString(registry.CURRENT_USER, `Control Panel\Accessibility\StickyKeys`, "Flags", "506")
+func newTweakSteps(p *plan.Plan, id string) tweakSteps {
+	return tweakSteps{plan: p, id: id, rec: journal.For("gaming", id)}
 }
 
-func (g *GameBooster) applyDisableFilterKeys() error {
-	return setRegString(registry.CURRENT_USER, `Control Panel\Accessibility\Keyboard Response`, "Flags", "122")
+func (s tweakSteps) add(a plan.Action, apply func() error) {
+	a.Module = "gaming"
+	a.Tweak = s.id
+	s.plan.Add(a, apply)
 }
 
-func (g *GameBooster) applyDisableToggleKeys() error {
-	return setRegString(registry.CURRENT_USER, `Control Panel\Accessibility\ToggleKeys`, "Flags", "58")
+func (s tweakSteps) setString(root reg.Root, keyPath, name, value string) {
+	s.add(plan.RegistryChange(registry, root, keyPath, name, strconv.Quote(value)), func() error {
+		return setRegString(s.rec, root, keyPath, name, value)
+	})
 }
 
-func (g *GameBooster) applyGPULowLatency() error {
-	gpu, err := g.DetectGPU()
-	if err != nil {
-		return err
-	}
-	switch gpu.Vendor {
-	case "nvidia":
-		// NVIDIA Low Latency Mode: set LowLatencyMode value
-		return setRegDWORD(registry.LOCAL_MACHINE, gpuClassGUID, "KMD_EnableGPUTaskScheduler", 1)
-	case "amd":
-		// AMD Anti-Lag toggle through driver registry
-		return setRegDWORD(registry.LOCAL_MACHINE, gpuClassGUID, "DisableDMACopy", 1)
-	default:
-		return nil
-	}
+func (s tweakSteps) setDWORD(root reg.Root, keyPath, name string, value uint32) {
+	s.add(plan.RegistryChange(registry, root, keyPath, name, strconv.FormatUint(uint64(value), 10)), func() error {
+		return setRegDWORD(s.rec, root, keyPath, name, value)
+	})
 }
 
-func (g *GameBooster) applyGPUMaxPerformance() error {
-	gpu, err := g.DetectGPU()
-	if err != nil {
-		return err
-	}
-	switch gpu.Vendor {
-	case "nvidia":
-		// Prefer Maximum Performance power management
-		if err := setRegDWORD(registry.LOCAL_MACHINE, gpuClassGUID, "PerfLevelSrc", 0x2222); err != nil {
+// stopService records a service's state and stops it.
+func (s tweakSteps) stopService(name string) {
+	s.add(plan.ServiceChange(runner, name, "stopped"), func() error {
+		if err := s.rec.Service(name); err != nil {
 			return err
 		}
-		return setRegDWORD(registry.LOCAL_MACHINE, gpuClassGUID, "PowerMizerEnable", 1)
-	case "amd":
-		// Disable ULPS (Ultra Low Power State) and set performance profile
-		if err := setRegDWORD(registry.LOCAL_MACHINE, gpuClassGUID, "UlpsEnable", 0); err != nil {
-			return err
-		}
-		return setRegDWORD(registry.LOCAL_MACHINE, gpuClassGUID, "PP_ThermalAutoThrottlingEnable", 0)
-	case "intel":
-		// Intel max performance mode
-		return setRegDWORD(registry.LOCAL_MACHINE, gpuClassGUID, "FeatureTestControl", 0x9240)
-	default:
-		return nil
-	}
+		return runner.Command(context.Background(), "sc", "stop", name).Run()
+	})
 }
 
-func (g *GameBooster) applyDisableGameDVR() error {
-	return setRegDWORD(registry.CURRENT_USER, `System\GameConfigStore`, "GameDVR_Enabled", 0)
+// tryRun runs a best-effort command whose failure is not an error, such as
+// killing a process that may not be running.
+func (s tweakSteps) tryRun(name string, args ...string) {
+	s.add(plan.Command(name, args...), func() error {
+		_ = runner.Command(context.Background(), name, args...).Run()
+		return nil
+	})
 }
 
-func (g *GameBooster) applyDisableGameBar() error {
-	if err := setRegDWORD(registry.CURRENT_USER, `SOFTWARE\Microsoft\Windows\CurrentVersion\GameDVR`, "AppCaptureEnabled", 0); err != nil {
-		return err
-	}
-	return setRegDWORD(registry.CURRENT_USER, `Software\Microsoft\GameBar`, "UseNexusForGameBarEnabled", 0)
-}
+// ---------- Custom tweak steps ----------
 
-func (g *GameBooster) applyDisableGameMode() error {
-	if err := setRegDWORD(registry.CURRENT_USER, `Software\Microsoft\GameBar`, "AllowAutoGameMode", 0); err != nil {
-		return err
+// ultimatePlanGUID is the built-in Ultimate Performance scheme that gets duplicated.
+const ultimatePlanGUID = "e9a42b02-d5df-448d-aa00-03f14749eb61"
+
+func (g *GameBooster) planUltimatePowerPlan(s tweakSteps) error {
+	// The new plan's GUID is only known once it has been duplicated, so the
+	// whole switch is a single step.
+	a := plan.Command("powercfg", "/duplicatescheme", ultimatePlanGUID)
+	a.Old = "unknown"
+	if out, err := runner.Command(context.Background(), "powercfg", "/getactivescheme").Output(); err == nil {
+		if guid := parseGUIDFromPowercfg(string(out)); guid != "" {
+			a.Old = guid
+		}
 	}
-	return setRegDWORD(registry.CURRENT_USER, `Software\Microsoft\GameBar`, "AutoGameModeEnabled", 0)
+	a.New = "Ultimate Performance"
+	s.add(a, func() error { return g.applyUltimatePowerPlan(s.rec) })
+	return nil
 }
 
-func (g *GameBooster) applyDisableFullscreenOptimize() error {
-	if err := setRegDWORD(registry.CURRENT_USER, `System\GameConfigStore`, "GameDVR_FSEBehaviorMode", 2); err != nil {
-		return err
-	}
-	if err := setRegDWORD(registry.CURRENT_USER, `System\GameConfigStore`, "GameDVR_HonorUserFSEBehaviorMode", 1); err != nil {
+func (g *GameBooster) applyUltimatePowerPlan(rec backup.Recorder) error {
+	if err := rec.PowerPlan(); err != nil {
 		return err
 	}
-	if err := setRegDWORD(registry.CURRENT_USER, `System\GameConfigStore`, "GameDVR_FSEBehavior", 2); err != nil {
-		return err
-	}
-	return setRegDWORD(registry.CURRENT_USER, `System\GameConfigStore`, "GameDVR_DXGIHonorFSEWindowsCompatible", 1)
-}
 
-func (g *GameBooster) applyUltimatePowerPlan() error {
 	// Duplicate the Ultimate Performance plan
-	out, err := cmd.Hidden("powercfg", "/duplicatescheme", "e9a42b02-d5df-448d-aa00-03f14749eb61").CombinedOutput()
+	out, err := runner.Command(context.Background(), "powercfg", "/duplicatescheme", ultimatePlanGUID).CombinedOutput()
 	if err != nil {
 		// Plan may already exist; try to find it
-		listOut, lerr := cmd.Hidden("powercfg", "/list").CombinedOutput()
+		listOut, lerr := runner.Command(context.Background(), "powercfg", "/list").CombinedOutput()
 		if lerr != nil {
 			return fmt.Errorf("powercfg list failed: %w", lerr)
 		}
@@ -574,21 +495,28 @@ func (g *GameBooster) applyUltimatePowerPlan() error {
 		if guid == "" {
 			return fmt.Errorf("could not create or find ultimate performance plan: %s", string(out))
 		}
-		return cmd.Hidden("powercfg", "/setactive", guid).Run()
+		return runner.Command(context.Background(), "powercfg", "/setactive", guid).Run()
 	}
 
 	// Parse the new GUID from output like: "Power Scheme GUID: xxxx-xxxx (Ultimate Performance)"
 	guid := parseGUIDFromPowercfg(string(out))
 	if guid == "" {
 		// Fallback: search list
-		listOut, _ := cmd.Hidden("powercfg", "/list").CombinedOutput()
+		listOut, _ := runner.Command(context.Background(), "powercfg", "/list").CombinedOutput()
 		guid = findUltimatePlanGUID(string(listOut))
 	}
 	if guid == "" {
 		return fmt.Errorf("could not determine plan GUID from: %s", string(out))
 	}
 
-	return cmd.Hidden("powercfg", "/setactive", guid).Run()
+	return runner.Command(context.Background(), "powercfg", "/setactive", guid).Run()
+}
+
+// isUltimatePowerPlanActive reports whether the active power scheme is an
+// Ultimate Performance plan.
+func (g *GameBooster) isUltimatePowerPlanActive() bool {
+	out, err := runner.Command(context.Background(), "powercfg", "/getactivescheme").Output()
+	return err == nil && findUltimatePlanGUID(string(out)) != ""
 }
 
 func parseGUIDFromPowercfg(output string) string {
@@ -642,31 +570,14 @@ func isGUID(s string) bool {
 	return true
 }
 
-func (g *GameBooster) applyCoreParking() error {
-	// Disable core parking by setting ValueMax to 0 (0% cores parked)
-	return setRegDWORD(registry.LOCAL_MACHINE,
-		`SYSTEM\CurrentControlSet\Control\Power\PowerSettings\54533251-82be-4824-96c1-47b60b740d00\0cc5b647-c1df-4637-891a-dec35c318583`,
-		"ValueMax", 0)
-}
-
-func (g *GameBooster) applyDisableHPET() error {
-	return cmd.Hidden("bcdedit", "/deletevalue", "useplatformclock").Run()
-}
-
-func (g *GameBooster) applyTimerResolution() error {
-	// Use powershell to call NtSetTimerResolution for 0.5ms (5000 * 100ns = 0.5ms)
-	// This is a best-effort runtime tweak. Persist by setting the global timer.
-	return setRegDWORD(registry.LOCAL_MACHINE,
-		`SYSTEM\CurrentControlSet\Control\Session Manager\kernel`,
-		"GlobalTimerResolutionRequests", 1)
-}
-
-func (g *GameBooster) applyDisableSysMain() error {
-	return cmd.Hidden("sc", "stop", "SysMain").Run()
-}
-
-func (g *GameBooster) applyDisableIndexing() error {
-	return cmd.Hidden("sc", "stop", "WSearch").Run()
+func (g *GameBooster) planDisableHPET(s tweakSteps) error {
+	s.add(plan.Command("bcdedit", "/deletevalue", "useplatformclock"), func() error {
+		if err := s.rec.BootOption("useplatformclock"); err != nil {
+			return err
+		}
+		return runner.Command(context.Background(), "bcdedit", "/deletevalue", "useplatformclock").Run()
+	})
+	return nil
 }
 
 // KillBloatware terminates known bloatware processes.
@@ -679,7 +590,7 @@ func (g *GameBooster) KillBloatware(aggressive bool) ([]string, error) {
 
 	var killed []string
 	for _, proc := range list {
-		err := cmd.Hidden("taskkill", "/F", "/IM", proc).Run()
+		err := runner.Command(context.Background(), "taskkill", "/F", "/IM", proc).Run()
 		if err == nil {
 			killed = append(killed, proc)
 		}
@@ -687,15 +598,17 @@ func (g *GameBooster) KillBloatware(aggressive bool) ([]string, error) {
 	return killed, nil
 }
 
-func (g *GameBooster) applyKillBloatware() error {
-	_, err := g.KillBloatware(true)
-	return err
+// isHPETDisabled reports whether the boot configuration no longer forces
+// the platform clock.
+func (g *GameBooster) isHPETDisabled() bool {
+	out, err := runner.Command(context.Background(), "bcdedit", "/enum", "{current}").Output()
+	return err == nil && !strings.Contains(strings.ToLower(string(out)), "useplatformclock")
 }
 
-func (g *GameBooster) applyDisableNagle() error {
+func (g *GameBooster) planDisableNagle(s tweakSteps) error {
 	// Enumerate network interfaces and disable Nagle on each
 	basePath := `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces`
-	key, err := registry.OpenKey(registry.LOCAL_MACHINE, basePath, registry.ENUMERATE_SUB_KEYS)
+	key, err := registry.OpenKey(reg.LocalMachine, basePath, reg.EnumerateSubKeys)
 	if err != nil {
 		return fmt.Errorf("open interfaces key: %w", err)
 	}
@@ -708,88 +621,216 @@ func (g *GameBooster) applyDisableNagle() error {
 
 	for _, sk := range subkeys {
 		ifPath := basePath + `\` + sk
-		_ = setRegDWORD(registry.LOCAL_MACHINE, ifPath, "TcpAckFrequency", 1)
-		_ = setRegDWORD(registry.LOCAL_MACHINE, ifPath, "TCPNoDelay", 1)
+		s.setDWORD(reg.LocalMachine, ifPath, "TcpAckFrequency", 1)
+		s.setDWORD(reg.LocalMachine, ifPath, "TCPNoDelay", 1)
 	}
 
 	return nil
 }
 
-func (g *GameBooster) applyDNSOptimize() error {
-	_ = cmd.Hidden("ipconfig", "/flushdns").Run()
-	return nil
+// isNagleDisabled reports whether every network interface has Nagle's
+// algorithm turned off.
+func (g *GameBooster) isNagleDisabled() bool {
+	basePath := `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces`
+	key, err := registry.OpenKey(reg.LocalMachine, basePath, reg.EnumerateSubKeys)
+	if err != nil {
+		return false
+	}
+	defer key.Close()
+
+	subkeys, err := key.ReadSubKeyNames(-1)
+	if err != nil || len(subkeys) == 0 {
+		return false
+	}
+	for _, sk := range subkeys {
+		ifPath := basePath + `\` + sk
+		if !regDWORD(reg.LocalMachine, ifPath, "TcpAckFrequency", 1).isSet() ||
+			!regDWORD(reg.LocalMachine, ifPath, "TCPNoDelay", 1).isSet() {
+			return false
+		}
+	}
+	return true
 }
 
-func (g *GameBooster) applyFlushNetwork() error {
-	_ = cmd.Hidden("ipconfig", "/flushdns").Run()
-	_ = cmd.Hidden("nbtstat", "-R").Run()
-	_ = cmd.Hidden("netsh", "winsock", "reset").Run()
-	_ = cmd.Hidden("netsh", "int", "ip", "reset").Run()
-	return nil
+// ---------- Applied state ----------
+
+// isSet reports whether the registry holds v.
+func (v tweakValue) isSet() bool {
+	k, err := registry.OpenKey(v.root, v.path, reg.QueryValue)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	if v.isString {
+		got, _, err := k.GetStringValue(v.name)
+		return err == nil && got == v.str
+	}
+	got, _, err := k.GetIntegerValue(v.name)
+	return err == nil && got == uint64(v.dword)
 }
 
-func (g *GameBooster) applyCPUPriorityHigh() error {
-	// Win32PrioritySeparation = 38 (0x26) -> foreground apps get max priority boost
-	return setRegDWORD(registry.LOCAL_MACHINE,
-		`SYSTEM\CurrentControlSet\Control\PriorityControl`,
-		"Win32PrioritySeparation", 0x26)
+// probeable reports whether the state def puts the system in can be read
+// back. Commands leave nothing behind to read.
+func (def *tweakDef) probeable() bool {
+	return len(def.Values) > 0 || len(def.GPU) > 0 || len(def.Services) > 0 || def.IsApplied != nil
+}
+
+// isApplied reports whether the system is in the state def puts it in,
+// reading the registry, the services and whatever IsApplied reads. vendor
+// returns the GPU vendor, see DetectGPU. A tweak that is not probeable
+// counts as applied once it has run in this session.
+func (g *GameBooster) isApplied(def *tweakDef, vendor func() string) bool {
+	if !def.probeable() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.appliedTweaks[def.ID]
+	}
+
+	values := def.Values
+	if len(def.GPU) > 0 {
+		gpuValues := def.GPU[vendor()]
+		if len(gpuValues) == 0 {
+			return false
+		}
+		values = append(values[:len(values):len(values)], gpuValues...)
+	}
+	for _, v := range values {
+		if !v.isSet() {
+			return false
+		}
+	}
+	for _, name := range def.Services {
+		if plan.ServiceState(runner, name) != "stopped" {
+			return false
+		}
+	}
+	return def.IsApplied == nil || def.IsApplied(g)
+}
+
+// probeAll reads the applied state of every tweak in the catalog. The GPU is
+// detected at most once.
+func (g *GameBooster) probeAll() map[string]bool {
+	var vendor string
+	detected := false
+	vendorOnce := func() string {
+		if !detected {
+			detected = true
+			if gpu, err := g.DetectGPU(); err == nil {
+				vendor = gpu.Vendor
+			}
+		}
+		return vendor
+	}
+
+	applied := make(map[string]bool)
+	for i := range tweakCatalog {
+		if g.isApplied(&tweakCatalog[i], vendorOnce) {
+			applied[tweakCatalog[i].ID] = true
+		}
+	}
+	return applied
 }
 
 // ---------- Tweak dispatcher ----------
 
-func (g *GameBooster) applyTweakByID(id string) error {
-	switch id {
-	case "mouse_raw_input":
-		return g.applyMouseRawInput()
-	case "mouse_disable_acceleration":
-		return g.applyMouseDisableAcceleration()
-	case "disable_smooth_scrolling":
-		return g.applyDisableSmoothScrolling()
-	case "keyboard_repeat_max":
-		return g.applyKeyboardRepeatMax()
-	case "disable_sticky_keys":
-		return g.applyDisableStickyKeys()
-	case "disable_filter_keys":
-		return g.applyDisableFilterKeys()
-	case "disable_toggle_keys":
-		return g.applyDisableToggleKeys()
-	case "gpu_low_latency":
-		return g.applyGPULowLatency()
-	case "gpu_max_performance":
-		return g.applyGPUMaxPerformance()
-	case "disable_game_dvr":
-		return g.applyDisableGameDVR()
-	case "disable_game_bar":
-		return g.applyDisableGameBar()
-	case "disable_game_mode":
-		return g.applyDisableGameMode()
-	case "disable_fullscreen_optimize":
-		return g.applyDisableFullscreenOptimize()
-	case "ultimate_power_plan":
-		return g.applyUltimatePowerPlan()
-	case "core_parking_off":
-		return g.applyCoreParking()
-	case "disable_hpet":
-		return g.applyDisableHPET()
-	case "timer_resolution":
-		return g.applyTimerResolution()
-	case "disable_sysmain":
-		return g.applyDisableSysMain()
-	case "disable_indexing":
-		return g.applyDisableIndexing()
-	case "kill_bloatware":
-		return g.applyKillBloatware()
-	case "disable_nagle":
-		return g.applyDisableNagle()
-	case "dns_optimize":
-		return g.applyDNSOptimize()
-	case "flush_network":
-		return g.applyFlushNetwork()
-	case "cpu_priority_high":
-		return g.applyCPUPriorityHigh()
-	default:
+// planTweakByID adds the changes a tweak makes to p. Nothing is changed until
+// the plan is executed.
+func (g *GameBooster) planTweakByID(p *plan.Plan, id string) error {
+	def := findTweak(id)
+	if def == nil {
 		return fmt.Errorf("unknown tweak: %s", id)
 	}
+	return g.planTweak(newTweakSteps(p, id), def)
+}
+
+// planTweak adds the steps def describes, in the order of its fields.
+func (g *GameBooster) planTweak(s tweakSteps, def *tweakDef) error {
+	values := def.Values
+	if len(def.GPU) > 0 {
+		gpu, err := g.DetectGPU()
+		if err != nil {
+			return err
+		}
+		values = append(values[:len(values):len(values)], def.GPU[gpu.Vendor]...)
+	}
+	for _, v := range values {
+		if v.isString {
+			s.setString(v.root, v.path, v.name, v.str)
+		} else {
+			s.setDWORD(v.root, v.path, v.name, v.dword)
+		}
+	}
+	for _, name := range def.Services {
+		s.stopService(name)
+	}
+	for _, c := range def.Commands {
+		s.tryRun(c[0], c[1:]...)
+	}
+	if def.Steps != nil {
+		return def.Steps(g, s)
+	}
+	return nil
+}
+
+// planTweaks builds a plan for a set of tweaks. A tweak that cannot be
+// planned, e.g. because GPU detection failed, is left out with a warning.
+// The plan creates a restore point before it changes anything, passes the
+// tweaks that were fully applied to done and then saves the boost session,
+// if there is one. If no tweak was applied, done is not called and no
+// session is started or saved.
+func (g *GameBooster) planTweaks(description string, ids []string, done func(applied []string)) *plan.Plan {
+	p := plan.New(description)
+	planned := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if err := g.planTweakByID(p, id); err != nil {
+			p.Warn("%s: %v", id, err)
+			continue
+		}
+		planned = append(planned, id)
+	}
+
+	var restorePoint string
+	p.Before(func() error {
+		rp, err := journal.CreateRestorePoint("Before: " + description)
+		if err != nil {
+			return fmt.Errorf("failed to create restore point: %w", err)
+		}
+		restorePoint = rp.ID
+		return nil
+	})
+	p.After(func(r *plan.Result) {
+		failed := make(map[string]bool)
+		for _, f := range r.Failures {
+			failed[f.Action.Tweak] = true
+		}
+		var applied []string
+		for _, id := range planned {
+			if !failed[id] {
+				applied = append(applied, id)
+			}
+		}
+
+		if len(applied) == 0 {
+			return
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		for _, id := range applied {
+			g.appliedTweaks[id] = true
+		}
+		done(applied)
+		if !g.status.Active {
+			return
+		}
+		if g.status.RestorePoint == "" {
+			g.status.RestorePoint = restorePoint
+		}
+		if err := saveSession(g.status); err != nil {
+			r.Warnings = append(r.Warnings, err.Error())
+		}
+	})
+	return p
 }
 
 // ---------- Public API ----------
@@ -810,116 +851,233 @@ func (g *GameBooster) GetProfiles() []GameProfile {
 	return result
 }
 
-// ApplyProfile backs up the current state and then applies all tweaks in a profile.
-func (g *GameBooster) ApplyProfile(profileID string) error {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
+// PlanProfile lists every change ApplyProfile would make for a profile,
+// without making any. Executing the plan creates a restore point, applies
+// the changes and updates the boost status.
+func (g *GameBooster) PlanProfile(profileID string) (*plan.Plan, error) {
 	profile := profiles.GetProfileByID(profileID)
 	if profile == nil {
-		return fmt.Errorf("unknown profile: %s", profileID)
+		return nil, fmt.Errorf("unknown profile: %s", profileID)
 	}
 
-	// Backup before applying
-	if err := g.BackupCurrentState(); err != nil {
-		return fmt.Errorf("backup failed: %w", err)
-	}
-
-	var applied []string
-	var errs []string
-
+	var ids []string
 	for tweakID, enabled := range profile.Tweaks {
-		if !enabled {
-			continue
-		}
-		if err := g.applyTweakByID(tweakID); err != nil {
-			errs = append(errs, fmt.Sprintf("%s: %v", tweakID, err))
-		} else {
-			applied = append(applied, tweakID)
-			g.appliedTweaks[tweakID] = true
+		if enabled {
+			ids = append(ids, tweakID)
 		}
 	}
+	sort.Strings(ids)
+
+	return g.planTweaks("Apply game profile "+profile.Name, ids, func(applied []string) {
+		g.status = BoostStatus{
+			Active:        true,
+			Profile:       profileID,
+			TweaksApplied: applied,
+			StartedAt:     time.Now().Format(time.RFC3339),
+		}
+	}), nil
+}
 
-	g.status = BoostStatus{
-		Active:        true,
-		Profile:       profileID,
-		TweaksApplied: applied,
-		StartedAt:     time.Now().Format(time.RFC3339),
-	}
-
-	if len(errs) > 0 {
-		return fmt.Errorf("profile applied with errors: %s", strings.Join(errs, "; "))
+// ApplyProfile creates a restore point and then applies all tweaks in a
+// profile, recording each change in the backup journal.
+func (g *GameBooster) ApplyProfile(profileID string) error {
+	p, err := g.PlanProfile(profileID)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return execute(p)
 }
 
 // ApplyGPUProfile detects the GPU and applies vendor-specific tweaks.
 func (g *GameBooster) ApplyGPUProfile() error {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	if err := g.BackupCurrentState(); err != nil {
-		return fmt.Errorf("backup failed: %w", err)
-	}
-
-	if err := g.applyGPUMaxPerformance(); err != nil {
-		return err
-	}
-	g.appliedTweaks["gpu_max_performance"] = true
+	ids := []string{"gpu_max_performance", "gpu_low_latency"}
+	return execute(g.planTweaks("Apply GPU profile", ids, func([]string) {}))
+}
 
-	if err := g.applyGPULowLatency(); err != nil {
+// execute applies a plan and folds its failures into one error.
+func execute(p *plan.Plan) error {
+	r, err := plan.Execute(p)
+	if err != nil {
 		return err
 	}
-	g.appliedTweaks["gpu_low_latency"] = true
-
-	return nil
+	return r.Err()
 }
 
-// GetAvailableTweaks returns all tweaks with their current state.
+// GetAvailableTweaks returns all tweaks with their current state, read from
+// the system.
 func (g *GameBooster) GetAvailableTweaks() []TweakInfo {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+	applied := g.probeAll()
+	revertible := journalTweaks()
 
 	result := make([]TweakInfo, len(tweakCatalog))
 	for i, td := range tweakCatalog {
-		applied := g.appliedTweaks[td.ID]
 		result[i] = TweakInfo{
 			ID:          td.ID,
 			Name:        td.Name,
 			Description: td.Description,
 			Category:    td.Category,
 			Enabled:     true,
-			Applied:     applied,
+			Applied:     applied[td.ID],
+			Revertible:  revertible[td.ID],
 		}
 	}
 	return result
 }
 
-// ApplyTweak applies a single tweak by ID, backing up first.
-func (g *GameBooster) ApplyTweak(tweakID string) error {
+// journalTweaks returns the tweaks with changes in the journal that have not
+// been restored.
+func journalTweaks() map[string]bool {
+	tweaks := make(map[string]bool)
+	entries, err := journal.Entries()
+	if err != nil {
+		return tweaks
+	}
+	for _, e := range entries {
+		if e.Module == "gaming" {
+			tweaks[e.Tweak] = true
+		}
+	}
+	return tweaks
+}
+
+// DetectStatus rebuilds the boost status from the system, for when the app
+// starts while an earlier boost is still in effect. A tweak counts as part of
+// the boost when the system is in the state it sets and the journal still
+// holds the changes it made, so settings that merely match a tweak are not
+// taken for a boost. The session saved by the earlier run supplies the
+// profile, start time and restore point, and keeps those of its tweaks that
+// are still in effect; without one, the profile is the one made up of exactly
+// the tweaks found, or "custom". A boost already started by this GameBooster
+// is kept as is.
+func (g *GameBooster) DetectStatus() *BoostStatus {
+	saved, _ := LoadSession()
+	probed := g.probeAll()
+	recorded := journalTweaks()
+	var ids []string
+	for _, td := range tweakCatalog {
+		if probed[td.ID] && recorded[td.ID] {
+			ids = append(ids, td.ID)
+		}
+	}
+
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	for _, id := range ids {
+		g.appliedTweaks[id] = true
+	}
+	if g.status.Active {
+		status := g.status
+		return &status
+	}
+
+	switch {
+	case saved != nil:
+		var kept []string
+		for _, id := range saved.TweaksApplied {
+			if probed[id] && recorded[id] {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) > 0 {
+			g.status = *saved
+			g.status.TweaksApplied = kept
+		}
+		// Otherwise the boost was undone, e.g. by a restart for
+		// tweaks like stopped services, and the session is over
+		_ = saveSession(g.status)
+	case len(ids) > 0:
+		g.status = BoostStatus{
+			Active:        true,
+			Profile:       matchProfile(ids),
+			TweaksApplied: ids,
+		}
+		_ = saveSession(g.status)
+	}
+	status := g.status
+	return &status
+}
+
+// matchProfile returns the profile whose probeable tweaks are exactly ids,
+// or "custom".
+func matchProfile(ids []string) string {
+	want := strings.Join(ids, ",")
+	for _, p := range profiles.AllProfiles() {
+		var tweaks []string
+		for _, td := range tweakCatalog {
+			if p.Tweaks[td.ID] && td.probeable() {
+				tweaks = append(tweaks, td.ID)
+			}
+		}
+		if strings.Join(tweaks, ",") == want {
+			return p.ID
+		}
+	}
+	return "custom"
+}
+
+// ApplyTweak creates a restore point and then applies a single tweak by ID,
+// recording its changes in the backup journal.
+func (g *GameBooster) ApplyTweak(tweakID string) error {
+	if findTweak(tweakID) == nil {
+		return fmt.Errorf("unknown tweak: %s", tweakID)
+	}
+
+	return execute(g.planTweaks("Apply tweak "+tweakID, []string{tweakID}, func(applied []string) {
+		// Update status
+		if !g.status.Active {
+			g.status.Active = true
+			g.status.StartedAt = time.Now().Format(time.RFC3339)
+			g.status.Profile = "custom"
+		}
+		if !slices.Contains(g.status.TweaksApplied, tweakID) {
+			g.status.TweaksApplied = append(g.status.TweaksApplied, tweakID)
+		}
+	}))
+}
 
-	if err := g.BackupCurrentState(); err != nil {
-		return fmt.Errorf("backup failed: %w", err)
+// RevertTweak undoes the changes a single tweak recorded in the journal,
+// leaving those of other tweaks in place, and drops it from the boost
+// status and the saved session. Commands the tweak ran, such as killing
+// processes, are not undone. The boost status keeps only the tweaks that
+// are still in effect afterwards, read from the system.
+func (g *GameBooster) RevertTweak(tweakID string) error {
+	if findTweak(tweakID) == nil {
+		return fmt.Errorf("unknown tweak: %s", tweakID)
 	}
 
-	if err := g.applyTweakByID(tweakID); err != nil {
+	g.mu.Lock()
+	err := journal.RestoreTweak("gaming", tweakID)
+	if err == nil {
+		delete(g.appliedTweaks, tweakID)
+	}
+	g.mu.Unlock()
+	if err != nil {
 		return err
 	}
 
-	g.appliedTweaks[tweakID] = true
+	// Read outside g.mu, which isApplied takes
+	probed := g.probeAll()
+	recorded := journalTweaks()
 
-	// Update status
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	if !g.status.Active {
-		g.status.Active = true
-		g.status.StartedAt = time.Now().Format(time.RFC3339)
-		g.status.Profile = "custom"
+		return nil
 	}
-	g.status.TweaksApplied = append(g.status.TweaksApplied, tweakID)
-
-	return nil
+	var remaining []string
+	for _, id := range g.status.TweaksApplied {
+		def := findTweak(id)
+		if probed[id] && def != nil && (recorded[id] || !def.probeable()) {
+			remaining = append(remaining, id)
+		}
+	}
+	if len(remaining) == 0 && len(g.status.TweaksApplied) > 0 {
+		// Nothing of the boost is left
+		g.status = BoostStatus{}
+	} else {
+		g.status.TweaksApplied = remaining
+	}
+	return saveSession(g.status)
 }
 
 // GetBoostStatus returns the current boost state.
@@ -930,23 +1088,21 @@ func (g *GameBooster) GetBoostStatus() *BoostStatus {
 	return &status
 }
 
-// RestoreAll restores the original system state from backup.
-// Always clears the in-memory boost state regardless of restore errors,
-// so the user can re-apply or see the boost as inactive.
+// RestoreAll undoes every change the game booster recorded in the journal.
+// Always clears the boost state and the saved session regardless of restore
+// errors, so the user can re-apply or see the boost as inactive.
 func (g *GameBooster) RestoreAll() error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	// Attempt to restore original settings from backup
-	restoreErr := g.RestoreOriginalState()
-
-	// Always re-enable services that were stopped (even if restore had errors)
-	_ = cmd.Hidden("sc", "start", "SysMain").Run()
-	_ = cmd.Hidden("sc", "start", "WSearch").Run()
+	restoreErr := journal.RestoreModule("gaming")
 
 	// Always clear state so the UI reflects boost as inactive
 	g.status = BoostStatus{}
 	g.appliedTweaks = make(map[string]bool)
 
+	if err := saveSession(g.status); err != nil && restoreErr == nil {
+		return err
+	}
 	return restoreErr
 }
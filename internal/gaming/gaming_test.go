@@ -1,9 +1,59 @@
 package gaming
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"cleanforge/internal/backup"
+	"cleanforge/internal/cmd"
+	"cleanforge/internal/plan"
+	"cleanforge/internal/reg"
 )
 
+// useFakeRunner swaps the package runner for a cmd.Fake for the duration of the test.
+func useFakeRunner(t *testing.T) *cmd.Fake {
+	t.Helper()
+	fake := cmd.NewFake()
+	prev := runner
+	runner = fake
+	t.Cleanup(func() { runner = prev })
+	return fake
+}
+
+// useMemoryRegistry swaps the package registry for a reg.Memory seeded from
+// the given .reg fixture for the duration of the test.
+func useMemoryRegistry(t *testing.T, fixture string) *reg.Memory {
+	t.Helper()
+	mem, err := reg.FromReg(fixture)
+	if err != nil {
+		t.Fatalf("load registry fixture: %v", err)
+	}
+	prev := registry
+	registry = mem
+	t.Cleanup(func() { registry = prev })
+	return mem
+}
+
+// useJournal swaps the package journal for one in a temp directory that
+// reads and restores through the current (usually fake) registry and runner.
+// The boost session is kept in that directory too. Call it after
+// useMemoryRegistry and useFakeRunner.
+func useJournal(t *testing.T) *backup.Journal {
+	t.Helper()
+	dir := t.TempDir()
+	j := backup.NewJournal(dir, registry, runner)
+	prev, prevSession := journal, sessionPath
+	journal = j
+	sessionPath = filepath.Join(dir, "boost-session.json")
+	t.Cleanup(func() { journal, sessionPath = prev, prevSession })
+	return j
+}
+
+// activeSchemeOutput is `powercfg /getactivescheme` output for the Balanced plan.
+const activeSchemeOutput = "Power Scheme GUID: 381b4222-f694-41f0-9685-ff5bb260df2e  (Balanced)"
+
 func TestNewGameBooster(t *testing.T) {
 	gb := NewGameBooster()
 
@@ -14,10 +64,6 @@ func TestNewGameBooster(t *testing.T) {
 	if gb.appliedTweaks == nil {
 		t.Error("appliedTweaks map not initialized")
 	}
-
-	if gb.backupPath == "" {
-		t.Error("backupPath is empty")
-	}
 }
 
 func TestGetBoostStatus(t *testing.T) {
@@ -332,3 +378,548 @@ func TestTweakCatalog(t *testing.T) {
 		seenIDs[tw.ID] = true
 	}
 }
+
+func TestApplyUltimatePowerPlanDuplicates(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("powercfg", "/getactivescheme").Returns(activeSchemeOutput)
+	fake.On("powercfg", "/duplicatescheme").Returns("Power Scheme GUID: 0a1b2c3d-1111-2222-3333-444455556666  (Ultimate Performance)")
+	fake.On("powercfg", "/setactive").Returns("")
+
+	gb := NewGameBooster()
+	if err := gb.applyUltimatePowerPlan(useJournal(t).For("gaming", "ultimate_power_plan")); err != nil {
+		t.Fatalf("applyUltimatePowerPlan returned error: %v", err)
+	}
+	if !fake.Called("powercfg", "/setactive", "0a1b2c3d-1111-2222-3333-444455556666") {
+		t.Errorf("expected duplicated plan to be activated, calls: %v", fake.Calls())
+	}
+}
+
+func TestApplyUltimatePowerPlanFallsBackToList(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("powercfg", "/getactivescheme").Returns(activeSchemeOutput)
+	fake.On("powercfg", "/duplicatescheme").Fails(1, "Unable to perform operation")
+	fake.On("powercfg", "/list").Returns(`Existing Power Schemes (* denotes currently active)
+Power Scheme GUID: 381b4222-f694-41f0-9685-ff5bb260df2e  (Balanced) *
+Power Scheme GUID: e9a42b02-d5df-448d-aa00-03f14749eb61  (Ultimate Performance)`)
+	fake.On("powercfg", "/setactive").Returns("")
+
+	gb := NewGameBooster()
+	if err := gb.applyUltimatePowerPlan(useJournal(t).For("gaming", "ultimate_power_plan")); err != nil {
+		t.Fatalf("applyUltimatePowerPlan returned error: %v", err)
+	}
+	if !fake.Called("powercfg", "/setactive", "e9a42b02-d5df-448d-aa00-03f14749eb61") {
+		t.Errorf("expected existing ultimate plan to be activated, calls: %v", fake.Calls())
+	}
+}
+
+func TestApplyUltimatePowerPlanNotFound(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("powercfg", "/getactivescheme").Returns(activeSchemeOutput)
+	fake.On("powercfg", "/duplicatescheme").Fails(1, "Unable to perform operation")
+	fake.On("powercfg", "/list").Returns("Power Scheme GUID: 381b4222-f694-41f0-9685-ff5bb260df2e  (Balanced) *")
+
+	gb := NewGameBooster()
+	if err := gb.applyUltimatePowerPlan(useJournal(t).For("gaming", "ultimate_power_plan")); err == nil {
+		t.Fatal("expected error when no ultimate plan can be created or found")
+	}
+	if fake.Called("powercfg", "/setactive") {
+		t.Error("no plan should be activated when none was found")
+	}
+}
+
+func TestDetectGPUWithFakeRunner(t *testing.T) {
+	tests := []struct {
+		name   string
+		csv    string
+		vendor string
+	}{
+		{"NVIDIA", "Node,DriverVersion,Name\r\nPC,31.0.15.3623,NVIDIA GeForce RTX 3080\r\n", "nvidia"},
+		{"AMD", "Node,DriverVersion,Name\r\nPC,31.0.21001.45002,AMD Radeon RX 6800\r\n", "amd"},
+		{"Intel", "Node,DriverVersion,Name\r\nPC,31.0.101.4502,Intel(R) UHD Graphics 770\r\n", "intel"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeRunner(t)
+			fake.On("wmic").Returns(tt.csv)
+
+			info, err := NewGameBooster().DetectGPU()
+			if err != nil {
+				t.Fatalf("DetectGPU returned error: %v", err)
+			}
+			if info.Vendor != tt.vendor {
+				t.Errorf("Vendor = %q, want %q", info.Vendor, tt.vendor)
+			}
+		})
+	}
+}
+
+func TestDisableNagleTweak(t *testing.T) {
+	mem := useMemoryRegistry(t, `
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{A}]
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{B}]
+`)
+	useJournal(t)
+	gb := NewGameBooster()
+	if err := gb.ApplyTweak("disable_nagle"); err != nil {
+		t.Fatalf("ApplyTweak(disable_nagle): %v", err)
+	}
+
+	for _, iface := range []string{"{A}", "{B}"} {
+		k, err := mem.OpenKey(reg.LocalMachine, `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\`+iface, reg.QueryValue)
+		if err != nil {
+			t.Fatalf("open %s: %v", iface, err)
+		}
+		for _, name := range []string{"TcpAckFrequency", "TCPNoDelay"} {
+			if v, _, err := k.GetIntegerValue(name); err != nil || v != 1 {
+				t.Errorf("%s %s = %d, %v, want 1", iface, name, v, err)
+			}
+		}
+		k.Close()
+	}
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	mem := useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\Control Panel\Mouse]
+"MouseSpeed"="1"
+"MouseThreshold1"="6"
+
+[HKEY_CURRENT_USER\System\GameConfigStore]
+"GameDVR_Enabled"=dword:00000001
+`)
+	fake := useFakeRunner(t)
+	fake.On("sc", "qc", "SysMain").Returns("        START_TYPE         : 2   AUTO_START")
+	fake.On("sc", "query", "SysMain").Returns("        STATE              : 4  RUNNING")
+	fake.On("sc", "stop", "SysMain").Returns("")
+	fake.On("sc", "config", "SysMain").Returns("")
+	fake.On("sc", "start", "SysMain").Returns("")
+	fake.On("sc", "qc", "WSearch").Returns("        START_TYPE         : 2   AUTO_START")
+	fake.On("sc", "query", "WSearch").Returns("        STATE              : 1  STOPPED")
+	fake.On("sc", "stop", "WSearch").Returns("")
+	fake.On("sc", "config", "WSearch").Returns("")
+	j := useJournal(t)
+
+	gb := NewGameBooster()
+	for _, id := range []string{"mouse_disable_acceleration", "disable_game_dvr", "disable_sysmain", "disable_indexing"} {
+		if err := gb.ApplyTweak(id); err != nil {
+			t.Fatalf("ApplyTweak(%s): %v", id, err)
+		}
+	}
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	for _, e := range entries {
+		if e.Module != "gaming" || e.Tweak == "" {
+			t.Errorf("entry %s attributed to %q/%q", e.Target, e.Module, e.Tweak)
+		}
+	}
+
+	if err := gb.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	if !fake.Called("sc", "start", "SysMain") {
+		t.Error("SysMain was running before the tweak and should be restarted")
+	}
+	if j.HasEntries() {
+		t.Error("journal should be empty after RestoreAll")
+	}
+
+	mouse, _ := mem.OpenKey(reg.CurrentUser, `Control Panel\Mouse`, reg.QueryValue)
+	if v, _, _ := mouse.GetStringValue("MouseSpeed"); v != "1" {
+		t.Errorf("MouseSpeed = %q, want %q", v, "1")
+	}
+	if _, _, err := mouse.GetStringValue("MouseThreshold2"); err == nil {
+		t.Error("MouseThreshold2 did not exist before the tweak and should have been deleted")
+	}
+
+	store, _ := mem.OpenKey(reg.CurrentUser, `System\GameConfigStore`, reg.QueryValue)
+	if v, typ, _ := store.GetIntegerValue("GameDVR_Enabled"); v != 1 || typ != reg.DWord {
+		t.Errorf("GameDVR_Enabled = %d (type %d), want 1 (DWORD)", v, typ)
+	}
+
+	if fake.Called("sc", "start", "WSearch") {
+		t.Error("restore started WSearch, which was stopped at backup time")
+	}
+}
+
+func TestRevertTweak(t *testing.T) {
+	mem := useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\Control Panel\Mouse]
+"MouseSpeed"="1"
+
+[HKEY_CURRENT_USER\System\GameConfigStore]
+"GameDVR_Enabled"=dword:00000001
+`)
+	fake := useFakeRunner(t)
+	fake.On("sc", "qc", "SysMain").Returns("        START_TYPE         : 2   AUTO_START")
+	fake.On("sc", "query", "SysMain").Returns("        STATE              : 4  RUNNING")
+	fake.On("sc", "stop", "SysMain").Returns("")
+	j := useJournal(t)
+
+	gb := NewGameBooster()
+	for _, id := range []string{"mouse_raw_input", "disable_game_dvr", "disable_sysmain"} {
+		if err := gb.ApplyTweak(id); err != nil {
+			t.Fatalf("ApplyTweak(%s): %v", id, err)
+		}
+	}
+
+	// The journal keeps restoring through fake; the booster now reads SysMain stopped
+	useFakeRunner(t).On("sc", "query", "SysMain").Returns("        STATE              : 1  STOPPED")
+	if err := gb.RevertTweak("mouse_raw_input"); err != nil {
+		t.Fatalf("RevertTweak: %v", err)
+	}
+	mouse, _ := mem.OpenKey(reg.CurrentUser, `Control Panel\Mouse`, reg.QueryValue)
+	if v, _, _ := mouse.GetStringValue("MouseSpeed"); v != "1" {
+		t.Errorf("MouseSpeed = %q, want %q", v, "1")
+	}
+	store, _ := mem.OpenKey(reg.CurrentUser, `System\GameConfigStore`, reg.QueryValue)
+	if v, _, _ := store.GetIntegerValue("GameDVR_Enabled"); v != 0 {
+		t.Errorf("GameDVR_Enabled = %d, want 0: only mouse_raw_input was reverted", v)
+	}
+	if fake.Called("sc", "config", "SysMain") || fake.Called("sc", "start", "SysMain") {
+		t.Error("reverting a mouse tweak touched SysMain")
+	}
+
+	status := gb.GetBoostStatus()
+	if !status.Active || len(status.TweaksApplied) != 2 {
+		t.Errorf("status = %+v, want the two other tweaks still applied", status)
+	}
+	for _, tw := range gb.GetAvailableTweaks() {
+		want := tw.ID == "disable_game_dvr" || tw.ID == "disable_sysmain"
+		if tw.Applied != want || tw.Revertible != want {
+			t.Errorf("%s: applied %v, revertible %v, want %v", tw.ID, tw.Applied, tw.Revertible, want)
+		}
+	}
+
+	fake.On("sc", "config", "SysMain").Returns("")
+	fake.On("sc", "start", "SysMain").Returns("")
+	for _, id := range []string{"disable_game_dvr", "disable_sysmain"} {
+		if err := gb.RevertTweak(id); err != nil {
+			t.Fatalf("RevertTweak(%s): %v", id, err)
+		}
+	}
+	if gb.GetBoostStatus().Active {
+		t.Error("boost should be inactive once every tweak is reverted")
+	}
+	if j.HasEntries() {
+		t.Error("journal should be empty once every tweak is reverted")
+	}
+	if err := gb.RevertTweak("no_such_tweak"); err == nil {
+		t.Error("RevertTweak of an unknown tweak should fail")
+	}
+}
+
+func TestRevertTweakSharingAValue(t *testing.T) {
+	mem := useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\Control Panel\Mouse]
+"MouseSpeed"="1"
+"MouseThreshold1"="6"
+"MouseThreshold2"="10"
+`)
+	useFakeRunner(t)
+	useJournal(t)
+
+	gb := NewGameBooster()
+	for _, id := range []string{"mouse_raw_input", "mouse_disable_acceleration", "mouse_disable_acceleration"} {
+		if err := gb.ApplyTweak(id); err != nil {
+			t.Fatalf("ApplyTweak(%s): %v", id, err)
+		}
+	}
+	want := "mouse_raw_input,mouse_disable_acceleration"
+	if got := strings.Join(gb.GetBoostStatus().TweaksApplied, ","); got != want {
+		t.Errorf("TweaksApplied = %s, want %s", got, want)
+	}
+
+	// Both set MouseSpeed; reverting one must leave the other whole
+	if err := gb.RevertTweak("mouse_raw_input"); err != nil {
+		t.Fatalf("RevertTweak: %v", err)
+	}
+	mouse, _ := mem.OpenKey(reg.CurrentUser, `Control Panel\Mouse`, reg.QueryValue)
+	if v, _, _ := mouse.GetStringValue("MouseSpeed"); v != "0" {
+		t.Errorf("MouseSpeed = %q, want it left at 0 for mouse_disable_acceleration", v)
+	}
+	for _, tw := range gb.GetAvailableTweaks() {
+		if tw.ID == "mouse_disable_acceleration" && (!tw.Applied || !tw.Revertible) {
+			t.Errorf("mouse_disable_acceleration: applied %v, revertible %v, want both", tw.Applied, tw.Revertible)
+		}
+		if tw.ID == "mouse_raw_input" && tw.Revertible {
+			t.Error("mouse_raw_input was reverted and should have nothing left to revert")
+		}
+	}
+	if got := strings.Join(gb.GetBoostStatus().TweaksApplied, ","); got != "mouse_disable_acceleration" {
+		t.Errorf("TweaksApplied = %s, want mouse_disable_acceleration", got)
+	}
+
+	if err := gb.RevertTweak("mouse_disable_acceleration"); err != nil {
+		t.Fatalf("RevertTweak: %v", err)
+	}
+	for name, want := range map[string]string{"MouseSpeed": "1", "MouseThreshold1": "6", "MouseThreshold2": "10"} {
+		if v, _, _ := mouse.GetStringValue(name); v != want {
+			t.Errorf("%s = %q after reverting both, want %q", name, v, want)
+		}
+	}
+	if gb.GetBoostStatus().Active {
+		t.Error("boost should be inactive once both tweaks are reverted")
+	}
+}
+
+func TestDetectStatusAfterRestart(t *testing.T) {
+	useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\System\GameConfigStore]
+"GameDVR_Enabled"=dword:00000001
+`)
+	fake := useFakeRunner(t)
+	fake.On("sc", "qc", "SysMain").Returns("        START_TYPE         : 2   AUTO_START")
+	fake.On("sc", "query", "SysMain").Returns("        STATE              : 4  RUNNING")
+	fake.On("sc", "stop", "SysMain").Returns("")
+	useJournal(t)
+	if err := NewGameBooster().ApplyProfile("casual"); err != nil {
+		t.Fatalf("ApplyProfile: %v", err)
+	}
+
+	saved, err := LoadSession()
+	if err != nil || saved == nil {
+		t.Fatalf("LoadSession = %v, %v, want the casual session", saved, err)
+	}
+
+	// A new booster, as after the app restarts, with SysMain now stopped
+	useFakeRunner(t).On("sc", "query", "SysMain").Returns("        STATE              : 1  STOPPED")
+	gb := NewGameBooster()
+	if gb.GetBoostStatus().Active {
+		t.Fatal("a new booster should not report a boost before DetectStatus")
+	}
+
+	status := gb.DetectStatus()
+	want := []string{"disable_game_bar", "disable_game_dvr", "disable_sysmain"}
+	if !status.Active || status.Profile != "casual" || strings.Join(status.TweaksApplied, ",") != strings.Join(want, ",") {
+		t.Errorf("status = %+v, want casual with %v", status, want)
+	}
+	if status.StartedAt != saved.StartedAt || status.RestorePoint == "" || status.RestorePoint != saved.RestorePoint {
+		t.Errorf("status = %+v, want the start time and restore point of the saved session %+v", status, saved)
+	}
+	for _, tw := range gb.GetAvailableTweaks() {
+		if tw.ID == "disable_game_dvr" && !tw.Applied {
+			t.Error("disable_game_dvr should read as applied from the registry")
+		}
+		if tw.ID == "kill_bloatware" && tw.Applied {
+			t.Error("kill_bloatware leaves nothing to read and was not run by this booster")
+		}
+	}
+}
+
+func TestDetectStatusEndsUndoneSession(t *testing.T) {
+	useMemoryRegistry(t, "")
+	fake := useFakeRunner(t)
+	fake.On("sc", "qc", "SysMain").Returns("        START_TYPE         : 2   AUTO_START")
+	fake.On("sc", "query", "SysMain").Returns("        STATE              : 4  RUNNING")
+	fake.On("sc", "stop", "SysMain").Returns("")
+	useJournal(t)
+	if err := NewGameBooster().ApplyTweak("disable_sysmain"); err != nil {
+		t.Fatalf("ApplyTweak: %v", err)
+	}
+
+	// After a reboot SysMain runs again, so nothing of the boost is left
+	if status := NewGameBooster().DetectStatus(); status.Active {
+		t.Errorf("status = %+v, want inactive", status)
+	}
+	if saved, err := LoadSession(); err != nil || saved != nil {
+		t.Errorf("LoadSession = %+v, %v, want the session cleared", saved, err)
+	}
+}
+
+func TestDetectStatusIgnoresMatchingDefaults(t *testing.T) {
+	useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\Control Panel\Accessibility\StickyKeys]
+"Flags"="506"
+`)
+	useFakeRunner(t)
+	useJournal(t)
+
+	gb := NewGameBooster()
+	if status := gb.DetectStatus(); status.Active {
+		t.Errorf("status = %+v, want inactive: nothing was recorded in the journal", status)
+	}
+	for _, tw := range gb.GetAvailableTweaks() {
+		if want := tw.ID == "disable_sticky_keys"; tw.Applied != want {
+			t.Errorf("%s: applied %v, want %v", tw.ID, tw.Applied, want)
+		}
+	}
+}
+
+func TestApplyTweakCreatesRestorePoints(t *testing.T) {
+	mem := useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\System\GameConfigStore]
+"GameDVR_Enabled"=dword:00000001
+`)
+	useFakeRunner(t)
+	j := useJournal(t)
+
+	gb := NewGameBooster()
+	for _, id := range []string{"disable_game_dvr", "mouse_disable_acceleration", "disable_game_dvr"} {
+		if err := gb.ApplyTweak(id); err != nil {
+			t.Fatalf("ApplyTweak(%s): %v", id, err)
+		}
+	}
+
+	points, err := j.RestorePoints()
+	if err != nil {
+		t.Fatalf("RestorePoints: %v", err)
+	}
+	// The first tweak starts from the pristine point; the other two each add one.
+	if len(points) != 3 || points[0].ID != backup.PristineID {
+		t.Fatalf("points = %+v, want pristine plus two", points)
+	}
+
+	if err := j.RestoreTo(points[2].ID); err != nil {
+		t.Fatalf("RestoreTo: %v", err)
+	}
+	mouse, err := mem.OpenKey(reg.CurrentUser, `Control Panel\Mouse`, reg.QueryValue)
+	if err != nil {
+		t.Fatalf("mouse tweak should be kept: %v", err)
+	}
+	if v, _, _ := mouse.GetStringValue("MouseSpeed"); v != "0" {
+		t.Errorf("MouseSpeed = %q, want the tweaked value %q", v, "0")
+	}
+
+	// Applying DVR twice must not lose the value from before the first apply.
+	if err := j.RestoreTo(backup.PristineID); err != nil {
+		t.Fatalf("RestoreTo(pristine): %v", err)
+	}
+	store, _ := mem.OpenKey(reg.CurrentUser, `System\GameConfigStore`, reg.QueryValue)
+	if v, _, _ := store.GetIntegerValue("GameDVR_Enabled"); v != 1 {
+		t.Errorf("GameDVR_Enabled = %d after restoring pristine, want 1", v)
+	}
+}
+
+func TestPlanProfileMakesNoChanges(t *testing.T) {
+	mem := useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\System\GameConfigStore]
+"GameDVR_Enabled"=dword:00000001
+`)
+	fake := useFakeRunner(t)
+	fake.On("sc", "qc", "SysMain").Returns("        START_TYPE         : 2   AUTO_START")
+	fake.On("sc", "query", "SysMain").Returns("        STATE              : 4  RUNNING")
+	fake.On("sc", "stop", "SysMain").Returns("")
+	j := useJournal(t)
+
+	gb := NewGameBooster()
+	p, err := gb.PlanProfile("casual")
+	if err != nil {
+		t.Fatalf("PlanProfile: %v", err)
+	}
+
+	lines := make(map[string]bool)
+	for _, a := range p.Actions {
+		lines[a.String()] = true
+	}
+	for _, want := range []string{
+		`HKCU\System\GameConfigStore\GameDVR_Enabled: 1 → 0`,
+		`HKCU\Software\Microsoft\GameBar\UseNexusForGameBarEnabled: not set → 0`,
+		"service SysMain: running → stopped",
+		"run taskkill /F /IM OneDrive.exe",
+	} {
+		if !lines[want] {
+			t.Errorf("plan is missing %q", want)
+		}
+	}
+
+	if fake.Called("sc", "stop") || fake.Called("taskkill") {
+		t.Errorf("planning ran a mutating command: %v", fake.Calls())
+	}
+	if j.HasEntries() {
+		t.Error("planning wrote to the journal")
+	}
+	store, _ := mem.OpenKey(reg.CurrentUser, `System\GameConfigStore`, reg.QueryValue)
+	if v, _, _ := store.GetIntegerValue("GameDVR_Enabled"); v != 1 {
+		t.Errorf("GameDVR_Enabled = %d after planning, want it untouched", v)
+	}
+	if gb.GetBoostStatus().Active {
+		t.Error("planning should not activate the boost")
+	}
+
+	r, err := plan.Execute(p)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("plan applied with errors: %v", err)
+	}
+	if v, _, _ := store.GetIntegerValue("GameDVR_Enabled"); v != 0 {
+		t.Errorf("GameDVR_Enabled = %d after executing, want 0", v)
+	}
+	if !fake.Called("sc", "stop", "SysMain") {
+		t.Error("executing the plan should stop SysMain")
+	}
+	status := gb.GetBoostStatus()
+	if !status.Active || status.Profile != "casual" || len(status.TweaksApplied) != 4 {
+		t.Errorf("status = %+v, want casual active with 4 tweaks", status)
+	}
+}
+
+func TestPlanTweaksWithNothingAppliedStartsNoSession(t *testing.T) {
+	// A value of a type the journal cannot restore is refused, so the
+	// registry tweak fails along with the service.
+	useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\System\GameConfigStore]
+"GameDVR_Enabled"=hex(0):01
+`)
+	fake := useFakeRunner(t)
+	fake.On("sc", "qc", "SysMain").Returns("        START_TYPE         : 2   AUTO_START")
+	fake.On("sc", "query", "SysMain").Returns("        STATE              : 4  RUNNING")
+	fake.On("sc", "stop", "SysMain").Fails(5, "Access is denied.")
+	useJournal(t)
+
+	gb := NewGameBooster()
+	called := false
+	p := gb.planTweaks("Apply game profile", []string{"disable_game_dvr", "disable_sysmain"}, func([]string) {
+		called = true
+		gb.status = BoostStatus{Active: true, Profile: "casual"}
+	})
+	r, err := plan.Execute(p)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(r.Applied) != 0 {
+		t.Fatalf("applied %v, want every change to fail", r.Applied)
+	}
+	if called || gb.GetBoostStatus().Active {
+		t.Error("a plan that applied nothing should not start a boost")
+	}
+	if _, err := os.Stat(sessionPath); !os.IsNotExist(err) {
+		t.Errorf("boost session saved though nothing was applied (stat: %v)", err)
+	}
+}
+
+func TestApplyTweakSetsCatalogValues(t *testing.T) {
+	for _, def := range tweakCatalog {
+		if len(def.Values) == 0 {
+			continue
+		}
+		t.Run(def.ID, func(t *testing.T) {
+			mem := useMemoryRegistry(t, "")
+			useFakeRunner(t)
+			useJournal(t)
+			if err := NewGameBooster().ApplyTweak(def.ID); err != nil {
+				t.Fatalf("ApplyTweak(%s): %v", def.ID, err)
+			}
+
+			for _, v := range def.Values {
+				k, err := mem.OpenKey(v.root, v.path, reg.QueryValue)
+				if err != nil {
+					t.Fatalf("open %s: %v", v.path, err)
+				}
+				if v.isString {
+					if got, _, err := k.GetStringValue(v.name); err != nil || got != v.str {
+						t.Errorf("%s = %q, %v, want %q", v.name, got, err, v.str)
+					}
+				} else if got, _, err := k.GetIntegerValue(v.name); err != nil || got != uint64(v.dword) {
+					t.Errorf("%s = %d, %v, want %d", v.name, got, err, v.dword)
+				}
+				k.Close()
+			}
+		})
+	}
+}
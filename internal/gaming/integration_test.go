@@ -3,12 +3,10 @@
 package gaming
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
 	"testing"
 
 	"cleanforge/internal/gaming/profiles"
+	"cleanforge/internal/reg"
 )
 
 // TestProfileConsistency verifies every profile's tweak IDs exist in the global tweak catalog.
@@ -94,99 +92,65 @@ func TestNuclearHasEverything(t *testing.T) {
 	}
 }
 
-// TestBackupRestoreFlow tests that the backup file mechanism works.
-// It creates a GameBooster with a temp backup path and verifies backup
-// file creation and the restore flow (reading the file back).
+// TestBackupRestoreFlow tests that tweaks applied through the GameBooster
+// are recorded in the backup journal under the "gaming" module and that
+// RestoreAll consumes them. It runs against an in-memory registry so it does
+// not touch the real system.
 func TestBackupRestoreFlow(t *testing.T) {
-	tmpDir := t.TempDir()
-	backupPath := filepath.Join(tmpDir, "backup_state.json")
+	mem := useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\Control Panel\Mouse]
+"MouseSpeed"="1"
+`)
+	useFakeRunner(t)
+	j := useJournal(t)
 
 	gb := &GameBooster{
 		appliedTweaks: make(map[string]bool),
-		backupPath:    backupPath,
 	}
 
-	// Write a backup state manually (since we cannot access the real registry)
-	state := &BackupState{
-		CreatedAt: "2025-01-01T00:00:00Z",
-		Entries: []BackupEntry{
-			{
-				Type:      "registry",
-				Root:      "HKCU",
-				KeyPath:   `Control Panel\Mouse`,
-				ValueName: "MouseSpeed",
-				Value:     "1",
-				ValueType: 1, // REG_SZ
-				Missing:   false,
-			},
-			{
-				Type:         "service",
-				ServiceName:  "SysMain",
-				ServiceState: "running",
-			},
-		},
+	if err := gb.ApplyTweak("mouse_raw_input"); err != nil {
+		t.Fatalf("ApplyTweak failed: %v", err)
 	}
 
-	if err := gb.writeBackup(state); err != nil {
-		t.Fatalf("writeBackup failed: %v", err)
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
 	}
-
-	// Verify backup file exists
-	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
-		t.Fatal("backup file was not created")
+	if len(entries) != 1 {
+		t.Fatalf("journal has %d entries, want 1", len(entries))
 	}
-
-	// Read back the backup
-	loaded, err := gb.readBackup()
-	if err != nil {
-		t.Fatalf("readBackup failed: %v", err)
+	entry := entries[0]
+	if entry.Module != "gaming" {
+		t.Errorf("entry.Module = %q, want %q", entry.Module, "gaming")
 	}
-
-	if loaded.CreatedAt != state.CreatedAt {
-		t.Errorf("backup CreatedAt = %q, want %q", loaded.CreatedAt, state.CreatedAt)
+	if entry.Tweak != "mouse_raw_input" {
+		t.Errorf("entry.Tweak = %q, want %q", entry.Tweak, "mouse_raw_input")
 	}
-	if len(loaded.Entries) != 2 {
-		t.Errorf("backup has %d entries, want 2", len(loaded.Entries))
+	if entry.Registry == nil || entry.Registry.String != "1" {
+		t.Errorf("entry.Registry = %+v, want original value %q", entry.Registry, "1")
 	}
 
-	// Verify first entry
-	if len(loaded.Entries) > 0 {
-		entry := loaded.Entries[0]
-		if entry.Type != "registry" {
-			t.Errorf("entry[0].Type = %q, want %q", entry.Type, "registry")
-		}
-		if entry.ValueName != "MouseSpeed" {
-			t.Errorf("entry[0].ValueName = %q, want %q", entry.ValueName, "MouseSpeed")
-		}
-		if entry.Value != "1" {
-			t.Errorf("entry[0].Value = %q, want %q", entry.Value, "1")
-		}
+	if err := gb.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll failed: %v", err)
 	}
-
-	// Verify second entry
-	if len(loaded.Entries) > 1 {
-		entry := loaded.Entries[1]
-		if entry.Type != "service" {
-			t.Errorf("entry[1].Type = %q, want %q", entry.Type, "service")
-		}
-		if entry.ServiceName != "SysMain" {
-			t.Errorf("entry[1].ServiceName = %q, want %q", entry.ServiceName, "SysMain")
-		}
-		if entry.ServiceState != "running" {
-			t.Errorf("entry[1].ServiceState = %q, want %q", entry.ServiceState, "running")
-		}
+	key, err := mem.OpenKey(reg.CurrentUser, `Control Panel\Mouse`, reg.QueryValue)
+	if err != nil {
+		t.Fatalf("OpenKey failed: %v", err)
+	}
+	defer key.Close()
+	if v, _, _ := key.GetStringValue("MouseSpeed"); v != "1" {
+		t.Errorf("MouseSpeed = %q after restore, want %q", v, "1")
+	}
+	if j.HasEntries() {
+		t.Error("journal should be empty after RestoreAll")
 	}
 }
 
 // TestBoostStatusTracking tests the boost status lifecycle: initially inactive,
 // becomes active after profile is tracked, resets after restore.
 func TestBoostStatusTracking(t *testing.T) {
-	tmpDir := t.TempDir()
-	backupPath := filepath.Join(tmpDir, "backup_state.json")
-
 	gb := &GameBooster{
 		appliedTweaks: make(map[string]bool),
-		backupPath:    backupPath,
 	}
 
 	// Initially inactive
@@ -331,43 +295,6 @@ func TestProfileFieldsPopulated(t *testing.T) {
 	}
 }
 
-// TestBackupStateJSON verifies the backup state round-trips through JSON correctly.
-func TestBackupStateJSON(t *testing.T) {
-	state := BackupState{
-		CreatedAt: "2025-01-01T12:00:00Z",
-		Entries: []BackupEntry{
-			{
-				Type:      "registry",
-				Root:      "HKCU",
-				KeyPath:   `Software\Test`,
-				ValueName: "TestVal",
-				Value:     "42",
-				ValueType: 4, // REG_DWORD
-			},
-		},
-	}
-
-	data, err := json.Marshal(state)
-	if err != nil {
-		t.Fatalf("json.Marshal failed: %v", err)
-	}
-
-	var loaded BackupState
-	if err := json.Unmarshal(data, &loaded); err != nil {
-		t.Fatalf("json.Unmarshal failed: %v", err)
-	}
-
-	if loaded.CreatedAt != state.CreatedAt {
-		t.Errorf("CreatedAt = %q, want %q", loaded.CreatedAt, state.CreatedAt)
-	}
-	if len(loaded.Entries) != 1 {
-		t.Fatalf("Entries length = %d, want 1", len(loaded.Entries))
-	}
-	if loaded.Entries[0].ValueName != "TestVal" {
-		t.Errorf("ValueName = %q, want %q", loaded.Entries[0].ValueName, "TestVal")
-	}
-}
-
 // TestGetProfileByID verifies that GetProfileByID returns the correct profile
 // or nil for unknown IDs.
 func TestGetProfileByID(t *testing.T) {
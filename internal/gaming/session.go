@@ -0,0 +1,67 @@
+package gaming
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SessionPath returns ~/.cleanforge/boost-session.json.
+func SessionPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(homeDir, ".cleanforge", "boost-session.json")
+}
+
+// sessionPath is where the boost session is saved.
+var sessionPath = SessionPath()
+
+// LoadSession returns the boost session saved by an earlier run, or nil when
+// no boost was active. A session still saved at startup was interrupted: the
+// app exited, crashed or the PC restarted before the boost was restored.
+func LoadSession() (*BoostStatus, error) {
+	data, err := os.ReadFile(sessionPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read boost session: %w", err)
+	}
+	var status BoostStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse boost session: %w", err)
+	}
+	if !status.Active {
+		return nil, nil
+	}
+	return &status, nil
+}
+
+// saveSession atomically replaces the saved boost session with status,
+// removing it once the boost is no longer active.
+func saveSession(status BoostStatus) error {
+	if !status.Active {
+		if err := os.Remove(sessionPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear boost session: %w", err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal boost session: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(sessionPath), 0755); err != nil {
+		return fmt.Errorf("failed to create boost session directory: %w", err)
+	}
+	tmp := sessionPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write boost session: %w", err)
+	}
+	if err := os.Rename(tmp, sessionPath); err != nil {
+		return fmt.Errorf("failed to replace boost session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package guard keeps deletions inside the folder they are meant for. It
+// refuses to remove protected folders such as the user's profile, drive
+// roots and System32, and never lets a symbolic link or junction lead a
+// removal out of the folder being cleaned.
+package guard
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Reason says why the guard refused to remove a path.
+type Reason string
+
+const (
+	Protected       Reason = "protected"         // the path is, holds or is inside a protected folder
+	OutsideRoot     Reason = "outside_root"      // a link in the path's folders leads outside the root
+	LinkOutsideRoot Reason = "link_outside_root" // the path is a link to somewhere outside the root
+)
+
+// Error is returned for a path the guard refused to remove.
+type Error struct {
+	Path   string `json:"path"`
+	Reason Reason `json:"reason"`
+	Detail string `json:"detail"`
+}
+
+func (e *Error) Error() string {
+	return e.Path + ": " + e.Detail
+}
+
+// Guard checks paths before they are removed. Filesystem roots are always
+// protected.
+type Guard struct {
+	keep   []string // never removed, nor any folder holding them
+	sealed []string // like keep, and nothing inside them is removed either
+}
+
+// New returns a guard protecting the folders in keep and sealed. Links in
+// them are resolved, so a protected folder is recognized however it is
+// reached.
+func New(keep, sealed []string) *Guard {
+	g := &Guard{}
+	for _, k := range keep {
+		if k != "" {
+			g.keep = append(g.keep, resolve(k))
+		}
+	}
+	for _, s := range sealed {
+		if s != "" {
+			g.sealed = append(g.sealed, resolve(s))
+		}
+	}
+	return g
+}
+
+// Default protects the running user's profile and, on Windows, the Windows
+// folder and System32.
+var Default = forSystem()
+
+func forSystem() *Guard {
+	home, _ := os.UserHomeDir()
+	if runtime.GOOS != "windows" {
+		return New([]string{home}, nil)
+	}
+	systemRoot := os.Getenv("SystemRoot")
+	if systemRoot == "" {
+		systemRoot = `C:\Windows`
+	}
+	return New([]string{home, systemRoot}, []string{filepath.Join(systemRoot, "System32")})
+}
+
+// Root returns the folder at path with its links resolved, as Check takes
+// it. Resolve a root when it is scanned, so a link swapped into its path
+// before the removal is caught.
+func Root(path string) string {
+	return resolve(path)
+}
+
+// Check returns an *Error if path must not be removed. root is the folder
+// path was found in, from Root; path must stay inside it once links are
+// resolved, and if path is itself a link its target must too. An empty root
+// only checks the protected folders. A path that does not exist passes, so
+// removing it reports that as usual.
+func (g *Guard) Check(root, path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil
+	}
+	// Where path really is: its folders resolved, but not path itself, as a
+	// link is removed and not what it leads to
+	real := filepath.Join(resolve(filepath.Dir(path)), filepath.Base(path))
+
+	if root != "" {
+		if !within(real, root) {
+			return &Error{Path: path, Reason: OutsideRoot, Detail: "a link leads it outside " + root + "; not removed"}
+		}
+		if isLink(info) {
+			if target, err := filepath.EvalSymlinks(path); err == nil && !within(target, root) {
+				return &Error{Path: path, Reason: LinkOutsideRoot, Detail: "links to " + target + ", outside " + root + "; not removed"}
+			}
+		}
+	}
+
+	if p := g.protecting(real); p != "" {
+		return &Error{Path: path, Reason: Protected, Detail: "would remove protected folder " + p + "; not removed"}
+	}
+	return nil
+}
+
+// protecting returns the protected folder that removing path would remove
+// or reach into, or "" if there is none.
+func (g *Guard) protecting(path string) string {
+	if filepath.Dir(path) == path {
+		return path
+	}
+	for _, k := range g.keep {
+		if within(k, path) {
+			return k
+		}
+	}
+	for _, s := range g.sealed {
+		if within(s, path) || within(path, s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// RemoveAll checks path and removes it with everything in it. A link is
+// removed itself, never what it leads to; os.RemoveAll likewise removes the
+// links inside a folder without following them.
+func (g *Guard) RemoveAll(root, path string) error {
+	if err := g.Check(root, path); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(path); err == nil && isLink(info) {
+		return os.Remove(path)
+	}
+	return os.RemoveAll(path)
+}
+
+// isLink reports whether info is a symbolic link or, on Windows, a junction
+// or another reparse point, which Lstat reports as irregular.
+func isLink(info fs.FileInfo) bool {
+	return info.Mode()&(fs.ModeSymlink|fs.ModeIrregular) != 0
+}
+
+// resolve returns path with its links resolved, or cleaned if it cannot be.
+func resolve(path string) string {
+	if r, err := filepath.EvalSymlinks(path); err == nil {
+		return r
+	}
+	return filepath.Clean(path)
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,156 @@
+package guard
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// layout creates a root holding a file, and an outside folder holding a
+// file that must survive whatever is removed under root.
+func layout(t *testing.T) (root, outside, kept string) {
+	t.Helper()
+	base := t.TempDir()
+	root, outside = filepath.Join(base, "root"), filepath.Join(base, "outside")
+	kept = filepath.Join(outside, "kept.txt")
+	for _, dir := range []string{root, outside} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{kept, filepath.Join(root, "junk.tmp")} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, outside, kept
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+}
+
+// reason returns the Reason of a *Error, or "" for any other error.
+func reason(err error) Reason {
+	var ge *Error
+	if errors.As(err, &ge) {
+		return ge.Reason
+	}
+	return ""
+}
+
+func TestCheckLinks(t *testing.T) {
+	root, outside, _ := layout(t)
+	symlink(t, outside, filepath.Join(root, "away"))
+	symlink(t, filepath.Join(root, "junk.tmp"), filepath.Join(root, "near"))
+	symlink(t, outside, filepath.Join(root, "parent"))
+	g := New(nil, nil)
+	root = Root(root)
+
+	tests := []struct {
+		path string
+		want Reason
+	}{
+		{filepath.Join(root, "junk.tmp"), ""},
+		{filepath.Join(root, "near"), ""},
+		{filepath.Join(root, "missing"), ""},
+		{root, ""},
+		{filepath.Join(root, "away"), LinkOutsideRoot},
+		{filepath.Join(root, "parent", "kept.txt"), OutsideRoot},
+		{filepath.Join(root, "..", "outside", "kept.txt"), OutsideRoot},
+	}
+	for _, tt := range tests {
+		if got := reason(g.Check(root, tt.path)); got != tt.want {
+			t.Errorf("Check(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	// Without a root only the link itself would go, which is harmless
+	if err := g.Check("", filepath.Join(root, "away")); err != nil {
+		t.Errorf("Check without a root = %v", err)
+	}
+}
+
+func TestCheckCatchesSwappedRoot(t *testing.T) {
+	root, outside, kept := layout(t)
+	scanned := Root(root)
+
+	// Between the scan and the removal the root is replaced by a link
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	symlink(t, outside, root)
+	if got := reason(New(nil, nil).RemoveAll(scanned, filepath.Join(root, "kept.txt"))); got != OutsideRoot {
+		t.Errorf("RemoveAll through the swapped root = %q, want %q", got, OutsideRoot)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("the file the link led to was removed: %v", err)
+	}
+}
+
+func TestCheckProtected(t *testing.T) {
+	base := t.TempDir()
+	home, system := filepath.Join(base, "home", "me"), filepath.Join(base, "windows", "system32")
+	for _, dir := range []string{filepath.Join(home, "Temp"), filepath.Join(system, "drivers")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g := New([]string{home}, []string{system})
+
+	tests := []struct {
+		path string
+		want Reason
+	}{
+		{home, Protected},
+		{filepath.Dir(home), Protected},
+		{filepath.Join(home, "Temp"), ""},
+		{system, Protected},
+		{filepath.Join(system, "drivers"), Protected},
+		{filepath.Dir(system), Protected},
+		{string(filepath.Separator), Protected},
+	}
+	for _, tt := range tests {
+		if got := reason(g.Check("", tt.path)); got != tt.want {
+			t.Errorf("Check(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// The profile is recognized when reached through a linked folder
+	link := filepath.Join(base, "users")
+	symlink(t, filepath.Dir(home), link)
+	if got := reason(g.Check("", filepath.Join(link, "me"))); got != Protected {
+		t.Errorf("Check through a linked folder = %q, want %q", got, Protected)
+	}
+}
+
+func TestRemoveAllKeepsLinkTargets(t *testing.T) {
+	root, outside, kept := layout(t)
+	tree := filepath.Join(root, "tree")
+	if err := os.MkdirAll(tree, 0755); err != nil {
+		t.Fatal(err)
+	}
+	symlink(t, outside, filepath.Join(tree, "inner"))
+	symlink(t, outside, filepath.Join(root, "away"))
+	g := New(nil, nil)
+	root = Root(root)
+
+	if err := g.RemoveAll(root, filepath.Join(root, "away")); reason(err) != LinkOutsideRoot {
+		t.Errorf("RemoveAll of a link outside the root = %v, want it refused", err)
+	}
+	if err := g.RemoveAll(root, tree); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if _, err := os.Lstat(tree); !os.IsNotExist(err) {
+		t.Errorf("the tree should be gone: %v", err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("the file a link led to was removed: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "away")); err != nil {
+		t.Errorf("the refused link was removed: %v", err)
+	}
+}
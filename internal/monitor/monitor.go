@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
 	"os"
@@ -15,6 +16,9 @@ import (
 	"github.com/shirou/gopsutil/v4/mem"
 )
 
+// runner executes external commands. Tests replace it with a cmd.Fake.
+var runner cmd.Runner = cmd.Default
+
 // MonitorSnapshot holds a point-in-time snapshot of system metrics.
 type MonitorSnapshot struct {
 	Timestamp int64   `json:"timestamp"`
@@ -88,7 +92,7 @@ func GetSnapshot() (*MonitorSnapshot, error) {
 func GetCPUTemp() (float64, error) {
 	// Method 1: MSAcpi_ThermalZoneTemperature via WMI
 	// This returns temperature in tenths of Kelvin
-	out, err := cmd.Hidden("powershell", "-NoProfile", "-Command",
+	out, err := runner.Command(context.Background(), "powershell", "-NoProfile", "-Command",
 		"Get-CimInstance MSAcpi_ThermalZoneTemperature -Namespace root/WMI -ErrorAction SilentlyContinue | Select-Object -ExpandProperty CurrentTemperature -First 1",
 	).Output()
 	if err == nil {
@@ -106,7 +110,7 @@ func GetCPUTemp() (float64, error) {
 	}
 
 	// Method 2: Open Hardware Monitor / LibreHardwareMonitor WMI
-	out, err = cmd.Hidden("powershell", "-NoProfile", "-Command",
+	out, err = runner.Command(context.Background(), "powershell", "-NoProfile", "-Command",
 		`Get-CimInstance -Namespace root/OpenHardwareMonitor -ClassName Sensor -ErrorAction SilentlyContinue | Where-Object { $_.SensorType -eq 'Temperature' -and $_.Name -like '*CPU*' } | Select-Object -ExpandProperty Value -First 1`,
 	).Output()
 	if err == nil {
@@ -120,7 +124,7 @@ func GetCPUTemp() (float64, error) {
 	}
 
 	// Method 3: LibreHardwareMonitor WMI namespace
-	out, err = cmd.Hidden("powershell", "-NoProfile", "-Command",
+	out, err = runner.Command(context.Background(), "powershell", "-NoProfile", "-Command",
 		`Get-CimInstance -Namespace root/LibreHardwareMonitor -ClassName Sensor -ErrorAction SilentlyContinue | Where-Object { $_.SensorType -eq 'Temperature' -and $_.Name -like '*CPU*' } | Select-Object -ExpandProperty Value -First 1`,
 	).Output()
 	if err == nil {
@@ -141,7 +145,7 @@ func GetCPUTemp() (float64, error) {
 // for AMD or other vendors.
 func GetGPUTemp() (float64, error) {
 	// Method 1: NVIDIA GPU via nvidia-smi
-	out, err := cmd.Hidden("nvidia-smi",
+	out, err := runner.Command(context.Background(), "nvidia-smi",
 		"--query-gpu=temperature.gpu",
 		"--format=csv,noheader,nounits",
 	).Output()
@@ -158,7 +162,7 @@ func GetGPUTemp() (float64, error) {
 	}
 
 	// Method 2: Open Hardware Monitor WMI for GPU temperature
-	out, err = cmd.Hidden("powershell", "-NoProfile", "-Command",
+	out, err = runner.Command(context.Background(), "powershell", "-NoProfile", "-Command",
 		`Get-CimInstance -Namespace root/OpenHardwareMonitor -ClassName Sensor -ErrorAction SilentlyContinue | Where-Object { $_.SensorType -eq 'Temperature' -and $_.Name -like '*GPU*' } | Select-Object -ExpandProperty Value -First 1`,
 	).Output()
 	if err == nil {
@@ -172,7 +176,7 @@ func GetGPUTemp() (float64, error) {
 	}
 
 	// Method 3: LibreHardwareMonitor WMI namespace for GPU
-	out, err = cmd.Hidden("powershell", "-NoProfile", "-Command",
+	out, err = runner.Command(context.Background(), "powershell", "-NoProfile", "-Command",
 		`Get-CimInstance -Namespace root/LibreHardwareMonitor -ClassName Sensor -ErrorAction SilentlyContinue | Where-Object { $_.SensorType -eq 'Temperature' -and $_.Name -like '*GPU*' } | Select-Object -ExpandProperty Value -First 1`,
 	).Output()
 	if err == nil {
@@ -402,7 +406,7 @@ func getDiskUsageAverage() float64 {
 // getFanSpeed attempts to read fan speed via WMI. Returns 0 if unavailable.
 func getFanSpeed() int {
 	// Try Win32_Fan WMI class
-	out, err := cmd.Hidden("powershell", "-NoProfile", "-Command",
+	out, err := runner.Command(context.Background(), "powershell", "-NoProfile", "-Command",
 		"Get-CimInstance Win32_Fan -ErrorAction SilentlyContinue | Select-Object -ExpandProperty DesiredSpeed -First 1",
 	).Output()
 	if err == nil {
@@ -416,7 +420,7 @@ func getFanSpeed() int {
 	}
 
 	// Try Open Hardware Monitor
-	out, err = cmd.Hidden("powershell", "-NoProfile", "-Command",
+	out, err = runner.Command(context.Background(), "powershell", "-NoProfile", "-Command",
 		`Get-CimInstance -Namespace root/OpenHardwareMonitor -ClassName Sensor -ErrorAction SilentlyContinue | Where-Object { $_.SensorType -eq 'Fan' } | Select-Object -ExpandProperty Value -First 1`,
 	).Output()
 	if err == nil {
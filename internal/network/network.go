@@ -12,10 +12,21 @@ import (
 	"time"
 	"unicode/utf16"
 
+	"cleanforge/internal/backup"
 	"cleanforge/internal/cmd"
-	"golang.org/x/sys/windows/registry"
+	"cleanforge/internal/plan"
+	"cleanforge/internal/reg"
 )
 
+// runner executes external commands. Tests replace it with a cmd.Fake.
+var runner cmd.Runner = cmd.Default
+
+// registry is the Windows registry. Tests replace it with a reg.Memory.
+var registry reg.Registry = reg.Default
+
+// journal records every change this package makes so it can be undone.
+var journal = backup.Default
+
 // Adapter cache — set by GetNetworkStatus(), reused by SetDNS()/ResetDNS()
 // to avoid redundant slow PowerShell calls.
 var (
@@ -49,7 +60,7 @@ func runElevated(ctx context.Context, psCommand string) ([]byte, error) {
 		`Start-Process powershell -Verb RunAs -Wait -WindowStyle Hidden -ArgumentList '-NoProfile -EncodedCommand %s'`,
 		encoded,
 	)
-	return cmd.HiddenContext(ctx, "powershell", "-NoProfile", "-Command", elevateCmd).CombinedOutput()
+	return runner.Command(ctx, "powershell", "-NoProfile", "-Command", elevateCmd).CombinedOutput()
 }
 
 func getCachedAdapter() string {
@@ -140,7 +151,7 @@ if ($cfg) {
     $dns = (Get-DnsClientServerAddress -InterfaceIndex $cfg.InterfaceIndex -AddressFamily IPv4 -ErrorAction SilentlyContinue).ServerAddresses -join ', '
     "$($cfg.InterfaceAlias)|$($cfg.IPv4Address.IPAddress)|$($cfg.IPv4DefaultGateway.NextHop)|$dns"
 }`
-	if out, err := cmd.HiddenContext(ctx, "powershell", "-NoProfile", "-Command", psCmd).Output(); err == nil {
+	if out, err := runner.Command(ctx, "powershell", "-NoProfile", "-Command", psCmd).Output(); err == nil {
 		result := strings.TrimSpace(string(out))
 		if result != "" {
 			parts := strings.SplitN(result, "|", 4)
@@ -170,7 +181,7 @@ if ($cfg) {
 	if status.Adapter != "" && (status.IPAddress == "" || status.CurrentDNS == "") {
 		ctx2, cancel2 := context.WithTimeout(context.Background(), cmdTimeout)
 		defer cancel2()
-		if out, err := cmd.HiddenContext(ctx2, "netsh", "interface", "ip", "show", "config", "name="+status.Adapter).CombinedOutput(); err == nil {
+		if out, err := runner.Command(ctx2, "netsh", "interface", "ip", "show", "config", "name="+status.Adapter).CombinedOutput(); err == nil {
 			lines := strings.Split(string(out), "\n")
 			for _, line := range lines {
 				line = strings.TrimSpace(line)
@@ -208,7 +219,7 @@ if ($cfg) {
 		ctx3, cancel3 := context.WithTimeout(context.Background(), cmdTimeout)
 		defer cancel3()
 		psDNS := fmt.Sprintf(`(Get-DnsClientServerAddress -InterfaceAlias '%s' -AddressFamily IPv4 -ErrorAction SilentlyContinue).ServerAddresses -join ', '`, status.Adapter)
-		if out, err := cmd.HiddenContext(ctx3, "powershell", "-NoProfile", "-Command", psDNS).Output(); err == nil {
+		if out, err := runner.Command(ctx3, "powershell", "-NoProfile", "-Command", psDNS).Output(); err == nil {
 			dns := strings.TrimSpace(string(out))
 			if dns != "" {
 				status.CurrentDNS = dns
@@ -251,6 +262,10 @@ func SetDNS(preset DNSPreset) error {
 		return fmt.Errorf("no active network adapter found: %w", err)
 	}
 
+	if err := journal.For("network", "set_dns").DNS(adapter); err != nil {
+		return err
+	}
+
 	dnsCmd := fmt.Sprintf(
 		`Set-DnsClientServerAddress -InterfaceAlias '%s' -ServerAddresses @('%s','%s')`,
 		adapter, preset.Primary, preset.Secondary,
@@ -259,7 +274,7 @@ func SetDNS(preset DNSPreset) error {
 	// Try non-elevated first
 	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
 	defer cancel()
-	if _, psErr := cmd.HiddenContext(ctx, "powershell", "-NoProfile", "-Command", dnsCmd).CombinedOutput(); psErr == nil {
+	if _, psErr := runner.Command(ctx, "powershell", "-NoProfile", "-Command", dnsCmd).CombinedOutput(); psErr == nil {
 		return nil
 	}
 
@@ -282,12 +297,16 @@ func ResetDNS() error {
 		return fmt.Errorf("no active network adapter found: %w", err)
 	}
 
+	if err := journal.For("network", "reset_dns").DNS(adapter); err != nil {
+		return err
+	}
+
 	dnsCmd := fmt.Sprintf(`Set-DnsClientServerAddress -InterfaceAlias '%s' -ResetServerAddresses`, adapter)
 
 	// Try non-elevated first
 	ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
 	defer cancel()
-	if _, psErr := cmd.HiddenContext(ctx, "powershell", "-NoProfile", "-Command", dnsCmd).CombinedOutput(); psErr == nil {
+	if _, psErr := runner.Command(ctx, "powershell", "-NoProfile", "-Command", dnsCmd).CombinedOutput(); psErr == nil {
 		return nil
 	}
 
@@ -305,43 +324,67 @@ func ResetDNS() error {
 // DisableNagle disables the Nagle algorithm on all network interfaces by setting
 // TcpAckFrequency=1 and TCPNoDelay=1 in the registry.
 func DisableNagle() error {
-	interfacesKey, err := registry.OpenKey(registry.LOCAL_MACHINE, tcpInterfacesPath, registry.READ)
+	p, err := PlanDisableNagle()
 	if err != nil {
-		return fmt.Errorf("failed to open TCP interfaces registry key: %w", err)
+		return err
+	}
+	r, err := plan.Execute(p)
+	if err != nil {
+		return err
+	}
+	return r.Err()
+}
+
+// PlanDisableNagle lists the registry values DisableNagle would set on each
+// interface, without setting them.
+func PlanDisableNagle() (*plan.Plan, error) {
+	rec := journal.For("network", "disable_nagle")
+	interfacesKey, err := registry.OpenKey(reg.LocalMachine, tcpInterfacesPath, reg.Read)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open TCP interfaces registry key: %w", err)
 	}
 	defer interfacesKey.Close()
 
 	subkeys, err := interfacesKey.ReadSubKeyNames(-1)
 	if err != nil {
-		return fmt.Errorf("failed to read interface subkeys: %w", err)
+		return nil, fmt.Errorf("failed to read interface subkeys: %w", err)
 	}
 
-	var lastErr error
+	p := plan.New("Disable Nagle's algorithm on all interfaces")
 	for _, subkey := range subkeys {
 		keyPath := tcpInterfacesPath + `\` + subkey
-		k, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.SET_VALUE)
-		if err != nil {
-			lastErr = fmt.Errorf("failed to open interface key %s: %w", subkey, err)
-			continue
-		}
-
-		if err := k.SetDWordValue("TcpAckFrequency", 1); err != nil {
-			lastErr = fmt.Errorf("failed to set TcpAckFrequency on %s: %w", subkey, err)
-		}
-		if err := k.SetDWordValue("TCPNoDelay", 1); err != nil {
-			lastErr = fmt.Errorf("failed to set TCPNoDelay on %s: %w", subkey, err)
+		for _, name := range []string{"TcpAckFrequency", "TCPNoDelay"} {
+			a := plan.RegistryChange(registry, reg.LocalMachine, keyPath, name, "1")
+			a.Module, a.Tweak = "network", "disable_nagle"
+			p.Add(a, func() error { return setNagleValue(rec, keyPath, name) })
 		}
+	}
+	return p, nil
+}
 
-		k.Close()
+// setNagleValue records one of an interface's Nagle-related values in the
+// journal and then sets it to 1.
+func setNagleValue(rec backup.Recorder, keyPath, name string) error {
+	if err := rec.RegistryValue(reg.LocalMachine, keyPath, name); err != nil {
+		return err
+	}
+	k, err := registry.OpenKey(reg.LocalMachine, keyPath, reg.SetValue)
+	if err != nil {
+		return fmt.Errorf("failed to open interface key %s: %w", keyPath, err)
 	}
+	defer k.Close()
 
-	return lastErr
+	if err := k.SetDWordValue(name, 1); err != nil {
+		return fmt.Errorf("failed to set %s: %w", name, err)
+	}
+	return nil
 }
 
 // EnableNagle restores the Nagle algorithm on all network interfaces by removing
 // the TcpAckFrequency and TCPNoDelay registry values.
 func EnableNagle() error {
-	interfacesKey, err := registry.OpenKey(registry.LOCAL_MACHINE, tcpInterfacesPath, registry.READ)
+	rec := journal.For("network", "enable_nagle")
+	interfacesKey, err := registry.OpenKey(reg.LocalMachine, tcpInterfacesPath, reg.Read)
 	if err != nil {
 		return fmt.Errorf("failed to open TCP interfaces registry key: %w", err)
 	}
@@ -355,7 +398,11 @@ func EnableNagle() error {
 	var lastErr error
 	for _, subkey := range subkeys {
 		keyPath := tcpInterfacesPath + `\` + subkey
-		k, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.SET_VALUE)
+		if err := recordNagle(rec, keyPath); err != nil {
+			lastErr = err
+			continue
+		}
+		k, err := registry.OpenKey(reg.LocalMachine, keyPath, reg.SetValue)
 		if err != nil {
 			lastErr = fmt.Errorf("failed to open interface key %s: %w", subkey, err)
 			continue
@@ -371,6 +418,16 @@ func EnableNagle() error {
 	return lastErr
 }
 
+// recordNagle records an interface's Nagle-related values in the journal.
+func recordNagle(rec backup.Recorder, keyPath string) error {
+	for _, name := range []string{"TcpAckFrequency", "TCPNoDelay"} {
+		if err := rec.RegistryValue(reg.LocalMachine, keyPath, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // FlushNetwork runs a full network flush sequence: flushdns, winsock reset,
 // IP reset, release, and renew. Collects output from all commands.
 func FlushNetwork() (string, error) {
@@ -389,7 +446,7 @@ func FlushNetwork() (string, error) {
 	var errs []string
 
 	for _, c := range commands {
-		out, err := cmd.Hidden(c.name, c.args...).CombinedOutput()
+		out, err := runner.Command(context.Background(), c.name, c.args...).CombinedOutput()
 		label := c.name + " " + strings.Join(c.args, " ")
 		if err != nil {
 			errs = append(errs, fmt.Sprintf("[%s] error: %s - %s", label, err.Error(), strings.TrimSpace(string(out))))
@@ -418,7 +475,7 @@ func GetActiveAdapter() (string, error) {
 
 	// Primary: PowerShell (works on any Windows locale)
 	psCmd := `(Get-NetIPConfiguration | Where-Object { $_.IPv4DefaultGateway -ne $null } | Select-Object -First 1).InterfaceAlias`
-	if out, err := cmd.HiddenContext(ctx, "powershell", "-NoProfile", "-Command", psCmd).Output(); err == nil {
+	if out, err := runner.Command(ctx, "powershell", "-NoProfile", "-Command", psCmd).Output(); err == nil {
 		adapter := strings.TrimSpace(string(out))
 		if adapter != "" {
 			return adapter, nil
@@ -430,7 +487,7 @@ func GetActiveAdapter() (string, error) {
 	//   EN: Configuration for interface "Wi-Fi"
 	//   PT: Configuração da interface "Wi-Fi"
 	//   ES: Configuración de la interfaz "Wi-Fi"
-	out, err := cmd.Hidden("netsh", "interface", "ip", "show", "config").CombinedOutput()
+	out, err := runner.Command(context.Background(), "netsh", "interface", "ip", "show", "config").CombinedOutput()
 	if err == nil {
 		lines := strings.Split(string(out), "\n")
 		configRe := regexp.MustCompile(`"([^"]+)"`)
@@ -469,7 +526,7 @@ func GetActiveAdapter() (string, error) {
 // ipconfig commands.
 func getAdapterFromRoute() (string, error) {
 	// Get the default interface IP from "route print 0.0.0.0"
-	out, err := cmd.Hidden("route", "print", "0.0.0.0").CombinedOutput()
+	out, err := runner.Command(context.Background(), "route", "print", "0.0.0.0").CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("failed to run route print: %w", err)
 	}
@@ -493,7 +550,7 @@ func getAdapterFromRoute() (string, error) {
 
 	// Try to match this IP to an adapter name via PowerShell (locale-independent)
 	psAdapter := fmt.Sprintf(`(Get-NetIPAddress -IPAddress '%s' -ErrorAction SilentlyContinue | Get-NetAdapter -ErrorAction SilentlyContinue).Name`, defaultIP)
-	if out, err := cmd.Hidden("powershell", "-NoProfile", "-Command", psAdapter).Output(); err == nil {
+	if out, err := runner.Command(context.Background(), "powershell", "-NoProfile", "-Command", psAdapter).Output(); err == nil {
 		adapter := strings.TrimSpace(string(out))
 		if adapter != "" {
 			return adapter, nil
@@ -501,7 +558,7 @@ func getAdapterFromRoute() (string, error) {
 	}
 
 	// Last resort: ipconfig parsing with locale-tolerant patterns
-	ipconfigOut, err := cmd.Hidden("ipconfig").CombinedOutput()
+	ipconfigOut, err := runner.Command(context.Background(), "ipconfig").CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("failed to run ipconfig: %w", err)
 	}
@@ -540,7 +597,7 @@ func getAdapterFromRoute() (string, error) {
 
 // PingTest pings the specified host and returns the average latency in milliseconds.
 func PingTest(host string) (float64, error) {
-	out, err := cmd.Hidden("ping", "-n", "4", host).CombinedOutput()
+	out, err := runner.Command(context.Background(), "ping", "-n", "4", host).CombinedOutput()
 	if err != nil {
 		return 0, fmt.Errorf("ping failed: %s - %w", strings.TrimSpace(string(out)), err)
 	}
@@ -586,7 +643,7 @@ func PingTest(host string) (float64, error) {
 // isNagleDisabled checks if Nagle's algorithm is disabled by reading the registry
 // for at least one interface with both TcpAckFrequency=1 and TCPNoDelay=1.
 func isNagleDisabled() bool {
-	interfacesKey, err := registry.OpenKey(registry.LOCAL_MACHINE, tcpInterfacesPath, registry.READ)
+	interfacesKey, err := registry.OpenKey(reg.LocalMachine, tcpInterfacesPath, reg.Read)
 	if err != nil {
 		return false
 	}
@@ -601,7 +658,7 @@ func isNagleDisabled() bool {
 	// We look for interfaces that have DhcpIPAddress or IPAddress set (i.e., real adapters).
 	for _, subkey := range subkeys {
 		keyPath := tcpInterfacesPath + `\` + subkey
-		k, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath, registry.READ)
+		k, err := registry.OpenKey(reg.LocalMachine, keyPath, reg.Read)
 		if err != nil {
 			continue
 		}
@@ -649,7 +706,7 @@ func MeasureLatency() (float64, error) {
 
 	for _, host := range hosts {
 		// Use a short timeout approach: ping with -n 2 for speed
-		out, err := cmd.Hidden("ping", "-n", "2", "-w", "2000", host).CombinedOutput()
+		out, err := runner.Command(context.Background(), "ping", "-n", "2", "-w", "2000", host).CombinedOutput()
 		if err != nil {
 			lastErr = err
 			continue
@@ -685,7 +742,7 @@ func SpeedTestBasic() (float64, error) {
 	// would require downloading a file which is beyond a simple utility.
 	// Instead we measure jitter by doing several pings.
 	start := time.Now()
-	_, err := cmd.Hidden("ping", "-n", "10", "-w", "1000", "1.1.1.1").CombinedOutput()
+	_, err := runner.Command(context.Background(), "ping", "-n", "10", "-w", "1000", "1.1.1.1").CombinedOutput()
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -1,9 +1,56 @@
 package network
 
 import (
+	"runtime"
+	"strings"
 	"testing"
+
+	"cleanforge/internal/backup"
+	"cleanforge/internal/cmd"
+	"cleanforge/internal/reg"
 )
 
+// useMemoryRegistry swaps the package registry for a reg.Memory seeded from
+// the given .reg fixture for the duration of the test.
+func useMemoryRegistry(t *testing.T, fixture string) *reg.Memory {
+	t.Helper()
+	mem, err := reg.FromReg(fixture)
+	if err != nil {
+		t.Fatalf("load registry fixture: %v", err)
+	}
+	prev := registry
+	registry = mem
+	t.Cleanup(func() { registry = prev })
+	return mem
+}
+
+// useFakeRunner swaps the package runner for a cmd.Fake for the duration of the
+// test and clears the adapter cache so each test starts from a cold state.
+func useFakeRunner(t *testing.T) *cmd.Fake {
+	t.Helper()
+	fake := cmd.NewFake()
+	prev := runner
+	runner = fake
+	setCachedAdapter("")
+	t.Cleanup(func() {
+		runner = prev
+		setCachedAdapter("")
+	})
+	return fake
+}
+
+// useJournal swaps the package journal for one in a temp directory that reads
+// and restores through the current (usually fake) registry and runner. Call it
+// after useMemoryRegistry and useFakeRunner.
+func useJournal(t *testing.T) *backup.Journal {
+	t.Helper()
+	j := backup.NewJournal(t.TempDir(), registry, runner)
+	prev := journal
+	journal = j
+	t.Cleanup(func() { journal = prev })
+	return j
+}
+
 func TestGetDNSPresets(t *testing.T) {
 	presets := GetDNSPresets()
 
@@ -95,6 +142,9 @@ func TestGetActiveAdapter(t *testing.T) {
 }
 
 func TestGetNetworkStatus(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("queries the live network stack through PowerShell and netsh")
+	}
 	// Non-panic test for GetNetworkStatus
 	defer func() {
 		if r := recover(); r != nil {
@@ -210,3 +260,230 @@ func TestDNSPresetsNoDuplicateIDs(t *testing.T) {
 		seen[p.ID] = true
 	}
 }
+
+func TestGetNetworkStatusPowerShell(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("powershell").Returns("Ethernet|192.168.1.20|192.168.1.1|1.1.1.1, 1.0.0.1\r\n")
+
+	status, err := GetNetworkStatus()
+	if err != nil {
+		t.Fatalf("GetNetworkStatus returned error: %v", err)
+	}
+	if status.Adapter != "Ethernet" || status.IPAddress != "192.168.1.20" ||
+		status.Gateway != "192.168.1.1" || status.CurrentDNS != "1.1.1.1, 1.0.0.1" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if getCachedAdapter() != "Ethernet" {
+		t.Errorf("adapter cache = %q, want %q", getCachedAdapter(), "Ethernet")
+	}
+	if fake.Called("netsh") {
+		t.Error("netsh fallback should not run when PowerShell succeeds")
+	}
+}
+
+func TestGetNetworkStatusNetshFallback(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("powershell").Fails(1, "Get-NetIPConfiguration : not recognized")
+	fake.On("netsh", "interface", "ip", "show", "config").Returns(`
+Configuração da interface "Wi-Fi"
+    DHCP habilitado:                          Sim
+    Endereço IP:                           10.0.0.42
+`)
+	fake.On("netsh", "interface", "ip", "show", "config", "name=Wi-Fi").Returns(`
+Configuração da interface "Wi-Fi"
+    Endereço IP:                           10.0.0.42
+    Gateway padrão:                        10.0.0.1
+    Servidores DNS configurados através de DHCP:  10.0.0.1
+`)
+
+	status, err := GetNetworkStatus()
+	if err != nil {
+		t.Fatalf("GetNetworkStatus returned error: %v", err)
+	}
+	if status.Adapter != "Wi-Fi" {
+		t.Errorf("Adapter = %q, want %q", status.Adapter, "Wi-Fi")
+	}
+	if status.IPAddress != "10.0.0.42" {
+		t.Errorf("IPAddress = %q, want %q", status.IPAddress, "10.0.0.42")
+	}
+	if status.Gateway != "10.0.0.1" {
+		t.Errorf("Gateway = %q, want %q", status.Gateway, "10.0.0.1")
+	}
+	if status.CurrentDNS != "10.0.0.1" {
+		t.Errorf("CurrentDNS = %q, want %q", status.CurrentDNS, "10.0.0.1")
+	}
+}
+
+func TestGetNetworkStatusNoAdapter(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("powershell").Fails(1, "")
+	fake.On("netsh").Fails(1, "")
+	fake.On("route").Fails(1, "")
+
+	status, err := GetNetworkStatus()
+	if err != nil {
+		t.Fatalf("GetNetworkStatus should never return an error, got: %v", err)
+	}
+	if status.Adapter != "" {
+		t.Errorf("Adapter = %q, want empty", status.Adapter)
+	}
+}
+
+func TestPingTestParsesAverage(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On("ping").Returns(`Ping statistics for 8.8.8.8:
+    Packets: Sent = 4, Received = 4, Lost = 0 (0% loss),
+Approximate round trip times in milli-seconds:
+    Minimum = 11ms, Maximum = 14ms, Average = 12ms`)
+
+	latency, err := PingTest("8.8.8.8")
+	if err != nil {
+		t.Fatalf("PingTest returned error: %v", err)
+	}
+	if latency != 12 {
+		t.Errorf("latency = %v, want 12", latency)
+	}
+}
+
+func TestSetDNSUsesCachedAdapter(t *testing.T) {
+	useMemoryRegistry(t, "")
+	fake := useFakeRunner(t)
+	useJournal(t)
+	setCachedAdapter("Ethernet")
+	fake.On("powershell").Returns("")
+	fake.On("powershell", "-NoProfile", "-Command", "(Get-NetAdapter -Name 'Ethernet' -ErrorAction Stop).InterfaceGuid").Returns("{11111111-0000-0000-0000-000000000000}")
+
+	if err := SetDNS(dnsPresets[0]); err != nil {
+		t.Fatalf("SetDNS returned error: %v", err)
+	}
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected a backup lookup and a single non-elevated PowerShell call, got %v", calls)
+	}
+	script := calls[1].Args[len(calls[1].Args)-1]
+	if script != "Set-DnsClientServerAddress -InterfaceAlias 'Ethernet' -ServerAddresses @('1.1.1.1','1.0.0.1')" {
+		t.Errorf("unexpected DNS command: %s", script)
+	}
+}
+
+func TestNagleRoundTrip(t *testing.T) {
+	useMemoryRegistry(t, `
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{11111111-0000-0000-0000-000000000000}]
+"DhcpIPAddress"="192.168.1.20"
+
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{22222222-0000-0000-0000-000000000000}]
+"DhcpIPAddress"="0.0.0.0"
+`)
+	useJournal(t)
+
+	if isNagleDisabled() {
+		t.Fatal("isNagleDisabled = true before DisableNagle")
+	}
+	if err := DisableNagle(); err != nil {
+		t.Fatalf("DisableNagle: %v", err)
+	}
+	if !isNagleDisabled() {
+		t.Error("isNagleDisabled = false after DisableNagle")
+	}
+	if err := EnableNagle(); err != nil {
+		t.Fatalf("EnableNagle: %v", err)
+	}
+	if isNagleDisabled() {
+		t.Error("isNagleDisabled = true after EnableNagle")
+	}
+}
+
+func TestDisableNagleMissingInterfacesKey(t *testing.T) {
+	useMemoryRegistry(t, "")
+	useJournal(t)
+	if err := DisableNagle(); err == nil {
+		t.Error("DisableNagle should fail when the interfaces key is missing")
+	}
+}
+
+func TestSetDNSUndoneByJournal(t *testing.T) {
+	useMemoryRegistry(t, `
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{11111111-0000-0000-0000-000000000000}]
+"NameServer"="9.9.9.9"
+`)
+	fake := useFakeRunner(t)
+	j := useJournal(t)
+	setCachedAdapter("Ethernet")
+	fake.On("powershell").Returns("")
+	fake.On("powershell", "-NoProfile", "-Command", "(Get-NetAdapter -Name 'Ethernet' -ErrorAction Stop).InterfaceGuid").Returns("{11111111-0000-0000-0000-000000000000}")
+
+	if err := SetDNS(dnsPresets[0]); err != nil {
+		t.Fatalf("SetDNS returned error: %v", err)
+	}
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Module != "network" || entries[0].Tweak != "set_dns" {
+		t.Fatalf("entries = %+v, want one network/set_dns entry", entries)
+	}
+
+	if err := j.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	calls := fake.Calls()
+	script := calls[len(calls)-1].Args[len(calls[len(calls)-1].Args)-1]
+	if script != "Set-DnsClientServerAddress -InterfaceAlias 'Ethernet' -ServerAddresses @('9.9.9.9')" {
+		t.Errorf("unexpected restore command: %s", script)
+	}
+}
+
+func TestDisableNagleUndoneByJournal(t *testing.T) {
+	mem := useMemoryRegistry(t, `
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{11111111-0000-0000-0000-000000000000}]
+"TcpAckFrequency"=dword:00000002
+`)
+	j := useJournal(t)
+
+	if err := DisableNagle(); err != nil {
+		t.Fatalf("DisableNagle: %v", err)
+	}
+	if err := j.RestoreModule("network"); err != nil {
+		t.Fatalf("RestoreModule: %v", err)
+	}
+
+	k, err := mem.OpenKey(reg.LocalMachine, tcpInterfacesPath+`\{11111111-0000-0000-0000-000000000000}`, reg.QueryValue)
+	if err != nil {
+		t.Fatalf("OpenKey: %v", err)
+	}
+	defer k.Close()
+	if v, _, err := k.GetIntegerValue("TcpAckFrequency"); err != nil || v != 2 {
+		t.Errorf("TcpAckFrequency = %d, %v; want 2", v, err)
+	}
+	if _, _, err := k.GetIntegerValue("TCPNoDelay"); err == nil {
+		t.Error("TCPNoDelay did not exist before DisableNagle and should be deleted")
+	}
+}
+
+func TestPlanDisableNagleMakesNoChanges(t *testing.T) {
+	useMemoryRegistry(t, `
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{11111111-0000-0000-0000-000000000000}]
+"TCPNoDelay"=dword:00000000
+`)
+	j := useJournal(t)
+
+	p, err := PlanDisableNagle()
+	if err != nil {
+		t.Fatalf("PlanDisableNagle: %v", err)
+	}
+	var got []string
+	for _, a := range p.Actions {
+		got = append(got, a.String())
+	}
+	iface := `HKLM\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{11111111-0000-0000-0000-000000000000}`
+	want := []string{
+		iface + `\TcpAckFrequency: not set → 1`,
+		iface + `\TCPNoDelay: 0 → 1`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("plan =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if isNagleDisabled() || j.HasEntries() {
+		t.Error("planning changed the registry or the journal")
+	}
+}
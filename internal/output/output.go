@@ -0,0 +1,77 @@
+// Package output writes command results in the format asked for on the
+// command line: JSON or YAML for other tools, or a table for people.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is how a command prints its result. It is a flag.Value.
+type Format string
+
+// The formats a command can print in.
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+)
+
+func (f *Format) String() string {
+	return string(*f)
+}
+
+// Set accepts "json", "yaml" or "table".
+func (f *Format) Set(s string) error {
+	switch Format(s) {
+	case Table, JSON, YAML:
+		*f = Format(s)
+		return nil
+	}
+	return fmt.Errorf("%q is not json, yaml or table", s)
+}
+
+// Structured reports whether f is meant for other tools rather than people.
+func (f Format) Structured() bool {
+	return f == JSON || f == YAML
+}
+
+// Write writes v to w as JSON or YAML. YAML keeps the field names and order
+// of the JSON encoding, so both describe a result the same way.
+func Write(w io.Writer, f Format, v interface{}) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		blockStyle(&doc)
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("%s output is not structured", f)
+}
+
+// blockStyle drops the flow style and quotes that n and its children got
+// from JSON. Strings that would read as another type stay quoted.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
@@ -0,0 +1,84 @@
+package output
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type result struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Enabled  string   `json:"enabled"`
+	Size     int64    `json:"totalSize"`
+	Items    []item   `json:"items"`
+	Empty    []string `json:"empty"`
+	Omitted  string   `json:"omitted,omitempty"`
+	Internal string   `json:"-"`
+}
+
+type item struct {
+	ID string `json:"id"`
+}
+
+func TestFormatFlag(t *testing.T) {
+	f := Table
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(new(bytes.Buffer))
+	fs.Var(&f, "output", "")
+	if err := fs.Parse([]string{"--output", "yaml"}); err != nil || f != YAML {
+		t.Errorf("--output yaml gave %q, %v", f, err)
+	}
+	if err := fs.Parse([]string{"--output", "xml"}); err == nil {
+		t.Error("--output xml should be rejected")
+	}
+	if !JSON.Structured() || !YAML.Structured() || Table.Structured() {
+		t.Error("only JSON and YAML are structured")
+	}
+}
+
+func TestWriteYAMLKeepsJSONNames(t *testing.T) {
+	v := result{Name: "C:\\Temp", Version: "1.0", Enabled: "true", Size: 42,
+		Items: []item{{"a"}, {"b"}}, Empty: []string{}, Internal: "secret"}
+	var buf bytes.Buffer
+	if err := Write(&buf, YAML, v); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got := buf.String()
+	want := `name: C:\Temp
+version: "1.0"
+enabled: "true"
+totalSize: 42
+items:
+  - id: a
+  - id: b
+empty: []
+`
+	if got != want {
+		t.Errorf("YAML =\n%s\nwant\n%s", got, want)
+	}
+
+	var back map[string]interface{}
+	if err := yaml.Unmarshal(buf.Bytes(), &back); err != nil {
+		t.Fatalf("output is not YAML: %v", err)
+	}
+	if back["version"] != "1.0" || back["enabled"] != "true" {
+		t.Errorf("strings changed type: %v", back)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, JSON, item{"a"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := buf.String(); got != "{\n  \"id\": \"a\"\n}\n" {
+		t.Errorf("JSON = %q", got)
+	}
+	if err := Write(&buf, Table, item{"a"}); err == nil || !strings.Contains(err.Error(), "table") {
+		t.Errorf("Write(Table) = %v, want an error", err)
+	}
+}
@@ -0,0 +1,345 @@
+// Package plan describes the changes a mutating operation would make so they
+// can be reviewed before anything is touched, and applies them once confirmed.
+//
+// Modules build a Plan without side effects: they only read the current
+// state of the system. Each Action carries the function that performs it,
+// so executing a plan makes exactly the changes that were shown.
+package plan
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cleanforge/internal/cmd"
+	"cleanforge/internal/reg"
+)
+
+// Kind identifies what an action changes.
+type Kind string
+
+const (
+	KindDelete     Kind = "delete"     // remove a file or directory tree
+	KindQuarantine Kind = "quarantine" // move a file or directory tree into quarantine
+	KindRegistry   Kind = "registry"   // set or delete a registry value
+	KindService    Kind = "service"    // start or stop a service
+	KindFile       Kind = "file"       // rewrite a file
+	KindCommand    Kind = "command"    // run an external command
+)
+
+// Action is a single change in a plan.
+type Action struct {
+	Kind   Kind   `json:"kind"`
+	Module string `json:"module"`
+	Tweak  string `json:"tweak,omitempty"` // tweak or cleanup category the action belongs to
+	Target string `json:"target"`          // path, registry value, service name or command line
+	Old    string `json:"old,omitempty"`   // current state, where it can be read
+	New    string `json:"new,omitempty"`   // state after the action
+	Size   int64  `json:"size,omitempty"`  // bytes freed by a delete, quarantine or cleanup command
+	Files  int    `json:"files,omitempty"` // files removed by a delete, quarantine or cleanup command
+
+	apply func() error
+}
+
+// String renders the action on one line, e.g.
+// `HKCU\Control Panel\Mouse\MouseSpeed: "1" → "0"`.
+func (a Action) String() string {
+	var s string
+	switch a.Kind {
+	case KindDelete:
+		s = "delete " + a.Target
+	case KindQuarantine:
+		s = "quarantine " + a.Target
+	case KindService:
+		s = "service " + a.Target
+	case KindFile:
+		s = "write " + a.Target
+	case KindCommand:
+		s = "run " + a.Target
+	default:
+		s = a.Target
+	}
+	if a.Old != "" || a.New != "" {
+		old := a.Old
+		if old == "" {
+			old = "?"
+		}
+		s += ": " + old + " → " + a.New
+	}
+	return s
+}
+
+// Plan is an ordered list of actions. Create one with New.
+type Plan struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Actions     []Action `json:"actions"`
+	// Warnings lists problems found while planning, such as a directory that
+	// could not be read. They are reported again when the plan is executed.
+	Warnings []string `json:"warnings,omitempty"`
+	// Skipped counts, by tweak or cleanup category, the entries left in
+	// place on purpose, such as files kept by a cleanup filter.
+	Skipped map[string]int `json:"skipped,omitempty"`
+
+	before   []func() error
+	after    []func(*Result)
+	observe  []func(Action, error)
+	executed bool
+}
+
+// nextID makes plan IDs unique within the process.
+var nextID uint64
+
+// New returns an empty plan.
+func New(description string) *Plan {
+	id := fmt.Sprintf("%s-%d", time.Now().UTC().Format("20060102T150405Z"), atomic.AddUint64(&nextID, 1))
+	return &Plan{ID: id, Description: description, Actions: []Action{}}
+}
+
+// Add appends an action and the function that performs it.
+func (p *Plan) Add(a Action, apply func() error) {
+	a.apply = apply
+	p.Actions = append(p.Actions, a)
+}
+
+// Warn records a problem found while planning.
+func (p *Plan) Warn(format string, args ...interface{}) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Skip records n entries of tweak that the plan leaves in place.
+func (p *Plan) Skip(tweak string, n int) {
+	if n <= 0 {
+		return
+	}
+	if p.Skipped == nil {
+		p.Skipped = make(map[string]int)
+	}
+	p.Skipped[tweak] += n
+}
+
+// Before registers fn to run once before any action is applied. Hooks
+// registered more than once run in the order they were added; if one fails,
+// the rest and every action are skipped.
+func (p *Plan) Before(fn func() error) {
+	p.before = append(p.before, fn)
+}
+
+// After registers fn to run with the result once every action has been tried.
+// Hooks registered more than once run in the order they were added.
+func (p *Plan) After(fn func(*Result)) {
+	p.after = append(p.after, fn)
+}
+
+// Observe registers fn to be called after each action has been tried, with
+// the error it returned.
+func (p *Plan) Observe(fn func(a Action, err error)) {
+	p.observe = append(p.observe, fn)
+}
+
+// Failure is an action that could not be applied.
+type Failure struct {
+	Action Action `json:"action"`
+	Err    string `json:"error"`
+	Cause  error  `json:"-"` // the error the action returned
+}
+
+// String renders the failure as "target: error".
+func (f Failure) String() string {
+	return f.Action.Target + ": " + f.Err
+}
+
+// Result reports which actions of an executed plan were applied.
+type Result struct {
+	Applied  []Action  `json:"applied"`
+	Failures []Failure `json:"failures"`
+	Warnings []string  `json:"warnings,omitempty"` // copied from the plan
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// Freed sums the bytes and files freed by the applied actions.
+func (r *Result) Freed() (int64, int) {
+	var size int64
+	var files int
+	for _, a := range r.Applied {
+		size += a.Size
+		files += a.Files
+	}
+	return size, files
+}
+
+// Errors returns the plan's warnings followed by every failure.
+func (r *Result) Errors() []string {
+	errs := make([]string, 0, len(r.Warnings)+len(r.Failures))
+	errs = append(errs, r.Warnings...)
+	for _, f := range r.Failures {
+		errs = append(errs, f.String())
+	}
+	return errs
+}
+
+// Err returns nil if the plan was applied cleanly, or an error listing every
+// warning and failure.
+func (r *Result) Err() error {
+	errs := r.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("plan applied with errors:\n%s", strings.Join(errs, "\n"))
+}
+
+// executing serializes plan execution so changes from different operations
+// never interleave.
+var executing sync.Mutex
+
+// Execute applies every action of a plan in order. A failed action does not
+// stop the ones after it; it is reported in the result instead. The error is
+// only set when the plan could not be started at all.
+func Execute(p *Plan) (*Result, error) {
+	return ExecuteContext(context.Background(), p, nil)
+}
+
+// ExecuteContext is like Execute, but stops before the next action once ctx
+// is cancelled and calls progress after each action has been tried, with the
+// number tried so far. A cancelled plan still runs its After hooks with the
+// actions applied up to that point, and both the partial result and ctx's
+// error are returned.
+func ExecuteContext(ctx context.Context, p *Plan, progress func(done int, a Action)) (*Result, error) {
+	executing.Lock()
+	defer executing.Unlock()
+
+	if p.executed {
+		return nil, errors.New("plan has already been executed")
+	}
+	p.executed = true
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, fn := range p.before {
+		if err := fn(); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &Result{Applied: []Action{}, Failures: []Failure{}, Warnings: p.Warnings, Started: time.Now()}
+	var cancelled error
+	for i, a := range p.Actions {
+		if cancelled = ctx.Err(); cancelled != nil {
+			break
+		}
+		err := apply(a)
+		if err != nil {
+			r.Failures = append(r.Failures, Failure{Action: a, Err: err.Error(), Cause: err})
+		} else {
+			r.Applied = append(r.Applied, a)
+		}
+		for _, fn := range p.observe {
+			fn(a, err)
+		}
+		if progress != nil {
+			progress(i+1, a)
+		}
+	}
+	r.Finished = time.Now()
+
+	for _, fn := range p.after {
+		fn(r)
+	}
+	return r, cancelled
+}
+
+// apply performs a single action. Actions without a function are
+// informational and always succeed.
+func apply(a Action) error {
+	if a.apply == nil {
+		return nil
+	}
+	return a.apply()
+}
+
+// ---------- Action constructors ----------
+
+// RegistryChange describes setting a registry value to newValue, reading
+// the current value from r. Pass "not set" as newValue for a deletion.
+func RegistryChange(r reg.Registry, root reg.Root, path, name, newValue string) Action {
+	return Action{
+		Kind:   KindRegistry,
+		Target: string(root) + `\` + path + `\` + name,
+		Old:    RegistryValue(r, root, path, name),
+		New:    newValue,
+	}
+}
+
+// RegistryValue renders a registry value for display: quoted strings,
+// decimal integers, hex for anything else, or "not set".
+func RegistryValue(r reg.Registry, root reg.Root, path, name string) string {
+	key, err := r.OpenKey(root, path, reg.QueryValue)
+	if errors.Is(err, reg.ErrNotExist) {
+		return "not set"
+	}
+	if err != nil {
+		return "unknown"
+	}
+	defer key.Close()
+
+	data, typ, err := key.GetValue(name)
+	if errors.Is(err, reg.ErrNotExist) {
+		return "not set"
+	}
+	if err != nil {
+		return "unknown"
+	}
+	switch typ {
+	case reg.SZ, reg.ExpandSZ:
+		s, _, err := key.GetStringValue(name)
+		if err != nil {
+			return "unknown"
+		}
+		return strconv.Quote(s)
+	case reg.DWord, reg.QWord:
+		n, _, err := key.GetIntegerValue(name)
+		if err != nil {
+			return "unknown"
+		}
+		return strconv.FormatUint(n, 10)
+	default:
+		return "hex:" + hex.EncodeToString(data)
+	}
+}
+
+// ServiceChange describes moving a service to newState ("running" or
+// "stopped"), reading the current state with `sc query`.
+func ServiceChange(r cmd.Runner, name, newState string) Action {
+	return Action{
+		Kind:   KindService,
+		Target: name,
+		Old:    ServiceState(r, name),
+		New:    newState,
+	}
+}
+
+// ServiceState reports "running" or "stopped" from `sc query`, or
+// "unknown" if the service cannot be queried.
+func ServiceState(r cmd.Runner, name string) string {
+	out, err := r.Command(context.Background(), "sc", "query", name).Output()
+	if err != nil {
+		return "unknown"
+	}
+	if strings.Contains(string(out), "RUNNING") {
+		return "running"
+	}
+	return "stopped"
+}
+
+// Command describes running an external program.
+func Command(name string, args ...string) Action {
+	return Action{Kind: KindCommand, Target: strings.Join(append([]string{name}, args...), " ")}
+}
@@ -0,0 +1,209 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cleanforge/internal/cmd"
+	"cleanforge/internal/reg"
+)
+
+func TestExecuteAppliesInOrderAndContinuesPastFailures(t *testing.T) {
+	var ran []string
+	p := New("test")
+	for _, name := range []string{"a", "b", "c"} {
+		p.Add(Action{Kind: KindCommand, Target: name}, func() error {
+			ran = append(ran, name)
+			if name == "b" {
+				return errors.New("boom")
+			}
+			return nil
+		})
+	}
+
+	r, err := Execute(p)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if len(r.Applied) != 2 || len(r.Failures) != 1 || r.Failures[0].String() != "b: boom" {
+		t.Errorf("result = %+v", r)
+	}
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "b: boom") {
+		t.Errorf("Err() = %v, want the failure listed", err)
+	}
+}
+
+func TestExecuteRunsOnce(t *testing.T) {
+	n := 0
+	p := New("test")
+	p.Add(Action{Kind: KindCommand, Target: "x"}, func() error { n++; return nil })
+
+	if _, err := Execute(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Execute(p); err == nil {
+		t.Error("executing a plan twice should fail")
+	}
+	if n != 1 {
+		t.Errorf("action ran %d times, want 1", n)
+	}
+}
+
+func TestExecuteContextStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ran []string
+	p := New("test")
+	for _, name := range []string{"a", "b", "c"} {
+		p.Add(Action{Kind: KindDelete, Target: name}, func() error {
+			ran = append(ran, name)
+			return nil
+		})
+	}
+	var after *Result
+	p.After(func(r *Result) { after = r })
+
+	var progress []int
+	r, err := ExecuteContext(ctx, p, func(done int, a Action) {
+		progress = append(progress, done)
+		if a.Target == "b" {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecuteContext error = %v, want context.Canceled", err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress %v, want %v", progress, want)
+	}
+	if r == nil || len(r.Applied) != 2 || after != r {
+		t.Errorf("partial result = %+v, after hook got %+v", r, after)
+	}
+}
+
+func TestBeforeAndAfterHooks(t *testing.T) {
+	applied := false
+	p := New("test")
+	p.Add(Action{Kind: KindCommand, Target: "x"}, func() error { applied = true; return nil })
+	var ran []string
+	p.Before(func() error { ran = append(ran, "first"); return nil })
+	p.Before(func() error { ran = append(ran, "second"); return errors.New("no restore point") })
+	p.Before(func() error { ran = append(ran, "third"); return nil })
+	p.After(func(*Result) { t.Error("After must not run when Before fails") })
+
+	if _, err := Execute(p); err == nil {
+		t.Fatal("Execute should fail when Before fails")
+	}
+	if applied {
+		t.Error("no action may run when Before fails")
+	}
+	if strings.Join(ran, ",") != "first,second" {
+		t.Errorf("Before hooks ran %v, want first and second in order", ran)
+	}
+
+	var got *Result
+	p = New("test")
+	p.Add(Action{Kind: KindCommand, Target: "x", Size: 10, Files: 2}, func() error { return nil })
+	p.Warn("skipped %s", "y")
+	p.After(func(r *Result) { got = r })
+	if _, err := Execute(p); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("After did not run")
+	}
+	if size, files := got.Freed(); size != 10 || files != 2 {
+		t.Errorf("Freed() = (%d, %d), want (10, 2)", size, files)
+	}
+	if errs := got.Errors(); len(errs) != 1 || errs[0] != "skipped y" {
+		t.Errorf("Errors() = %v, want the plan warning", errs)
+	}
+}
+
+func TestObserveAndSeveralAfterHooks(t *testing.T) {
+	p := New("test")
+	p.Add(Action{Kind: KindDelete, Target: "a"}, func() error { return nil })
+	p.Add(Action{Kind: KindDelete, Target: "b"}, func() error { return errors.New("in use") })
+	p.Skip("cat", 3)
+	p.Skip("cat", 0)
+
+	var seen []string
+	p.Observe(func(a Action, err error) {
+		seen = append(seen, fmt.Sprintf("%s:%v", a.Target, err))
+	})
+	var order []int
+	p.After(func(*Result) { order = append(order, 1) })
+	p.After(func(*Result) { order = append(order, 2) })
+
+	r, err := Execute(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a:<nil>", "b:in use"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("observed %v, want %v", seen, want)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(order, want) {
+		t.Errorf("After hooks ran in order %v, want %v", order, want)
+	}
+	if p.Skipped["cat"] != 3 || len(p.Skipped) != 1 {
+		t.Errorf("Skipped = %v, want cat: 3", p.Skipped)
+	}
+	if r.Started.IsZero() || r.Finished.Before(r.Started) {
+		t.Errorf("result times = %v .. %v", r.Started, r.Finished)
+	}
+}
+
+func TestRegistryChange(t *testing.T) {
+	mem, err := reg.FromReg(`
+[HKEY_CURRENT_USER\Control Panel\Mouse]
+"MouseSpeed"="1"
+"Flags"=dword:0000001a
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, new, want string
+	}{
+		{"MouseSpeed", `"0"`, `HKCU\Control Panel\Mouse\MouseSpeed: "1" → "0"`},
+		{"Flags", "0", `HKCU\Control Panel\Mouse\Flags: 26 → 0`},
+		{"Missing", "1", `HKCU\Control Panel\Mouse\Missing: not set → 1`},
+	}
+	for _, tt := range tests {
+		a := RegistryChange(mem, reg.CurrentUser, `Control Panel\Mouse`, tt.name, tt.new)
+		if got := a.String(); got != tt.want {
+			t.Errorf("RegistryChange(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestServiceChange(t *testing.T) {
+	fake := cmd.NewFake()
+	fake.On("sc", "query", "SysMain").Returns("        STATE              : 4  RUNNING")
+
+	a := ServiceChange(fake, "SysMain", "stopped")
+	if got, want := a.String(), "service SysMain: running → stopped"; got != want {
+		t.Errorf("ServiceChange = %q, want %q", got, want)
+	}
+	if got := ServiceState(fake, "Missing"); got != "unknown" {
+		t.Errorf("ServiceState(Missing) = %q, want unknown", got)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if got, want := Command("ipconfig", "/flushdns").String(), "run ipconfig /flushdns"; got != want {
+		t.Errorf("Command = %q, want %q", got, want)
+	}
+}
@@ -5,11 +5,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
-	"golang.org/x/sys/windows/registry"
+	"cleanforge/internal/backup"
+	"cleanforge/internal/plan"
+	"cleanforge/internal/reg"
 )
 
+// registry is the Windows registry. Tests replace it with a reg.Memory.
+var registry reg.Registry = reg.Default
+
+// journal records every change this package makes so it can be undone.
+var journal = backup.Default
+
 // PrivacyTweak represents a single privacy configuration change.
 type PrivacyTweak struct {
 	ID          string `json:"id"`
@@ -31,7 +40,7 @@ type registryTweak struct {
 
 // registryEntry describes a single registry value to set.
 type registryEntry struct {
-	rootKey registry.Key
+	rootKey reg.Root
 	path    string
 	name    string
 	value   uint32
@@ -65,7 +74,7 @@ var allTweaks = []registryTweak{
 		description: "Disables Windows diagnostic data collection (AllowTelemetry=0)",
 		category:    "telemetry",
 		entries: []registryEntry{
-			{registry.LOCAL_MACHINE, `SOFTWARE\Policies\Microsoft\Windows\DataCollection`, "AllowTelemetry", 0},
+			{reg.LocalMachine, `SOFTWARE\Policies\Microsoft\Windows\DataCollection`, "AllowTelemetry", 0},
 		},
 	},
 	{
@@ -74,8 +83,8 @@ var allTweaks = []registryTweak{
 		description: "Prevents Windows from tracking and sending your activity history",
 		category:    "tracking",
 		entries: []registryEntry{
-			{registry.LOCAL_MACHINE, `SOFTWARE\Policies\Microsoft\Windows\System`, "EnableActivityFeed", 0},
-			{registry.LOCAL_MACHINE, `SOFTWARE\Policies\Microsoft\Windows\System`, "PublishUserActivities", 0},
+			{reg.LocalMachine, `SOFTWARE\Policies\Microsoft\Windows\System`, "EnableActivityFeed", 0},
+			{reg.LocalMachine, `SOFTWARE\Policies\Microsoft\Windows\System`, "PublishUserActivities", 0},
 		},
 	},
 	{
@@ -93,7 +102,7 @@ var allTweaks = []registryTweak{
 		description: "Prevents apps from using your advertising ID for targeted ads",
 		category:    "ads",
 		entries: []registryEntry{
-			{registry.CURRENT_USER, `SOFTWARE\Microsoft\Windows\CurrentVersion\AdvertisingInfo`, "Enabled", 0},
+			{reg.CurrentUser, `SOFTWARE\Microsoft\Windows\CurrentVersion\AdvertisingInfo`, "Enabled", 0},
 		},
 	},
 	{
@@ -102,7 +111,7 @@ var allTweaks = []registryTweak{
 		description: "Disables Cortana assistant and its data collection",
 		category:    "cortana",
 		entries: []registryEntry{
-			{registry.LOCAL_MACHINE, `SOFTWARE\Policies\Microsoft\Windows\Windows Search`, "AllowCortana", 0},
+			{reg.LocalMachine, `SOFTWARE\Policies\Microsoft\Windows\Windows Search`, "AllowCortana", 0},
 		},
 	},
 	{
@@ -111,7 +120,7 @@ var allTweaks = []registryTweak{
 		description: "Removes Bing web search suggestions from the Start Menu search",
 		category:    "cortana",
 		entries: []registryEntry{
-			{registry.CURRENT_USER, `SOFTWARE\Policies\Microsoft\Windows\Explorer`, "DisableSearchBoxSuggestions", 1},
+			{reg.CurrentUser, `SOFTWARE\Policies\Microsoft\Windows\Explorer`, "DisableSearchBoxSuggestions", 1},
 		},
 	},
 	{
@@ -120,7 +129,7 @@ var allTweaks = []registryTweak{
 		description: "Stops Windows from asking for feedback",
 		category:    "telemetry",
 		entries: []registryEntry{
-			{registry.CURRENT_USER, `SOFTWARE\Microsoft\Siuf\Rules`, "NumberOfSIUFInPeriod", 0},
+			{reg.CurrentUser, `SOFTWARE\Microsoft\Siuf\Rules`, "NumberOfSIUFInPeriod", 0},
 		},
 	},
 	{
@@ -129,7 +138,7 @@ var allTweaks = []registryTweak{
 		description: "Prevents Microsoft from using diagnostic data for personalized tips and ads",
 		category:    "ads",
 		entries: []registryEntry{
-			{registry.CURRENT_USER, `SOFTWARE\Microsoft\Windows\CurrentVersion\Privacy`, "TailoredExperiencesWithDiagnosticDataEnabled", 0},
+			{reg.CurrentUser, `SOFTWARE\Microsoft\Windows\CurrentVersion\Privacy`, "TailoredExperiencesWithDiagnosticDataEnabled", 0},
 		},
 	},
 	{
@@ -138,9 +147,9 @@ var allTweaks = []registryTweak{
 		description: "Disables Windows tips, suggestions, and recommended content",
 		category:    "ads",
 		entries: []registryEntry{
-			{registry.CURRENT_USER, `SOFTWARE\Microsoft\Windows\CurrentVersion\ContentDeliveryManager`, "SubscribedContent-338389Enabled", 0},
-			{registry.CURRENT_USER, `SOFTWARE\Microsoft\Windows\CurrentVersion\ContentDeliveryManager`, "SoftLandingEnabled", 0},
-			{registry.CURRENT_USER, `SOFTWARE\Microsoft\Windows\CurrentVersion\ContentDeliveryManager`, "SystemPaneSuggestionsEnabled", 0},
+			{reg.CurrentUser, `SOFTWARE\Microsoft\Windows\CurrentVersion\ContentDeliveryManager`, "SubscribedContent-338389Enabled", 0},
+			{reg.CurrentUser, `SOFTWARE\Microsoft\Windows\CurrentVersion\ContentDeliveryManager`, "SoftLandingEnabled", 0},
+			{reg.CurrentUser, `SOFTWARE\Microsoft\Windows\CurrentVersion\ContentDeliveryManager`, "SystemPaneSuggestionsEnabled", 0},
 		},
 	},
 	{
@@ -156,7 +165,7 @@ var allTweaks = []registryTweak{
 		description: "Prevents automatic connection to suggested open hotspots and shared networks",
 		category:    "tracking",
 		entries: []registryEntry{
-			{registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\WcmSvc\wifinetworkmanager\config`, "AutoConnectAllowedOEM", 0},
+			{reg.LocalMachine, `SOFTWARE\Microsoft\WcmSvc\wifinetworkmanager\config`, "AutoConnectAllowedOEM", 0},
 		},
 	},
 	{
@@ -165,7 +174,7 @@ var allTweaks = []registryTweak{
 		description: "Stops Windows from sending error reports to Microsoft",
 		category:    "telemetry",
 		entries: []registryEntry{
-			{registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows\Windows Error Reporting`, "Disabled", 1},
+			{reg.LocalMachine, `SOFTWARE\Microsoft\Windows\Windows Error Reporting`, "Disabled", 1},
 		},
 	},
 }
@@ -202,15 +211,51 @@ func ApplyTweak(tweakID string) error {
 	return fmt.Errorf("unknown tweak ID: %s", tweakID)
 }
 
+// PlanTweaks lists every change applying the tweaks with the given IDs would
+// make, without making any.
+func PlanTweaks(ids []string) (*plan.Plan, error) {
+	p := plan.New("Apply privacy tweaks " + strings.Join(ids, ", "))
+	for _, id := range ids {
+		found := false
+		for _, t := range allTweaks {
+			if t.id == id {
+				planTweak(p, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown tweak ID: %s", id)
+		}
+	}
+	return p, nil
+}
+
 // ApplyAll applies all available privacy tweaks.
 func ApplyAll() error {
-	var lastErr error
+	p, err := PlanAll()
+	if err != nil {
+		return err
+	}
+	return execute(p)
+}
+
+// PlanAll lists every change ApplyAll would make, without making any.
+func PlanAll() (*plan.Plan, error) {
+	p := plan.New("Apply all privacy protections")
 	for _, t := range allTweaks {
-		if err := applyTweak(t); err != nil {
-			lastErr = fmt.Errorf("failed to apply %s: %w", t.id, err)
-		}
+		planTweak(p, t)
 	}
-	return lastErr
+	return p, nil
+}
+
+// execute applies a plan and folds its failures into one error.
+func execute(p *plan.Plan) error {
+	r, err := plan.Execute(p)
+	if err != nil {
+		return err
+	}
+	return r.Err()
 }
 
 // RestoreAll restores all privacy tweaks to Windows defaults.
@@ -224,43 +269,81 @@ func RestoreAll() error {
 	return lastErr
 }
 
-// applyTweak applies a single tweak's registry changes or hosts file modifications.
+// applyTweak applies a single tweak's registry changes or hosts file modifications,
+// recording the previous state in the backup journal.
 func applyTweak(t registryTweak) error {
-	// Special case: location tracking uses a string registry value
-	if t.id == "disable_location" {
-		return applyLocationTweak()
-	}
-
-	// Special case: hosts file blocking
-	if t.id == "block_telemetry_hosts" {
-		return applyHostsBlock()
-	}
+	p := plan.New("Apply privacy tweak " + t.name)
+	planTweak(p, t)
+	return execute(p)
+}
 
-	// Apply all registry entries for this tweak
-	for _, entry := range t.entries {
-		if err := setRegistryDWORD(entry.rootKey, entry.path, entry.name, entry.value); err != nil {
-			return fmt.Errorf("failed to set %s\\%s: %w", entry.path, entry.name, err)
+// planTweak adds a tweak's registry changes or hosts file modification to a
+// plan. Each change records the previous state in the backup journal right
+// before it is made.
+func planTweak(p *plan.Plan, t registryTweak) {
+	rec := journal.For("privacy", t.id)
+	add := func(a plan.Action, apply func() error) {
+		a.Module = "privacy"
+		a.Tweak = t.id
+		p.Add(a, apply)
+	}
+
+	switch t.id {
+	case "disable_location":
+		// Special case: location tracking uses a string registry value
+		add(plan.RegistryChange(registry, reg.CurrentUser, locationKeyPath, "Value", strconv.Quote("Deny")), func() error {
+			return applyLocationTweak(rec)
+		})
+
+	case "block_telemetry_hosts":
+		// Special case: hosts file blocking
+		old := "telemetry hosts not blocked"
+		if isHostsBlockApplied() {
+			old = "telemetry hosts blocked"
+		}
+		add(plan.Action{
+			Kind:   plan.KindFile,
+			Target: getHostsFilePath(),
+			Old:    old,
+			New:    fmt.Sprintf("%d telemetry hosts blocked", len(telemetryHosts)),
+		}, func() error {
+			return applyHostsBlock(rec)
+		})
+
+	default:
+		// Apply all registry entries for this tweak
+		for _, entry := range t.entries {
+			newValue := strconv.FormatUint(uint64(entry.value), 10)
+			add(plan.RegistryChange(registry, entry.rootKey, entry.path, entry.name, newValue), func() error {
+				if err := setRegistryDWORD(rec, entry.rootKey, entry.path, entry.name, entry.value); err != nil {
+					return fmt.Errorf("failed to set %s\\%s: %w", entry.path, entry.name, err)
+				}
+				return nil
+			})
 		}
 	}
-
-	return nil
 }
 
 // restoreTweak restores a single tweak to Windows defaults.
 func restoreTweak(t registryTweak) error {
+	rec := journal.For("privacy", t.id)
+
 	// Special case: location tracking
 	if t.id == "disable_location" {
-		return restoreLocationTweak()
+		return restoreLocationTweak(rec)
 	}
 
 	// Special case: hosts file
 	if t.id == "block_telemetry_hosts" {
+		if err := rec.File(getHostsFilePath()); err != nil {
+			return err
+		}
 		return removeHostsBlock()
 	}
 
 	// Delete all registry values for this tweak to restore defaults
 	for _, entry := range t.entries {
-		if err := deleteRegistryValue(entry.rootKey, entry.path, entry.name); err != nil {
+		if err := deleteRegistryValue(rec, entry.rootKey, entry.path, entry.name); err != nil {
 			// Ignore errors from values that don't exist
 			continue
 		}
@@ -298,9 +381,15 @@ func isTweakApplied(t registryTweak) bool {
 
 // --- Location Tweak (string value) ---
 
-func applyLocationTweak() error {
-	keyPath := `SOFTWARE\Microsoft\Windows\CurrentVersion\CapabilityAccessManager\ConsentStore\location`
-	k, _, err := registry.CreateKey(registry.CURRENT_USER, keyPath, registry.SET_VALUE)
+// locationKeyPath holds the per-user location consent setting.
+const locationKeyPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\CapabilityAccessManager\ConsentStore\location`
+
+func applyLocationTweak(rec backup.Recorder) error {
+	keyPath := locationKeyPath
+	if err := rec.RegistryValue(reg.CurrentUser, keyPath, "Value"); err != nil {
+		return err
+	}
+	k, _, err := registry.CreateKey(reg.CurrentUser, keyPath, reg.SetValue)
 	if err != nil {
 		return fmt.Errorf("failed to create location key: %w", err)
 	}
@@ -313,14 +402,18 @@ func applyLocationTweak() error {
 	return nil
 }
 
-func restoreLocationTweak() error {
-	keyPath := `SOFTWARE\Microsoft\Windows\CurrentVersion\CapabilityAccessManager\ConsentStore\location`
-	k, err := registry.OpenKey(registry.CURRENT_USER, keyPath, registry.SET_VALUE)
+func restoreLocationTweak(rec backup.Recorder) error {
+	keyPath := locationKeyPath
+	k, err := registry.OpenKey(reg.CurrentUser, keyPath, reg.SetValue)
 	if err != nil {
 		return nil // Key doesn't exist, nothing to restore
 	}
 	defer k.Close()
 
+	if err := rec.RegistryValue(reg.CurrentUser, keyPath, "Value"); err != nil {
+		return err
+	}
+
 	if err := k.SetStringValue("Value", "Allow"); err != nil {
 		return fmt.Errorf("failed to restore location Value: %w", err)
 	}
@@ -329,8 +422,8 @@ func restoreLocationTweak() error {
 }
 
 func isLocationTweakApplied() bool {
-	keyPath := `SOFTWARE\Microsoft\Windows\CurrentVersion\CapabilityAccessManager\ConsentStore\location`
-	k, err := registry.OpenKey(registry.CURRENT_USER, keyPath, registry.READ)
+	keyPath := locationKeyPath
+	k, err := registry.OpenKey(reg.CurrentUser, keyPath, reg.Read)
 	if err != nil {
 		return false
 	}
@@ -354,9 +447,13 @@ func getHostsFilePath() string {
 	return filepath.Join(systemRoot, "System32", "drivers", "etc", "hosts")
 }
 
-func applyHostsBlock() error {
+func applyHostsBlock(rec backup.Recorder) error {
 	hostsPath := getHostsFilePath()
 
+	if err := rec.File(hostsPath); err != nil {
+		return err
+	}
+
 	// First, remove any existing CleanForge block to avoid duplicates
 	if err := removeHostsBlock(); err != nil {
 		// Non-fatal; continue with appending
@@ -452,9 +549,13 @@ func isHostsBlockApplied() bool {
 
 // --- Registry Helpers ---
 
-// setRegistryDWORD creates or opens the specified key and sets a DWORD value.
-func setRegistryDWORD(rootKey registry.Key, path, name string, value uint32) error {
-	k, _, err := registry.CreateKey(rootKey, path, registry.SET_VALUE)
+// setRegistryDWORD records the current value in the journal, then creates or
+// opens the specified key and sets a DWORD value.
+func setRegistryDWORD(rec backup.Recorder, rootKey reg.Root, path, name string, value uint32) error {
+	if err := rec.RegistryValue(rootKey, path, name); err != nil {
+		return err
+	}
+	k, _, err := registry.CreateKey(rootKey, path, reg.SetValue)
 	if err != nil {
 		return fmt.Errorf("failed to create/open key %s: %w", path, err)
 	}
@@ -468,8 +569,8 @@ func setRegistryDWORD(rootKey registry.Key, path, name string, value uint32) err
 }
 
 // readRegistryDWORD reads a DWORD value from the registry.
-func readRegistryDWORD(rootKey registry.Key, path, name string) (uint32, error) {
-	k, err := registry.OpenKey(rootKey, path, registry.READ)
+func readRegistryDWORD(rootKey reg.Root, path, name string) (uint32, error) {
+	k, err := registry.OpenKey(rootKey, path, reg.Read)
 	if err != nil {
 		return 0, err
 	}
@@ -483,9 +584,13 @@ func readRegistryDWORD(rootKey registry.Key, path, name string) (uint32, error)
 	return uint32(val), nil
 }
 
-// deleteRegistryValue deletes a named value from the given registry key.
-func deleteRegistryValue(rootKey registry.Key, path, name string) error {
-	k, err := registry.OpenKey(rootKey, path, registry.SET_VALUE)
+// deleteRegistryValue records the current value in the journal and then
+// deletes it from the given registry key.
+func deleteRegistryValue(rec backup.Recorder, rootKey reg.Root, path, name string) error {
+	if err := rec.RegistryValue(rootKey, path, name); err != nil {
+		return err
+	}
+	k, err := registry.OpenKey(rootKey, path, reg.SetValue)
 	if err != nil {
 		return err
 	}
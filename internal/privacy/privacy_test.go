@@ -5,8 +5,26 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"cleanforge/internal/backup"
+	"cleanforge/internal/cmd"
+	"cleanforge/internal/plan"
+	"cleanforge/internal/reg"
 )
 
+// useMemoryRegistry swaps the package registry for an empty reg.Memory, and
+// the journal for one in a temp directory backed by it, for the duration of
+// the test.
+func useMemoryRegistry(t *testing.T) *reg.Memory {
+	t.Helper()
+	mem := reg.NewMemory()
+	prevRegistry, prevJournal := registry, journal
+	registry = mem
+	journal = backup.NewJournal(t.TempDir(), mem, cmd.NewFake())
+	t.Cleanup(func() { registry, journal = prevRegistry, prevJournal })
+	return mem
+}
+
 func TestAllTweaksNotEmpty(t *testing.T) {
 	if len(allTweaks) == 0 {
 		t.Fatal("allTweaks should not be empty")
@@ -276,3 +294,200 @@ func TestLocationTweakDoesNotPanic(t *testing.T) {
 
 	_ = isLocationTweakApplied()
 }
+
+func TestRegistryTweakRoundTrip(t *testing.T) {
+	for _, tw := range allTweaks {
+		if len(tw.entries) == 0 {
+			continue
+		}
+		t.Run(tw.id, func(t *testing.T) {
+			useMemoryRegistry(t)
+
+			if isTweakApplied(tw) {
+				t.Fatal("tweak reported applied on an empty registry")
+			}
+			if err := applyTweak(tw); err != nil {
+				t.Fatalf("applyTweak: %v", err)
+			}
+			if !isTweakApplied(tw) {
+				t.Error("tweak not reported applied after applyTweak")
+			}
+			if err := restoreTweak(tw); err != nil {
+				t.Fatalf("restoreTweak: %v", err)
+			}
+			if isTweakApplied(tw) {
+				t.Error("tweak still reported applied after restoreTweak")
+			}
+			for _, e := range tw.entries {
+				if _, err := readRegistryDWORD(e.rootKey, e.path, e.name); err == nil {
+					t.Errorf("%s\\%s still present after restore", e.path, e.name)
+				}
+			}
+		})
+	}
+}
+
+func TestLocationTweakRoundTrip(t *testing.T) {
+	mem := useMemoryRegistry(t)
+	const keyPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\CapabilityAccessManager\ConsentStore\location`
+
+	if err := applyLocationTweak(journal.For("privacy", "disable_location")); err != nil {
+		t.Fatalf("applyLocationTweak: %v", err)
+	}
+	if !isLocationTweakApplied() {
+		t.Error("location tweak not reported applied")
+	}
+	if err := restoreLocationTweak(journal.For("privacy", "disable_location")); err != nil {
+		t.Fatalf("restoreLocationTweak: %v", err)
+	}
+
+	k, err := mem.OpenKey(reg.CurrentUser, keyPath, reg.QueryValue)
+	if err != nil {
+		t.Fatalf("OpenKey: %v", err)
+	}
+	defer k.Close()
+	if v, _, _ := k.GetStringValue("Value"); v != "Allow" {
+		t.Errorf("location Value = %q, want %q", v, "Allow")
+	}
+}
+
+func TestApplyTweakIsJournaled(t *testing.T) {
+	mem := useMemoryRegistry(t)
+	tw := allTweaks[0]
+	e := tw.entries[0]
+
+	// Seed a pre-existing value so restore has something to put back.
+	k, _, err := mem.CreateKey(e.rootKey, e.path, reg.SetValue)
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	_ = k.SetDWordValue(e.name, e.value+1)
+	k.Close()
+
+	if err := ApplyTweak(tw.id); err != nil {
+		t.Fatalf("ApplyTweak: %v", err)
+	}
+	entries, err := journal.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(entries) != len(tw.entries) {
+		t.Fatalf("journal has %d entries, want %d", len(entries), len(tw.entries))
+	}
+	for _, entry := range entries {
+		if entry.Module != "privacy" || entry.Tweak != tw.id {
+			t.Errorf("entry %s attributed to %q/%q, want privacy/%s", entry.Target, entry.Module, entry.Tweak, tw.id)
+		}
+	}
+
+	if err := journal.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	if v, err := readRegistryDWORD(e.rootKey, e.path, e.name); err != nil || v != e.value+1 {
+		t.Errorf("%s = %d, %v after journal restore, want %d", e.name, v, err, e.value+1)
+	}
+}
+
+func TestHostsBlockJournalRoundTrip(t *testing.T) {
+	useMemoryRegistry(t)
+	systemRoot := t.TempDir()
+	t.Setenv("SystemRoot", systemRoot)
+
+	hostsPath := getHostsFilePath()
+	if err := os.MkdirAll(filepath.Dir(hostsPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	initialContent := "# Hosts file\n127.0.0.1 localhost\n"
+	if err := os.WriteFile(hostsPath, []byte(initialContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyTweak("block_telemetry_hosts"); err != nil {
+		t.Fatalf("ApplyTweak: %v", err)
+	}
+	if !isHostsBlockApplied() {
+		t.Fatal("hosts block not applied")
+	}
+
+	if err := journal.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	data, _ := os.ReadFile(hostsPath)
+	if string(data) != initialContent {
+		t.Errorf("hosts after restore = %q, want %q", data, initialContent)
+	}
+}
+
+func TestPlanAllMakesNoChanges(t *testing.T) {
+	useMemoryRegistry(t)
+	t.Setenv("SystemRoot", t.TempDir())
+	hostsPath := getHostsFilePath()
+	if err := os.MkdirAll(filepath.Dir(hostsPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hostsPath, []byte("127.0.0.1 localhost\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := PlanAll()
+	if err != nil {
+		t.Fatalf("PlanAll: %v", err)
+	}
+
+	want := 2 // location and hosts file
+	for _, tw := range allTweaks {
+		want += len(tw.entries)
+	}
+	if len(p.Actions) != want {
+		t.Fatalf("plan has %d actions, want %d", len(p.Actions), want)
+	}
+	for _, a := range p.Actions {
+		if a.Module != "privacy" || a.Tweak == "" {
+			t.Errorf("action %s attributed to %q/%q", a, a.Module, a.Tweak)
+		}
+	}
+	if journal.HasEntries() || isHostsBlockApplied() {
+		t.Error("planning changed the journal or the hosts file")
+	}
+	for _, tw := range allTweaks {
+		if isTweakApplied(tw) && len(tw.entries) > 0 {
+			t.Errorf("%s applied by planning", tw.id)
+		}
+	}
+
+	r, err := plan.Execute(p)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("plan applied with errors: %v", err)
+	}
+	for _, tw := range allTweaks {
+		if !isTweakApplied(tw) {
+			t.Errorf("%s not applied after executing the plan", tw.id)
+		}
+	}
+}
+
+func TestPlanTweaks(t *testing.T) {
+	useMemoryRegistry(t)
+
+	p, err := PlanTweaks([]string{"disable_telemetry", "disable_error_reporting"})
+	if err != nil {
+		t.Fatalf("PlanTweaks: %v", err)
+	}
+	tweaks := make(map[string]bool)
+	for _, a := range p.Actions {
+		tweaks[a.Tweak] = true
+	}
+	if len(tweaks) != 2 || !tweaks["disable_telemetry"] || !tweaks["disable_error_reporting"] {
+		t.Errorf("plan covers tweaks %v, want the two asked for", tweaks)
+	}
+	if journal.HasEntries() {
+		t.Error("planning changed the journal")
+	}
+
+	if _, err := PlanTweaks([]string{"disable_telemetry", "nonexistent_tweak_id"}); err == nil {
+		t.Error("PlanTweaks with an unknown ID should return error")
+	}
+}
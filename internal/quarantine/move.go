@@ -0,0 +1,163 @@
+package quarantine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"cleanforge/internal/guard"
+)
+
+// errPartialMove reports that a tree was copied into place but some of the
+// original could not be removed.
+var errPartialMove = errors.New("copied, but the original could not be fully removed")
+
+// move renames src to dst. If that fails, for example across volumes, it
+// copies the tree and removes the original instead, as far as the deletion
+// guard allows.
+func move(src, dst string) error {
+	renameErr := os.Rename(src, dst)
+	if renameErr == nil {
+		return nil
+	}
+	if _, err := os.Lstat(src); err != nil {
+		return renameErr
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("failed to move %s: %w", src, err)
+	}
+	if err := guard.Default.RemoveAll("", src); err != nil {
+		if info, statErr := os.Lstat(src); statErr == nil && !info.IsDir() {
+			// A file is removed all at once, so the copy is not needed.
+			os.Remove(dst)
+			return fmt.Errorf("failed to remove %s: %w", src, err)
+		}
+		return fmt.Errorf("%s: %w: %v", src, errPartialMove, err)
+	}
+	return nil
+}
+
+// copyTree copies a file, or a directory and everything in it.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info)
+		}
+	})
+}
+
+func copyFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// hashTree returns the SHA-256 of a file, or for a directory the SHA-256 of
+// every file's slash-separated relative path and hash in lexical order, along
+// with the total size and number of files. Symlinks are hashed by target and
+// not followed.
+func hashTree(root string) (string, int64, int, error) {
+	info, err := os.Lstat(root)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if !info.IsDir() {
+		sum, err := hashEntry(root, info)
+		return sum, info.Size(), 1, err
+	}
+
+	type file struct{ rel, sum string }
+	var files []file
+	var size int64
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := hashEntry(path, info)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		files = append(files, file{filepath.ToSlash(rel), sum})
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].rel < files[j].rel })
+	h := sha256.New()
+	for _, f := range files {
+		fmt.Fprintf(h, "%s\x00%s\n", f.rel, f.sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, len(files), nil
+}
+
+func hashEntry(path string, info fs.FileInfo) (string, error) {
+	h := sha256.New()
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(h, "symlink:"+link)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
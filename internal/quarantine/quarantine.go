@@ -0,0 +1,467 @@
+// Package quarantine keeps files removed by the cleaner for a while instead
+// of deleting them, so a bad cleanup can be undone.
+//
+// Each cleanup is a run stored in its own directory:
+//
+//	<dir>/<run ID>/run.json        description and creation time
+//	<dir>/<run ID>/manifest.jsonl  one Item per quarantined file or directory
+//	<dir>/<run ID>/items/<item ID> the quarantined file or directory itself
+//
+// Runs older than the store's retention are purged when the next run begins.
+package quarantine
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cleanforge/internal/guard"
+)
+
+// DefaultRetention is how long quarantined runs are kept unless the store's
+// Retention says otherwise.
+const DefaultRetention = 30 * 24 * time.Hour
+
+const (
+	runFilename      = "run.json"
+	manifestFilename = "manifest.jsonl"
+	itemsDirname     = "items"
+)
+
+// Item is a file or directory tree moved into quarantine.
+type Item struct {
+	ID       int    `json:"id"`
+	Original string `json:"original"` // where it was, and is restored to
+	Dir      bool   `json:"dir"`
+	Size     int64  `json:"size"`
+	Files    int    `json:"files"`
+	SHA256   string `json:"sha256"` // of the file, or of every file's path and hash for a directory
+	Moved    string `json:"moved"`  // RFC 3339
+
+	// Restored is set once the item has been moved back.
+	Restored bool `json:"restored,omitempty"`
+}
+
+// Run is the set of items quarantined by one cleanup.
+type Run struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Created     string `json:"created"` // RFC 3339
+	Items       []Item `json:"items"`
+
+	store *Store
+}
+
+// Size sums the items that have not been restored.
+func (r *Run) Size() (int64, int) {
+	var size int64
+	var files int
+	for _, it := range r.Items {
+		if !it.Restored {
+			size += it.Size
+			files += it.Files
+		}
+	}
+	return size, files
+}
+
+// Store is a quarantine directory.
+type Store struct {
+	mu  sync.Mutex
+	dir string
+
+	// Retention is how long runs are kept. Zero means DefaultRetention.
+	Retention time.Duration
+}
+
+// NewStore returns a store kept in dir. An empty dir means DefaultDir().
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Default is the store the cleaner and the restore commands share.
+var Default = NewStore("")
+
+// DefaultDir returns ~/.cleanforge/quarantine.
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(homeDir, ".cleanforge", "quarantine")
+}
+
+// Dir returns the directory holding the runs.
+func (s *Store) Dir() string {
+	if s.dir != "" {
+		return s.dir
+	}
+	return DefaultDir()
+}
+
+func (s *Store) retention() time.Duration {
+	if s.Retention > 0 {
+		return s.Retention
+	}
+	return DefaultRetention
+}
+
+func (s *Store) runDir(id string) string {
+	return filepath.Join(s.Dir(), id)
+}
+
+// removeRun deletes a run's directory. The deletion guard keeps it inside
+// the store, whatever links were put there.
+func (s *Store) removeRun(id string) error {
+	return guard.Default.RemoveAll(s.Dir(), s.runDir(id))
+}
+
+// checkRunID rejects IDs that would reach outside the store.
+func checkRunID(id string) error {
+	if id == "" || id == "." || id == ".." || filepath.Base(id) != id {
+		return fmt.Errorf("unknown quarantine run: %s", id)
+	}
+	return nil
+}
+
+// Begin starts a new run, purging expired runs first.
+func (s *Store) Begin(description string) (*Run, error) {
+	if _, err := s.PurgeExpired(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.Dir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	now := time.Now()
+	base := now.UTC().Format("20060102T150405Z")
+	for n := 1; ; n++ {
+		id := base
+		if n > 1 {
+			id += "-" + strconv.Itoa(n)
+		}
+		err := os.Mkdir(s.runDir(id), 0755)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create quarantine run: %w", err)
+		}
+
+		r := &Run{ID: id, Description: description, Created: now.Format(time.RFC3339), Items: []Item{}, store: s}
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal quarantine run: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(s.runDir(id), runFilename), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write quarantine run: %w", err)
+		}
+		return r, nil
+	}
+}
+
+// Move moves the file or directory at path into the run and records it in
+// the manifest. A path the deletion guard protects is refused with a
+// *guard.Error.
+func (r *Run) Move(path string) (Item, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Item{}, err
+	}
+	if err := guard.Default.Check("", path); err != nil {
+		return Item{}, err
+	}
+	it := Item{ID: len(r.Items) + 1, Original: path, Dir: info.IsDir(), Moved: time.Now().Format(time.RFC3339)}
+	it.SHA256, it.Size, it.Files, err = hashTree(path)
+	if err != nil {
+		return Item{}, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	itemsDir := filepath.Join(s.runDir(r.ID), itemsDirname)
+	if err := os.MkdirAll(itemsDir, 0755); err != nil {
+		return Item{}, fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	moveErr := move(path, filepath.Join(itemsDir, strconv.Itoa(it.ID)))
+	if moveErr != nil && !errors.Is(moveErr, errPartialMove) {
+		return Item{}, moveErr
+	}
+
+	// A partial move still left content in quarantine, so it is recorded.
+	r.Items = append(r.Items, it)
+	if err := r.appendItem(it); err != nil {
+		return it, err
+	}
+	return it, moveErr
+}
+
+// Close removes the run if nothing was moved into it.
+func (r *Run) Close() error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if len(r.Items) > 0 {
+		return nil
+	}
+	return r.store.removeRun(r.ID)
+}
+
+// appendItem writes it to the run's manifest. Callers must hold the store's mu.
+func (r *Run) appendItem(it Item) error {
+	data, err := json.Marshal(it)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantine item: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(r.store.runDir(r.ID), manifestFilename), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open quarantine manifest: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write quarantine manifest: %w", err)
+	}
+	return f.Close()
+}
+
+// Runs returns every run in the store, newest first.
+func (s *Store) Runs() ([]*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dirs, err := os.ReadDir(s.Dir())
+	if os.IsNotExist(err) {
+		return []*Run{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine directory: %w", err)
+	}
+
+	runs := []*Run{}
+	for _, d := range dirs {
+		if _, err := os.Stat(filepath.Join(s.runDir(d.Name()), runFilename)); err != nil {
+			continue // not a run
+		}
+		r, err := s.load(d.Name())
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	// IDs start with the UTC creation time, so they sort by age.
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID > runs[j].ID })
+	return runs, nil
+}
+
+// Run returns the run with the given ID.
+func (s *Store) Run(id string) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(id)
+}
+
+// load reads a run and its manifest. Callers must hold s.mu.
+func (s *Store) load(id string) (*Run, error) {
+	if err := checkRunID(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(s.runDir(id), runFilename))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("unknown quarantine run: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine run %s: %w", id, err)
+	}
+	r := &Run{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse quarantine run %s: %w", id, err)
+	}
+	r.ID, r.Items, r.store = id, []Item{}, s
+
+	f, err := os.Open(filepath.Join(s.runDir(id), manifestFilename))
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quarantine manifest: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var it Item
+		if err := json.Unmarshal([]byte(text), &it); err != nil {
+			return nil, fmt.Errorf("failed to parse quarantine manifest %s line %d: %w", id, line, err)
+		}
+		r.Items = append(r.Items, it)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read quarantine manifest: %w", err)
+	}
+	return r, nil
+}
+
+// rewrite atomically replaces a run's manifest. Callers must hold s.mu.
+func (s *Store) rewrite(r *Run) error {
+	var b strings.Builder
+	for _, it := range r.Items {
+		data, err := json.Marshal(it)
+		if err != nil {
+			return fmt.Errorf("failed to marshal quarantine item: %w", err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	path := filepath.Join(s.runDir(r.ID), manifestFilename)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write quarantine manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace quarantine manifest: %w", err)
+	}
+	return nil
+}
+
+// RestoreItem moves one item of a run back to its original path. The item's
+// hash is checked first, and an existing file at the original path is never
+// overwritten.
+func (s *Store) RestoreItem(runID string, itemID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.load(runID)
+	if err != nil {
+		return err
+	}
+	for i := range r.Items {
+		if r.Items[i].ID == itemID {
+			if err := s.restore(r, i); err != nil {
+				return err
+			}
+			return s.finish(r)
+		}
+	}
+	return fmt.Errorf("quarantine run %s has no item %d", runID, itemID)
+}
+
+// RestoreRun moves every item of a run that has not been restored yet back
+// to its original path. It carries on past items that fail and returns an
+// error listing them.
+func (s *Store) RestoreRun(runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.load(runID)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for i := range r.Items {
+		if r.Items[i].Restored {
+			continue
+		}
+		if err := s.restore(r, i); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if err := s.finish(r); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("restore completed with errors:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// restore moves r.Items[i] back and marks it restored. Callers must hold s.mu.
+func (s *Store) restore(r *Run, i int) error {
+	it := &r.Items[i]
+	if it.Restored {
+		return fmt.Errorf("%s has already been restored", it.Original)
+	}
+	stored := filepath.Join(s.runDir(r.ID), itemsDirname, strconv.Itoa(it.ID))
+	sum, _, _, err := hashTree(stored)
+	if err != nil {
+		return fmt.Errorf("%s: cannot read quarantined copy: %w", it.Original, err)
+	}
+	if sum != it.SHA256 {
+		return fmt.Errorf("%s: quarantined copy does not match its recorded hash", it.Original)
+	}
+	if _, err := os.Lstat(it.Original); err == nil {
+		return fmt.Errorf("%s: already exists", it.Original)
+	}
+	if err := os.MkdirAll(filepath.Dir(it.Original), 0755); err != nil {
+		return fmt.Errorf("%s: %w", it.Original, err)
+	}
+	if err := move(stored, it.Original); err != nil {
+		return fmt.Errorf("%s: %w", it.Original, err)
+	}
+	it.Restored = true
+	return nil
+}
+
+// finish saves a run after a restore, and removes it once every item is
+// back. Callers must hold s.mu.
+func (s *Store) finish(r *Run) error {
+	for _, it := range r.Items {
+		if !it.Restored {
+			return s.rewrite(r)
+		}
+	}
+	return s.removeRun(r.ID)
+}
+
+// Purge permanently deletes a run.
+func (s *Store) Purge(runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := checkRunID(runID); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(s.runDir(runID), runFilename)); err != nil {
+		return fmt.Errorf("unknown quarantine run: %s", runID)
+	}
+	if err := s.removeRun(runID); err != nil {
+		return fmt.Errorf("failed to purge quarantine run %s: %w", runID, err)
+	}
+	return nil
+}
+
+// PurgeExpired permanently deletes runs older than the retention period and
+// returns their IDs.
+func (s *Store) PurgeExpired() ([]string, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-s.retention())
+	purged := []string{}
+	for _, r := range runs {
+		created, err := time.Parse(time.RFC3339, r.Created)
+		if err == nil && created.After(cutoff) {
+			continue
+		}
+		if err := s.Purge(r.ID); err != nil {
+			return purged, err
+		}
+		purged = append(purged, r.ID)
+	}
+	return purged, nil
+}
@@ -0,0 +1,228 @@
+package quarantine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTree creates files (relative path -> content) under a new temp dir.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMoveRecordsManifest(t *testing.T) {
+	src := writeTree(t, map[string]string{"a.tmp": "hello", "cache/b.bin": "0123456789", "cache/c.bin": "x"})
+	s := NewStore(t.TempDir())
+
+	run, err := s.Begin("Clean test")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	file, err := run.Move(filepath.Join(src, "a.tmp"))
+	if err != nil {
+		t.Fatalf("Move file: %v", err)
+	}
+	dir, err := run.Move(filepath.Join(src, "cache"))
+	if err != nil {
+		t.Fatalf("Move dir: %v", err)
+	}
+	if err := run.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if file.Size != 5 || file.Files != 1 || file.Dir || len(file.SHA256) != 64 {
+		t.Errorf("file item = %+v", file)
+	}
+	if dir.Size != 11 || dir.Files != 2 || !dir.Dir {
+		t.Errorf("dir item = %+v", dir)
+	}
+	if entries, _ := os.ReadDir(src); len(entries) != 0 {
+		t.Errorf("originals should be gone, %d entries left", len(entries))
+	}
+
+	runs, err := s.Runs()
+	if err != nil {
+		t.Fatalf("Runs: %v", err)
+	}
+	if len(runs) != 1 || runs[0].ID != run.ID || runs[0].Description != "Clean test" || len(runs[0].Items) != 2 {
+		t.Fatalf("runs = %+v", runs)
+	}
+	if size, files := runs[0].Size(); size != 16 || files != 3 {
+		t.Errorf("run size = (%d, %d), want (16, 3)", size, files)
+	}
+	if runs[0].Items[1].Original != filepath.Join(src, "cache") || runs[0].Items[1].SHA256 != dir.SHA256 {
+		t.Errorf("manifest item = %+v, want %+v", runs[0].Items[1], dir)
+	}
+}
+
+func TestRestoreItemAndRun(t *testing.T) {
+	src := writeTree(t, map[string]string{"a.tmp": "a", "b.tmp": "b", "sub/c.tmp": "c"})
+	s := NewStore(t.TempDir())
+	run, err := s.Begin("Clean test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.tmp", "b.tmp", "sub"} {
+		if _, err := run.Move(filepath.Join(src, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.RestoreItem(run.ID, 2); err != nil {
+		t.Fatalf("RestoreItem: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(src, "b.tmp")); err != nil || string(data) != "b" {
+		t.Errorf("b.tmp not restored: %q, %v", data, err)
+	}
+	if err := s.RestoreItem(run.ID, 2); err == nil {
+		t.Error("restoring an item twice should fail")
+	}
+
+	// An item whose original path is taken again is not overwritten.
+	if err := os.WriteFile(filepath.Join(src, "a.tmp"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = s.RestoreRun(run.ID)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("RestoreRun error = %v, want a.tmp reported", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(src, "a.tmp")); string(data) != "new" {
+		t.Errorf("a.tmp was overwritten with %q", data)
+	}
+	if data, err := os.ReadFile(filepath.Join(src, "sub", "c.tmp")); err != nil || string(data) != "c" {
+		t.Errorf("sub/c.tmp not restored: %q, %v", data, err)
+	}
+
+	// Once the last item is back the run is removed.
+	if err := os.Remove(filepath.Join(src, "a.tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RestoreRun(run.ID); err != nil {
+		t.Fatalf("RestoreRun: %v", err)
+	}
+	if runs, _ := s.Runs(); len(runs) != 0 {
+		t.Errorf("fully restored run should be removed, have %d runs", len(runs))
+	}
+}
+
+func TestRestoreRejectsTamperedItem(t *testing.T) {
+	src := writeTree(t, map[string]string{"a.tmp": "original"})
+	s := NewStore(t.TempDir())
+	run, err := s.Begin("Clean test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run.Move(filepath.Join(src, "a.tmp")); err != nil {
+		t.Fatal(err)
+	}
+	stored := filepath.Join(s.Dir(), run.ID, itemsDirname, "1")
+	if err := os.WriteFile(stored, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RestoreItem(run.ID, 1); err == nil || !strings.Contains(err.Error(), "hash") {
+		t.Errorf("RestoreItem error = %v, want a hash mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "a.tmp")); !os.IsNotExist(err) {
+		t.Error("a tampered item should not be restored")
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	s := NewStore(t.TempDir())
+	s.Retention = time.Hour
+
+	old, err := s.Begin("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := writeTree(t, map[string]string{"a.tmp": "a"})
+	if _, err := old.Move(filepath.Join(src, "a.tmp")); err != nil {
+		t.Fatal(err)
+	}
+	// Backdate the run past the retention period.
+	old.Created = time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	data := `{"id":"` + old.ID + `","description":"old","created":"` + old.Created + `"}`
+	if err := os.WriteFile(filepath.Join(s.Dir(), old.ID, runFilename), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Beginning a new run purges the expired one.
+	fresh, err := s.Begin("fresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := s.Runs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].ID != fresh.ID || runs[0].Description != "fresh" || len(runs[0].Items) != 0 {
+		t.Errorf("runs after purge = %+v, want only %s", runs, fresh.ID)
+	}
+}
+
+func TestRunIDsStayInsideStore(t *testing.T) {
+	s := NewStore(t.TempDir())
+	for _, id := range []string{"", "..", "../x", `a/b`} {
+		if _, err := s.Run(id); err == nil {
+			t.Errorf("Run(%q) should fail", id)
+		}
+		if err := s.Purge(id); err == nil {
+			t.Errorf("Purge(%q) should fail", id)
+		}
+	}
+}
+
+func TestCloseRemovesEmptyRun(t *testing.T) {
+	s := NewStore(t.TempDir())
+	run, err := s.Begin("nothing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if runs, _ := s.Runs(); len(runs) != 0 {
+		t.Errorf("empty run should be removed, have %+v", runs)
+	}
+}
+
+func TestPurgeKeepsLinkTargets(t *testing.T) {
+	src := writeTree(t, map[string]string{"cache/a.bin": "junk"})
+	outside := writeTree(t, map[string]string{"thesis.docx": "keep"})
+	if err := os.Symlink(outside, filepath.Join(src, "cache", "docs")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	s := NewStore(t.TempDir())
+
+	run, err := s.Begin("Clean test")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := run.Move(filepath.Join(src, "cache")); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if err := s.Purge(run.ID); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir(), run.ID)); !os.IsNotExist(err) {
+		t.Errorf("the run should be purged: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "thesis.docx")); err != nil {
+		t.Errorf("the file a quarantined link led to was removed: %v", err)
+	}
+}
@@ -0,0 +1,350 @@
+package reg
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// Memory is a thread-safe, in-memory Registry. Key and value names are
+// case-insensitive but keep the spelling they were created with, and values
+// are stored in the same byte encoding Windows uses, so code behaves the
+// same against a Memory as against the real registry.
+//
+// Access masks are enforced: writing through a key opened with QueryValue
+// fails with ErrAccessDenied, as it would on Windows.
+type Memory struct {
+	mu    sync.RWMutex
+	roots map[Root]*node
+}
+
+type node struct {
+	name     string
+	children map[string]*node
+	values   map[string]*value
+	deleted  bool
+}
+
+type value struct {
+	name string
+	typ  ValueType
+	data []byte
+}
+
+func newNode(name string) *node {
+	return &node{
+		name:     name,
+		children: make(map[string]*node),
+		values:   make(map[string]*value),
+	}
+}
+
+// NewMemory returns an empty in-memory registry with all predefined hives.
+func NewMemory() *Memory {
+	m := &Memory{roots: make(map[Root]*node)}
+	for _, r := range []Root{LocalMachine, CurrentUser, ClassesRoot, Users, CurrentConfig} {
+		m.roots[r] = newNode(string(r))
+	}
+	return m
+}
+
+// splitPath breaks a key path into its components, ignoring empty ones.
+func splitPath(path string) []string {
+	var parts []string
+	for _, p := range strings.Split(path, `\`) {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// lookup walks to the key at path, creating missing keys when create is set.
+// Callers must hold m.mu (write-locked when create is set).
+func (m *Memory) lookup(root Root, path string, create bool) (*node, bool, error) {
+	n, ok := m.roots[root]
+	if !ok {
+		_, err := ParseRoot(string(root))
+		if err != nil {
+			return nil, false, err
+		}
+		return nil, false, ErrNotExist
+	}
+	existed := true
+	for _, part := range splitPath(path) {
+		child, ok := n.children[strings.ToLower(part)]
+		if !ok {
+			if !create {
+				return nil, false, ErrNotExist
+			}
+			child = newNode(part)
+			n.children[strings.ToLower(part)] = child
+			existed = false
+		}
+		n = child
+	}
+	return n, existed, nil
+}
+
+// OpenKey opens an existing key.
+func (m *Memory) OpenKey(root Root, path string, access Access) (Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, _, err := m.lookup(root, path, false)
+	if err != nil {
+		return nil, err
+	}
+	return &memKey{m: m, n: n, access: access}, nil
+}
+
+// CreateKey opens a key, creating it and any missing parents.
+func (m *Memory) CreateKey(root Root, path string, access Access) (Key, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, existed, err := m.lookup(root, path, true)
+	if err != nil {
+		return nil, false, err
+	}
+	return &memKey{m: m, n: n, access: access}, existed, nil
+}
+
+// DeleteKey removes a key. Like RegDeleteKey it refuses to delete a key that
+// still has subkeys.
+func (m *Memory) DeleteKey(root Root, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		return ErrAccessDenied
+	}
+	parent, _, err := m.lookup(root, strings.Join(parts[:len(parts)-1], `\`), false)
+	if err != nil {
+		return err
+	}
+	name := strings.ToLower(parts[len(parts)-1])
+	n, ok := parent.children[name]
+	if !ok {
+		return ErrNotExist
+	}
+	if len(n.children) > 0 {
+		return ErrAccessDenied
+	}
+	n.deleted = true
+	delete(parent.children, name)
+	return nil
+}
+
+// memKey is an open handle on a Memory key.
+type memKey struct {
+	m      *Memory
+	n      *node
+	access Access
+}
+
+// check verifies the handle is still valid and was opened with need.
+func (k *memKey) check(need Access) error {
+	if k.n.deleted {
+		return ErrNotExist
+	}
+	if k.access&need != need {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+func (k *memKey) GetValue(name string) ([]byte, ValueType, error) {
+	k.m.mu.RLock()
+	defer k.m.mu.RUnlock()
+	if err := k.check(QueryValue); err != nil {
+		return nil, None, err
+	}
+	v, ok := k.n.values[strings.ToLower(name)]
+	if !ok {
+		return nil, None, ErrNotExist
+	}
+	return append([]byte(nil), v.data...), v.typ, nil
+}
+
+func (k *memKey) GetStringValue(name string) (string, ValueType, error) {
+	data, typ, err := k.GetValue(name)
+	if err != nil {
+		return "", typ, err
+	}
+	if typ != SZ && typ != ExpandSZ {
+		return "", typ, ErrUnexpectedType
+	}
+	return decodeString(data), typ, nil
+}
+
+func (k *memKey) GetIntegerValue(name string) (uint64, ValueType, error) {
+	data, typ, err := k.GetValue(name)
+	if err != nil {
+		return 0, typ, err
+	}
+	switch {
+	case typ == DWord && len(data) == 4:
+		return uint64(binary.LittleEndian.Uint32(data)), typ, nil
+	case typ == QWord && len(data) == 8:
+		return binary.LittleEndian.Uint64(data), typ, nil
+	}
+	return 0, typ, ErrUnexpectedType
+}
+
+func (k *memKey) GetStringsValue(name string) ([]string, ValueType, error) {
+	data, typ, err := k.GetValue(name)
+	if err != nil {
+		return nil, typ, err
+	}
+	if typ != MultiSZ {
+		return nil, typ, ErrUnexpectedType
+	}
+	return decodeStrings(data), typ, nil
+}
+
+// setValue stores a value of any type.
+func (k *memKey) setValue(name string, typ ValueType, data []byte) error {
+	k.m.mu.Lock()
+	defer k.m.mu.Unlock()
+	if err := k.check(SetValue); err != nil {
+		return err
+	}
+	k.n.values[strings.ToLower(name)] = &value{name: name, typ: typ, data: append([]byte(nil), data...)}
+	return nil
+}
+
+func (k *memKey) SetStringValue(name, value string) error {
+	return k.setValue(name, SZ, encodeString(value))
+}
+
+func (k *memKey) SetExpandStringValue(name, value string) error {
+	return k.setValue(name, ExpandSZ, encodeString(value))
+}
+
+func (k *memKey) SetDWordValue(name string, value uint32) error {
+	return k.setValue(name, DWord, binary.LittleEndian.AppendUint32(nil, value))
+}
+
+func (k *memKey) SetQWordValue(name string, value uint64) error {
+	return k.setValue(name, QWord, binary.LittleEndian.AppendUint64(nil, value))
+}
+
+func (k *memKey) SetBinaryValue(name string, value []byte) error {
+	return k.setValue(name, Binary, value)
+}
+
+func (k *memKey) SetStringsValue(name string, value []string) error {
+	return k.setValue(name, MultiSZ, encodeStrings(value))
+}
+
+func (k *memKey) DeleteValue(name string) error {
+	k.m.mu.Lock()
+	defer k.m.mu.Unlock()
+	if err := k.check(SetValue); err != nil {
+		return err
+	}
+	lower := strings.ToLower(name)
+	if _, ok := k.n.values[lower]; !ok {
+		return ErrNotExist
+	}
+	delete(k.n.values, lower)
+	return nil
+}
+
+// ReadSubKeyNames returns subkey names in case-insensitive order.
+func (k *memKey) ReadSubKeyNames(n int) ([]string, error) {
+	k.m.mu.RLock()
+	defer k.m.mu.RUnlock()
+	if err := k.check(EnumerateSubKeys); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(k.n.children))
+	for _, c := range k.n.children {
+		names = append(names, c.name)
+	}
+	return limit(sortFold(names), n), nil
+}
+
+// ReadValueNames returns value names in case-insensitive order.
+func (k *memKey) ReadValueNames(n int) ([]string, error) {
+	k.m.mu.RLock()
+	defer k.m.mu.RUnlock()
+	if err := k.check(QueryValue); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(k.n.values))
+	for _, v := range k.n.values {
+		names = append(names, v.name)
+	}
+	return limit(sortFold(names), n), nil
+}
+
+func (k *memKey) Close() error { return nil }
+
+func sortFold(names []string) []string {
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names
+}
+
+func limit(names []string, n int) []string {
+	if n > 0 && len(names) > n {
+		return names[:n]
+	}
+	return names
+}
+
+// encodeString converts s to NUL-terminated UTF-16LE, the REG_SZ encoding.
+func encodeString(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	buf := make([]byte, 0, (len(u)+1)*2)
+	for _, c := range u {
+		buf = binary.LittleEndian.AppendUint16(buf, c)
+	}
+	return binary.LittleEndian.AppendUint16(buf, 0)
+}
+
+// decodeString converts REG_SZ bytes back to a string, stopping at the first NUL.
+func decodeString(data []byte) string {
+	u := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		c := binary.LittleEndian.Uint16(data[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
+
+// encodeStrings converts strings to REG_MULTI_SZ bytes: each string
+// NUL-terminated, followed by an empty one.
+func encodeStrings(ss []string) []byte {
+	var buf []byte
+	for _, s := range ss {
+		buf = append(buf, encodeString(s)...)
+	}
+	return binary.LittleEndian.AppendUint16(buf, 0)
+}
+
+// decodeStrings converts REG_MULTI_SZ bytes back to strings, stopping at the
+// first empty one.
+func decodeStrings(data []byte) []string {
+	var ss []string
+	var u []uint16
+	for i := 0; i+1 < len(data); i += 2 {
+		c := binary.LittleEndian.Uint16(data[i:])
+		if c != 0 {
+			u = append(u, c)
+			continue
+		}
+		if len(u) == 0 {
+			break
+		}
+		ss = append(ss, string(utf16.Decode(u)))
+		u = u[:0]
+	}
+	return ss
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package reg
+
+// native has no registry to talk to outside Windows.
+type native struct{}
+
+func (native) OpenKey(Root, string, Access) (Key, error) {
+	return nil, ErrUnsupported
+}
+
+func (native) CreateKey(Root, string, Access) (Key, bool, error) {
+	return nil, false, ErrUnsupported
+}
+
+func (native) DeleteKey(Root, string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,151 @@
+package reg
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// native is the Windows registry.
+type native struct{}
+
+func (native) OpenKey(root Root, path string, access Access) (Key, error) {
+	rk, err := predefined(root)
+	if err != nil {
+		return nil, err
+	}
+	k, err := registry.OpenKey(rk, path, uint32(access))
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	return nativeKey{k}, nil
+}
+
+func (native) CreateKey(root Root, path string, access Access) (Key, bool, error) {
+	rk, err := predefined(root)
+	if err != nil {
+		return nil, false, err
+	}
+	k, existed, err := registry.CreateKey(rk, path, uint32(access))
+	if err != nil {
+		return nil, false, mapErr(err)
+	}
+	return nativeKey{k}, existed, nil
+}
+
+func (native) DeleteKey(root Root, path string) error {
+	rk, err := predefined(root)
+	if err != nil {
+		return err
+	}
+	return mapErr(registry.DeleteKey(rk, path))
+}
+
+func predefined(root Root) (registry.Key, error) {
+	switch root {
+	case LocalMachine:
+		return registry.LOCAL_MACHINE, nil
+	case CurrentUser:
+		return registry.CURRENT_USER, nil
+	case ClassesRoot:
+		return registry.CLASSES_ROOT, nil
+	case Users:
+		return registry.USERS, nil
+	case CurrentConfig:
+		return registry.CURRENT_CONFIG, nil
+	}
+	_, err := ParseRoot(string(root))
+	return 0, err
+}
+
+// mapErr translates Win32 errors into the package's sentinel errors so
+// callers can test for them the same way regardless of backend.
+func mapErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, registry.ErrNotExist):
+		return ErrNotExist
+	case errors.Is(err, registry.ErrUnexpectedType):
+		return ErrUnexpectedType
+	case errors.Is(err, windows.ERROR_ACCESS_DENIED):
+		return ErrAccessDenied
+	}
+	return err
+}
+
+// nativeKey adapts registry.Key to Key.
+type nativeKey struct {
+	k registry.Key
+}
+
+func (n nativeKey) GetValue(name string) ([]byte, ValueType, error) {
+	size, _, err := n.k.GetValue(name, nil)
+	if err != nil {
+		return nil, None, mapErr(err)
+	}
+	buf := make([]byte, size)
+	size, typ, err := n.k.GetValue(name, buf)
+	if err != nil {
+		return nil, None, mapErr(err)
+	}
+	return buf[:size], ValueType(typ), nil
+}
+
+func (n nativeKey) GetStringValue(name string) (string, ValueType, error) {
+	s, typ, err := n.k.GetStringValue(name)
+	return s, ValueType(typ), mapErr(err)
+}
+
+func (n nativeKey) GetIntegerValue(name string) (uint64, ValueType, error) {
+	v, typ, err := n.k.GetIntegerValue(name)
+	return v, ValueType(typ), mapErr(err)
+}
+
+func (n nativeKey) GetStringsValue(name string) ([]string, ValueType, error) {
+	v, typ, err := n.k.GetStringsValue(name)
+	return v, ValueType(typ), mapErr(err)
+}
+
+func (n nativeKey) SetStringValue(name, value string) error {
+	return mapErr(n.k.SetStringValue(name, value))
+}
+
+func (n nativeKey) SetExpandStringValue(name, value string) error {
+	return mapErr(n.k.SetExpandStringValue(name, value))
+}
+
+func (n nativeKey) SetDWordValue(name string, value uint32) error {
+	return mapErr(n.k.SetDWordValue(name, value))
+}
+
+func (n nativeKey) SetQWordValue(name string, value uint64) error {
+	return mapErr(n.k.SetQWordValue(name, value))
+}
+
+func (n nativeKey) SetBinaryValue(name string, value []byte) error {
+	return mapErr(n.k.SetBinaryValue(name, value))
+}
+
+func (n nativeKey) SetStringsValue(name string, value []string) error {
+	return mapErr(n.k.SetStringsValue(name, value))
+}
+
+func (n nativeKey) DeleteValue(name string) error {
+	return mapErr(n.k.DeleteValue(name))
+}
+
+func (n nativeKey) ReadSubKeyNames(count int) ([]string, error) {
+	names, err := n.k.ReadSubKeyNames(count)
+	return names, mapErr(err)
+}
+
+func (n nativeKey) ReadValueNames(count int) ([]string, error) {
+	names, err := n.k.ReadValueNames(count)
+	return names, mapErr(err)
+}
+
+func (n nativeKey) Close() error {
+	return n.k.Close()
+}
@@ -0,0 +1,134 @@
+package reg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Root identifies a predefined registry hive.
+type Root string
+
+const (
+	LocalMachine  Root = "HKLM"
+	CurrentUser   Root = "HKCU"
+	ClassesRoot   Root = "HKCR"
+	Users         Root = "HKU"
+	CurrentConfig Root = "HKCC"
+)
+
+// ParseRoot accepts either the short ("HKLM") or long ("HKEY_LOCAL_MACHINE")
+// form of a hive name, case-insensitively.
+func ParseRoot(name string) (Root, error) {
+	switch strings.ToUpper(name) {
+	case "HKLM", "HKEY_LOCAL_MACHINE":
+		return LocalMachine, nil
+	case "HKCU", "HKEY_CURRENT_USER":
+		return CurrentUser, nil
+	case "HKCR", "HKEY_CLASSES_ROOT":
+		return ClassesRoot, nil
+	case "HKU", "HKEY_USERS":
+		return Users, nil
+	case "HKCC", "HKEY_CURRENT_CONFIG":
+		return CurrentConfig, nil
+	default:
+		return "", fmt.Errorf("unknown registry root key: %s", name)
+	}
+}
+
+// LongName returns the HKEY_* spelling used by regedit exports.
+func (r Root) LongName() string {
+	switch r {
+	case LocalMachine:
+		return "HKEY_LOCAL_MACHINE"
+	case CurrentUser:
+		return "HKEY_CURRENT_USER"
+	case ClassesRoot:
+		return "HKEY_CLASSES_ROOT"
+	case Users:
+		return "HKEY_USERS"
+	case CurrentConfig:
+		return "HKEY_CURRENT_CONFIG"
+	}
+	return string(r)
+}
+
+// ValueType is a registry value type. The numeric values match the Win32
+// REG_* constants so they can be persisted and compared across backends.
+type ValueType uint32
+
+const (
+	None     ValueType = 0
+	SZ       ValueType = 1
+	ExpandSZ ValueType = 2
+	Binary   ValueType = 3
+	DWord    ValueType = 4
+	MultiSZ  ValueType = 7
+	QWord    ValueType = 11
+)
+
+// Access is a key access mask. The values match the Win32 KEY_* rights.
+type Access uint32
+
+const (
+	QueryValue       Access = 0x0001
+	SetValue         Access = 0x0002
+	CreateSubKey     Access = 0x0004
+	EnumerateSubKeys Access = 0x0008
+	Read             Access = 0x20019
+	Write            Access = 0x20006
+	AllAccess        Access = 0xf003f
+)
+
+var (
+	// ErrNotExist is returned when a key or value does not exist.
+	ErrNotExist = errors.New("registry: key or value does not exist")
+	// ErrUnexpectedType is returned when a value is read as the wrong type.
+	ErrUnexpectedType = errors.New("registry: unexpected value type")
+	// ErrAccessDenied is returned when a key was opened without the rights
+	// an operation needs.
+	ErrAccessDenied = errors.New("registry: access denied")
+	// ErrUnsupported is returned by the native backend on platforms without
+	// a Windows registry.
+	ErrUnsupported = errors.New("registry: not supported on this platform")
+)
+
+// Registry opens, creates and deletes keys. Modules hold a Registry instead
+// of calling the Win32 API directly so tests can substitute a Memory store.
+type Registry interface {
+	OpenKey(root Root, path string, access Access) (Key, error)
+	// CreateKey opens the key, creating it and any missing parents first.
+	// existed reports whether the key was already present.
+	CreateKey(root Root, path string, access Access) (key Key, existed bool, err error)
+	// DeleteKey removes a key that has no subkeys.
+	DeleteKey(root Root, path string) error
+}
+
+// Key is an open registry key. Its method set mirrors
+// golang.org/x/sys/windows/registry.Key.
+type Key interface {
+	// GetValue returns the raw bytes and type of a value.
+	GetValue(name string) ([]byte, ValueType, error)
+	GetStringValue(name string) (string, ValueType, error)
+	GetIntegerValue(name string) (uint64, ValueType, error)
+	GetStringsValue(name string) ([]string, ValueType, error)
+
+	SetStringValue(name, value string) error
+	SetExpandStringValue(name, value string) error
+	SetDWordValue(name string, value uint32) error
+	SetQWordValue(name string, value uint64) error
+	SetBinaryValue(name string, value []byte) error
+	SetStringsValue(name string, value []string) error
+	DeleteValue(name string) error
+
+	// ReadSubKeyNames and ReadValueNames return at most n names, or all
+	// of them when n <= 0.
+	ReadSubKeyNames(n int) ([]string, error)
+	ReadValueNames(n int) ([]string, error)
+
+	Close() error
+}
+
+// Default is the Registry used by all modules unless replaced. On Windows it
+// is the system registry; elsewhere every call fails with ErrUnsupported.
+var Default Registry = native{}
@@ -0,0 +1,297 @@
+package reg
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const fixture = `Windows Registry Editor Version 5.00
+
+; mouse settings
+[HKEY_CURRENT_USER\Control Panel\Mouse]
+"MouseSpeed"="1"
+"MouseThreshold1"="6"
+
+[HKEY_CURRENT_USER\System\GameConfigStore]
+"GameDVR_Enabled"=dword:00000001
+"Path"="C:\\Games\\\"quoted\""
+@="default"
+
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{AAA}]
+"Blob"=hex:de,ad,\
+  be,ef
+"Big"=hex(b):00,00,00,00,01,00,00,00
+"Expand"=hex(2):25,00,54,00,45,00,4d,00,50,00,25,00,00,00
+
+[HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{BBB}]
+`
+
+func mustFixture(t *testing.T) *Memory {
+	t.Helper()
+	m, err := FromReg(fixture)
+	if err != nil {
+		t.Fatalf("FromReg: %v", err)
+	}
+	return m
+}
+
+func TestParseRoot(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Root
+	}{
+		{"HKLM", LocalMachine},
+		{"hkey_local_machine", LocalMachine},
+		{"HKCU", CurrentUser},
+		{"HKEY_CLASSES_ROOT", ClassesRoot},
+		{"HKU", Users},
+		{"HKCC", CurrentConfig},
+	}
+	for _, tt := range tests {
+		got, err := ParseRoot(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRoot(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseRoot("HKXX"); err == nil {
+		t.Error("ParseRoot(HKXX) should fail")
+	}
+}
+
+func TestImportValues(t *testing.T) {
+	m := mustFixture(t)
+
+	t.Run("strings", func(t *testing.T) {
+		k, err := m.OpenKey(CurrentUser, `control panel\mouse`, QueryValue)
+		if err != nil {
+			t.Fatalf("OpenKey: %v", err)
+		}
+		defer k.Close()
+		got, typ, err := k.GetStringValue("mousespeed")
+		if err != nil || got != "1" || typ != SZ {
+			t.Errorf("MouseSpeed = %q, %d, %v, want %q, SZ", got, typ, err, "1")
+		}
+	})
+
+	t.Run("escapes and default value", func(t *testing.T) {
+		k, err := m.OpenKey(CurrentUser, `System\GameConfigStore`, QueryValue)
+		if err != nil {
+			t.Fatalf("OpenKey: %v", err)
+		}
+		defer k.Close()
+		got, _, _ := k.GetStringValue("Path")
+		if want := `C:\Games\"quoted"`; got != want {
+			t.Errorf("Path = %q, want %q", got, want)
+		}
+		got, _, _ = k.GetStringValue("")
+		if got != "default" {
+			t.Errorf("default value = %q, want %q", got, "default")
+		}
+		n, typ, err := k.GetIntegerValue("GameDVR_Enabled")
+		if err != nil || n != 1 || typ != DWord {
+			t.Errorf("GameDVR_Enabled = %d, %d, %v, want 1, DWord", n, typ, err)
+		}
+	})
+
+	t.Run("hex types", func(t *testing.T) {
+		k, err := m.OpenKey(LocalMachine, `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces\{AAA}`, QueryValue)
+		if err != nil {
+			t.Fatalf("OpenKey: %v", err)
+		}
+		defer k.Close()
+		data, typ, _ := k.GetValue("Blob")
+		if !reflect.DeepEqual(data, []byte{0xde, 0xad, 0xbe, 0xef}) || typ != Binary {
+			t.Errorf("Blob = %x (%d), want deadbeef (Binary)", data, typ)
+		}
+		n, typ, _ := k.GetIntegerValue("Big")
+		if n != 1<<32 || typ != QWord {
+			t.Errorf("Big = %d (%d), want %d (QWord)", n, typ, uint64(1)<<32)
+		}
+		s, typ, _ := k.GetStringValue("Expand")
+		if s != "%TEMP%" || typ != ExpandSZ {
+			t.Errorf("Expand = %q (%d), want %%TEMP%% (ExpandSZ)", s, typ)
+		}
+	})
+}
+
+func TestImportDeletes(t *testing.T) {
+	m := mustFixture(t)
+	err := m.Import(strings.NewReader(`
+[HKEY_CURRENT_USER\Control Panel\Mouse]
+"MouseSpeed"=-
+
+[-HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\Tcpip]
+`))
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	k, _ := m.OpenKey(CurrentUser, `Control Panel\Mouse`, QueryValue)
+	if _, _, err := k.GetStringValue("MouseSpeed"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("MouseSpeed err = %v, want ErrNotExist", err)
+	}
+	if _, err := m.OpenKey(LocalMachine, `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters`, Read); !errors.Is(err, ErrNotExist) {
+		t.Errorf("OpenKey deleted tree err = %v, want ErrNotExist", err)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"value before key", `"a"="b"`},
+		{"bad root", `[HKEY_NOWHERE\x]`},
+		{"bad dword", "[HKEY_CURRENT_USER\\x]\n\"a\"=dword:zz"},
+		{"unterminated string", "[HKEY_CURRENT_USER\\x]\n\"a\"=\"b"},
+		{"unknown data", "[HKEY_CURRENT_USER\\x]\n\"a\"=word:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromReg(tt.src); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	m := mustFixture(t)
+	var first strings.Builder
+	if err := m.Export(&first); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	again, err := FromReg(first.String())
+	if err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	var second strings.Builder
+	if err := again.Export(&second); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("round trip changed export:\n%s\n---\n%s", first.String(), second.String())
+	}
+	if !strings.Contains(first.String(), `"GameDVR_Enabled"=dword:00000001`) {
+		t.Errorf("export missing dword value:\n%s", first.String())
+	}
+}
+
+func TestCreateSetDelete(t *testing.T) {
+	m := NewMemory()
+
+	k, existed, err := m.CreateKey(CurrentUser, `Software\CleanForge\Test`, AllAccess)
+	if err != nil || existed {
+		t.Fatalf("CreateKey = %v, existed=%v", err, existed)
+	}
+	if err := k.SetDWordValue("Count", 7); err != nil {
+		t.Fatalf("SetDWordValue: %v", err)
+	}
+	if err := k.SetStringValue("Name", "x"); err != nil {
+		t.Fatalf("SetStringValue: %v", err)
+	}
+	if _, _, err := k.GetStringValue("Count"); !errors.Is(err, ErrUnexpectedType) {
+		t.Errorf("GetStringValue on DWORD err = %v, want ErrUnexpectedType", err)
+	}
+	if err := k.SetStringsValue("List", []string{"a", "bc"}); err != nil {
+		t.Fatalf("SetStringsValue: %v", err)
+	}
+	if v, typ, err := k.GetStringsValue("List"); err != nil || typ != MultiSZ || !reflect.DeepEqual(v, []string{"a", "bc"}) {
+		t.Errorf("GetStringsValue = %q, %d, %v, want [a bc]", v, typ, err)
+	}
+	if _, _, err := k.GetStringsValue("Name"); !errors.Is(err, ErrUnexpectedType) {
+		t.Errorf("GetStringsValue on SZ err = %v, want ErrUnexpectedType", err)
+	}
+	names, _ := k.ReadValueNames(0)
+	if want := []string{"Count", "List", "Name"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadValueNames = %v, want %v", names, want)
+	}
+	if err := k.DeleteValue("count"); err != nil {
+		t.Errorf("DeleteValue: %v", err)
+	}
+	if err := k.DeleteValue("count"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("second DeleteValue err = %v, want ErrNotExist", err)
+	}
+
+	_, existed, _ = m.CreateKey(CurrentUser, `SOFTWARE\cleanforge\test`, AllAccess)
+	if !existed {
+		t.Error("CreateKey should be case-insensitive and report existing key")
+	}
+
+	if err := m.DeleteKey(CurrentUser, `Software\CleanForge`); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("DeleteKey with subkeys err = %v, want ErrAccessDenied", err)
+	}
+	if err := m.DeleteKey(CurrentUser, `Software\CleanForge\Test`); err != nil {
+		t.Errorf("DeleteKey: %v", err)
+	}
+	if err := k.SetDWordValue("Count", 1); !errors.Is(err, ErrNotExist) {
+		t.Errorf("write through deleted key err = %v, want ErrNotExist", err)
+	}
+}
+
+func TestAccessMask(t *testing.T) {
+	m := mustFixture(t)
+
+	k, err := m.OpenKey(CurrentUser, `Control Panel\Mouse`, QueryValue)
+	if err != nil {
+		t.Fatalf("OpenKey: %v", err)
+	}
+	if err := k.SetStringValue("MouseSpeed", "0"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("write with QueryValue err = %v, want ErrAccessDenied", err)
+	}
+	if _, err := k.ReadSubKeyNames(0); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("enumerate with QueryValue err = %v, want ErrAccessDenied", err)
+	}
+
+	w, _ := m.OpenKey(CurrentUser, `Control Panel\Mouse`, SetValue)
+	if _, _, err := w.GetStringValue("MouseSpeed"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("read with SetValue err = %v, want ErrAccessDenied", err)
+	}
+}
+
+func TestReadSubKeyNames(t *testing.T) {
+	m := mustFixture(t)
+	k, err := m.OpenKey(LocalMachine, `SYSTEM\CurrentControlSet\Services\Tcpip\Parameters\Interfaces`, Read)
+	if err != nil {
+		t.Fatalf("OpenKey: %v", err)
+	}
+	names, err := k.ReadSubKeyNames(-1)
+	if err != nil {
+		t.Fatalf("ReadSubKeyNames: %v", err)
+	}
+	if want := []string{"{AAA}", "{BBB}"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadSubKeyNames = %v, want %v", names, want)
+	}
+	if names, _ := k.ReadSubKeyNames(1); len(names) != 1 {
+		t.Errorf("ReadSubKeyNames(1) returned %d names", len(names))
+	}
+}
+
+func TestMemoryConcurrentAccess(t *testing.T) {
+	m := NewMemory()
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			k, _, err := m.CreateKey(LocalMachine, `SOFTWARE\Concurrent`, AllAccess)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = k.SetDWordValue("v", uint32(i))
+			_, _, _ = k.GetIntegerValue("v")
+			_, _ = k.ReadValueNames(0)
+		}(i)
+	}
+	wg.Wait()
+
+	k, _ := m.OpenKey(LocalMachine, `SOFTWARE\Concurrent`, Read)
+	if _, _, err := k.GetIntegerValue("v"); err != nil {
+		t.Errorf("GetIntegerValue after concurrent writes: %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+package reg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// regHeader is the first line regedit writes to a .reg export.
+const regHeader = "Windows Registry Editor Version 5.00"
+
+// FromReg returns a Memory seeded from the contents of a .reg file.
+func FromReg(src string) (*Memory, error) {
+	m := NewMemory()
+	if err := m.Import(strings.NewReader(src)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Import applies a .reg file to the store, the way `reg import` would. It
+// understands key headers ([path] and [-path] to delete), quoted strings,
+// dword:, hex: and hex(N): values (with backslash line continuations), @ for
+// the default value and "name"=- to delete a value. Lines starting with ';'
+// are comments.
+func (m *Memory) Import(r io.Reader) error {
+	var (
+		cur     *node
+		pending string
+		start   int
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if pending != "" {
+			line = pending + strings.TrimLeft(line, " \t")
+		} else {
+			start = lineNo
+		}
+		if strings.HasSuffix(line, `\`) && !strings.HasPrefix(line, "[") && !strings.HasSuffix(line, `"`) {
+			pending = strings.TrimSuffix(line, `\`)
+			continue
+		}
+		pending = ""
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, ";"),
+			trimmed == regHeader, trimmed == "REGEDIT4":
+			continue
+		case strings.HasPrefix(trimmed, "["):
+			n, err := m.importKey(trimmed)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", start, err)
+			}
+			cur = n
+		default:
+			if cur == nil {
+				return fmt.Errorf("line %d: value outside of a key", start)
+			}
+			if err := m.importValue(cur, trimmed); err != nil {
+				return fmt.Errorf("line %d: %w", start, err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if pending != "" {
+		return fmt.Errorf("line %d: unterminated continuation", start)
+	}
+	return nil
+}
+
+// importKey handles a [path] or [-path] header and returns the key that
+// subsequent values belong to (nil after a deletion).
+func (m *Memory) importKey(header string) (*node, error) {
+	if !strings.HasSuffix(header, "]") {
+		return nil, fmt.Errorf("malformed key header %q", header)
+	}
+	path := header[1 : len(header)-1]
+	remove := strings.HasPrefix(path, "-")
+	path = strings.TrimPrefix(path, "-")
+
+	rootName, subPath, _ := strings.Cut(path, `\`)
+	root, err := ParseRoot(rootName)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if remove {
+		m.deleteTree(root, subPath)
+		return nil, nil
+	}
+	n, _, err := m.lookup(root, subPath, true)
+	return n, err
+}
+
+// deleteTree removes a key and everything beneath it. Missing keys are ignored.
+func (m *Memory) deleteTree(root Root, path string) {
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		return
+	}
+	parent, _, err := m.lookup(root, strings.Join(parts[:len(parts)-1], `\`), false)
+	if err != nil {
+		return
+	}
+	name := strings.ToLower(parts[len(parts)-1])
+	if n, ok := parent.children[name]; ok {
+		markDeleted(n)
+		delete(parent.children, name)
+	}
+}
+
+func markDeleted(n *node) {
+	n.deleted = true
+	for _, c := range n.children {
+		markDeleted(c)
+	}
+}
+
+// importValue parses a single name=data line into n.
+func (m *Memory) importValue(n *node, line string) error {
+	var name, rest string
+	if strings.HasPrefix(line, "@") {
+		rest = strings.TrimSpace(line[1:])
+	} else {
+		s, tail, err := unquote(line)
+		if err != nil {
+			return err
+		}
+		name, rest = s, strings.TrimSpace(tail)
+	}
+	if !strings.HasPrefix(rest, "=") {
+		return fmt.Errorf("expected '=' after value name %q", name)
+	}
+	data := strings.TrimSpace(rest[1:])
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if data == "-" {
+		delete(n.values, strings.ToLower(name))
+		return nil
+	}
+	typ, raw, err := parseData(data)
+	if err != nil {
+		return fmt.Errorf("value %q: %w", name, err)
+	}
+	n.values[strings.ToLower(name)] = &value{name: name, typ: typ, data: raw}
+	return nil
+}
+
+// parseData decodes the right-hand side of a value line.
+func parseData(data string) (ValueType, []byte, error) {
+	switch {
+	case strings.HasPrefix(data, `"`):
+		s, tail, err := unquote(data)
+		if err != nil {
+			return None, nil, err
+		}
+		if strings.TrimSpace(tail) != "" {
+			return None, nil, fmt.Errorf("trailing data after string: %q", tail)
+		}
+		return SZ, encodeString(s), nil
+
+	case strings.HasPrefix(data, "dword:"):
+		v, err := strconv.ParseUint(data[len("dword:"):], 16, 32)
+		if err != nil {
+			return None, nil, fmt.Errorf("invalid dword: %w", err)
+		}
+		return DWord, binary.LittleEndian.AppendUint32(nil, uint32(v)), nil
+
+	case strings.HasPrefix(data, "hex"):
+		typ := Binary
+		rest := data[len("hex"):]
+		if strings.HasPrefix(rest, "(") {
+			end := strings.Index(rest, ")")
+			if end < 0 {
+				return None, nil, fmt.Errorf("malformed hex type in %q", data)
+			}
+			t, err := strconv.ParseUint(rest[1:end], 16, 32)
+			if err != nil {
+				return None, nil, fmt.Errorf("invalid hex type: %w", err)
+			}
+			typ = ValueType(t)
+			rest = rest[end+1:]
+		}
+		if !strings.HasPrefix(rest, ":") {
+			return None, nil, fmt.Errorf("malformed hex data %q", data)
+		}
+		raw, err := parseHexList(rest[1:])
+		if err != nil {
+			return None, nil, err
+		}
+		return typ, raw, nil
+	}
+	return None, nil, fmt.Errorf("unrecognized data %q", data)
+}
+
+// parseHexList decodes a comma-separated list of hex bytes.
+func parseHexList(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return []byte{}, nil
+	}
+	var out []byte
+	for _, part := range strings.Split(s, ",") {
+		b, err := hex.DecodeString(strings.TrimSpace(part))
+		if err != nil || len(b) != 1 {
+			return nil, fmt.Errorf("invalid hex byte %q", part)
+		}
+		out = append(out, b[0])
+	}
+	return out, nil
+}
+
+// unquote reads a .reg quoted string from the start of s, handling \\ and \"
+// escapes, and returns the decoded text and the remainder of s.
+func unquote(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", fmt.Errorf("expected quoted string in %q", s)
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string in %q", s)
+}
+
+// quote is the inverse of unquote.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// Export writes the whole store in .reg format with keys and values sorted,
+// so two exports of equal stores are byte-identical.
+func (m *Memory) Export(w io.Writer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\r\n", regHeader)
+	for _, root := range []Root{ClassesRoot, CurrentUser, LocalMachine, Users, CurrentConfig} {
+		exportNode(bw, root.LongName(), m.roots[root], true)
+	}
+	return bw.Flush()
+}
+
+func exportNode(w *bufio.Writer, path string, n *node, isRoot bool) {
+	if !isRoot || len(n.values) > 0 {
+		fmt.Fprintf(w, "\r\n[%s]\r\n", path)
+		names := make([]string, 0, len(n.values))
+		for k := range n.values {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			v := n.values[k]
+			name := "@"
+			if v.name != "" {
+				name = quote(v.name)
+			}
+			fmt.Fprintf(w, "%s=%s\r\n", name, formatData(v))
+		}
+	}
+
+	keys := make([]string, 0, len(n.children))
+	for k := range n.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		c := n.children[k]
+		exportNode(w, path+`\`+c.name, c, false)
+	}
+}
+
+func formatData(v *value) string {
+	switch {
+	case v.typ == SZ:
+		return quote(decodeString(v.data))
+	case v.typ == DWord && len(v.data) == 4:
+		return fmt.Sprintf("dword:%08x", binary.LittleEndian.Uint32(v.data))
+	}
+	parts := make([]string, len(v.data))
+	for i, b := range v.data {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	prefix := "hex"
+	if v.typ != Binary {
+		prefix = fmt.Sprintf("hex(%x)", uint32(v.typ))
+	}
+	return prefix + ":" + strings.Join(parts, ",")
+}
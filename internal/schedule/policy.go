@@ -0,0 +1,245 @@
+// Package schedule runs cleanups automatically according to policies such as
+// "every Sunday clean safe categories older than 7 days" or "when free space
+// drops under 15%, clean low-risk categories".
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"cleanforge/internal/cleaner"
+)
+
+// configFilename is the file under ~/.cleanforge that policies are read from.
+const configFilename = "schedule.json"
+
+// freeSpaceCooldown is how long a policy triggered only by free space waits
+// after running before it may run again, so a disk that stays full is not
+// cleaned on every tick.
+const freeSpaceCooldown = 6 * time.Hour
+
+// ConfigPath returns the path of the user's schedule file.
+func ConfigPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(homeDir, ".cleanforge", configFilename)
+}
+
+// Policy says what to clean and when. It runs when its time trigger (Every,
+// or Weekday and At) is due and, if FreeBelow is set, the system drive has
+// less free space than that. A policy with only FreeBelow runs whenever space
+// is low, at most once per six hours. The schedule file looks like
+//
+//	{
+//	  "policies": [
+//	    {"id": "weekly", "weekday": "sunday", "at": "03:00", "risk": "safe", "olderThan": "7d"},
+//	    {"id": "low_space", "freeBelow": 15, "risk": "low"}
+//	  ]
+//	}
+type Policy struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name,omitempty"`
+	Disabled   bool     `json:"disabled,omitempty"`
+	Categories []string `json:"categories,omitempty"` // category IDs to clean
+	Risk       string   `json:"risk,omitempty"`       // or every category this risky or safer: "safe", "low" or "medium"
+	OlderThan  string   `json:"olderThan,omitempty"`  // only clean files last modified longer ago, e.g. "7d"
+	Every      string   `json:"every,omitempty"`      // run at this interval, e.g. "24h" or "7d"
+	Weekday    string   `json:"weekday,omitempty"`    // run weekly on this day, e.g. "sunday"
+	At         string   `json:"at,omitempty"`         // time of day for Weekday, or daily without it, e.g. "03:00"
+	FreeBelow  float64  `json:"freeBelow,omitempty"`  // percent of free space on the system drive
+
+	olderThan  time.Duration
+	every      time.Duration
+	weekday    time.Weekday
+	hasWeekday bool
+	at         time.Duration // since midnight
+	hasAt      bool
+}
+
+// riskRank orders the category risk levels.
+var riskRank = map[string]int{"safe": 0, "low": 1, "medium": 2}
+
+// validID restricts policy IDs to the form category IDs use.
+var validID = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// LoadPolicies reads the policies in path. A missing file has none. If any
+// policy is invalid the whole file is rejected.
+func LoadPolicies(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule: %w", err)
+	}
+
+	var cfg struct {
+		Policies []Policy `json:"policies"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule %s: %w", path, err)
+	}
+
+	var errs []string
+	seen := make(map[string]bool)
+	for i := range cfg.Policies {
+		pol := &cfg.Policies[i]
+		if seen[pol.ID] {
+			errs = append(errs, fmt.Sprintf("policy %q: duplicate id", pol.ID))
+		}
+		seen[pol.ID] = true
+		for _, e := range pol.parse() {
+			errs = append(errs, fmt.Sprintf("policy %q: %s", pol.ID, e))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid schedule %s:\n  %s", path, strings.Join(errs, "\n  "))
+	}
+	return cfg.Policies, nil
+}
+
+// parse validates pol and fills in its parsed fields, returning every
+// problem found.
+func (pol *Policy) parse() []string {
+	var errs []string
+	if !validID.MatchString(pol.ID) {
+		errs = append(errs, "id must be lower case letters, digits and underscores")
+	}
+	if pol.Name == "" {
+		pol.Name = pol.ID
+	}
+	if len(pol.Categories) == 0 && pol.Risk == "" {
+		errs = append(errs, "categories or risk is required")
+	}
+	if _, ok := riskRank[pol.Risk]; pol.Risk != "" && !ok {
+		errs = append(errs, fmt.Sprintf("risk %q must be safe, low or medium", pol.Risk))
+	}
+	if pol.Every == "" && pol.Weekday == "" && pol.At == "" && pol.FreeBelow == 0 {
+		errs = append(errs, "every, weekday, at or freeBelow is required")
+	}
+	if pol.Every != "" && (pol.Weekday != "" || pol.At != "") {
+		errs = append(errs, "every cannot be combined with weekday or at")
+	}
+	if pol.FreeBelow < 0 || pol.FreeBelow >= 100 {
+		errs = append(errs, fmt.Sprintf("freeBelow %g must be a percentage between 0 and 100", pol.FreeBelow))
+	}
+
+	var err error
+	if pol.OlderThan != "" {
+		if pol.olderThan, err = cleaner.ParseAge(pol.OlderThan); err != nil {
+			errs = append(errs, fmt.Sprintf("olderThan: %v", err))
+		}
+	}
+	if pol.Every != "" {
+		if pol.every, err = cleaner.ParseAge(pol.Every); err != nil {
+			errs = append(errs, fmt.Sprintf("every: %v", err))
+		}
+	}
+	if pol.Weekday != "" {
+		pol.weekday, pol.hasWeekday = parseWeekday(pol.Weekday)
+		if !pol.hasWeekday {
+			errs = append(errs, fmt.Sprintf("weekday %q is not a day of the week", pol.Weekday))
+		}
+	}
+	if pol.At != "" {
+		t, err := time.Parse("15:04", pol.At)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("at %q must be a time such as 03:00", pol.At))
+		}
+		pol.at, pol.hasAt = time.Duration(t.Hour())*time.Hour+time.Duration(t.Minute())*time.Minute, true
+	}
+	return errs
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) || strings.EqualFold(s, d.String()[:3]) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// due reports whether pol should run at now and why. last is when it last
+// ran, and seen when the scheduler first saw it; a new time-triggered policy
+// waits for its next occurrence instead of running at once. free reports
+// the percentage of free space and is only asked when pol has FreeBelow.
+func (pol *Policy) due(now, last, seen time.Time, free func() (float64, error)) (string, bool) {
+	var reason string
+	switch {
+	case pol.every > 0:
+		if now.Sub(later(last, seen)) < pol.every {
+			return "", false
+		}
+		reason = "every " + pol.Every
+	case pol.hasWeekday || pol.hasAt:
+		occurrence := pol.previous(now)
+		if !occurrence.After(later(last, seen)) {
+			return "", false
+		}
+		reason = "scheduled for " + occurrence.Format("Mon 15:04")
+	default:
+		if !last.IsZero() && now.Sub(last) < freeSpaceCooldown {
+			return "", false
+		}
+	}
+
+	if pol.FreeBelow > 0 {
+		percent, err := free()
+		if err != nil || percent >= pol.FreeBelow {
+			return "", false
+		}
+		cond := fmt.Sprintf("free space %.1f%% under %g%%", percent, pol.FreeBelow)
+		if reason == "" {
+			return cond, true
+		}
+		reason += ", " + cond
+	}
+	return reason, true
+}
+
+// previous returns the latest time of day At, on Weekday if set, that is
+// not after now.
+func (pol *Policy) previous(now time.Time) time.Time {
+	for days := 0; ; days++ {
+		d := now.AddDate(0, 0, -days)
+		t := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, now.Location()).Add(pol.at)
+		if (!pol.hasWeekday || t.Weekday() == pol.weekday) && !t.After(now) {
+			return t
+		}
+	}
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// categoryIDs returns the IDs of the categories pol cleans: its Categories,
+// or every category at most as risky as its Risk. Duplicate file categories
+// are never chosen by risk, as what they remove has to be picked.
+func (pol *Policy) categoryIDs(categories []cleaner.CleanCategory) []string {
+	if len(pol.Categories) > 0 {
+		return pol.Categories
+	}
+	var ids []string
+	for _, cat := range categories {
+		rank, ok := riskRank[cat.Risk]
+		if ok && rank <= riskRank[pol.Risk] && cat.Type != cleaner.TypeDuplicates {
+			ids = append(ids, cat.ID)
+		}
+	}
+	return ids
+}
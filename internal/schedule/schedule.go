@@ -0,0 +1,266 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"cleanforge/internal/cleaner"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// Clock tells the time. Tests use a fake one.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Record is one line of the schedule log: what one policy run did.
+type Record struct {
+	Time         string   `json:"time"` // RFC 3339, when the run started
+	Policy       string   `json:"policy"`
+	Reason       string   `json:"reason"` // why the policy was due
+	Categories   []string `json:"categories"`
+	Plan         string   `json:"plan,omitempty"` // ID of the cleanup plan, as in the audit log
+	FreedSpace   int64    `json:"freedSpace"`
+	DeletedFiles int      `json:"deletedFiles"`
+	Errors       int      `json:"errors"`
+	DurationMs   int64    `json:"durationMs"`
+	Error        string   `json:"error,omitempty"` // why the run failed as a whole
+}
+
+// policyState is what the scheduler remembers about a policy between runs.
+type policyState struct {
+	Seen    time.Time `json:"seen"`
+	LastRun time.Time `json:"lastRun,omitempty"`
+}
+
+// Scheduler runs the policies that are due. Its fields may be replaced
+// before the first Tick, which tests do to use a fake clock and free space.
+type Scheduler struct {
+	Policies   []Policy
+	NewCleaner func() *cleaner.Cleaner      // returns a cleaner for one run
+	Clock      Clock                        // tells the time, the system clock by default
+	FreeSpace  func() (float64, error)      // percent free on the system drive
+	StatePath  string                       // when each policy last ran
+	LogPath    string                       // JSON Lines log of every run
+	Logf       func(string, ...interface{}) // also told about every run, if set
+
+	mu sync.Mutex
+}
+
+// New returns a scheduler for policies that keeps its state and log under
+// ~/.cleanforge.
+func New(policies []Policy, newCleaner func() *cleaner.Cleaner) *Scheduler {
+	dir := filepath.Dir(ConfigPath())
+	return &Scheduler{
+		Policies:   policies,
+		NewCleaner: newCleaner,
+		Clock:      systemClock{},
+		FreeSpace:  systemDriveFree,
+		StatePath:  filepath.Join(dir, "schedule-state.json"),
+		LogPath:    filepath.Join(cleaner.AuditDir(), "schedule.jsonl"),
+	}
+}
+
+// systemDriveFree returns the percentage of free space on the drive Windows
+// is installed on, or on / elsewhere.
+func systemDriveFree() (float64, error) {
+	root := "/"
+	if runtime.GOOS == "windows" {
+		drive := os.Getenv("SystemDrive")
+		if drive == "" {
+			drive = "C:"
+		}
+		root = drive + `\`
+	}
+	usage, err := disk.Usage(root)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read free space of %s: %w", root, err)
+	}
+	return 100 - usage.UsedPercent, nil
+}
+
+// Run calls Tick every interval until ctx is cancelled, starting at once.
+// Errors are reported through Logf and do not stop it.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Tick(ctx); err != nil {
+			s.logf("schedule: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs every enabled policy that is due, one after another, and
+// returns what each run did. A policy whose cleanup fails is still recorded
+// as run, so it is not retried until it is due again.
+func (s *Scheduler) Tick(ctx context.Context) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.loadState()
+	if err != nil {
+		return nil, err
+	}
+	now := s.Clock.Now()
+
+	var records []Record
+	for i := range s.Policies {
+		pol := &s.Policies[i]
+		if pol.Disabled {
+			continue
+		}
+		st := state[pol.ID]
+		if st.Seen.IsZero() {
+			st.Seen = now
+		}
+		if reason, ok := pol.due(now, st.LastRun, st.Seen, s.FreeSpace); ok {
+			if err := ctx.Err(); err != nil {
+				break
+			}
+			rec := s.run(ctx, pol, reason)
+			records = append(records, rec)
+			st.LastRun = now
+			if err := s.log(rec); err != nil {
+				s.logf("schedule: %v", err)
+			}
+		}
+		state[pol.ID] = st
+	}
+
+	if err := s.saveState(state); err != nil {
+		return records, err
+	}
+	return records, nil
+}
+
+// run cleans what pol asks for.
+func (s *Scheduler) run(ctx context.Context, pol *Policy, reason string) Record {
+	start := s.Clock.Now()
+	rec := Record{Time: start.Format(time.RFC3339), Policy: pol.ID, Reason: reason}
+	defer func() {
+		rec.DurationMs = s.Clock.Now().Sub(start).Milliseconds()
+		if rec.Error != "" {
+			s.logf("%s (%s) failed: %s", pol.Name, reason, rec.Error)
+			return
+		}
+		s.logf("%s (%s): freed %d bytes in %d files, %d errors", pol.Name, reason, rec.FreedSpace, rec.DeletedFiles, rec.Errors)
+	}()
+
+	c := s.NewCleaner()
+	c.SetMinAge(pol.olderThan)
+	rec.Categories = pol.categoryIDs(c.Categories())
+	if len(rec.Categories) == 0 {
+		rec.Error = "no category to clean"
+		return rec
+	}
+
+	p, err := c.PlanContext(ctx, rec.Categories, nil)
+	if err != nil {
+		rec.Error = err.Error()
+		return rec
+	}
+	rec.Plan = p.ID
+	result, err := cleaner.ExecuteContext(ctx, p, nil)
+	if result != nil {
+		rec.FreedSpace, rec.DeletedFiles, rec.Errors = result.FreedSpace, result.DeletedFiles, len(result.Errors)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+func (s *Scheduler) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// log appends rec to the schedule log.
+func (s *Scheduler) log(rec Record) error {
+	if err := os.MkdirAll(filepath.Dir(s.LogPath), 0755); err != nil {
+		return fmt.Errorf("failed to create schedule log directory: %w", err)
+	}
+	f, err := os.OpenFile(s.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open schedule log: %w", err)
+	}
+	data, _ := json.Marshal(rec)
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write schedule log: %w", err)
+	}
+	return f.Close()
+}
+
+// History returns the runs recorded in the schedule log, oldest first.
+func (s *Scheduler) History() ([]Record, error) {
+	data, err := os.ReadFile(s.LogPath)
+	if os.IsNotExist(err) {
+		return []Record{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule log: %w", err)
+	}
+	records := []Record{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var rec Record
+		if line == "" || json.Unmarshal([]byte(line), &rec) != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func (s *Scheduler) loadState() (map[string]policyState, error) {
+	state := make(map[string]policyState)
+	data, err := os.ReadFile(s.StatePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule state: %w", err)
+	}
+	return state, nil
+}
+
+// saveState atomically replaces the schedule state.
+func (s *Scheduler) saveState(state map[string]policyState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.StatePath), 0755); err != nil {
+		return fmt.Errorf("failed to create schedule state directory: %w", err)
+	}
+	tmp := s.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schedule state: %w", err)
+	}
+	if err := os.Rename(tmp, s.StatePath); err != nil {
+		return fmt.Errorf("failed to replace schedule state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package schedule
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cleanforge/internal/cleaner"
+)
+
+// fakeClock is a Clock that tells the time it is set to.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// loadPolicies writes a schedule file with the given policies and loads it.
+func loadPolicies(t *testing.T, policies string) []Policy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	if err := os.WriteFile(path, []byte(`{"policies": [`+policies+`]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pols, err := LoadPolicies(path)
+	if err != nil {
+		t.Fatalf("LoadPolicies: %v", err)
+	}
+	return pols
+}
+
+// newTestScheduler returns a scheduler keeping its files in a temp dir,
+// cleaning a cleaner whose home folder is home.
+func newTestScheduler(t *testing.T, pols []Policy, clock *fakeClock, home string, free float64) *Scheduler {
+	t.Helper()
+	dir := t.TempDir()
+	s := New(pols, func() *cleaner.Cleaner {
+		env := map[string]string{"HOME": home}
+		return cleaner.NewCleanerFor(&cleaner.Paths{OS: "linux", Getenv: func(key string) string { return env[key] }})
+	})
+	s.Clock = clock
+	s.FreeSpace = func() (float64, error) { return free, nil }
+	s.StatePath = filepath.Join(dir, "state.json")
+	s.LogPath = filepath.Join(dir, "schedule.jsonl")
+	return s
+}
+
+func TestLoadPoliciesMissingFile(t *testing.T) {
+	pols, err := LoadPolicies(filepath.Join(t.TempDir(), "schedule.json"))
+	if err != nil || pols != nil {
+		t.Errorf("LoadPolicies = (%v, %v), want no policies", pols, err)
+	}
+}
+
+func TestLoadPoliciesRejectsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"no trigger":    `{"id": "a", "risk": "safe"}`,
+		"no categories": `{"id": "a", "every": "24h"}`,
+		"bad risk":      `{"id": "a", "every": "24h", "risk": "high"}`,
+		"bad weekday":   `{"id": "a", "weekday": "someday", "risk": "safe"}`,
+		"bad time":      `{"id": "a", "at": "25:00", "risk": "safe"}`,
+		"bad age":       `{"id": "a", "every": "24h", "risk": "safe", "olderThan": "soon"}`,
+		"every and at":  `{"id": "a", "every": "24h", "at": "03:00", "risk": "safe"}`,
+		"bad free":      `{"id": "a", "freeBelow": 120, "risk": "safe"}`,
+		"duplicate":     `{"id": "a", "every": "1h", "risk": "safe"}, {"id": "a", "every": "2h", "risk": "safe"}`,
+		"unknown field": `{"id": "a", "every": "1h", "risk": "safe", "when": "now"}`,
+	}
+	for name, policies := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schedule.json")
+			if err := os.WriteFile(path, []byte(`{"policies": [`+policies+`]}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadPolicies(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestWeeklyPolicyRunsOncePerWeek(t *testing.T) {
+	pols := loadPolicies(t, `{"id": "weekly", "weekday": "sunday", "at": "03:00", "risk": "safe"}`)
+	// Wednesday: the policy is new, so last Sunday does not count
+	clock := &fakeClock{now: time.Date(2026, 3, 4, 12, 0, 0, 0, time.Local)}
+	s := newTestScheduler(t, pols, clock, t.TempDir(), 50)
+
+	runs := 0
+	for _, step := range []struct {
+		at   time.Time
+		runs int
+	}{
+		{clock.now, 0},
+		{time.Date(2026, 3, 8, 2, 59, 0, 0, time.Local), 0}, // Sunday, before 03:00
+		{time.Date(2026, 3, 8, 3, 1, 0, 0, time.Local), 1},
+		{time.Date(2026, 3, 8, 23, 0, 0, 0, time.Local), 1},
+		{time.Date(2026, 3, 14, 12, 0, 0, 0, time.Local), 1}, // Saturday
+		{time.Date(2026, 3, 16, 9, 0, 0, 0, time.Local), 2},  // Monday, Sunday's run was missed
+	} {
+		clock.now = step.at
+		records, err := s.Tick(context.Background())
+		if err != nil {
+			t.Fatalf("Tick at %v: %v", step.at, err)
+		}
+		runs += len(records)
+		if runs != step.runs {
+			t.Fatalf("at %v: %d runs, want %d", step.at, runs, step.runs)
+		}
+	}
+}
+
+func TestFreeSpacePolicy(t *testing.T) {
+	pols := loadPolicies(t, `{"id": "low_space", "freeBelow": 15, "risk": "safe"}`)
+	clock := &fakeClock{now: time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, pols, clock, t.TempDir(), 20)
+
+	if records, _ := s.Tick(context.Background()); len(records) != 0 {
+		t.Fatalf("ran with 20%% free: %+v", records)
+	}
+	s.FreeSpace = func() (float64, error) { return 9.5, nil }
+	records, err := s.Tick(context.Background())
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Tick = (%+v, %v), want a run with 9.5%% free", records, err)
+	}
+	if !strings.Contains(records[0].Reason, "9.5%") {
+		t.Errorf("reason %q does not mention the free space", records[0].Reason)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if records, _ := s.Tick(context.Background()); len(records) != 0 {
+		t.Errorf("ran again an hour later: %+v", records)
+	}
+	clock.now = clock.now.Add(freeSpaceCooldown)
+	if records, _ := s.Tick(context.Background()); len(records) != 1 {
+		t.Errorf("did not run again after the cooldown: %+v", records)
+	}
+}
+
+func TestTickCleansOldFilesAndLogs(t *testing.T) {
+	home := t.TempDir()
+	cache := filepath.Join(home, ".config", "Code", "Cache")
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old, recent := filepath.Join(cache, "old"), filepath.Join(cache, "recent")
+	for _, path := range []string{old, recent} {
+		if err := os.WriteFile(path, []byte("12345"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tenDaysAgo := time.Now().Add(-10 * 24 * time.Hour)
+	if err := os.Chtimes(old, tenDaysAgo, tenDaysAgo); err != nil {
+		t.Fatal(err)
+	}
+
+	pols := loadPolicies(t, `{"id": "daily", "every": "24h", "categories": ["vscode_cache"], "olderThan": "7d"}`)
+	clock := &fakeClock{now: time.Now()}
+	s := newTestScheduler(t, pols, clock, home, 50)
+	if records, _ := s.Tick(context.Background()); len(records) != 0 {
+		t.Fatalf("a new policy ran at once: %+v", records)
+	}
+
+	clock.now = clock.now.Add(25 * time.Hour)
+	records, err := s.Tick(context.Background())
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Tick = (%+v, %v), want one run", records, err)
+	}
+	if rec := records[0]; rec.Error != "" || rec.FreedSpace != 5 || rec.DeletedFiles != 1 {
+		t.Errorf("record = %+v, want 5 bytes in 1 file freed", rec)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("the old file should be deleted")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Error("the recent file should be kept")
+	}
+
+	history, err := s.History()
+	if err != nil || len(history) != 1 || history[0].Policy != "daily" || history[0].Plan == "" {
+		t.Errorf("History = (%+v, %v), want the run", history, err)
+	}
+}
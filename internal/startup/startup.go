@@ -1,15 +1,26 @@
 package startup
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"cleanforge/internal/backup"
 	"cleanforge/internal/cmd"
-	"golang.org/x/sys/windows/registry"
+	"cleanforge/internal/reg"
 )
 
+// runner executes external commands. Tests replace it with a cmd.Fake.
+var runner cmd.Runner = cmd.Default
+
+// registry is the Windows registry. Tests replace it with a reg.Memory.
+var registry reg.Registry = reg.Default
+
+// journal records every change this package makes so it can be undone.
+var journal = backup.Default
+
 // ---------- Types ----------
 
 // StartupItem represents a single program configured to run at Windows boot.
@@ -92,28 +103,28 @@ func NewStartupManager() *StartupManager {
 
 // GetStartupItems collects startup items from all sources.
 func (m *StartupManager) GetStartupItems() ([]StartupItem, error) {
-	var items []StartupItem
+	items := []StartupItem{}
 
 	// 1. HKCU Run
-	hkcuItems, err := m.readRegistryRun(registry.CURRENT_USER, `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`, "registry_hkcu", true)
+	hkcuItems, err := m.readRegistryRun(reg.CurrentUser, `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`, "registry_hkcu", true)
 	if err == nil {
 		items = append(items, hkcuItems...)
 	}
 
 	// HKCU disabled items
-	hkcuDisabled, err := m.readRegistryRun(registry.CURRENT_USER, `SOFTWARE\Microsoft\Windows\CurrentVersion\Run\`+disabledSubkey, "registry_hkcu", false)
+	hkcuDisabled, err := m.readRegistryRun(reg.CurrentUser, `SOFTWARE\Microsoft\Windows\CurrentVersion\Run\`+disabledSubkey, "registry_hkcu", false)
 	if err == nil {
 		items = append(items, hkcuDisabled...)
 	}
 
 	// 2. HKLM Run
-	hklmItems, err := m.readRegistryRun(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`, "registry_hklm", true)
+	hklmItems, err := m.readRegistryRun(reg.LocalMachine, `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`, "registry_hklm", true)
 	if err == nil {
 		items = append(items, hklmItems...)
 	}
 
 	// HKLM disabled items
-	hklmDisabled, err := m.readRegistryRun(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows\CurrentVersion\Run\`+disabledSubkey, "registry_hklm", false)
+	hklmDisabled, err := m.readRegistryRun(reg.LocalMachine, `SOFTWARE\Microsoft\Windows\CurrentVersion\Run\`+disabledSubkey, "registry_hklm", false)
 	if err == nil {
 		items = append(items, hklmDisabled...)
 	}
@@ -135,15 +146,16 @@ func (m *StartupManager) GetStartupItems() ([]StartupItem, error) {
 
 // DisableStartupItem disables a startup item by moving it to a disabled subkey or renaming it.
 func (m *StartupManager) DisableStartupItem(item StartupItem) error {
+	rec := journal.For("startup", "disable_item")
 	switch item.Location {
 	case "registry_hkcu":
-		return m.disableRegistryItem(registry.CURRENT_USER, item)
+		return m.disableRegistryItem(rec, reg.CurrentUser, item)
 	case "registry_hklm":
-		return m.disableRegistryItem(registry.LOCAL_MACHINE, item)
+		return m.disableRegistryItem(rec, reg.LocalMachine, item)
 	case "startup_folder":
-		return m.disableStartupFolderItem(item)
+		return m.disableStartupFolderItem(rec, item)
 	case "task_scheduler":
-		return m.disableScheduledTask(item)
+		return m.disableScheduledTask(rec, item)
 	default:
 		return fmt.Errorf("unknown location: %s", item.Location)
 	}
@@ -151,15 +163,16 @@ func (m *StartupManager) DisableStartupItem(item StartupItem) error {
 
 // EnableStartupItem re-enables a previously disabled startup item.
 func (m *StartupManager) EnableStartupItem(item StartupItem) error {
+	rec := journal.For("startup", "enable_item")
 	switch item.Location {
 	case "registry_hkcu":
-		return m.enableRegistryItem(registry.CURRENT_USER, item)
+		return m.enableRegistryItem(rec, reg.CurrentUser, item)
 	case "registry_hklm":
-		return m.enableRegistryItem(registry.LOCAL_MACHINE, item)
+		return m.enableRegistryItem(rec, reg.LocalMachine, item)
 	case "startup_folder":
-		return m.enableStartupFolderItem(item)
+		return m.enableStartupFolderItem(rec, item)
 	case "task_scheduler":
-		return m.enableScheduledTask(item)
+		return m.enableScheduledTask(rec, item)
 	default:
 		return fmt.Errorf("unknown location: %s", item.Location)
 	}
@@ -173,7 +186,9 @@ func (m *StartupManager) EstimateImpact(path string) string {
 
 	// Extract executable name from path (may include arguments)
 	exePath := extractExePath(path)
-	exeName := strings.ToLower(filepath.Base(exePath))
+	// Startup entries are Windows paths; normalise separators so the base name
+	// is extracted the same way on every host.
+	exeName := strings.ToLower(filepath.Base(strings.ReplaceAll(exePath, `\`, "/")))
 
 	// Check known impact map
 	if impact, ok := knownImpact[exeName]; ok {
@@ -201,8 +216,8 @@ func (m *StartupManager) EstimateImpact(path string) string {
 
 // ---------- Registry reading ----------
 
-func (m *StartupManager) readRegistryRun(root registry.Key, keyPath, location string, enabled bool) ([]StartupItem, error) {
-	key, err := registry.OpenKey(root, keyPath, registry.QUERY_VALUE)
+func (m *StartupManager) readRegistryRun(root reg.Root, keyPath, location string, enabled bool) ([]StartupItem, error) {
+	key, err := registry.OpenKey(root, keyPath, reg.QueryValue)
 	if err != nil {
 		return nil, err
 	}
@@ -282,7 +297,7 @@ func (m *StartupManager) readStartupFolder() ([]StartupItem, error) {
 // ---------- Task Scheduler reading ----------
 
 func (m *StartupManager) readTaskSchedulerStartup() ([]StartupItem, error) {
-	out, err := cmd.Hidden("schtasks", "/query", "/fo", "CSV", "/nh").CombinedOutput()
+	out, err := runner.Command(context.Background(), "schtasks", "/query", "/fo", "CSV", "/nh").CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("schtasks failed: %w", err)
 	}
@@ -342,13 +357,13 @@ func isStartupTask(taskName string) bool {
 
 // ---------- Disable / Enable registry ----------
 
-func (m *StartupManager) disableRegistryItem(root registry.Key, item StartupItem) error {
+func (m *StartupManager) disableRegistryItem(rec backup.Recorder, root reg.Root, item StartupItem) error {
 	if !item.Enabled {
 		return nil
 	}
 
 	// Read the current value
-	srcKey, err := registry.OpenKey(root, item.RegistryKey, registry.QUERY_VALUE|registry.SET_VALUE)
+	srcKey, err := registry.OpenKey(root, item.RegistryKey, reg.QueryValue|reg.SetValue)
 	if err != nil {
 		return fmt.Errorf("open source key: %w", err)
 	}
@@ -361,7 +376,13 @@ func (m *StartupManager) disableRegistryItem(root registry.Key, item StartupItem
 
 	// Write to the disabled subkey
 	disabledPath := item.RegistryKey + `\` + disabledSubkey
-	dstKey, _, err := registry.CreateKey(root, disabledPath, registry.ALL_ACCESS)
+	if err := rec.RegistryValue(root, item.RegistryKey, item.RegistryValue); err != nil {
+		return err
+	}
+	if err := rec.RegistryValue(root, disabledPath, item.RegistryValue); err != nil {
+		return err
+	}
+	dstKey, _, err := registry.CreateKey(root, disabledPath, reg.AllAccess)
 	if err != nil {
 		return fmt.Errorf("create disabled key: %w", err)
 	}
@@ -372,7 +393,7 @@ func (m *StartupManager) disableRegistryItem(root registry.Key, item StartupItem
 	dstKey.Close()
 
 	// Delete from the original key
-	srcKey2, err := registry.OpenKey(root, item.RegistryKey, registry.SET_VALUE)
+	srcKey2, err := registry.OpenKey(root, item.RegistryKey, reg.SetValue)
 	if err != nil {
 		return fmt.Errorf("reopen source key: %w", err)
 	}
@@ -381,7 +402,7 @@ func (m *StartupManager) disableRegistryItem(root registry.Key, item StartupItem
 	return srcKey2.DeleteValue(item.RegistryValue)
 }
 
-func (m *StartupManager) enableRegistryItem(root registry.Key, item StartupItem) error {
+func (m *StartupManager) enableRegistryItem(rec backup.Recorder, root reg.Root, item StartupItem) error {
 	if item.Enabled {
 		return nil
 	}
@@ -394,7 +415,13 @@ func (m *StartupManager) enableRegistryItem(root registry.Key, item StartupItem)
 		disabledPath = item.RegistryKey + `\` + disabledSubkey
 	}
 
-	srcKey, err := registry.OpenKey(root, disabledPath, registry.QUERY_VALUE|registry.SET_VALUE)
+	// Determine the original (enabled) key path
+	enabledPath := item.RegistryKey
+	if strings.HasSuffix(enabledPath, `\`+disabledSubkey) {
+		enabledPath = strings.TrimSuffix(enabledPath, `\`+disabledSubkey)
+	}
+
+	srcKey, err := registry.OpenKey(root, disabledPath, reg.QueryValue|reg.SetValue)
 	if err != nil {
 		return fmt.Errorf("open disabled key: %w", err)
 	}
@@ -405,18 +432,21 @@ func (m *StartupManager) enableRegistryItem(root registry.Key, item StartupItem)
 		return fmt.Errorf("read disabled value: %w", err)
 	}
 
+	if err := rec.RegistryValue(root, disabledPath, item.RegistryValue); err != nil {
+		srcKey.Close()
+		return err
+	}
+	if err := rec.RegistryValue(root, enabledPath, item.RegistryValue); err != nil {
+		srcKey.Close()
+		return err
+	}
+
 	// Delete from disabled
 	_ = srcKey.DeleteValue(item.RegistryValue)
 	srcKey.Close()
 
-	// Determine the original (enabled) key path
-	enabledPath := item.RegistryKey
-	if strings.HasSuffix(enabledPath, `\`+disabledSubkey) {
-		enabledPath = strings.TrimSuffix(enabledPath, `\`+disabledSubkey)
-	}
-
 	// Write back to the original key
-	dstKey, _, err := registry.CreateKey(root, enabledPath, registry.ALL_ACCESS)
+	dstKey, _, err := registry.CreateKey(root, enabledPath, reg.AllAccess)
 	if err != nil {
 		return fmt.Errorf("open original key: %w", err)
 	}
@@ -427,15 +457,18 @@ func (m *StartupManager) enableRegistryItem(root registry.Key, item StartupItem)
 
 // ---------- Disable / Enable startup folder ----------
 
-func (m *StartupManager) disableStartupFolderItem(item StartupItem) error {
+func (m *StartupManager) disableStartupFolderItem(rec backup.Recorder, item StartupItem) error {
 	if !item.Enabled {
 		return nil
 	}
 	newPath := item.Path + ".disabled"
+	if err := rec.Rename(item.Path, newPath); err != nil {
+		return err
+	}
 	return os.Rename(item.Path, newPath)
 }
 
-func (m *StartupManager) enableStartupFolderItem(item StartupItem) error {
+func (m *StartupManager) enableStartupFolderItem(rec backup.Recorder, item StartupItem) error {
 	if item.Enabled {
 		return nil
 	}
@@ -445,17 +478,26 @@ func (m *StartupManager) enableStartupFolderItem(item StartupItem) error {
 		originalPath = originalPath + ".disabled"
 	}
 	newPath := strings.TrimSuffix(originalPath, ".disabled")
+	if err := rec.Rename(originalPath, newPath); err != nil {
+		return err
+	}
 	return os.Rename(originalPath, newPath)
 }
 
 // ---------- Disable / Enable task scheduler ----------
 
-func (m *StartupManager) disableScheduledTask(item StartupItem) error {
-	return cmd.Hidden("schtasks", "/Change", "/TN", item.Path, "/Disable").Run()
+func (m *StartupManager) disableScheduledTask(rec backup.Recorder, item StartupItem) error {
+	if err := rec.Task(item.Path, item.Enabled); err != nil {
+		return err
+	}
+	return runner.Command(context.Background(), "schtasks", "/Change", "/TN", item.Path, "/Disable").Run()
 }
 
-func (m *StartupManager) enableScheduledTask(item StartupItem) error {
-	return cmd.Hidden("schtasks", "/Change", "/TN", item.Path, "/Enable").Run()
+func (m *StartupManager) enableScheduledTask(rec backup.Recorder, item StartupItem) error {
+	if err := rec.Task(item.Path, item.Enabled); err != nil {
+		return err
+	}
+	return runner.Command(context.Background(), "schtasks", "/Change", "/TN", item.Path, "/Enable").Run()
 }
 
 // ---------- Helpers ----------
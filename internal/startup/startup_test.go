@@ -1,9 +1,31 @@
 package startup
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"cleanforge/internal/backup"
+	"cleanforge/internal/cmd"
+	"cleanforge/internal/reg"
 )
 
+// useMemoryRegistry swaps the package registry for a reg.Memory seeded from
+// the given .reg fixture, and the journal for one in a temp directory backed
+// by it, for the duration of the test.
+func useMemoryRegistry(t *testing.T, fixture string) *reg.Memory {
+	t.Helper()
+	mem, err := reg.FromReg(fixture)
+	if err != nil {
+		t.Fatalf("load registry fixture: %v", err)
+	}
+	prevRegistry, prevJournal := registry, journal
+	registry = mem
+	journal = backup.NewJournal(t.TempDir(), mem, cmd.NewFake())
+	t.Cleanup(func() { registry, journal = prevRegistry, prevJournal })
+	return mem
+}
+
 func TestNewStartupManager(t *testing.T) {
 	sm := NewStartupManager()
 	if sm == nil {
@@ -321,3 +343,111 @@ func TestIsStartupTask(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistryItemDisableEnable(t *testing.T) {
+	const runKey = `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`
+	useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\SOFTWARE\Microsoft\Windows\CurrentVersion\Run]
+"Discord"="C:\\Users\\test\\AppData\\Local\\Discord\\Update.exe --processStart Discord.exe"
+"Spotify"="C:\\Program Files\\Spotify\\Spotify.exe"
+`)
+	sm := NewStartupManager()
+
+	items, err := sm.readRegistryRun(reg.CurrentUser, runKey, "registry_hkcu", true)
+	if err != nil {
+		t.Fatalf("readRegistryRun: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	var spotify StartupItem
+	for _, it := range items {
+		if it.Name == "Spotify" {
+			spotify = it
+		}
+	}
+	if spotify.Impact != "medium" {
+		t.Errorf("Spotify impact = %q, want %q", spotify.Impact, "medium")
+	}
+
+	if err := sm.DisableStartupItem(spotify); err != nil {
+		t.Fatalf("DisableStartupItem: %v", err)
+	}
+	enabled, _ := sm.readRegistryRun(reg.CurrentUser, runKey, "registry_hkcu", true)
+	disabled, _ := sm.readRegistryRun(reg.CurrentUser, runKey+`\`+disabledSubkey, "registry_hkcu", false)
+	if len(enabled) != 1 || len(disabled) != 1 || disabled[0].Name != "Spotify" {
+		t.Fatalf("after disable: enabled=%v disabled=%v", enabled, disabled)
+	}
+	if disabled[0].Path != spotify.Path {
+		t.Errorf("disabled path = %q, want %q", disabled[0].Path, spotify.Path)
+	}
+
+	if err := sm.EnableStartupItem(disabled[0]); err != nil {
+		t.Fatalf("EnableStartupItem: %v", err)
+	}
+	enabled, _ = sm.readRegistryRun(reg.CurrentUser, runKey, "registry_hkcu", true)
+	disabled, _ = sm.readRegistryRun(reg.CurrentUser, runKey+`\`+disabledSubkey, "registry_hkcu", false)
+	if len(enabled) != 2 || len(disabled) != 0 {
+		t.Errorf("after enable: enabled=%v disabled=%v", enabled, disabled)
+	}
+}
+
+func TestDisableRegistryItemUndoneByJournal(t *testing.T) {
+	const runKey = `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`
+	useMemoryRegistry(t, `
+[HKEY_CURRENT_USER\SOFTWARE\Microsoft\Windows\CurrentVersion\Run]
+"Spotify"="C:\\Program Files\\Spotify\\Spotify.exe"
+`)
+	sm := NewStartupManager()
+
+	items, _ := sm.readRegistryRun(reg.CurrentUser, runKey, "registry_hkcu", true)
+	if len(items) != 1 {
+		t.Fatalf("got %d items, want 1", len(items))
+	}
+	if err := sm.DisableStartupItem(items[0]); err != nil {
+		t.Fatalf("DisableStartupItem: %v", err)
+	}
+
+	entries, err := journal.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	for _, e := range entries {
+		if e.Module != "startup" || e.Tweak != "disable_item" {
+			t.Errorf("entry %s attributed to %q/%q", e.Target, e.Module, e.Tweak)
+		}
+	}
+
+	if err := journal.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	enabled, _ := sm.readRegistryRun(reg.CurrentUser, runKey, "registry_hkcu", true)
+	disabled, _ := sm.readRegistryRun(reg.CurrentUser, runKey+`\`+disabledSubkey, "registry_hkcu", false)
+	if len(enabled) != 1 || len(disabled) != 0 {
+		t.Errorf("after restore: enabled=%v disabled=%v", enabled, disabled)
+	}
+}
+
+func TestDisableStartupFolderItemUndoneByJournal(t *testing.T) {
+	useMemoryRegistry(t, "")
+	shortcut := filepath.Join(t.TempDir(), "App.lnk")
+	if err := os.WriteFile(shortcut, []byte("lnk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sm := NewStartupManager()
+	item := StartupItem{Name: "App", Path: shortcut, Location: "startup_folder", Enabled: true}
+	if err := sm.DisableStartupItem(item); err != nil {
+		t.Fatalf("DisableStartupItem: %v", err)
+	}
+	if _, err := os.Stat(shortcut + ".disabled"); err != nil {
+		t.Fatalf("shortcut was not renamed: %v", err)
+	}
+
+	if err := journal.RestoreAll(); err != nil {
+		t.Fatalf("RestoreAll: %v", err)
+	}
+	if _, err := os.Stat(shortcut); err != nil {
+		t.Errorf("shortcut was not moved back: %v", err)
+	}
+}
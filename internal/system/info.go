@@ -1,6 +1,7 @@
 package system
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 	"strings"
@@ -14,6 +15,9 @@ import (
 	"github.com/shirou/gopsutil/v4/mem"
 )
 
+// runner executes external commands. Tests replace it with a cmd.Fake.
+var runner cmd.Runner = cmd.Default
+
 // staticCache holds hardware info that never changes during a session.
 var (
 	staticOnce  sync.Once
@@ -241,7 +245,7 @@ func GetGPUInfo() (name string, driver string) {
 		return "", ""
 	}
 
-	out, err := cmd.Hidden("wmic", "path", "win32_VideoController", "get", "Name,DriverVersion", "/format:csv").Output()
+	out, err := runner.Command(context.Background(), "wmic", "path", "win32_VideoController", "get", "Name,DriverVersion", "/format:csv").Output()
 	if err != nil {
 		return "", ""
 	}
@@ -338,7 +342,7 @@ func GetRAMModules() []RAMModule {
 		return nil
 	}
 
-	out, err := cmd.Hidden("wmic", "memorychip", "get", "Manufacturer,Capacity,Speed,PartNumber,DeviceLocator,FormFactor,BankLabel", "/format:csv").Output()
+	out, err := runner.Command(context.Background(), "wmic", "memorychip", "get", "Manufacturer,Capacity,Speed,PartNumber,DeviceLocator,FormFactor,BankLabel", "/format:csv").Output()
 	if err != nil {
 		return nil
 	}
@@ -418,7 +422,7 @@ func GetGPUDetails() []GPUDetail {
 		"$($_.Name)|$($_.DriverVersion)|$vram"
 	}`
 
-	out, err := cmd.Hidden("powershell", "-NoProfile", "-Command", psScript).Output()
+	out, err := runner.Command(context.Background(), "powershell", "-NoProfile", "-Command", psScript).Output()
 	if err != nil {
 		return getGPUDetailsFallback()
 	}
@@ -451,7 +455,7 @@ func GetGPUDetails() []GPUDetail {
 
 // getGPUDetailsFallback uses wmic (AdapterRAM is 32-bit, caps at ~4 GB).
 func getGPUDetailsFallback() []GPUDetail {
-	out, err := cmd.Hidden("wmic", "path", "win32_VideoController", "get", "Name,DriverVersion,AdapterRAM", "/format:csv").Output()
+	out, err := runner.Command(context.Background(), "wmic", "path", "win32_VideoController", "get", "Name,DriverVersion,AdapterRAM", "/format:csv").Output()
 	if err != nil {
 		return nil
 	}
@@ -486,7 +490,7 @@ func GetPhysicalDisks() []PhysDisk {
 		return nil
 	}
 
-	out, err := cmd.Hidden("wmic", "diskdrive", "get", "Model,Size,MediaType,InterfaceType", "/format:csv").Output()
+	out, err := runner.Command(context.Background(), "wmic", "diskdrive", "get", "Model,Size,MediaType,InterfaceType", "/format:csv").Output()
 	if err != nil {
 		return nil
 	}
@@ -521,6 +525,22 @@ func GetPhysicalDisks() []PhysDisk {
 	return disks
 }
 
+// lowDiskFreePercent is the free space below which a disk costs the health
+// score the most and is reported by LowDisks.
+const lowDiskFreePercent = 10
+
+// LowDisks returns the disks with less than 10% free space, the ones the
+// health score is penalized most for.
+func LowDisks(info *SystemInfo) []DiskInfo {
+	var low []DiskInfo
+	for _, d := range info.Disks {
+		if 100.0-d.UsagePercent < lowDiskFreePercent {
+			low = append(low, d)
+		}
+	}
+	return low
+}
+
 // CalculateHealthScore computes a system health score from 0 to 100 based on
 // CPU usage, RAM usage, disk free space, and system uptime.
 func CalculateHealthScore(info *SystemInfo) int {
@@ -544,7 +564,7 @@ func CalculateHealthScore(info *SystemInfo) int {
 	worstDiskPenalty := 0
 	for _, d := range info.Disks {
 		freePercent := 100.0 - d.UsagePercent
-		if freePercent < 10 {
+		if freePercent < lowDiskFreePercent {
 			if worstDiskPenalty < 25 {
 				worstDiskPenalty = 25
 			}
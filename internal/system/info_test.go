@@ -179,6 +179,20 @@ func TestCalculateHealthScoreNeverNegative(t *testing.T) {
 	}
 }
 
+func TestLowDisks(t *testing.T) {
+	info := &SystemInfo{
+		Disks: []DiskInfo{
+			{Drive: "C:", UsagePercent: 95},
+			{Drive: "D:", UsagePercent: 85},
+			{Drive: "E:", UsagePercent: 90},
+		},
+	}
+	low := LowDisks(info)
+	if len(low) != 1 || low[0].Drive != "C:" {
+		t.Errorf("LowDisks = %+v, want only C:", low)
+	}
+}
+
 func TestFormatUptime(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -1,6 +1,7 @@
 package toolkit
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,9 @@ import (
 	"golang.org/x/sys/windows"
 )
 
+// runner executes external commands. Tests replace it with a cmd.Fake.
+var runner cmd.Runner = cmd.Default
+
 // ToolResult holds the outcome of a system tool operation.
 type ToolResult struct {
 	Name    string   `json:"name"`
@@ -97,7 +101,7 @@ func RunSFC() (*ToolResult, error) {
 		return result, nil
 	}
 
-	out, err := cmd.Hidden("sfc", "/scannow").CombinedOutput()
+	out, err := runner.Command(context.Background(), "sfc", "/scannow").CombinedOutput()
 	output := strings.TrimSpace(string(out))
 	result.Output = output
 
@@ -125,7 +129,7 @@ func RunDISM() (*ToolResult, error) {
 		return result, nil
 	}
 
-	out, err := cmd.Hidden("DISM", "/Online", "/Cleanup-Image", "/RestoreHealth").CombinedOutput()
+	out, err := runner.Command(context.Background(), "DISM", "/Online", "/Cleanup-Image", "/RestoreHealth").CombinedOutput()
 	output := strings.TrimSpace(string(out))
 	result.Output = output
 
@@ -144,7 +148,7 @@ func RunDISM() (*ToolResult, error) {
 func GetBloatwareApps() ([]BloatwareApp, error) {
 	// Query all installed AppX packages via PowerShell
 	psCmd := `Get-AppxPackage | Select-Object Name, PackageFullName, Publisher | ConvertTo-Csv -NoTypeInformation`
-	out, err := cmd.Hidden("powershell", "-NoProfile", "-NonInteractive", "-Command", psCmd).CombinedOutput()
+	out, err := runner.Command(context.Background(), "powershell", "-NoProfile", "-NonInteractive", "-Command", psCmd).CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("failed to query AppX packages: %s - %w", strings.TrimSpace(string(out)), err)
 	}
@@ -219,7 +223,7 @@ func RemoveBloatware(packageNames []string) (*ToolResult, error) {
 
 	for _, pkgName := range packageNames {
 		psCmd := fmt.Sprintf(`Get-AppxPackage -Name "%s" | Remove-AppxPackage`, pkgName)
-		out, err := cmd.Hidden("powershell", "-NoProfile", "-NonInteractive", "-Command", psCmd).CombinedOutput()
+		out, err := runner.Command(context.Background(), "powershell", "-NoProfile", "-NonInteractive", "-Command", psCmd).CombinedOutput()
 		output := strings.TrimSpace(string(out))
 
 		if err != nil {
@@ -258,7 +262,7 @@ func RepairWindowsUpdate() (*ToolResult, error) {
 	var errors []string
 
 	// Stop the Windows Update service
-	out, err := cmd.Hidden("net", "stop", "wuauserv").CombinedOutput()
+	out, err := runner.Command(context.Background(), "net", "stop", "wuauserv").CombinedOutput()
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Failed to stop wuauserv: %s - %s", err.Error(), strings.TrimSpace(string(out))))
 	} else {
@@ -266,7 +270,7 @@ func RepairWindowsUpdate() (*ToolResult, error) {
 	}
 
 	// Stop BITS service
-	out, err = cmd.Hidden("net", "stop", "bits").CombinedOutput()
+	out, err = runner.Command(context.Background(), "net", "stop", "bits").CombinedOutput()
 	if err != nil {
 		// BITS might not be running; non-critical
 		outputs = append(outputs, fmt.Sprintf("BITS service: %s", strings.TrimSpace(string(out))))
@@ -286,7 +290,7 @@ func RepairWindowsUpdate() (*ToolResult, error) {
 	}
 
 	// Restart the Windows Update service
-	out, err = cmd.Hidden("net", "start", "wuauserv").CombinedOutput()
+	out, err = runner.Command(context.Background(), "net", "start", "wuauserv").CombinedOutput()
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Failed to start wuauserv: %s - %s", err.Error(), strings.TrimSpace(string(out))))
 	} else {
@@ -294,7 +298,7 @@ func RepairWindowsUpdate() (*ToolResult, error) {
 	}
 
 	// Restart BITS service
-	out, err = cmd.Hidden("net", "start", "bits").CombinedOutput()
+	out, err = runner.Command(context.Background(), "net", "start", "bits").CombinedOutput()
 	if err != nil {
 		outputs = append(outputs, fmt.Sprintf("BITS restart: %s", strings.TrimSpace(string(out))))
 	} else {
@@ -323,7 +327,7 @@ func RebuildIconCache() (*ToolResult, error) {
 	cacheDir := filepath.Join(localAppData, "Microsoft", "Windows", "Explorer")
 
 	// Kill explorer.exe first to release file handles
-	out, err := cmd.Hidden("taskkill", "/f", "/im", "explorer.exe").CombinedOutput()
+	out, err := runner.Command(context.Background(), "taskkill", "/f", "/im", "explorer.exe").CombinedOutput()
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Failed to kill explorer.exe: %s - %s", err.Error(), strings.TrimSpace(string(out))))
 	} else {
@@ -355,7 +359,7 @@ func RebuildIconCache() (*ToolResult, error) {
 	}
 
 	// Restart explorer.exe
-	err = cmd.Hidden("cmd", "/c", "start", "explorer.exe").Start()
+	err = runner.Command(context.Background(), "cmd", "/c", "start", "explorer.exe").Start()
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Failed to restart explorer.exe: %s", err.Error()))
 	} else {
@@ -387,7 +391,7 @@ func RebuildFontCache() (*ToolResult, error) {
 	var errors []string
 
 	// Stop Font Cache service
-	out, err := cmd.Hidden("net", "stop", "FontCache").CombinedOutput()
+	out, err := runner.Command(context.Background(), "net", "stop", "FontCache").CombinedOutput()
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Failed to stop FontCache: %s - %s", err.Error(), strings.TrimSpace(string(out))))
 	} else {
@@ -395,7 +399,7 @@ func RebuildFontCache() (*ToolResult, error) {
 	}
 
 	// Also stop the Font Cache 3.0.0.0 service
-	_, err = cmd.Hidden("net", "stop", "FontCache3.0.0.0").CombinedOutput()
+	_, err = runner.Command(context.Background(), "net", "stop", "FontCache3.0.0.0").CombinedOutput()
 	if err != nil {
 		// This service might not exist on all systems; non-critical
 		outputs = append(outputs, "FontCache 3.0.0.0 service not found or already stopped")
@@ -438,7 +442,7 @@ func RebuildFontCache() (*ToolResult, error) {
 	}
 
 	// Restart Font Cache service
-	out, err = cmd.Hidden("net", "start", "FontCache").CombinedOutput()
+	out, err = runner.Command(context.Background(), "net", "start", "FontCache").CombinedOutput()
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Failed to start FontCache: %s - %s", err.Error(), strings.TrimSpace(string(out))))
 	} else {
@@ -470,7 +474,7 @@ func ResetWindowsSearch() (*ToolResult, error) {
 	var errors []string
 
 	// Stop Windows Search service
-	out, err := cmd.Hidden("net", "stop", "WSearch").CombinedOutput()
+	out, err := runner.Command(context.Background(), "net", "stop", "WSearch").CombinedOutput()
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Failed to stop WSearch: %s - %s", err.Error(), strings.TrimSpace(string(out))))
 	} else {
@@ -493,7 +497,7 @@ func ResetWindowsSearch() (*ToolResult, error) {
 	}
 
 	// Restart Windows Search service
-	out, err = cmd.Hidden("net", "start", "WSearch").CombinedOutput()
+	out, err = runner.Command(context.Background(), "net", "start", "WSearch").CombinedOutput()
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Failed to start WSearch: %s - %s", err.Error(), strings.TrimSpace(string(out))))
 	} else {
@@ -3,6 +3,7 @@ package main
 import (
 	"embed"
 	"os"
+	"strings"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -14,12 +15,21 @@ import (
 var assets embed.FS
 
 func main() {
-	// Check for CLI mode
+	// Check for CLI or daemon mode
 	for _, arg := range os.Args[1:] {
 		if arg == "--cli" || arg == "-c" {
 			runCLI()
 			return
 		}
+		if arg == "--daemon" {
+			os.Exit(runDaemon())
+		}
+	}
+	// Any other command runs without the window
+	if len(os.Args) > 1 {
+		if arg := os.Args[1]; arg == "-h" || arg == "--help" || !strings.HasPrefix(arg, "-") {
+			os.Exit(runCommand(os.Args[1:]))
+		}
 	}
 
 	app := NewApp()